@@ -17,8 +17,8 @@ package config
 import "github.com/redpanda-data/benthos/v4/public/service"
 
 // SessionFields defines a re-usable set of config fields for an AWS session
-// that is compatible with the public service APIs and avoids importing the full
-// AWS dependencies.
+// that is compatible with the public service APIs, which GetSession constructs
+// the session from.
 func SessionFields() []*service.ConfigField {
 	return []*service.ConfigField{
 		service.NewStringField("region").
@@ -57,8 +57,6 @@ const (
 	s3iFieldForcePathStyleURLs       = "force_path_style_urls"
 	s3iFieldDeleteObjects            = "delete_objects"
 	s3iFieldSQS                      = "sqs"
-	s3iBookmarksSection              = "bookmarks_file"
-	s3iBookmarksFilePath             = "path"
 	siFieldWatcher                   = "watcher"
 	siFieldWatcherPollInterval       = "poll_interval"
 	siFieldSequentialBatchingSupport = "sequential_batching"
@@ -110,7 +108,6 @@ type s3iConfig struct {
 	DeleteObjects                    bool
 	SQS                              s3iSQSConfig
 	CodecCtor                        codec.DeprecatedFallbackCodec
-	BookmarkFilePath                 string
 	WatcherPollInterval              time.Duration
 	SequentialBatchingProcessingFlag bool
 }
@@ -137,11 +134,6 @@ func s3iConfigFromParsed(pConf *service.ParsedConfig) (conf s3iConfig, err error
 		}
 	}
 
-	bfc := pConf.Namespace(s3iBookmarksSection)
-	if conf.BookmarkFilePath, err = bfc.FieldString(s3iBookmarksFilePath); err != nil {
-		return
-	}
-
 	wConf := pConf.Namespace(siFieldWatcher)
 
 	if conf.WatcherPollInterval, err = wConf.FieldDuration(siFieldWatcherPollInterval); err != nil {
@@ -277,9 +269,14 @@ func init() {
 				return nil, err
 			}
 
+			bm, err := bookmark.BookmarkFileManagerFromParsed(pConf, bookmark.WithResources(res))
+			if err != nil {
+				return nil, err
+			}
+
 			var rdr service.BatchInput
 
-			if rdr, err = newAmazonS3Reader(conf, sess, res); err != nil {
+			if rdr, err = newAmazonS3Reader(conf, sess, bm, res); err != nil {
 				return nil, err
 			}
 
@@ -337,7 +334,7 @@ func deleteS3ObjectAckFn(
 			b, _ := bookmark.NewBookmark(bucket, key, 0)
 			bm.AddBookmark(b)
 
-			if saveBmErr := bm.SaveToFile(); saveBmErr != nil {
+			if saveBmErr := bm.RequestSave(ctx); saveBmErr != nil {
 				fmt.Printf("Error saving bookmarks: %v\n", saveBmErr)
 				return nil
 			}
@@ -820,17 +817,19 @@ type s3PendingObject struct {
 }
 
 // NewAmazonS3 creates a new Amazon S3 bucket reader.Type.
-func newAmazonS3Reader(conf s3iConfig, awsConf aws.Config, nm *service.Resources) (*awsS3Reader, error) {
+func newAmazonS3Reader(conf s3iConfig, awsConf aws.Config, bm *bookmark.BookmarkManager, nm *service.Resources) (*awsS3Reader, error) {
 	if conf.Bucket == "" && conf.SQS.URL == "" {
 		return nil, errors.New("either a bucket or an sqs.url must be specified")
 	}
 	if conf.Prefix != "" && conf.SQS.URL != "" {
 		return nil, errors.New("cannot specify both a prefix and sqs.url")
 	}
-	bm := bookmark.NewBookmarkManager(conf.BookmarkFilePath)
 
 	// Load existing bookmarks from file
-	if err := bm.LoadFromFile(); err != nil {
+	if err := bm.Open(context.Background()); err != nil {
+		if errors.Is(err, bookmark.ErrBookmarkFileLocked) {
+			return nil, err
+		}
 		fmt.Printf("Error loading bookmarks: %v\n", err)
 
 	}
@@ -1079,5 +1078,8 @@ func (a *awsS3Reader) Close(ctx context.Context) (err error) {
 		err = a.object.scanner.Close(ctx)
 		a.object = nil
 	}
+	if berr := a.bm.Close(ctx); err == nil {
+		err = berr
+	}
 	return
 }
@@ -0,0 +1,370 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// adminReadHeaderTimeout bounds how long clients of the admin API may take to
+// send their request headers
+const adminReadHeaderTimeout = 10 * time.Second
+
+// adminQueryTimeout bounds how long a query of the admin API may run
+const adminQueryTimeout = 10 * time.Second
+
+// apiShutdownTimeout bounds how long closing waits for API requests in flight
+const apiShutdownTimeout = 5 * time.Second
+
+// WithAdminAPI serves an HTTP API for inspecting and changing bookmarks on the
+// given address while the manager is open. The endpoints are:
+//
+//	GET    /health                         the health and bookmark count
+//	GET    /bookmarks?topic=<topic>        all bookmarks, optionally of a topic
+//	DELETE /bookmarks?topic=<topic>        removes the bookmarks of the group,
+//	                                       optionally of a topic
+//	GET    /bookmarks/<topic>/<partition>  a bookmark
+//	PUT    /bookmarks/<topic>/<partition>  sets a bookmark
+//	DELETE /bookmarks/<topic>/<partition>  removes a bookmark
+//	GET    /query?sql=<query>              the result of a read-only SQL query,
+//	                                       see Query
+//
+// All endpoints but /health and /query take a group query parameter selecting the
+// consumer group, which defaults to the group of the manager. The list is
+// sorted by group, topic and partition, or by descending lag with sort=lag,
+// and returns up to limit bookmarks when it's set, along with an
+// X-Next-Cursor header to pass as the cursor parameter for the next page
+// unless it's the last. Removals require a confirm=true query parameter, and
+// changes are rate limited per client, see WithAdminWriteLimit. Changes are
+// saved like those of components and are subject to the regression policy.
+// The API has no authentication beyond the client certificates of
+// WithAdminTLS, so the address should only be reachable by operators.
+func WithAdminAPI(address string) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.adminAPI = &adminServer{address: address, bm: bm}
+	}
+}
+
+// adminSetRequest is the body of a PUT request. The offset is a number or the
+// name of a sentinel offset, and omitted metadata keeps the metadata of the
+// existing bookmark.
+type adminSetRequest struct {
+	Offset    json.RawMessage        `json:"offset"`
+	Timestamp time.Time              `json:"timestamp"`
+	Metadata  map[string]interface{} `json:"metadata"`
+}
+
+type adminServer struct {
+	address string
+	bm      *BookmarkManager
+
+	mut     sync.Mutex
+	server  *http.Server
+	limiter *adminWriteLimiter
+}
+
+func (a *adminServer) start() error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.server != nil {
+		return nil
+	}
+	var tlsConf *tls.Config
+	if a.bm.adminTLS != nil {
+		var err error
+		if tlsConf, err = a.bm.adminTLS.serverConfig(); err != nil {
+			return err
+		}
+	}
+	listener, err := net.Listen("tcp", a.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on admin API address %s: %w", a.address, err)
+	}
+
+	a.server = &http.Server{Handler: a.handler(), ReadHeaderTimeout: adminReadHeaderTimeout, TLSConfig: tlsConf}
+	go func(server *http.Server) {
+		var err error
+		if server.TLSConfig != nil {
+			// The certificates are loaded into the TLS config already
+			err = server.ServeTLS(listener, "", "")
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.bm.log.Errorf("Admin API stopped: %v", err)
+		}
+	}(a.server)
+	a.bm.log.Infof("Serving the bookmark admin API on %s, TLS %t", listener.Addr(), tlsConf != nil)
+	return nil
+}
+
+// handler returns the routes of the API, creating the write limiter when
+// writes are limited
+func (a *adminServer) handler() http.Handler {
+	if a.bm.adminWriteLimit > 0 {
+		a.limiter = newAdminWriteLimiter(a.bm.adminWriteLimit, a.bm.adminWriteBurst)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", a.handleHealth)
+	mux.HandleFunc("GET /bookmarks", a.handleList)
+	mux.HandleFunc("DELETE /bookmarks", a.limitWrites(a.handleDeleteAll))
+	mux.HandleFunc("GET /bookmarks/{topic}/{partition}", a.handleGet)
+	mux.HandleFunc("PUT /bookmarks/{topic}/{partition}", a.limitWrites(a.handleSet))
+	mux.HandleFunc("DELETE /bookmarks/{topic}/{partition}", a.limitWrites(a.handleDelete))
+	mux.HandleFunc("GET /query", a.handleQuery)
+	return a.withCORS(mux)
+}
+
+func (a *adminServer) close(ctx context.Context) {
+	a.mut.Lock()
+	server := a.server
+	a.server = nil
+	a.mut.Unlock()
+	if server == nil {
+		return
+	}
+
+	if err := server.Shutdown(ctx); err != nil {
+		_ = server.Close()
+	}
+}
+
+func (a *adminServer) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	writeAdminJSON(w, http.StatusOK, map[string]any{
+		"status":    "ok",
+		"file":      a.bm.filePath,
+		"bookmarks": a.bm.Count(),
+	})
+}
+
+// group returns the consumer group selected by the group query parameter
+func (a *adminServer) group(r *http.Request) string {
+	if group := r.URL.Query().Get("group"); group != "" {
+		return group
+	}
+	return a.bm.group
+}
+
+func (a *adminServer) handleList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := parseAdminPage(query)
+	if err != nil {
+		writeAdminJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var bookmarks []*Bookmark
+	if !query.Has("topic") && !query.Has("group") {
+		bookmarks = a.bm.GetAllBookmarks()
+	} else {
+		bookmarks = a.bm.GetBookmarksByGroup(a.group(r))
+	}
+	if topic := query.Get("topic"); topic != "" {
+		filtered := bookmarks[:0]
+		for _, b := range bookmarks {
+			if b.Topic == topic {
+				filtered = append(filtered, b)
+			}
+		}
+		bookmarks = filtered
+	}
+
+	bookmarks, next := page.apply(a.bm, bookmarks)
+	if next != "" {
+		w.Header().Set(adminNextCursorHeader, next)
+	}
+	writeAdminJSON(w, http.StatusOK, bookmarks)
+}
+
+func (a *adminServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	bookmark, err := a.bm.GetGroupBookmark(a.group(r), r.PathValue("topic"), r.PathValue("partition"))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, bookmark)
+}
+
+func (a *adminServer) handleSet(w http.ResponseWriter, r *http.Request) {
+	var req adminSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAdminJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+	if len(req.Offset) == 0 {
+		writeAdminJSON(w, http.StatusBadRequest, map[string]string{"error": "offset is required"})
+		return
+	}
+	offset, err := ParseOffset(strings.Trim(string(req.Offset), `"`))
+	if err != nil {
+		writeAdminJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	group, topic, partition := a.group(r), r.PathValue("topic"), r.PathValue("partition")
+	if req.Metadata == nil {
+		if existing, err := a.bm.GetGroupBookmark(group, topic, partition); err == nil {
+			req.Metadata = existing.Metadata
+		}
+	}
+	if req.Timestamp.IsZero() {
+		req.Timestamp = time.Now()
+	}
+	bookmark, err := NewBookmarkWithTimestamp(topic, partition, offset, req.Timestamp, req.Metadata)
+	if err != nil {
+		writeAdminJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	bookmark.Group = group
+	if err := a.bm.AddBookmark(bookmark); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if err := a.bm.RequestSave(r.Context()); err != nil {
+		writeAdminJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("bookmark was set but failed to save: %v", err)})
+		return
+	}
+	a.bm.log.Infof("Admin API set bookmark %s to offset %s", bookmark.TopicPartition(), FormatOffset(offset))
+	writeAdminJSON(w, http.StatusOK, bookmark)
+}
+
+// confirmed returns true if a destructive request carries confirm=true, and
+// rejects it otherwise
+func confirmed(w http.ResponseWriter, r *http.Request) bool {
+	if r.URL.Query().Get("confirm") == "true" {
+		return true
+	}
+	writeAdminJSON(w, http.StatusBadRequest, map[string]string{"error": "removing bookmarks requires the confirm=true query parameter"})
+	return false
+}
+
+func (a *adminServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if !confirmed(w, r) {
+		return
+	}
+	key := NewGroupTopicPartition(a.group(r), r.PathValue("topic"), r.PathValue("partition"))
+	if err := a.bm.RemoveGroupBookmark(key.Group, key.Topic, key.Partition); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if err := a.bm.RequestSave(r.Context()); err != nil {
+		writeAdminJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("bookmark was removed but failed to save: %v", err)})
+		return
+	}
+	a.bm.log.Infof("Admin API removed bookmark %s", key)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *adminServer) handleDeleteAll(w http.ResponseWriter, r *http.Request) {
+	if !confirmed(w, r) {
+		return
+	}
+	group, topic := a.group(r), r.URL.Query().Get("topic")
+
+	removed := 0
+	for _, b := range a.bm.GetBookmarksByGroup(group) {
+		if topic != "" && b.Topic != topic {
+			continue
+		}
+		if err := a.bm.RemoveGroupBookmark(group, b.Topic, b.Partition); err != nil && !errors.Is(err, ErrBookmarkNotFound) {
+			writeAdminError(w, err)
+			return
+		}
+		removed++
+	}
+	if err := a.bm.RequestSave(r.Context()); err != nil {
+		writeAdminJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("bookmarks were removed but failed to save: %v", err)})
+		return
+	}
+	a.bm.log.Infof("Admin API removed %d bookmarks of group %q and topic %q", removed, group, topic)
+	writeAdminJSON(w, http.StatusOK, map[string]int{"removed": removed})
+}
+
+func (a *adminServer) handleQuery(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("sql")
+	if query == "" {
+		writeAdminJSON(w, http.StatusBadRequest, map[string]string{"error": "the sql query parameter is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), adminQueryTimeout)
+	defer cancel()
+
+	result, err := a.bm.Query(ctx, query)
+	if err != nil {
+		writeAdminJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, result)
+}
+
+// writeAdminError writes err with the status matching its cause
+func writeAdminError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, ErrBookmarkNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrOffsetRegression), errors.Is(err, ErrFencedLeaderEpoch):
+		status = http.StatusConflict
+	}
+	writeAdminJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// startAPIs starts the admin and gRPC APIs when configured
+func (bm *BookmarkManager) startAPIs() error {
+	if bm.adminAPI != nil {
+		if err := bm.adminAPI.start(); err != nil {
+			return err
+		}
+	}
+	if bm.grpcAPI != nil {
+		if err := bm.grpcAPI.start(); err != nil {
+			bm.stopAPIs(context.Background())
+			return err
+		}
+	}
+	return nil
+}
+
+// stopAPIs stops the admin and gRPC APIs when configured, waiting for requests
+// in flight
+func (bm *BookmarkManager) stopAPIs(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, apiShutdownTimeout)
+	defer cancel()
+
+	if bm.adminAPI != nil {
+		bm.adminAPI.close(ctx)
+	}
+	if bm.grpcAPI != nil {
+		bm.grpcAPI.close(ctx)
+	}
+}
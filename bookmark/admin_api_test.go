@@ -0,0 +1,198 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// newTestAdminServer serves the admin API of a manager with a bookmark file in
+// a temporary directory
+func newTestAdminServer(t *testing.T, opts ...ManagerOption) (*BookmarkManager, *httptest.Server) {
+	t.Helper()
+
+	bm := NewBookmarkManager(filepath.Join(t.TempDir(), "bookmarks.json"), opts...)
+	a := &adminServer{bm: bm}
+	srv := httptest.NewServer(a.handler())
+	t.Cleanup(srv.Close)
+	return bm, srv
+}
+
+// adminRequest sends a request to the admin API and decodes its JSON response
+// into v unless it's nil
+func adminRequest(t *testing.T, srv *httptest.Server, method, path, body string, v any) *http.Response {
+	t.Helper()
+
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, srv.URL+path, r)
+	require.NoError(t, err)
+	res, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	if v != nil {
+		require.NoError(t, json.NewDecoder(res.Body).Decode(v))
+	}
+	return res
+}
+
+func TestAdminAPISetGetAndDelete(t *testing.T) {
+	bm, srv := newTestAdminServer(t, WithAdminWriteLimit(0, 0))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "set", method: http.MethodPut, path: "/bookmarks/orders/0", body: `{"offset":42}`, status: http.StatusOK},
+		{name: "set in a group", method: http.MethodPut, path: "/bookmarks/orders/0?group=audit", body: `{"offset":"earliest"}`, status: http.StatusOK},
+		{name: "set without an offset", method: http.MethodPut, path: "/bookmarks/orders/0", body: `{}`, status: http.StatusBadRequest},
+		{name: "get", method: http.MethodGet, path: "/bookmarks/orders/0", status: http.StatusOK},
+		{name: "get a missing bookmark", method: http.MethodGet, path: "/bookmarks/orders/9", status: http.StatusNotFound},
+		{name: "delete without confirm", method: http.MethodDelete, path: "/bookmarks/orders/0", status: http.StatusBadRequest},
+		{name: "delete", method: http.MethodDelete, path: "/bookmarks/orders/0?confirm=true", status: http.StatusNoContent},
+		{name: "delete a missing bookmark", method: http.MethodDelete, path: "/bookmarks/orders/0?confirm=true", status: http.StatusNotFound},
+		{name: "delete all without confirm", method: http.MethodDelete, path: "/bookmarks?group=audit", status: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := adminRequest(t, srv, test.method, test.path, test.body, nil)
+			assert.Equal(t, test.status, res.StatusCode)
+		})
+	}
+
+	_, err := bm.GetBookmark("orders", "0")
+	assert.ErrorIs(t, err, ErrBookmarkNotFound)
+	b, err := bm.GetGroupBookmark("audit", "orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, OffsetEarliest, b.Offset)
+
+	var removed map[string]int
+	res := adminRequest(t, srv, http.MethodDelete, "/bookmarks?group=audit&confirm=true", "", &removed)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, map[string]int{"removed": 1}, removed)
+	assert.Zero(t, bm.Count())
+}
+
+func TestAdminAPIListPages(t *testing.T) {
+	bm, srv := newTestAdminServer(t)
+	for _, b := range []*Bookmark{
+		{Topic: "orders", Partition: "0", Offset: 10, HighWatermark: 12},
+		{Topic: "orders", Partition: "1", Offset: 10, HighWatermark: 111},
+		{Topic: "orders", Partition: "2", Offset: 10},
+		{Topic: "orders", Partition: "10", Offset: 10, HighWatermark: 31},
+		{Topic: "payments", Partition: "0", Offset: 10, HighWatermark: 21},
+	} {
+		require.NoError(t, bm.AddBookmark(b))
+	}
+
+	tests := []struct {
+		name  string
+		query url.Values
+		pages [][]string
+	}{
+		{
+			name:  "by key",
+			query: url.Values{"limit": {"2"}},
+			pages: [][]string{{"orders:0", "orders:1"}, {"orders:2", "orders:10"}, {"payments:0"}},
+		},
+		{
+			name:  "by lag",
+			query: url.Values{"limit": {"2"}, "sort": {"lag"}},
+			pages: [][]string{{"orders:1", "orders:10"}, {"payments:0", "orders:0"}, {"orders:2"}},
+		},
+		{
+			name:  "of a topic",
+			query: url.Values{"topic": {"payments"}},
+			pages: [][]string{{"payments:0"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query := test.query
+			for i, expected := range test.pages {
+				var bookmarks []*Bookmark
+				res := adminRequest(t, srv, http.MethodGet, "/bookmarks?"+query.Encode(), "", &bookmarks)
+				require.Equal(t, http.StatusOK, res.StatusCode)
+
+				keys := make([]string, 0, len(bookmarks))
+				for _, b := range bookmarks {
+					keys = append(keys, b.TopicPartition().String())
+				}
+				assert.Equal(t, expected, keys, "page %d", i)
+
+				cursor := res.Header.Get(adminNextCursorHeader)
+				if i == len(test.pages)-1 {
+					assert.Empty(t, cursor, "cursor of the last page")
+					break
+				}
+				require.NotEmpty(t, cursor, "cursor of page %d", i)
+				query.Set("cursor", cursor)
+			}
+		})
+	}
+
+	res := adminRequest(t, srv, http.MethodGet, "/bookmarks?limit=0", "", nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res = adminRequest(t, srv, http.MethodGet, "/bookmarks?cursor=bogus", "", nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestAdminAPIRateLimitsWrites(t *testing.T) {
+	_, srv := newTestAdminServer(t, WithAdminWriteLimit(rate.Limit(0.001), 2))
+
+	for i := 0; i < 2; i++ {
+		res := adminRequest(t, srv, http.MethodPut, "/bookmarks/orders/0", `{"offset":1}`, nil)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+	res := adminRequest(t, srv, http.MethodPut, "/bookmarks/orders/0", `{"offset":2}`, nil)
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.NotEmpty(t, res.Header.Get("Retry-After"))
+
+	// Reads aren't limited
+	res = adminRequest(t, srv, http.MethodGet, "/bookmarks/orders/0", "", nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestAdminAPIQuery(t *testing.T) {
+	bm, srv := newTestAdminServer(t)
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 10}))
+
+	var result QueryResult
+	res := adminRequest(t, srv, http.MethodGet, "/query?"+url.Values{"sql": {"SELECT topic, offset FROM bookmarks"}}.Encode(), "", &result)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []string{"topic", "offset"}, result.Columns)
+	assert.Equal(t, [][]any{{"orders", float64(10)}}, result.Rows)
+
+	res = adminRequest(t, srv, http.MethodGet, "/query", "", nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res = adminRequest(t, srv, http.MethodGet, "/query?"+url.Values{"sql": {"DELETE FROM bookmarks"}}.Encode(), "", nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Default write rate of each admin API client
+const (
+	defaultAdminWriteLimit = rate.Limit(1)
+	defaultAdminWriteBurst = 10
+)
+
+// adminMaxIdleClients is the number of client limiters kept before those of
+// idle clients are dropped
+const adminMaxIdleClients = 1024
+
+// WithAdminWriteLimit limits the PUT and DELETE requests of the admin API to
+// limit per second with bursts of burst requests for each client address,
+// which defaults to 1 per second with bursts of 10. Requests above the limit
+// are rejected with 429 Too Many Requests. A zero limit disables it.
+func WithAdminWriteLimit(limit rate.Limit, burst int) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.adminWriteLimit = limit
+		bm.adminWriteBurst = burst
+	}
+}
+
+// adminWriteLimiter limits the rate of the writes of each client address
+type adminWriteLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mut     sync.Mutex
+	clients map[string]*rate.Limiter
+}
+
+func newAdminWriteLimiter(limit rate.Limit, burst int) *adminWriteLimiter {
+	return &adminWriteLimiter{limit: limit, burst: burst, clients: make(map[string]*rate.Limiter)}
+}
+
+// reserve takes a token of the client of a request, returning how long to
+// wait before retrying when there is none
+func (l *adminWriteLimiter) reserve(r *http.Request) (time.Duration, bool) {
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	limiter, exists := l.clients[client]
+	if !exists {
+		if len(l.clients) >= adminMaxIdleClients {
+			l.dropIdle()
+		}
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.clients[client] = limiter
+	}
+
+	now := time.Now()
+	reservation := limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay, false
+	}
+	return 0, true
+}
+
+// dropIdle drops the limiters of clients that refilled their burst, which
+// start over with a full burst anyway. The caller must hold mut.
+func (l *adminWriteLimiter) dropIdle() {
+	for client, limiter := range l.clients {
+		if limiter.Tokens() >= float64(l.burst) {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// limitWrites rejects the requests of clients exceeding the write limit
+func (a *adminServer) limitWrites(next http.HandlerFunc) http.HandlerFunc {
+	if a.limiter == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if retryAfter, ok := a.limiter.reserve(r); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			writeAdminJSON(w, http.StatusTooManyRequests, map[string]string{"error": "too many changes, slow down"})
+			return
+		}
+		next(w, r)
+	}
+}
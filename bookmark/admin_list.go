@@ -0,0 +1,145 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// adminNextCursorHeader is the response header of GET /bookmarks holding the
+// cursor of the next page, which is omitted on the last page
+const adminNextCursorHeader = "X-Next-Cursor"
+
+// Sort orders of GET /bookmarks
+const (
+	adminSortKey = "key"
+	adminSortLag = "lag"
+)
+
+// adminPage is a page of GET /bookmarks. A page holds up to limit bookmarks,
+// all when zero, following the position of the cursor returned with the
+// previous page. Cursors hold the sort key of the last bookmark of a page
+// rather than an index, so that pages stay in order when bookmarks are added
+// or removed in between. Partitions without a known lag sort last by lag.
+type adminPage struct {
+	sort   string
+	limit  int
+	cursor *adminCursor
+}
+
+// adminCursor is the position after the last bookmark of a page. It's sent
+// to clients as base64 encoded JSON, which they should treat as opaque.
+type adminCursor struct {
+	Sort      string `json:"sort"`
+	Lag       int64  `json:"lag,omitempty"`
+	Group     string `json:"group,omitempty"`
+	Topic     string `json:"topic"`
+	Partition string `json:"partition"`
+}
+
+func parseAdminPage(query url.Values) (adminPage, error) {
+	page := adminPage{sort: adminSortKey}
+	if s := query.Get("sort"); s != "" {
+		if s != adminSortKey && s != adminSortLag {
+			return page, fmt.Errorf("unknown sort order %q, expected key or lag", s)
+		}
+		page.sort = s
+	}
+	if l := query.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit <= 0 {
+			return page, fmt.Errorf("invalid limit %q, expected a positive number", l)
+		}
+		page.limit = limit
+	}
+	if c := query.Get("cursor"); c != "" {
+		cursor, err := decodeAdminCursor(c)
+		if err != nil {
+			return page, err
+		}
+		if cursor.Sort != page.sort {
+			return page, errors.New("cursor belongs to another sort order")
+		}
+		page.cursor = cursor
+	}
+	return page, nil
+}
+
+func decodeAdminCursor(s string) (*adminCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var cursor adminCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cursor, nil
+}
+
+func (c adminCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// apply sorts bookmarks, sorted by key, and returns the bookmarks of the page
+// along with the cursor of the next page, empty on the last page
+func (p adminPage) apply(bm *BookmarkManager, bookmarks []*Bookmark) ([]*Bookmark, string) {
+	lags := make(map[*Bookmark]int64, len(bookmarks))
+	if p.sort == adminSortLag {
+		for _, b := range bookmarks {
+			lags[b] = -1
+			if l, known := bm.lagOf(b); known {
+				lags[b] = l.Lag
+			}
+		}
+		sort.SliceStable(bookmarks, func(i, j int) bool {
+			return lags[bookmarks[i]] > lags[bookmarks[j]]
+		})
+	}
+
+	if p.cursor != nil {
+		start := sort.Search(len(bookmarks), func(i int) bool {
+			return p.cursor.before(bookmarks[i], lags[bookmarks[i]])
+		})
+		bookmarks = bookmarks[start:]
+	}
+	if p.limit == 0 || len(bookmarks) <= p.limit {
+		return bookmarks, ""
+	}
+
+	bookmarks = bookmarks[:p.limit]
+	last := bookmarks[len(bookmarks)-1]
+	next := adminCursor{Sort: p.sort, Group: last.Group, Topic: last.Topic, Partition: last.Partition}
+	if p.sort == adminSortLag {
+		next.Lag = lags[last]
+	}
+	return bookmarks, next.encode()
+}
+
+// before returns true if the cursor sorts before a bookmark with the given
+// lag, which is only compared when sorting by lag
+func (c *adminCursor) before(b *Bookmark, lag int64) bool {
+	if c.Sort == adminSortLag && lag != c.Lag {
+		return lag < c.Lag
+	}
+	return lessBookmark(&Bookmark{Group: c.Group, Topic: c.Topic, Partition: c.Partition}, b)
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// AdminTLSConfig configures the TLS of the admin API. Client certificates
+// are required and verified against the client CA when its file is set.
+type AdminTLSConfig struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+}
+
+// WithAdminTLS serves the admin API over TLS
+func WithAdminTLS(conf AdminTLSConfig) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.adminTLS = &conf
+	}
+}
+
+// WithAdminCORS allows web pages of the given origins, or of all with "*",
+// to call the admin API from browsers
+func WithAdminCORS(origins ...string) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.adminCORSOrigins = origins
+	}
+}
+
+// serverConfig loads the certificates of the TLS config
+func (c AdminTLSConfig) serverConfig() (*tls.Config, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, errors.New("admin API TLS requires a certificate and key file")
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load admin API certificate: %w", err)
+	}
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.ClientCAFile == "" {
+		return conf, nil
+	}
+
+	pem, err := os.ReadFile(c.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read admin API client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("admin API client CA file %s holds no certificates", c.ClientCAFile)
+	}
+	conf.ClientCAs = pool
+	conf.ClientAuth = tls.RequireAndVerifyClientCert
+	return conf, nil
+}
+
+// withCORS answers preflight requests and allows the configured origins to
+// read the responses of the API
+func (a *adminServer) withCORS(next http.Handler) http.Handler {
+	origins := a.bm.adminCORSOrigins
+	if len(origins) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || (!slices.Contains(origins, "*") && !slices.Contains(origins, origin)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Add("Vary", "Origin")
+		h.Set("Access-Control-Expose-Headers", strings.Join([]string{adminNextCursorHeader, "Retry-After"}, ", "))
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", "GET, PUT, DELETE")
+			h.Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,265 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Rules that raise alerts
+const (
+	AlertRuleStale       = "stale"
+	AlertRuleRegression  = "offset_regression"
+	AlertRuleSaveFailure = "save_failure"
+	AlertRuleLag         = "lag"
+)
+
+// Alert is the payload posted to the alert webhook. Text duplicates the
+// message for chat tools that render a text field.
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Message   string    `json:"message"`
+	Text      string    `json:"text"`
+	File      string    `json:"file"`
+	Topic     string    `json:"topic,omitempty"`
+	Partition string    `json:"partition,omitempty"`
+	Offset    *int64    `json:"offset,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// WebhookAlertConfig configures the alert rules and the webhook alerts are
+// posted to
+type WebhookAlertConfig struct {
+	URL     string
+	Headers map[string]string
+
+	// StaleAfter raises an alert for bookmarks that haven't been updated for
+	// the given period, checked every CheckInterval. A zero value disables
+	// the rule.
+	StaleAfter    time.Duration
+	CheckInterval time.Duration
+
+	OnRegression  bool
+	OnSaveFailure bool
+}
+
+// WithWebhookAlerts posts an alert to a webhook whenever one of the configured
+// rules matches. Stale bookmarks alert once until they are updated again, and
+// save failures alert once until a save succeeds again.
+func WithWebhookAlerts(conf WebhookAlertConfig) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.alerts = &webhookAlerter{
+			conf:   conf,
+			bm:     bm,
+			client: &http.Client{Timeout: eventPublishTimeout},
+		}
+	}
+}
+
+type webhookAlerter struct {
+	conf   WebhookAlertConfig
+	bm     *BookmarkManager
+	client *http.Client
+
+	mut          sync.Mutex
+	queue        chan Alert
+	stop         chan struct{}
+	done         chan struct{}
+	stale        map[TopicPartition]time.Time
+	saveFailures int
+}
+
+func (a *webhookAlerter) start() {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.queue != nil {
+		return
+	}
+	a.queue = make(chan Alert, eventQueueSize)
+	a.stop = make(chan struct{})
+	a.done = make(chan struct{})
+	a.stale = make(map[TopicPartition]time.Time)
+	go a.run(a.queue, a.stop)
+}
+
+func (a *webhookAlerter) close(ctx context.Context) {
+	a.mut.Lock()
+	stop, done := a.stop, a.done
+	a.queue, a.stop = nil, nil
+	a.mut.Unlock()
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// raise queues an alert without blocking
+func (a *webhookAlerter) raise(alert Alert) {
+	alert.Text = alert.Message
+	alert.File = a.bm.filePath
+	alert.Time = time.Now()
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.queue == nil {
+		return
+	}
+	select {
+	case a.queue <- alert:
+	default:
+		a.bm.log.Warnf("Dropped %s alert as the webhook couldn't keep up", alert.Rule)
+	}
+}
+
+// onEvent raises an alert for offset regressions
+func (a *webhookAlerter) onEvent(ev Event) {
+	if !a.conf.OnRegression || ev.Kind != EventRewound {
+		return
+	}
+	offset := ev.Offset
+	a.raise(Alert{
+		Rule:      AlertRuleRegression,
+		Message:   fmt.Sprintf("Bookmark %s/%s moved back from offset %d to %d", ev.Topic, ev.Partition, *ev.PreviousOffset, ev.Offset),
+		Topic:     ev.Topic,
+		Partition: ev.Partition,
+		Offset:    &offset,
+	})
+}
+
+// onSave raises an alert for the first of consecutive save failures
+func (a *webhookAlerter) onSave(err error) {
+	if !a.conf.OnSaveFailure {
+		return
+	}
+
+	a.mut.Lock()
+	if err == nil {
+		a.saveFailures = 0
+		a.mut.Unlock()
+		return
+	}
+	a.saveFailures++
+	first := a.saveFailures == 1
+	a.mut.Unlock()
+
+	if first {
+		a.raise(Alert{
+			Rule:    AlertRuleSaveFailure,
+			Message: fmt.Sprintf("Failed to save bookmarks to %s: %v", a.bm.filePath, err),
+		})
+	}
+}
+
+// checkStale raises an alert for each bookmark that became stale since the
+// last check
+func (a *webhookAlerter) checkStale(now time.Time) {
+	_ = a.bm.ForEach(func(b *Bookmark) error {
+		key := b.TopicPartition()
+		if now.Sub(b.Timestamp) < a.conf.StaleAfter {
+			delete(a.stale, key)
+			return nil
+		}
+		if alerted, exists := a.stale[key]; exists && alerted.Equal(b.Timestamp) {
+			return nil
+		}
+		a.stale[key] = b.Timestamp
+
+		offset := b.Offset
+		a.raise(Alert{
+			Rule:      AlertRuleStale,
+			Message:   fmt.Sprintf("Bookmark %s/%s hasn't been updated for %s", b.Topic, b.Partition, now.Sub(b.Timestamp).Truncate(time.Second)),
+			Topic:     b.Topic,
+			Partition: b.Partition,
+			Offset:    &offset,
+		})
+		return nil
+	})
+}
+
+func (a *webhookAlerter) run(queue <-chan Alert, stop <-chan struct{}) {
+	defer close(a.done)
+
+	var tick <-chan time.Time
+	if a.conf.StaleAfter > 0 {
+		ticker := time.NewTicker(a.conf.CheckInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case alert := <-queue:
+			if err := a.post(alert); err != nil {
+				a.bm.log.Warnf("Failed to post %s alert: %v", alert.Rule, err)
+			}
+		case now := <-tick:
+			a.checkStale(now)
+		case <-stop:
+			// Deliver what has been raised before closing
+			for {
+				select {
+				case alert := <-queue:
+					if err := a.post(alert); err != nil {
+						a.bm.log.Warnf("Failed to post %s alert: %v", alert.Rule, err)
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (a *webhookAlerter) post(alert Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.conf.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range a.conf.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %s", res.Status)
+	}
+	return nil
+}
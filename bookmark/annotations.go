@@ -0,0 +1,52 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"maps"
+	"os"
+	"runtime/debug"
+)
+
+// Annotations written to every bookmark file unless overridden
+const (
+	AnnotationHostname = "hostname"
+	AnnotationVersion  = "version"
+)
+
+// defaultAnnotations returns the annotations describing the current process
+func defaultAnnotations() map[string]string {
+	annotations := make(map[string]string)
+	if host, err := os.Hostname(); err == nil {
+		annotations[AnnotationHostname] = host
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		annotations[AnnotationVersion] = info.Main.Version
+	}
+	return annotations
+}
+
+// WithAnnotations adds annotations to the bookmark file, such as the name of
+// the pipeline owning it, on top of the hostname and version annotations
+func WithAnnotations(annotations map[string]string) ManagerOption {
+	return func(bm *BookmarkManager) {
+		maps.Copy(bm.annotations, annotations)
+	}
+}
+
+// GetAnnotations returns the annotations written to the bookmark file
+func (bm *BookmarkManager) GetAnnotations() map[string]string {
+	return maps.Clone(bm.annotations)
+}
@@ -0,0 +1,230 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// auditPruneInterval is how often saves remove expired audit entries
+const auditPruneInterval = time.Hour
+
+// AuditEntry records a change of a bookmark in the audit log
+type AuditEntry struct {
+	Time           time.Time      `json:"time"`
+	Kind           EventKind      `json:"kind"`
+	Group          string         `json:"group,omitempty"`
+	Topic          string         `json:"topic"`
+	Partition      string         `json:"partition"`
+	PreviousOffset *int64         `json:"previous_offset,omitempty"`
+	Offset         int64          `json:"offset"`
+	Writer         WriterIdentity `json:"writer"`
+}
+
+// WithAuditLog records every bookmark change in an append-only audit log at
+// <path>.audit, one JSON entry per line, along with the writer that made it.
+// Entries are appended when the bookmarks are saved and removed once they are
+// older than retention. Zero retention keeps entries forever.
+func WithAuditLog(retention time.Duration) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.audit = &auditLog{path: bm.filePath + ".audit", retention: retention}
+	}
+}
+
+type auditLog struct {
+	path      string
+	retention time.Duration
+
+	mut        sync.Mutex
+	pending    []AuditEntry
+	lastPruned time.Time
+}
+
+// record buffers the entry of an event until the next flush. The caller must
+// hold the manager mutex.
+func (a *auditLog) record(ev Event, writer WriterIdentity) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	a.pending = append(a.pending, AuditEntry{
+		Time:           time.Now(),
+		Kind:           ev.Kind,
+		Group:          ev.Group,
+		Topic:          ev.Topic,
+		Partition:      ev.Partition,
+		PreviousOffset: ev.PreviousOffset,
+		Offset:         ev.Offset,
+		Writer:         writer,
+	})
+}
+
+// flush appends the buffered entries to the audit log and syncs it, and
+// removes expired entries when they are due. Entries are kept buffered when
+// discard drops the entries recorded since the last flush
+func (a *auditLog) discard() {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	a.pending = nil
+}
+
+// appending fails.
+func (a *auditLog) flush() error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.retention > 0 && time.Since(a.lastPruned) >= auditPruneInterval {
+		if err := a.prune(); err != nil {
+			return err
+		}
+	}
+	if len(a.pending) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range a.pending {
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to marshal audit entry: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to append to audit log: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+	a.pending = a.pending[:0]
+	return nil
+}
+
+// prune rewrites the audit log without the entries older than the retention.
+// The caller must hold mut.
+func (a *auditLog) prune() error {
+	now := time.Now()
+	entries, err := a.read()
+	if err != nil {
+		return err
+	}
+
+	cutoff := now.Add(-a.retention)
+	kept := 0
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if entry.Time.Before(cutoff) {
+			continue
+		}
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to marshal audit entry: %w", err)
+		}
+		kept++
+	}
+
+	if kept < len(entries) {
+		tempFile := a.path + ".tmp"
+		if err := os.WriteFile(tempFile, buf.Bytes(), 0644); err != nil {
+			os.Remove(tempFile)
+			return fmt.Errorf("failed to write audit log: %w", err)
+		}
+		if err := os.Rename(tempFile, a.path); err != nil {
+			os.Remove(tempFile)
+			return fmt.Errorf("failed to write audit log: %w", err)
+		}
+	}
+	a.lastPruned = now
+	return nil
+}
+
+// read returns the entries of the audit log, skipping lines that fail to
+// decode, such as one torn by a crash during an append. The caller must hold
+// mut.
+func (a *auditLog) read() ([]AuditEntry, error) {
+	f, err := os.Open(a.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// GetAuditEntries returns the audit entries of a partition recorded at or after
+// since, oldest first, including changes not saved yet. An empty partition
+// returns the entries of all partitions of the topic, and an empty topic those
+// of all topics. It fails when the audit log isn't enabled.
+func (bm *BookmarkManager) GetAuditEntries(topic, partition string, since time.Time) ([]AuditEntry, error) {
+	if bm.audit == nil {
+		return nil, errors.New("audit log is not enabled")
+	}
+	if partition != "" {
+		partition = NormalizePartition(partition)
+	}
+
+	bm.audit.mut.Lock()
+	defer bm.audit.mut.Unlock()
+
+	entries, err := bm.audit.read()
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, bm.audit.pending...)
+
+	var matched []AuditEntry
+	for _, entry := range entries {
+		if entry.Time.Before(since) ||
+			(topic != "" && entry.Topic != topic) ||
+			(partition != "" && entry.Partition != partition) {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	return matched, nil
+}
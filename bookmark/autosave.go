@@ -0,0 +1,172 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// autoSaver is a running auto-save loop
+type autoSaver struct {
+	stop chan struct{}
+	done chan struct{}
+
+	// kick requests an early save, it holds at most one request
+	kick chan struct{}
+
+	// finalErr is the result of the final save, set before done is closed
+	finalErr error
+}
+
+// WithAutoSave makes Open start saving the bookmarks at the given interval,
+// see StartAutoSave
+func WithAutoSave(interval time.Duration) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.autoSaveInterval = interval
+	}
+}
+
+// WithAutoSaveMaxUpdates makes a running auto-save save early once maxUpdates
+// bookmarks changed since the last save, rather than waiting for its next
+// interval, which bounds the updates replayed after a crash during bursts.
+// Updates of the same bookmark between saves count once. Zero only saves at
+// the interval.
+func WithAutoSaveMaxUpdates(maxUpdates int) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.autoSaveMaxUpdates = maxUpdates
+	}
+}
+
+// StartAutoSave saves the bookmarks at the given interval whenever they
+// changed since the last save, and once more when ctx is cancelled or
+// StopAutoSave is called. Saves replace the file atomically, and failures are
+// logged and retried at the next interval.
+func (bm *BookmarkManager) StartAutoSave(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("auto-save interval must be positive")
+	}
+
+	bm.autoSaveMutex.Lock()
+	defer bm.autoSaveMutex.Unlock()
+
+	if s := bm.autoSave; s != nil {
+		select {
+		case <-s.done:
+		default:
+			return errors.New("auto-save is already running")
+		}
+	}
+
+	s := &autoSaver{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+		kick: make(chan struct{}, 1),
+	}
+	bm.autoSave = s
+	go bm.runAutoSave(ctx, interval, s)
+	return nil
+}
+
+// StopAutoSave stops the auto-save started with StartAutoSave and returns the
+// result of its final save
+func (bm *BookmarkManager) StopAutoSave() error {
+	bm.autoSaveMutex.Lock()
+	s := bm.autoSave
+	bm.autoSave = nil
+	bm.autoSaveMutex.Unlock()
+
+	if s == nil {
+		return nil
+	}
+	close(s.stop)
+	<-s.done
+	return s.finalErr
+}
+
+// AutoSaving returns whether an auto-save is running
+func (bm *BookmarkManager) AutoSaving() bool {
+	bm.autoSaveMutex.Lock()
+	defer bm.autoSaveMutex.Unlock()
+
+	if bm.autoSave == nil {
+		return false
+	}
+	select {
+	case <-bm.autoSave.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// RequestSave saves the bookmarks when they changed, unless an auto-save is
+// running which will save them at its next interval, or earlier once the
+// maximum of pending updates is reached. Components call it after updating
+// bookmarks so that enabling auto-save coalesces the writes and moves them off
+// their hot path. Flush forces a save regardless.
+func (bm *BookmarkManager) RequestSave(ctx context.Context) error {
+	bm.autoSaveMutex.Lock()
+	s := bm.autoSave
+	bm.autoSaveMutex.Unlock()
+
+	if s == nil {
+		return bm.SaveDirty(ctx)
+	}
+	select {
+	case <-s.done:
+		return bm.SaveDirty(ctx)
+	default:
+	}
+	if bm.autoSaveMaxUpdates > 0 && bm.dirty.len() >= bm.autoSaveMaxUpdates {
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (bm *BookmarkManager) runAutoSave(ctx context.Context, interval time.Duration, s *autoSaver) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			if err := bm.SaveDirty(ctx); err != nil {
+				bm.log.Errorf("Failed to auto-save bookmarks: %v", err)
+			}
+		case <-s.kick:
+			if err := bm.SaveDirty(ctx); err != nil {
+				bm.log.Errorf("Failed to auto-save bookmarks: %v", err)
+			}
+			ticker.Reset(interval)
+		case <-s.stop:
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+	}
+
+	// The final save runs after ctx is cancelled
+	if s.finalErr = bm.SaveDirty(context.Background()); s.finalErr != nil {
+		bm.log.Errorf("Failed to auto-save bookmarks on shutdown: %v", s.finalErr)
+	}
+}
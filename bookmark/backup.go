@@ -0,0 +1,215 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupTimeFormat is the timestamp suffix of backup files, which has a fixed
+// width so that backups sort by name in the order they were taken
+const backupTimeFormat = "20060102T150405.000000000Z"
+
+// BackupInfo describes a backup of the bookmark file
+type BackupInfo struct {
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`
+}
+
+// WithBackups keeps the given number of previous bookmark files as
+// timestamped backups named <path>.bak.<timestamp>, which loads fall back to
+// when the bookmark file is missing or can't be decoded. Every
+// save that replaces the bookmark file with a changed state moves it into a
+// new backup and removes the oldest backups beyond count. Zero disables
+// automatic backups.
+func WithBackups(count int) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.backups = count
+	}
+}
+
+// backupPath returns the path of a backup taken at the given time
+func (bm *BookmarkManager) backupPath(at time.Time) string {
+	return bm.filePath + ".bak." + at.UTC().Format(backupTimeFormat)
+}
+
+// ListBackups returns the backups of the bookmark file, newest first
+func (bm *BookmarkManager) ListBackups() ([]BackupInfo, error) {
+	dir := filepath.Dir(bm.filePath)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	prefix := filepath.Base(bm.filePath) + ".bak."
+	var backups []BackupInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		createdAt, err := time.Parse(backupTimeFormat, strings.TrimPrefix(name, prefix))
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{
+			Path:      filepath.Join(dir, name),
+			CreatedAt: createdAt,
+			Size:      info.Size(),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// rotateBackups moves the bookmark file into a new backup before it's
+// replaced by a save and removes the backups beyond the configured count. The
+// caller must hold saveMutex.
+func (bm *BookmarkManager) rotateBackups(now time.Time) error {
+	if bm.backups <= 0 {
+		return nil
+	}
+	if err := os.Rename(bm.filePath, bm.backupPath(now)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to back up bookmark file: %w", err)
+	}
+
+	backups, err := bm.ListBackups()
+	if err != nil {
+		return err
+	}
+	for _, b := range backups[min(bm.backups, len(backups)):] {
+		if err := os.Remove(b.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			bm.log.Warnf("Failed to remove old backup %s: %v", b.Path, err)
+		}
+	}
+	return nil
+}
+
+// readBackups reads the newest backup that decodes and passes its checksum.
+// It returns a nil file when there's no usable backup.
+func (bm *BookmarkManager) readBackups(report *RecoveryReport) *BookmarkFile {
+	backups, err := bm.ListBackups()
+	if err != nil {
+		report.problemf("%v", err)
+		return nil
+	}
+
+	for _, b := range backups {
+		data, err := os.ReadFile(b.Path)
+		if err != nil {
+			report.problemf("failed to read backup %s: %v", b.Path, err)
+			continue
+		}
+
+		bookmarkFile, err := decodeBookmarkFile(data, report)
+		if err != nil {
+			report.problemf("failed to decode backup %s: %v", b.Path, err)
+			continue
+		}
+
+		report.Source = b.Path
+		report.RecoveredFromBackup = true
+		return bookmarkFile
+	}
+	return nil
+}
+
+// Backup saves the full state to the bookmark file and copies it into a new
+// backup, returning its path. Backups taken this way count towards the
+// backups kept by automatic rotation.
+func (bm *BookmarkManager) Backup(ctx context.Context) (string, error) {
+	if bm.readOnly {
+		return "", ErrReadOnly
+	}
+	if err := bm.Compact(ctx); err != nil {
+		return "", err
+	}
+
+	bm.saveMutex.Lock()
+	defer bm.saveMutex.Unlock()
+
+	unlock, err := bm.lockFile(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(bm.filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read bookmark file: %w", err)
+	}
+
+	path := bm.backupPath(time.Now())
+	tempFile := path + ".tmp"
+	if err := bm.writeTempFile(tempFile, data); err != nil {
+		os.Remove(tempFile)
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	return path, nil
+}
+
+// Restore replaces all bookmarks and sink positions with the content of a
+// backup, or of any other bookmark file, and saves them. Offsets may move
+// backwards regardless of the regression policy, as rolling back is the
+// point of a restore. Changes made since the backup, including those in the
+// write-ahead log, are discarded.
+func (bm *BookmarkManager) Restore(ctx context.Context, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	report := RecoveryReport{LoadedAt: time.Now(), Source: path, RecoveredFromBackup: true}
+	bookmarkFile, err := decodeBookmarkFile(data, &report)
+	if err != nil {
+		return fmt.Errorf("failed to decode backup %s: %w", path, err)
+	}
+
+	bm.saveMutex.Lock()
+	bm.mutex.Lock()
+	bm.applyBookmarkFile(bookmarkFile, &report)
+	// Every entry is rewritten by the compaction below
+	bm.dirty.reset()
+	bm.recovery = report
+	bm.mutex.Unlock()
+	bm.saveMutex.Unlock()
+
+	bm.log.Infof("Restored bookmarks from %s: %s", path, report)
+	if err := bm.Compact(ctx); err != nil {
+		return fmt.Errorf("failed to save restored bookmarks: %w", err)
+	}
+	return nil
+}
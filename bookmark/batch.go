@@ -0,0 +1,160 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// AddBookmarks adds or updates several bookmarks while taking the lock once.
+// The bookmarks are validated and checked for regressions first, and when any
+// of them fails none of them is stored. Later bookmarks of the same topic and
+// partition replace earlier ones.
+func (bm *BookmarkManager) AddBookmarks(bookmarks []*Bookmark) error {
+	for i, bookmark := range bookmarks {
+		if bookmark == nil {
+			return fmt.Errorf("bookmark %d cannot be nil", i)
+		}
+		if err := bm.validateBookmark(bookmark); err != nil {
+			return fmt.Errorf("invalid bookmark %s/%s: %w", bookmark.Topic, bookmark.Partition, err)
+		}
+		bm.assignGroup(bookmark)
+	}
+
+	defer bm.dispatchEvents()
+	bm.mutex.Lock()
+	defer bm.mutex.Unlock()
+
+	return bm.addBookmarksLocked(bookmarks)
+}
+
+// addBookmarksLocked stores validated bookmarks like AddBookmarks. The caller
+// must hold the write lock of the mutex.
+func (bm *BookmarkManager) addBookmarksLocked(bookmarks []*Bookmark) error {
+	staged := make(map[TopicPartition]*Bookmark, len(bookmarks))
+	accepted := make([]*Bookmark, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		key := bookmark.TopicPartition()
+		previous, exists := staged[key]
+		if !exists {
+			previous, _ = bm.bookmarks.get(key)
+		}
+		err := bm.checkRegression(previous, bookmark.Offset)
+		if errors.Is(err, errRegressionIgnored) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkLeaderEpoch(previous, bookmark.LeaderEpoch); err != nil {
+			return err
+		}
+		if err := bm.checkMetadataBudget(previous, bookmark); err != nil {
+			return err
+		}
+		staged[key] = bookmark
+		accepted = append(accepted, bookmark)
+	}
+	for _, bookmark := range accepted {
+		key := bookmark.TopicPartition()
+		previous, _ := bm.bookmarks.get(key)
+		bookmark.Version = nextVersion(previous)
+		if ev, changed := changeEvent(previous, bookmark); changed {
+			bm.emit(ev)
+		}
+		bm.bookmarks.set(key, bookmark)
+		bm.dirty.markBookmark(key)
+	}
+	return nil
+}
+
+// UpdateOffsets updates the offsets of several existing bookmarks while
+// taking the lock once, setting their timestamps to the current time. When a
+// bookmark is missing or an offset is rejected none of them is updated.
+func (bm *BookmarkManager) UpdateOffsets(offsets map[TopicPartition]int64) error {
+	keys := make([]TopicPartition, 0, len(offsets))
+	for tp, offset := range offsets {
+		if err := validateOffset(offset); err != nil {
+			return fmt.Errorf("invalid offset for %s/%s: %w", tp.Topic, tp.Partition, err)
+		}
+		keys = append(keys, tp)
+	}
+	// Sorted so that events are emitted in a consistent order
+	sortTopicPartitions(keys)
+
+	defer bm.dispatchEvents()
+	bm.mutex.Lock()
+	defer bm.mutex.Unlock()
+
+	accepted := keys[:0]
+	for _, tp := range keys {
+		bookmark, exists := bm.bookmarks.get(bm.generateKey(tp.Topic, tp.Partition))
+		if !exists {
+			return fmt.Errorf("%w for topic: %s, partition: %s", ErrBookmarkNotFound, tp.Topic, tp.Partition)
+		}
+		err := bm.checkRegression(bookmark, offsets[tp])
+		if errors.Is(err, errRegressionIgnored) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		accepted = append(accepted, tp)
+	}
+
+	// The regression policy was applied to every offset above
+	now := time.Now()
+	for _, tp := range accepted {
+		key := bm.generateKey(tp.Topic, tp.Partition)
+		bookmark, _ := bm.bookmarks.get(key)
+		if err := bm.applyOffsetLocked(key, bookmark, offsets[tp], now, offsetOpts{force: true}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveBookmarksByTopic removes all bookmarks of a topic in the group of the
+// manager while taking the lock once and returns the number of removed
+// bookmarks
+func (bm *BookmarkManager) RemoveBookmarksByTopic(topic string) (int, error) {
+	if topic == "" {
+		return 0, errors.New("topic cannot be empty")
+	}
+
+	defer bm.dispatchEvents()
+	bm.mutex.Lock()
+	defer bm.mutex.Unlock()
+
+	var removed []*Bookmark
+	for _, bookmark := range bm.bookmarks.all() {
+		if bookmark.Group == bm.group && bookmark.Topic == topic {
+			removed = append(removed, bookmark)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		return lessBookmark(removed[i], removed[j])
+	})
+
+	for _, bookmark := range removed {
+		bm.emit(removeEvent(bookmark))
+		bm.bookmarks.remove(bookmark.TopicPartition())
+		bm.dirty.markBookmark(bookmark.TopicPartition())
+	}
+	return len(removed), nil
+}
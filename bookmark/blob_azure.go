@@ -0,0 +1,105 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+// azureBlobBucket is a blob bucket of an Azure Blob Storage container, where
+// versions are ETags
+type azureBlobBucket struct {
+	client    *azblob.Client
+	container string
+}
+
+func newAzureBlobBucket(u *url.URL) (*azureBlobBucket, error) {
+	if connStr := os.Getenv("AZURE_STORAGE_CONNECTION_STRING"); connStr != "" {
+		client, err := azblob.NewClientFromConnectionString(connStr, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &azureBlobBucket{client: client, container: u.Host}, nil
+	}
+
+	account := u.Query().Get("account")
+	if account == "" {
+		account = os.Getenv("AZURE_STORAGE_ACCOUNT")
+	}
+	if account == "" {
+		return nil, errors.New("an account query parameter, AZURE_STORAGE_ACCOUNT or AZURE_STORAGE_CONNECTION_STRING is required")
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+	client, err := azblob.NewClient(fmt.Sprintf("https://%s.blob.core.windows.net/", account), cred, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &azureBlobBucket{client: client, container: u.Host}, nil
+}
+
+func (b *azureBlobBucket) read(ctx context.Context, key string) ([]byte, string, error) {
+	resp, err := b.client.DownloadStream(ctx, b.container, key, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil, "", errBlobNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, string(*resp.ETag), nil
+}
+
+func (b *azureBlobBucket) write(ctx context.Context, key string, data []byte, version string) (string, error) {
+	etag := azcore.ETag(version)
+	conditions := &blob.ModifiedAccessConditions{IfMatch: &etag}
+	if version == "" {
+		etag = azcore.ETagAny
+		conditions = &blob.ModifiedAccessConditions{IfNoneMatch: &etag}
+	}
+
+	resp, err := b.client.UploadBuffer(ctx, b.container, key, data, &azblob.UploadBufferOptions{
+		AccessConditions: &blob.AccessConditions{ModifiedAccessConditions: conditions},
+	})
+	if bloberror.HasCode(err, bloberror.ConditionNotMet, bloberror.BlobAlreadyExists) {
+		return "", errBlobPrecondition
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(*resp.ETag), nil
+}
+
+func (b *azureBlobBucket) close() error {
+	return nil
+}
@@ -0,0 +1,325 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+var (
+	// errBlobNotFound is returned by blob buckets when the object doesn't exist
+	errBlobNotFound = errors.New("object not found")
+
+	// errBlobPrecondition is returned by blob buckets when the object was
+	// changed since the version a write is conditional on
+	errBlobPrecondition = errors.New("object was changed")
+)
+
+// blobBucket is the object storage of a cloud, which the blob store reads and
+// conditionally writes an object of
+type blobBucket interface {
+	// read returns the content and version of an object, or errBlobNotFound
+	read(ctx context.Context, key string) ([]byte, string, error)
+
+	// write replaces an object if its version is still the given one, where
+	// an empty version requires that the object doesn't exist, and returns
+	// the new version, or errBlobPrecondition
+	write(ctx context.Context, key string, data []byte, version string) (string, error)
+
+	close() error
+}
+
+// BlobBookmarkManagerConfig configures a BlobBookmarkManager
+type BlobBookmarkManagerConfig struct {
+	// URL is the object the bookmarks are stored in, s3://<bucket>/<key>,
+	// gs://<bucket>/<key> or azblob://<container>/<key>
+	URL string
+
+	// AWS is the config of S3 clients, the default config is loaded when nil
+	AWS *aws.Config
+}
+
+// BlobBookmarkManager stores all bookmarks in a single object of S3, Google
+// Cloud Storage or Azure Blob Storage, selected by the scheme of its URL, in
+// the layout of bookmark files. The object is read into memory on creation,
+// Put and Delete change the memory and Flush writes the object when they
+// changed it.
+//
+// Writes are conditional on the version of the object last read or written,
+// so that instances sharing the object don't overwrite each others changes.
+// Flush fails with ErrBookmarkConflict when another instance wrote the object
+// in the meantime, in which case Reload reads its current state.
+//
+// S3 URLs accept the region and endpoint query parameters. Azure URLs use the
+// AZURE_STORAGE_CONNECTION_STRING environment variable, or the account query
+// parameter or AZURE_STORAGE_ACCOUNT environment variable with the default
+// Azure credentials. Google Cloud Storage uses the default credentials.
+type BlobBookmarkManager struct {
+	bucket blobBucket
+	key    string
+	url    string
+
+	mutex     sync.RWMutex
+	bookmarks map[TopicPartition]*Bookmark
+	createdAt time.Time
+	version   string
+	dirty     bool
+}
+
+var _ GroupBookmarkStore = (*BlobBookmarkManager)(nil)
+
+// NewBlobBookmarkManager opens the bucket of the URL and loads the bookmarks
+// of its object, which doesn't need to exist yet
+func NewBlobBookmarkManager(ctx context.Context, conf BlobBookmarkManagerConfig) (*BlobBookmarkManager, error) {
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blob URL: %w", err)
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" {
+		return nil, fmt.Errorf("blob URL %s must be in the form <scheme>://<bucket>/<key>", conf.URL)
+	}
+
+	var bucket blobBucket
+	switch u.Scheme {
+	case "s3":
+		bucket, err = newS3BlobBucket(ctx, u, conf.AWS)
+	case "gs":
+		bucket, err = newGCSBlobBucket(ctx, u)
+	case "azblob":
+		bucket, err = newAzureBlobBucket(u)
+	default:
+		return nil, fmt.Errorf("unknown blob URL scheme %q, expected s3, gs or azblob", u.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bucket: %w", err)
+	}
+
+	m := &BlobBookmarkManager{
+		bucket:    bucket,
+		key:       key,
+		url:       conf.URL,
+		bookmarks: make(map[TopicPartition]*Bookmark),
+	}
+	if err := m.Reload(ctx); err != nil {
+		bucket.close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload replaces the bookmarks in memory with those of the object,
+// discarding changes that weren't flushed
+func (m *BlobBookmarkManager) Reload(ctx context.Context) error {
+	data, version, err := m.bucket.read(ctx, m.key)
+	if errors.Is(err, errBlobNotFound) {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		m.bookmarks = make(map[TopicPartition]*Bookmark)
+		m.createdAt, m.version, m.dirty = time.Time{}, "", false
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", m.url, err)
+	}
+
+	var report RecoveryReport
+	bookmarkFile, err := decodeBookmarkFile(data, &report)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", m.url, err)
+	}
+	bookmarks := make(map[TopicPartition]*Bookmark, len(bookmarkFile.Bookmarks))
+	for _, b := range bookmarkFile.Bookmarks {
+		bookmarks[b.TopicPartition()] = b
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.bookmarks = bookmarks
+	m.createdAt, m.version, m.dirty = bookmarkFile.CreatedAt, version, false
+	return nil
+}
+
+// Get returns the bookmark of a topic and partition in the default group
+func (m *BlobBookmarkManager) Get(ctx context.Context, topic, partition string) (*Bookmark, error) {
+	return m.GetInGroup(ctx, "", topic, partition)
+}
+
+// GetInGroup returns the bookmark of a topic and partition in a group
+func (m *BlobBookmarkManager) GetInGroup(ctx context.Context, group, topic, partition string) (*Bookmark, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	b, exists := m.bookmarks[NewGroupTopicPartition(group, topic, partition)]
+	if !exists {
+		return nil, notFoundError(group, topic, partition)
+	}
+	return b, nil
+}
+
+// Put adds or replaces a bookmark, which is written by the next Flush
+func (m *BlobBookmarkManager) Put(ctx context.Context, bookmark *Bookmark) error {
+	if bookmark == nil {
+		return errors.New("bookmark cannot be nil")
+	}
+	if err := bookmark.validate(); err != nil {
+		return fmt.Errorf("invalid bookmark: %w", err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.bookmarks[bookmark.TopicPartition()] = bookmark
+	m.dirty = true
+	return nil
+}
+
+// Delete removes the bookmark of a topic and partition in the default group
+func (m *BlobBookmarkManager) Delete(ctx context.Context, topic, partition string) error {
+	return m.DeleteInGroup(ctx, "", topic, partition)
+}
+
+// DeleteInGroup removes the bookmark of a topic and partition in a group, which
+// is written by the next Flush
+func (m *BlobBookmarkManager) DeleteInGroup(ctx context.Context, group, topic, partition string) error {
+	tp := NewGroupTopicPartition(group, topic, partition)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.bookmarks[tp]; !exists {
+		return notFoundError(group, topic, partition)
+	}
+	delete(m.bookmarks, tp)
+	m.dirty = true
+	return nil
+}
+
+// List returns all bookmarks sorted by topic and partition
+func (m *BlobBookmarkManager) List(ctx context.Context) ([]*Bookmark, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.sortedBookmarks(), nil
+}
+
+// sortedBookmarks returns the bookmarks sorted by topic and partition. The
+// caller must hold mutex.
+func (m *BlobBookmarkManager) sortedBookmarks() []*Bookmark {
+	bookmarks := make([]*Bookmark, 0, len(m.bookmarks))
+	for _, b := range m.bookmarks {
+		bookmarks = append(bookmarks, b)
+	}
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return lessBookmark(bookmarks[i], bookmarks[j])
+	})
+	return bookmarks
+}
+
+// Flush writes the object when bookmarks changed since the last flush,
+// failing with ErrBookmarkConflict when another instance wrote it since it
+// was last read or written
+func (m *BlobBookmarkManager) Flush(ctx context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.dirty {
+		return nil
+	}
+
+	now := time.Now()
+	bookmarkFile := BookmarkFile{
+		Version:   FileVersion,
+		CreatedAt: m.createdAt,
+		UpdatedAt: now,
+		Bookmarks: m.sortedBookmarks(),
+	}
+	if bookmarkFile.CreatedAt.IsZero() {
+		bookmarkFile.CreatedAt = now
+	}
+	checksum, err := fileChecksum(bookmarkFile.Bookmarks, nil, nil)
+	if err != nil {
+		return err
+	}
+	bookmarkFile.Checksum = checksum
+	data, err := json.Marshal(bookmarkFile)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bookmarks: %w", err)
+	}
+
+	version, err := m.bucket.write(ctx, m.key, data, m.version)
+	if errors.Is(err, errBlobPrecondition) {
+		return fmt.Errorf("failed to write %s: %w", m.url, ErrBookmarkConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", m.url, err)
+	}
+	m.createdAt, m.version, m.dirty = bookmarkFile.CreatedAt, version, false
+	return nil
+}
+
+// Close flushes pending changes and closes the bucket
+func (m *BlobBookmarkManager) Close(ctx context.Context) error {
+	err := m.Flush(ctx)
+	if closeErr := m.bucket.close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	blbmFieldSection = "bookmarks_blob"
+	blbmFieldURL     = "url"
+)
+
+// BlobBookmarkManagerConfigFields returns the config fields of a blob
+// bookmark store
+func BlobBookmarkManagerConfigFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewObjectField(blbmFieldSection,
+			service.NewStringField(blbmFieldURL).
+				Description("The object bookmarks are stored in. S3 URLs accept the `region` and `endpoint` query parameters and Azure URLs the `account` query parameter, credentials are taken from the environment of each cloud.").
+				Examples("s3://my-bucket/bookmarks.json?region=eu-west-1", "gs://my-bucket/bookmarks.json", "azblob://my-container/bookmarks.json?account=myaccount"),
+		).Description("The blob bookmarks store configuration"),
+	}
+}
+
+// BlobBookmarkManagerFromParsed creates a blob bookmark store from the config
+// fields defined by BlobBookmarkManagerConfigFields
+func BlobBookmarkManagerFromParsed(ctx context.Context, pConf *service.ParsedConfig) (*BlobBookmarkManager, error) {
+	var conf BlobBookmarkManagerConfig
+
+	var err error
+	if conf.URL, err = pConf.Namespace(blbmFieldSection).FieldString(blbmFieldURL); err != nil {
+		return nil, err
+	}
+
+	return NewBlobBookmarkManager(ctx, conf)
+}
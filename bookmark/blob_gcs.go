@@ -0,0 +1,90 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+)
+
+// gcsBlobBucket is a blob bucket of Google Cloud Storage, where versions are
+// object generations
+type gcsBlobBucket struct {
+	client *storage.Client
+	bucket *storage.BucketHandle
+}
+
+func newGCSBlobBucket(ctx context.Context, u *url.URL) (*gcsBlobBucket, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsBlobBucket{client: client, bucket: client.Bucket(u.Host)}, nil
+}
+
+func (b *gcsBlobBucket) read(ctx context.Context, key string) ([]byte, string, error) {
+	r, err := b.bucket.Object(key).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, "", errBlobNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, strconv.FormatInt(r.Attrs.Generation, 10), nil
+}
+
+func (b *gcsBlobBucket) write(ctx context.Context, key string, data []byte, version string) (string, error) {
+	conditions := storage.Conditions{DoesNotExist: true}
+	if version != "" {
+		generation, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			return "", err
+		}
+		conditions = storage.Conditions{GenerationMatch: generation}
+	}
+
+	w := b.bucket.Object(key).If(conditions).NewWriter(ctx)
+	w.ContentType = "application/json"
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return "", err
+	}
+	err := w.Close()
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+		return "", errBlobPrecondition
+	}
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(w.Attrs().Generation, 10), nil
+}
+
+func (b *gcsBlobBucket) close() error {
+	return b.client.Close()
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// s3BlobBucket is a blob bucket of S3, where versions are ETags
+type s3BlobBucket struct {
+	client *s3.Client
+	bucket string
+}
+
+func newS3BlobBucket(ctx context.Context, u *url.URL, awsConf *aws.Config) (*s3BlobBucket, error) {
+	var conf aws.Config
+	if awsConf != nil {
+		conf = awsConf.Copy()
+	} else {
+		var err error
+		if conf, err = config.LoadDefaultConfig(ctx); err != nil {
+			return nil, err
+		}
+	}
+	query := u.Query()
+	if region := query.Get("region"); region != "" {
+		conf.Region = region
+	}
+	if endpoint := query.Get("endpoint"); endpoint != "" {
+		conf.BaseEndpoint = &endpoint
+	}
+
+	client := s3.NewFromConfig(conf, func(o *s3.Options) {
+		o.UsePathStyle = query.Has("endpoint")
+	})
+	return &s3BlobBucket{client: client, bucket: u.Host}, nil
+}
+
+func (b *s3BlobBucket) read(ctx context.Context, key string) ([]byte, string, error) {
+	out, err := b.client.GetObject(ctx, &s3.GetObjectInput{Bucket: &b.bucket, Key: &key})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, "", errBlobNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, aws.ToString(out.ETag), nil
+}
+
+func (b *s3BlobBucket) write(ctx context.Context, key string, data []byte, version string) (string, error) {
+	input := &s3.PutObjectInput{
+		Bucket:      &b.bucket,
+		Key:         &key,
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}
+	if version == "" {
+		input.IfNoneMatch = aws.String("*")
+	} else {
+		input.IfMatch = &version
+	}
+
+	out, err := b.client.PutObject(ctx, input)
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "PreconditionFailed", "ConditionalRequestConflict":
+			return "", errBlobPrecondition
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.ETag), nil
+}
+
+func (b *s3BlobBucket) close() error {
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+func parseBookmarkMethodSpec() *bloblang.PluginSpec {
+	return bloblang.NewPluginSpec().
+		Category("Object & Array Manipulation").
+		Description("Validates an object against the bookmark shape and normalizes it into the canonical form with the fields `topic`, `partition`, `offset`, `timestamp` (RFC3339) and `metadata`. Numeric partitions are converted to strings, and timestamps may be provided either as RFC3339 strings or unix seconds. An error is returned when the topic or partition are missing, or when the offset or timestamp are invalid.").
+		Example("", `root = this.parse_bookmark()`,
+			[2]string{
+				`{"topic":"orders","partition":3,"offset":42,"timestamp":"2024-06-01T10:00:00Z"}`,
+				`{"metadata":{},"offset":42,"partition":"3","timestamp":"2024-06-01T10:00:00Z","topic":"orders"}`,
+			},
+		)
+}
+
+func init() {
+	bloblang.MustRegisterMethodV2("parse_bookmark", parseBookmarkMethodSpec(),
+		func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+			return bloblang.ObjectMethod(func(obj map[string]any) (any, error) {
+				b, err := parseBookmarkObject(obj)
+				if err != nil {
+					return nil, err
+				}
+				return b.ToDict(), nil
+			}), nil
+		})
+}
+
+// parseBookmarkObject converts a loosely typed object into a validated bookmark
+func parseBookmarkObject(obj map[string]any) (*Bookmark, error) {
+	topic, ok := obj["topic"].(string)
+	if !ok {
+		return nil, errors.New("invalid or missing topic")
+	}
+
+	rawPartition, exists := obj["partition"]
+	if !exists || rawPartition == nil {
+		return nil, errors.New("invalid or missing partition")
+	}
+	partition, err := partitionFromValue(rawPartition)
+	if err != nil {
+		return nil, err
+	}
+
+	rawOffset, exists := obj["offset"]
+	if !exists {
+		return nil, errors.New("invalid or missing offset")
+	}
+	offset, err := offsetFromValue(rawOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Now()
+	if rawTs, exists := obj["timestamp"]; exists && rawTs != nil {
+		if timestamp, err = bloblang.ValueAsTimestamp(rawTs); err != nil {
+			return nil, fmt.Errorf("invalid timestamp format: %v", err)
+		}
+	}
+
+	metadata, ok := obj["metadata"].(map[string]any)
+	if !ok {
+		metadata = make(map[string]interface{})
+	}
+
+	return NewBookmarkWithTimestamp(topic, partition, offset, timestamp, metadata)
+}
+
+// partitionFromValue converts a string or numeric value into a partition
+func partitionFromValue(v any) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	n, err := bloblang.ValueAsInt64(v)
+	if err != nil {
+		return "", fmt.Errorf("invalid partition: %v", err)
+	}
+	return fmt.Sprintf("%d", n), nil
+}
+
+// offsetFromValue converts a numeric value or a string parsed by ParseOffset
+// into an offset, rejecting fractional numbers and negative numbers other
+// than the sentinel offsets
+func offsetFromValue(v any) (int64, error) {
+	if s, ok := v.(string); ok {
+		return ParseOffset(s)
+	}
+	if f, ok := v.(float64); ok && f != math.Trunc(f) {
+		return 0, fmt.Errorf("offset must be an integer, got %v", f)
+	}
+	offset, err := bloblang.ValueAsInt64(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset: %v", err)
+	}
+	if err := validateOffset(offset); err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltBucket is the bucket of bolt databases holding the bookmarks
+var boltBucket = []byte("bookmarks")
+
+// boltOpenTimeout bounds how long opening waits for another process to
+// release the database
+const boltOpenTimeout = 10 * time.Second
+
+// BoltBookmarkManager persists each bookmark as a key of an embedded bbolt
+// database, keyed by group, topic and partition. Every Put is a transaction
+// of its own that is durable once it returns, so saving an offset never
+// rewrites the other bookmarks and lookups don't load the whole state.
+type BoltBookmarkManager struct {
+	db *bolt.DB
+}
+
+var _ GroupBookmarkStore = (*BoltBookmarkManager)(nil)
+
+// NewBoltBookmarkManager opens or creates the database at path. Only one
+// process can open a database at a time.
+func NewBoltBookmarkManager(path string) (*BoltBookmarkManager, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create bookmark bucket: %w", err)
+	}
+	return &BoltBookmarkManager{db: db}, nil
+}
+
+// Get returns the bookmark of a topic and partition in the default group
+func (m *BoltBookmarkManager) Get(ctx context.Context, topic, partition string) (*Bookmark, error) {
+	return m.GetInGroup(ctx, "", topic, partition)
+}
+
+// GetInGroup returns the bookmark of a topic and partition in a group
+func (m *BoltBookmarkManager) GetInGroup(ctx context.Context, group, topic, partition string) (*Bookmark, error) {
+	var b *Bookmark
+	err := m.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltBucket).Get([]byte(NewGroupTopicPartition(group, topic, partition).String()))
+		if data == nil {
+			return notFoundError(group, topic, partition)
+		}
+		var err error
+		b, err = decodeBoltBookmark(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Put adds or replaces a bookmark in a transaction of its own
+func (m *BoltBookmarkManager) Put(ctx context.Context, bookmark *Bookmark) error {
+	return m.PutBookmarks(ctx, []*Bookmark{bookmark})
+}
+
+// PutBookmarks adds or replaces several bookmarks in one transaction, so that
+// either all or none of them are stored
+func (m *BoltBookmarkManager) PutBookmarks(ctx context.Context, bookmarks []*Bookmark) error {
+	for i, b := range bookmarks {
+		if b == nil {
+			return fmt.Errorf("bookmark %d cannot be nil", i)
+		}
+		if err := b.validate(); err != nil {
+			return fmt.Errorf("invalid bookmark %s/%s: %w", b.Topic, b.Partition, err)
+		}
+	}
+
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for _, b := range bookmarks {
+			data, err := json.Marshal(b)
+			if err != nil {
+				return fmt.Errorf("failed to marshal bookmark %s/%s: %w", b.Topic, b.Partition, err)
+			}
+			if err := bucket.Put([]byte(b.TopicPartition().String()), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write bookmarks: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the bookmark of a topic and partition in the default group
+func (m *BoltBookmarkManager) Delete(ctx context.Context, topic, partition string) error {
+	return m.DeleteInGroup(ctx, "", topic, partition)
+}
+
+// DeleteInGroup removes the bookmark of a topic and partition in a group
+func (m *BoltBookmarkManager) DeleteInGroup(ctx context.Context, group, topic, partition string) error {
+	return m.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		key := []byte(NewGroupTopicPartition(group, topic, partition).String())
+		if bucket.Get(key) == nil {
+			return notFoundError(group, topic, partition)
+		}
+		return bucket.Delete(key)
+	})
+}
+
+// List returns all bookmarks sorted by topic and partition
+func (m *BoltBookmarkManager) List(ctx context.Context) ([]*Bookmark, error) {
+	var bookmarks []*Bookmark
+	err := m.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).ForEach(func(k, v []byte) error {
+			b, err := decodeBoltBookmark(v)
+			if err != nil {
+				return fmt.Errorf("failed to read bookmark %s: %w", k, err)
+			}
+			bookmarks = append(bookmarks, b)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return lessBookmark(bookmarks[i], bookmarks[j])
+	})
+	return bookmarks, nil
+}
+
+// Flush does nothing, as every Put commits its transaction
+func (m *BoltBookmarkManager) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close closes the database
+func (m *BoltBookmarkManager) Close() error {
+	return m.db.Close()
+}
+
+func decodeBoltBookmark(data []byte) (*Bookmark, error) {
+	var b Bookmark
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, err
+	}
+	if b.Metadata == nil {
+		b.Metadata = make(map[string]interface{})
+	}
+	return &b, nil
+}
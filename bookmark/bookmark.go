@@ -18,24 +18,189 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 )
 
 // Bookmark represents a single bookmark entry for a topic-partition combination.
+// The group separates the bookmarks of consumers reading the same topic, and
+// is empty for the default group. The leader epoch, high watermark and lag
+// are those of the partition when the offset was read, and are only set by
+// consumers of Kafka, see UpdateOffsetState. The version is assigned by the
+// manager and grows with every change of the offset, timestamp or metadata,
+// see CompareAndSetOffset.
 type Bookmark struct {
-	Topic     string                 `json:"topic"`
-	Partition string                 `json:"partition"`
-	Offset    int                    `json:"offset"`
-	Timestamp time.Time              `json:"timestamp"`
-	Metadata  map[string]interface{} `json:"metadata"`
+	Group         string                 `json:"group,omitempty"`
+	Topic         string                 `json:"topic"`
+	Partition     string                 `json:"partition"`
+	Offset        int64                  `json:"offset"`
+	Timestamp     time.Time              `json:"timestamp"`
+	Metadata      map[string]interface{} `json:"metadata"`
+	LeaderEpoch   *int32                 `json:"leader_epoch,omitempty"`
+	HighWatermark int64                  `json:"high_watermark,omitempty"`
+	Lag           int64                  `json:"lag,omitempty"`
+	Version       int64                  `json:"version,omitempty"`
+}
+
+// TopicPartition identifies a bookmark and is the key bookmarks are stored
+// under. Create it with NewTopicPartition, NewGroupTopicPartition or
+// KafkaTopicPartition so that integer partitions are normalized. Partitions
+// are strings rather than Kafka partition numbers because the S3 input
+// bookmarks object keys, see KafkaPartition for Kafka partitions.
+type TopicPartition struct {
+	Group     string
+	Topic     string
+	Partition string
+}
+
+// NewTopicPartition returns the key of a topic and partition, with the
+// partition normalized by NormalizePartition
+func NewTopicPartition(topic, partition string) TopicPartition {
+	return TopicPartition{Topic: topic, Partition: NormalizePartition(partition)}
+}
+
+// NewGroupTopicPartition returns the key of a topic and partition of a
+// consumer group, with the partition normalized by NormalizePartition
+func NewGroupTopicPartition(group, topic, partition string) TopicPartition {
+	return TopicPartition{Group: group, Topic: topic, Partition: NormalizePartition(partition)}
+}
+
+// KafkaTopicPartition returns the key of a Kafka topic and partition
+func KafkaTopicPartition(topic string, partition int32) TopicPartition {
+	return TopicPartition{Topic: topic, Partition: strconv.FormatInt(int64(partition), 10)}
+}
+
+// KafkaPartition returns the partition as a Kafka partition number
+func (tp TopicPartition) KafkaPartition() (int32, error) {
+	partition, err := strconv.ParseInt(tp.Partition, 10, 32)
+	if err != nil || partition < 0 {
+		return 0, fmt.Errorf("partition %q is not a Kafka partition", tp.Partition)
+	}
+	return int32(partition), nil
+}
+
+var topicEscaper = strings.NewReplacer("%", "%25", ":", "%3A", "/", "%2F")
+
+// String returns the topic and partition separated by a colon, prefixed by
+// the group and a slash unless it's the default group. Colons, slashes and
+// percent signs in the group and topic are percent-encoded so that the result
+// can be split again by ParseTopicPartition, which leaves Kafka topics and S3
+// buckets unchanged as they can't contain any of them.
+func (tp TopicPartition) String() string {
+	s := topicEscaper.Replace(tp.Topic) + ":" + tp.Partition
+	if tp.Group != "" {
+		s = topicEscaper.Replace(tp.Group) + "/" + s
+	}
+	return s
+}
+
+// ParseTopicPartition parses the "[group/]topic:partition" form returned by
+// TopicPartition.String
+func ParseTopicPartition(s string) (TopicPartition, error) {
+	topic, partition, found := strings.Cut(s, ":")
+	if !found {
+		return TopicPartition{}, fmt.Errorf("%q must be in the form topic:partition", s)
+	}
+	group := ""
+	if g, t, grouped := strings.Cut(topic, "/"); grouped {
+		var err error
+		if group, err = url.PathUnescape(g); err != nil {
+			return TopicPartition{}, fmt.Errorf("invalid group in %q: %w", s, err)
+		}
+		topic = t
+	}
+	topic, err := url.PathUnescape(topic)
+	if err != nil {
+		return TopicPartition{}, fmt.Errorf("invalid topic in %q: %w", s, err)
+	}
+	return NewGroupTopicPartition(group, topic, partition), nil
+}
+
+// TopicPartition returns the group, topic and partition identifying the
+// bookmark
+func (b *Bookmark) TopicPartition() TopicPartition {
+	return NewGroupTopicPartition(b.Group, b.Topic, b.Partition)
+}
+
+// KafkaPartition returns the partition of the bookmark as a Kafka partition
+// number
+func (b *Bookmark) KafkaPartition() (int32, error) {
+	return b.TopicPartition().KafkaPartition()
+}
+
+// NormalizePartition returns integer partitions in canonical decimal form, so
+// that partition "01" is the same as partition "1". Partitions that aren't
+// integers, such as most S3 object keys, are returned unchanged.
+func NormalizePartition(partition string) string {
+	if partition == "" {
+		return partition
+	}
+	for _, r := range partition {
+		if r < '0' || r > '9' {
+			return partition
+		}
+	}
+	if trimmed := strings.TrimLeft(partition, "0"); trimmed != "" {
+		return trimmed
+	}
+	return "0"
+}
+
+// comparePartitions orders partitions numerically when both parse as integers,
+// so that partition 10 sorts after partition 2, and lexically otherwise.
+// Numeric partitions sort before other partitions.
+func comparePartitions(a, b string) int {
+	x, errX := strconv.ParseInt(a, 10, 64)
+	y, errY := strconv.ParseInt(b, 10, 64)
+	switch {
+	case errX == nil && errY == nil:
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	case errX == nil:
+		return -1
+	case errY == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// sortTopicPartitions sorts topic-partitions by topic, then by partition
+func sortTopicPartitions(tps []TopicPartition) {
+	sort.Slice(tps, func(i, j int) bool {
+		if tps[i].Group != tps[j].Group {
+			return tps[i].Group < tps[j].Group
+		}
+		if tps[i].Topic != tps[j].Topic {
+			return tps[i].Topic < tps[j].Topic
+		}
+		return comparePartitions(tps[i].Partition, tps[j].Partition) < 0
+	})
+}
+
+// lessBookmark orders bookmarks by group, then by topic, then by partition
+func lessBookmark(a, b *Bookmark) bool {
+	if a.Group != b.Group {
+		return a.Group < b.Group
+	}
+	if a.Topic != b.Topic {
+		return a.Topic < b.Topic
+	}
+	return comparePartitions(a.Partition, b.Partition) < 0
 }
 
 // NewBookmark creates a new Bookmark with validation and default values
-func NewBookmark(topic, partition string, offset int) (*Bookmark, error) {
+func NewBookmark(topic, partition string, offset int64) (*Bookmark, error) {
 	b := &Bookmark{
 		Topic:     topic,
-		Partition: partition,
+		Partition: NormalizePartition(partition),
 		Offset:    offset,
 		Timestamp: time.Now(),
 		Metadata:  make(map[string]interface{}),
@@ -49,14 +214,14 @@ func NewBookmark(topic, partition string, offset int) (*Bookmark, error) {
 }
 
 // NewBookmarkWithTimestamp creates a new Bookmark with custom timestamp
-func NewBookmarkWithTimestamp(topic, partition string, offset int, timestamp time.Time, metadata map[string]interface{}) (*Bookmark, error) {
+func NewBookmarkWithTimestamp(topic, partition string, offset int64, timestamp time.Time, metadata map[string]interface{}) (*Bookmark, error) {
 	if metadata == nil {
 		metadata = make(map[string]interface{})
 	}
 
 	b := &Bookmark{
 		Topic:     topic,
-		Partition: partition,
+		Partition: NormalizePartition(partition),
 		Offset:    offset,
 		Timestamp: timestamp,
 		Metadata:  metadata,
@@ -77,12 +242,23 @@ func (b *Bookmark) validate() error {
 	if strings.TrimSpace(b.Partition) == "" {
 		return errors.New("partition must be a non-empty string")
 	}
-	if b.Offset < 0 {
-		return errors.New("offset must be a non-negative integer")
+	if err := validateOffset(b.Offset); err != nil {
+		return err
+	}
+	if hasControlCharacter(b.Topic) {
+		return errors.New("topic must not contain control characters")
+	}
+	if hasControlCharacter(b.Partition) {
+		return errors.New("partition must not contain control characters")
 	}
 	return nil
 }
 
+// hasControlCharacter returns true if s contains any unicode control character
+func hasControlCharacter(s string) bool {
+	return strings.IndexFunc(s, unicode.IsControl) >= 0
+}
+
 // TimestampUTC returns timestamp in UTC timezone
 // Note: This is a simplified version. You'll need to implement TimestampUtils.ToUTC
 // or use a timezone conversion library for the exact Eastern timezone conversion
@@ -99,13 +275,27 @@ func (b *Bookmark) TimestampUTCISO() string {
 
 // ToDict converts bookmark to a map (dictionary equivalent)
 func (b *Bookmark) ToDict() map[string]interface{} {
-	return map[string]interface{}{
+	d := map[string]interface{}{
 		"topic":     b.Topic,
 		"partition": b.Partition,
 		"offset":    b.Offset,
 		"timestamp": b.Timestamp.Format(time.RFC3339),
 		"metadata":  b.Metadata,
 	}
+	if b.Group != "" {
+		d["group"] = b.Group
+	}
+	if b.LeaderEpoch != nil {
+		d["leader_epoch"] = *b.LeaderEpoch
+	}
+	if b.HighWatermark != 0 {
+		d["high_watermark"] = b.HighWatermark
+		d["lag"] = b.Lag
+	}
+	if b.Version != 0 {
+		d["version"] = b.Version
+	}
+	return d
 }
 
 // ToJSON converts bookmark to JSON string
@@ -117,7 +307,8 @@ func (b *Bookmark) ToJSON() (string, error) {
 	return string(data), nil
 }
 
-// FromDict creates a bookmark from a map (dictionary equivalent)
+// FromDict creates a bookmark from a map (dictionary equivalent), such as one
+// returned by ToDict or decoded from JSON
 func FromDict(data map[string]interface{}) (*Bookmark, error) {
 	topic, ok := data["topic"].(string)
 	if !ok {
@@ -129,7 +320,9 @@ func FromDict(data map[string]interface{}) (*Bookmark, error) {
 		return nil, errors.New("invalid or missing partition")
 	}
 
-	offset, ok := data["offset"].(float64) // JSON numbers are float64
+	// Offsets are int64 when returned by ToDict and float64 or json.Number
+	// when decoded from JSON
+	offset, ok := asInt64(data["offset"])
 	if !ok {
 		return nil, errors.New("invalid or missing offset")
 	}
@@ -150,7 +343,21 @@ func FromDict(data map[string]interface{}) (*Bookmark, error) {
 		metadata = make(map[string]interface{})
 	}
 
-	return NewBookmarkWithTimestamp(topic, partition, int(offset), timestamp, metadata)
+	b, err := NewBookmarkWithTimestamp(topic, partition, offset, timestamp, metadata)
+	if err != nil {
+		return nil, err
+	}
+	if group, ok := data["group"].(string); ok {
+		b.Group = group
+	}
+	if epoch, ok := asInt64(data["leader_epoch"]); ok {
+		leaderEpoch := int32(epoch)
+		b.LeaderEpoch = &leaderEpoch
+	}
+	b.HighWatermark, _ = asInt64(data["high_watermark"])
+	b.Lag, _ = asInt64(data["lag"])
+	b.Version, _ = asInt64(data["version"])
+	return b, nil
 }
 
 // FromJSON creates a bookmark from JSON string
@@ -0,0 +1,667 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: bookmark.proto
+
+package bookmarkpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EventKind int32
+
+const (
+	EventKind_EVENT_KIND_UNSPECIFIED EventKind = 0
+	EventKind_EVENT_KIND_CREATED     EventKind = 1
+	EventKind_EVENT_KIND_ADVANCED    EventKind = 2
+	EventKind_EVENT_KIND_REWOUND     EventKind = 3
+	EventKind_EVENT_KIND_REMOVED     EventKind = 4
+	EventKind_EVENT_KIND_EXPIRED     EventKind = 5
+)
+
+// Enum value maps for EventKind.
+var (
+	EventKind_name = map[int32]string{
+		0: "EVENT_KIND_UNSPECIFIED",
+		1: "EVENT_KIND_CREATED",
+		2: "EVENT_KIND_ADVANCED",
+		3: "EVENT_KIND_REWOUND",
+		4: "EVENT_KIND_REMOVED",
+		5: "EVENT_KIND_EXPIRED",
+	}
+	EventKind_value = map[string]int32{
+		"EVENT_KIND_UNSPECIFIED": 0,
+		"EVENT_KIND_CREATED":     1,
+		"EVENT_KIND_ADVANCED":    2,
+		"EVENT_KIND_REWOUND":     3,
+		"EVENT_KIND_REMOVED":     4,
+		"EVENT_KIND_EXPIRED":     5,
+	}
+)
+
+func (x EventKind) Enum() *EventKind {
+	p := new(EventKind)
+	*p = x
+	return p
+}
+
+func (x EventKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EventKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_bookmark_proto_enumTypes[0].Descriptor()
+}
+
+func (EventKind) Type() protoreflect.EnumType {
+	return &file_bookmark_proto_enumTypes[0]
+}
+
+func (x EventKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EventKind.Descriptor instead.
+func (EventKind) EnumDescriptor() ([]byte, []int) {
+	return file_bookmark_proto_rawDescGZIP(), []int{0}
+}
+
+// Bookmark is the position of a consumer in a topic partition. Negative
+// offsets are sentinels: -1 is latest, -2 earliest and -3 none.
+type Bookmark struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Partition     string                 `protobuf:"bytes,2,opt,name=partition,proto3" json:"partition,omitempty"`
+	Offset        int64                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Metadata      *structpb.Struct       `protobuf:"bytes,5,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Bookmark) Reset() {
+	*x = Bookmark{}
+	mi := &file_bookmark_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Bookmark) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Bookmark) ProtoMessage() {}
+
+func (x *Bookmark) ProtoReflect() protoreflect.Message {
+	mi := &file_bookmark_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Bookmark.ProtoReflect.Descriptor instead.
+func (*Bookmark) Descriptor() ([]byte, []int) {
+	return file_bookmark_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Bookmark) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *Bookmark) GetPartition() string {
+	if x != nil {
+		return x.Partition
+	}
+	return ""
+}
+
+func (x *Bookmark) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *Bookmark) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Bookmark) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type GetBookmarkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Partition     string                 `protobuf:"bytes,2,opt,name=partition,proto3" json:"partition,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBookmarkRequest) Reset() {
+	*x = GetBookmarkRequest{}
+	mi := &file_bookmark_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBookmarkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBookmarkRequest) ProtoMessage() {}
+
+func (x *GetBookmarkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bookmark_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBookmarkRequest.ProtoReflect.Descriptor instead.
+func (*GetBookmarkRequest) Descriptor() ([]byte, []int) {
+	return file_bookmark_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetBookmarkRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *GetBookmarkRequest) GetPartition() string {
+	if x != nil {
+		return x.Partition
+	}
+	return ""
+}
+
+// SetBookmarkRequest sets the offset of a bookmark. The timestamp defaults to
+// the current time and omitted metadata keeps the metadata of an existing
+// bookmark.
+type SetBookmarkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Partition     string                 `protobuf:"bytes,2,opt,name=partition,proto3" json:"partition,omitempty"`
+	Offset        int64                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Metadata      *structpb.Struct       `protobuf:"bytes,5,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetBookmarkRequest) Reset() {
+	*x = SetBookmarkRequest{}
+	mi := &file_bookmark_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetBookmarkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetBookmarkRequest) ProtoMessage() {}
+
+func (x *SetBookmarkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bookmark_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetBookmarkRequest.ProtoReflect.Descriptor instead.
+func (*SetBookmarkRequest) Descriptor() ([]byte, []int) {
+	return file_bookmark_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SetBookmarkRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *SetBookmarkRequest) GetPartition() string {
+	if x != nil {
+		return x.Partition
+	}
+	return ""
+}
+
+func (x *SetBookmarkRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *SetBookmarkRequest) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *SetBookmarkRequest) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+// ListBookmarksRequest optionally restricts the list to a topic
+type ListBookmarksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBookmarksRequest) Reset() {
+	*x = ListBookmarksRequest{}
+	mi := &file_bookmark_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBookmarksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBookmarksRequest) ProtoMessage() {}
+
+func (x *ListBookmarksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bookmark_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBookmarksRequest.ProtoReflect.Descriptor instead.
+func (*ListBookmarksRequest) Descriptor() ([]byte, []int) {
+	return file_bookmark_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListBookmarksRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+type ListBookmarksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bookmarks     []*Bookmark            `protobuf:"bytes,1,rep,name=bookmarks,proto3" json:"bookmarks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBookmarksResponse) Reset() {
+	*x = ListBookmarksResponse{}
+	mi := &file_bookmark_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBookmarksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBookmarksResponse) ProtoMessage() {}
+
+func (x *ListBookmarksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_bookmark_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBookmarksResponse.ProtoReflect.Descriptor instead.
+func (*ListBookmarksResponse) Descriptor() ([]byte, []int) {
+	return file_bookmark_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListBookmarksResponse) GetBookmarks() []*Bookmark {
+	if x != nil {
+		return x.Bookmarks
+	}
+	return nil
+}
+
+// WatchRequest optionally restricts the events to a topic, to topics matching
+// glob patterns such as "orders-*" and to kinds of events
+type WatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	TopicPatterns []string               `protobuf:"bytes,2,rep,name=topic_patterns,json=topicPatterns,proto3" json:"topic_patterns,omitempty"`
+	Kinds         []EventKind            `protobuf:"varint,3,rep,packed,name=kinds,proto3,enum=bookmark.v1.EventKind" json:"kinds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_bookmark_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bookmark_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_bookmark_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WatchRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetTopicPatterns() []string {
+	if x != nil {
+		return x.TopicPatterns
+	}
+	return nil
+}
+
+func (x *WatchRequest) GetKinds() []EventKind {
+	if x != nil {
+		return x.Kinds
+	}
+	return nil
+}
+
+// BookmarkEvent is a change of a bookmark. The previous offset is set for all
+// kinds but created.
+type BookmarkEvent struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Kind           EventKind              `protobuf:"varint,1,opt,name=kind,proto3,enum=bookmark.v1.EventKind" json:"kind,omitempty"`
+	Topic          string                 `protobuf:"bytes,2,opt,name=topic,proto3" json:"topic,omitempty"`
+	Partition      string                 `protobuf:"bytes,3,opt,name=partition,proto3" json:"partition,omitempty"`
+	Offset         int64                  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	PreviousOffset *int64                 `protobuf:"varint,5,opt,name=previous_offset,json=previousOffset,proto3,oneof" json:"previous_offset,omitempty"`
+	Timestamp      *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *BookmarkEvent) Reset() {
+	*x = BookmarkEvent{}
+	mi := &file_bookmark_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookmarkEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookmarkEvent) ProtoMessage() {}
+
+func (x *BookmarkEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_bookmark_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookmarkEvent.ProtoReflect.Descriptor instead.
+func (*BookmarkEvent) Descriptor() ([]byte, []int) {
+	return file_bookmark_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BookmarkEvent) GetKind() EventKind {
+	if x != nil {
+		return x.Kind
+	}
+	return EventKind_EVENT_KIND_UNSPECIFIED
+}
+
+func (x *BookmarkEvent) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *BookmarkEvent) GetPartition() string {
+	if x != nil {
+		return x.Partition
+	}
+	return ""
+}
+
+func (x *BookmarkEvent) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *BookmarkEvent) GetPreviousOffset() int64 {
+	if x != nil && x.PreviousOffset != nil {
+		return *x.PreviousOffset
+	}
+	return 0
+}
+
+func (x *BookmarkEvent) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+var File_bookmark_proto protoreflect.FileDescriptor
+
+const file_bookmark_proto_rawDesc = "" +
+	"\n" +
+	"\x0ebookmark.proto\x12\vbookmark.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xc5\x01\n" +
+	"\bBookmark\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x1c\n" +
+	"\tpartition\x18\x02 \x01(\tR\tpartition\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x03R\x06offset\x128\n" +
+	"\ttimestamp\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x123\n" +
+	"\bmetadata\x18\x05 \x01(\v2\x17.google.protobuf.StructR\bmetadata\"H\n" +
+	"\x12GetBookmarkRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x1c\n" +
+	"\tpartition\x18\x02 \x01(\tR\tpartition\"\xcf\x01\n" +
+	"\x12SetBookmarkRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x1c\n" +
+	"\tpartition\x18\x02 \x01(\tR\tpartition\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x03R\x06offset\x128\n" +
+	"\ttimestamp\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x123\n" +
+	"\bmetadata\x18\x05 \x01(\v2\x17.google.protobuf.StructR\bmetadata\",\n" +
+	"\x14ListBookmarksRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\"L\n" +
+	"\x15ListBookmarksResponse\x123\n" +
+	"\tbookmarks\x18\x01 \x03(\v2\x15.bookmark.v1.BookmarkR\tbookmarks\"y\n" +
+	"\fWatchRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12%\n" +
+	"\x0etopic_patterns\x18\x02 \x03(\tR\rtopicPatterns\x12,\n" +
+	"\x05kinds\x18\x03 \x03(\x0e2\x16.bookmark.v1.EventKindR\x05kinds\"\x83\x02\n" +
+	"\rBookmarkEvent\x12*\n" +
+	"\x04kind\x18\x01 \x01(\x0e2\x16.bookmark.v1.EventKindR\x04kind\x12\x14\n" +
+	"\x05topic\x18\x02 \x01(\tR\x05topic\x12\x1c\n" +
+	"\tpartition\x18\x03 \x01(\tR\tpartition\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x03R\x06offset\x12,\n" +
+	"\x0fprevious_offset\x18\x05 \x01(\x03H\x00R\x0epreviousOffset\x88\x01\x01\x128\n" +
+	"\ttimestamp\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestampB\x12\n" +
+	"\x10_previous_offset*\xa0\x01\n" +
+	"\tEventKind\x12\x1a\n" +
+	"\x16EVENT_KIND_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12EVENT_KIND_CREATED\x10\x01\x12\x17\n" +
+	"\x13EVENT_KIND_ADVANCED\x10\x02\x12\x16\n" +
+	"\x12EVENT_KIND_REWOUND\x10\x03\x12\x16\n" +
+	"\x12EVENT_KIND_REMOVED\x10\x04\x12\x16\n" +
+	"\x12EVENT_KIND_EXPIRED\x10\x052\xb9\x02\n" +
+	"\x0fBookmarkService\x12E\n" +
+	"\vGetBookmark\x12\x1f.bookmark.v1.GetBookmarkRequest\x1a\x15.bookmark.v1.Bookmark\x12E\n" +
+	"\vSetBookmark\x12\x1f.bookmark.v1.SetBookmarkRequest\x1a\x15.bookmark.v1.Bookmark\x12V\n" +
+	"\rListBookmarks\x12!.bookmark.v1.ListBookmarksRequest\x1a\".bookmark.v1.ListBookmarksResponse\x12@\n" +
+	"\x05Watch\x12\x19.bookmark.v1.WatchRequest\x1a\x1a.bookmark.v1.BookmarkEvent0\x01B:Z8rpanda-connect-native-plugin-example/bookmark/bookmarkpbb\x06proto3"
+
+var (
+	file_bookmark_proto_rawDescOnce sync.Once
+	file_bookmark_proto_rawDescData []byte
+)
+
+func file_bookmark_proto_rawDescGZIP() []byte {
+	file_bookmark_proto_rawDescOnce.Do(func() {
+		file_bookmark_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_bookmark_proto_rawDesc), len(file_bookmark_proto_rawDesc)))
+	})
+	return file_bookmark_proto_rawDescData
+}
+
+var file_bookmark_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_bookmark_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_bookmark_proto_goTypes = []any{
+	(EventKind)(0),                // 0: bookmark.v1.EventKind
+	(*Bookmark)(nil),              // 1: bookmark.v1.Bookmark
+	(*GetBookmarkRequest)(nil),    // 2: bookmark.v1.GetBookmarkRequest
+	(*SetBookmarkRequest)(nil),    // 3: bookmark.v1.SetBookmarkRequest
+	(*ListBookmarksRequest)(nil),  // 4: bookmark.v1.ListBookmarksRequest
+	(*ListBookmarksResponse)(nil), // 5: bookmark.v1.ListBookmarksResponse
+	(*WatchRequest)(nil),          // 6: bookmark.v1.WatchRequest
+	(*BookmarkEvent)(nil),         // 7: bookmark.v1.BookmarkEvent
+	(*timestamppb.Timestamp)(nil), // 8: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),       // 9: google.protobuf.Struct
+}
+var file_bookmark_proto_depIdxs = []int32{
+	8,  // 0: bookmark.v1.Bookmark.timestamp:type_name -> google.protobuf.Timestamp
+	9,  // 1: bookmark.v1.Bookmark.metadata:type_name -> google.protobuf.Struct
+	8,  // 2: bookmark.v1.SetBookmarkRequest.timestamp:type_name -> google.protobuf.Timestamp
+	9,  // 3: bookmark.v1.SetBookmarkRequest.metadata:type_name -> google.protobuf.Struct
+	1,  // 4: bookmark.v1.ListBookmarksResponse.bookmarks:type_name -> bookmark.v1.Bookmark
+	0,  // 5: bookmark.v1.WatchRequest.kinds:type_name -> bookmark.v1.EventKind
+	0,  // 6: bookmark.v1.BookmarkEvent.kind:type_name -> bookmark.v1.EventKind
+	8,  // 7: bookmark.v1.BookmarkEvent.timestamp:type_name -> google.protobuf.Timestamp
+	2,  // 8: bookmark.v1.BookmarkService.GetBookmark:input_type -> bookmark.v1.GetBookmarkRequest
+	3,  // 9: bookmark.v1.BookmarkService.SetBookmark:input_type -> bookmark.v1.SetBookmarkRequest
+	4,  // 10: bookmark.v1.BookmarkService.ListBookmarks:input_type -> bookmark.v1.ListBookmarksRequest
+	6,  // 11: bookmark.v1.BookmarkService.Watch:input_type -> bookmark.v1.WatchRequest
+	1,  // 12: bookmark.v1.BookmarkService.GetBookmark:output_type -> bookmark.v1.Bookmark
+	1,  // 13: bookmark.v1.BookmarkService.SetBookmark:output_type -> bookmark.v1.Bookmark
+	5,  // 14: bookmark.v1.BookmarkService.ListBookmarks:output_type -> bookmark.v1.ListBookmarksResponse
+	7,  // 15: bookmark.v1.BookmarkService.Watch:output_type -> bookmark.v1.BookmarkEvent
+	12, // [12:16] is the sub-list for method output_type
+	8,  // [8:12] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_bookmark_proto_init() }
+func file_bookmark_proto_init() {
+	if File_bookmark_proto != nil {
+		return
+	}
+	file_bookmark_proto_msgTypes[6].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_bookmark_proto_rawDesc), len(file_bookmark_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_bookmark_proto_goTypes,
+		DependencyIndexes: file_bookmark_proto_depIdxs,
+		EnumInfos:         file_bookmark_proto_enumTypes,
+		MessageInfos:      file_bookmark_proto_msgTypes,
+	}.Build()
+	File_bookmark_proto = out.File
+	file_bookmark_proto_goTypes = nil
+	file_bookmark_proto_depIdxs = nil
+}
@@ -0,0 +1,269 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: bookmark.proto
+
+package bookmarkpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	BookmarkService_GetBookmark_FullMethodName   = "/bookmark.v1.BookmarkService/GetBookmark"
+	BookmarkService_SetBookmark_FullMethodName   = "/bookmark.v1.BookmarkService/SetBookmark"
+	BookmarkService_ListBookmarks_FullMethodName = "/bookmark.v1.BookmarkService/ListBookmarks"
+	BookmarkService_Watch_FullMethodName         = "/bookmark.v1.BookmarkService/Watch"
+)
+
+// BookmarkServiceClient is the client API for BookmarkService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// BookmarkService manages the bookmarks of a bookmark manager
+type BookmarkServiceClient interface {
+	// GetBookmark returns a bookmark, or NOT_FOUND
+	GetBookmark(ctx context.Context, in *GetBookmarkRequest, opts ...grpc.CallOption) (*Bookmark, error)
+	// SetBookmark creates or replaces a bookmark, subject to the regression
+	// policy of the manager
+	SetBookmark(ctx context.Context, in *SetBookmarkRequest, opts ...grpc.CallOption) (*Bookmark, error)
+	// ListBookmarks returns all bookmarks sorted by topic and partition
+	ListBookmarks(ctx context.Context, in *ListBookmarksRequest, opts ...grpc.CallOption) (*ListBookmarksResponse, error)
+	// Watch streams the changes of bookmarks until the client cancels. Streams
+	// that fall behind are ended with RESOURCE_EXHAUSTED.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BookmarkEvent], error)
+}
+
+type bookmarkServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBookmarkServiceClient(cc grpc.ClientConnInterface) BookmarkServiceClient {
+	return &bookmarkServiceClient{cc}
+}
+
+func (c *bookmarkServiceClient) GetBookmark(ctx context.Context, in *GetBookmarkRequest, opts ...grpc.CallOption) (*Bookmark, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Bookmark)
+	err := c.cc.Invoke(ctx, BookmarkService_GetBookmark_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookmarkServiceClient) SetBookmark(ctx context.Context, in *SetBookmarkRequest, opts ...grpc.CallOption) (*Bookmark, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Bookmark)
+	err := c.cc.Invoke(ctx, BookmarkService_SetBookmark_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookmarkServiceClient) ListBookmarks(ctx context.Context, in *ListBookmarksRequest, opts ...grpc.CallOption) (*ListBookmarksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListBookmarksResponse)
+	err := c.cc.Invoke(ctx, BookmarkService_ListBookmarks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookmarkServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BookmarkEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BookmarkService_ServiceDesc.Streams[0], BookmarkService_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, BookmarkEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BookmarkService_WatchClient = grpc.ServerStreamingClient[BookmarkEvent]
+
+// BookmarkServiceServer is the server API for BookmarkService service.
+// All implementations must embed UnimplementedBookmarkServiceServer
+// for forward compatibility.
+//
+// BookmarkService manages the bookmarks of a bookmark manager
+type BookmarkServiceServer interface {
+	// GetBookmark returns a bookmark, or NOT_FOUND
+	GetBookmark(context.Context, *GetBookmarkRequest) (*Bookmark, error)
+	// SetBookmark creates or replaces a bookmark, subject to the regression
+	// policy of the manager
+	SetBookmark(context.Context, *SetBookmarkRequest) (*Bookmark, error)
+	// ListBookmarks returns all bookmarks sorted by topic and partition
+	ListBookmarks(context.Context, *ListBookmarksRequest) (*ListBookmarksResponse, error)
+	// Watch streams the changes of bookmarks until the client cancels. Streams
+	// that fall behind are ended with RESOURCE_EXHAUSTED.
+	Watch(*WatchRequest, grpc.ServerStreamingServer[BookmarkEvent]) error
+	mustEmbedUnimplementedBookmarkServiceServer()
+}
+
+// UnimplementedBookmarkServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBookmarkServiceServer struct{}
+
+func (UnimplementedBookmarkServiceServer) GetBookmark(context.Context, *GetBookmarkRequest) (*Bookmark, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBookmark not implemented")
+}
+func (UnimplementedBookmarkServiceServer) SetBookmark(context.Context, *SetBookmarkRequest) (*Bookmark, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetBookmark not implemented")
+}
+func (UnimplementedBookmarkServiceServer) ListBookmarks(context.Context, *ListBookmarksRequest) (*ListBookmarksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBookmarks not implemented")
+}
+func (UnimplementedBookmarkServiceServer) Watch(*WatchRequest, grpc.ServerStreamingServer[BookmarkEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedBookmarkServiceServer) mustEmbedUnimplementedBookmarkServiceServer() {}
+func (UnimplementedBookmarkServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeBookmarkServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BookmarkServiceServer will
+// result in compilation errors.
+type UnsafeBookmarkServiceServer interface {
+	mustEmbedUnimplementedBookmarkServiceServer()
+}
+
+func RegisterBookmarkServiceServer(s grpc.ServiceRegistrar, srv BookmarkServiceServer) {
+	// If the following call pancis, it indicates UnimplementedBookmarkServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&BookmarkService_ServiceDesc, srv)
+}
+
+func _BookmarkService_GetBookmark_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBookmarkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookmarkServiceServer).GetBookmark(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookmarkService_GetBookmark_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookmarkServiceServer).GetBookmark(ctx, req.(*GetBookmarkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookmarkService_SetBookmark_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetBookmarkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookmarkServiceServer).SetBookmark(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookmarkService_SetBookmark_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookmarkServiceServer).SetBookmark(ctx, req.(*SetBookmarkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookmarkService_ListBookmarks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBookmarksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookmarkServiceServer).ListBookmarks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookmarkService_ListBookmarks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookmarkServiceServer).ListBookmarks(ctx, req.(*ListBookmarksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookmarkService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BookmarkServiceServer).Watch(m, &grpc.GenericServerStream[WatchRequest, BookmarkEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BookmarkService_WatchServer = grpc.ServerStreamingServer[BookmarkEvent]
+
+// BookmarkService_ServiceDesc is the grpc.ServiceDesc for BookmarkService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BookmarkService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bookmark.v1.BookmarkService",
+	HandlerType: (*BookmarkServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetBookmark",
+			Handler:    _BookmarkService_GetBookmark_Handler,
+		},
+		{
+			MethodName: "SetBookmark",
+			Handler:    _BookmarkService_SetBookmark_Handler,
+		},
+		{
+			MethodName: "ListBookmarks",
+			Handler:    _BookmarkService_ListBookmarks_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _BookmarkService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "bookmark.proto",
+}
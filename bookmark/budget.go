@@ -0,0 +1,92 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrFileSizeBudgetExceeded is returned when the serialized bookmarks exceed
+// the configured maximum file size even after trimming metadata, and for
+// bookmarks whose metadata would grow the file past it
+var ErrFileSizeBudgetExceeded = errors.New("bookmark file size budget exceeded")
+
+// WithMaxFileSize limits the serialized size of the bookmark file to maxSize
+// bytes. Bookmarks carrying metadata are rejected when they would grow the
+// file past the limit, and saves drop all metadata with a warning when the
+// file exceeds it anyway. A zero value disables the limit.
+func WithMaxFileSize(maxSize int64) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.maxFileSize = maxSize
+	}
+}
+
+// checkMetadataBudget rejects a bookmark replacing previous, which is nil for
+// new bookmarks, when its metadata would grow the bookmark file past the size
+// limit. The size of the file is estimated from the last save and the
+// bookmarks accepted since, accepted bookmarks are added to the estimate.
+func (bm *BookmarkManager) checkMetadataBudget(previous, bookmark *Bookmark) error {
+	if bm.maxFileSize <= 0 || len(bookmark.Metadata) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(bookmark)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bookmark: %w", err)
+	}
+	grow := int64(len(data))
+	if previous != nil {
+		if data, err = json.Marshal(previous); err == nil {
+			grow -= int64(len(data))
+		}
+	}
+
+	if size := bm.fileSize.Load() + grow; grow > 0 && size > bm.maxFileSize {
+		return fmt.Errorf("%w: the metadata of bookmark %s/%s would grow the file to about %d bytes, over the limit of %d bytes", ErrFileSizeBudgetExceeded, bookmark.Topic, bookmark.Partition, size, bm.maxFileSize)
+	}
+	bm.fileSize.Add(grow)
+	return nil
+}
+
+// trimToBudget serializes the bookmark file with all bookmark metadata
+// dropped, which is the only non-essential data of a bookmark, and logs the
+// bookmarks trimmed. Offsets are never trimmed, so if the result still
+// exceeds the budget the write is refused.
+func (bm *BookmarkManager) trimToBudget(bookmarkFile BookmarkFile, size int) ([]byte, error) {
+	trimmed := make([]*Bookmark, 0, len(bookmarkFile.Bookmarks))
+	withMetadata := 0
+	for _, b := range bookmarkFile.Bookmarks {
+		if len(b.Metadata) > 0 {
+			withMetadata++
+		}
+		t := *b
+		t.Metadata = make(map[string]interface{})
+		trimmed = append(trimmed, &t)
+	}
+	bookmarkFile.Bookmarks = trimmed
+	bm.log.Warnf("Bookmark file of %d bytes exceeds the limit of %d bytes, dropped the metadata of %d bookmarks from it", size, bm.maxFileSize, withMetadata)
+
+	data, err := bm.marshalFile(bookmarkFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bookmarks: %w", err)
+	}
+
+	if int64(len(data)) > bm.maxFileSize {
+		return nil, fmt.Errorf("%w: %d bytes without metadata exceeds the limit of %d bytes", ErrFileSizeBudgetExceeded, len(data), bm.maxFileSize)
+	}
+	return data, nil
+}
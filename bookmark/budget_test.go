@@ -0,0 +1,84 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxFileSize(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+
+	// Written without a limit, the metadata is far over the budget below
+	unlimited := NewBookmarkManager(path)
+	for p := 0; p < 4; p++ {
+		require.NoError(t, unlimited.AddBookmark(&Bookmark{
+			Topic:     "orders",
+			Partition: strconv.Itoa(p),
+			Offset:    int64(p),
+			Metadata:  map[string]interface{}{"note": strings.Repeat("x", 500)},
+		}))
+	}
+	require.NoError(t, unlimited.SaveToFile(ctx))
+
+	const budget = 1500
+	bm := NewBookmarkManager(path, WithMaxFileSize(budget))
+	require.NoError(t, bm.LoadFromFile(ctx))
+
+	// Bookmarks growing the file with metadata are rejected, others are not
+	err := bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "9", Offset: 1, Metadata: map[string]interface{}{"note": "x"}})
+	assert.ErrorIs(t, err, ErrFileSizeBudgetExceeded)
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "9", Offset: 1}))
+
+	// Saves drop the metadata to fit the file into the budget
+	require.NoError(t, bm.SaveToFile(ctx))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), int64(budget))
+
+	loaded := NewBookmarkManager(path)
+	require.NoError(t, loaded.LoadFromFile(ctx))
+	require.Equal(t, 5, loaded.Count())
+	for _, b := range loaded.GetAllBookmarks() {
+		assert.Empty(t, b.Metadata, b.Partition)
+	}
+	b, err := loaded.GetBookmark("orders", "3")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), b.Offset)
+
+	// Once the file fits, metadata within the budget is accepted again
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "9", Offset: 2, Metadata: map[string]interface{}{"note": "x"}}))
+}
+
+func TestMaxFileSizeRefusesSavesWithoutMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	bm := NewBookmarkManager(path, WithMaxFileSize(200))
+	for p := 0; p < 10; p++ {
+		require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: strconv.Itoa(p), Offset: int64(p)}))
+	}
+
+	assert.ErrorIs(t, bm.SaveToFile(context.Background()), ErrFileSizeBudgetExceeded)
+	assert.NoFileExists(t, path)
+	assert.True(t, bm.IsDirty())
+}
@@ -0,0 +1,238 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// WithResources sets the resources of the component owning the manager, which
+// provide access to cache resources and the logger
+func WithResources(res *service.Resources) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.res = res
+		bm.log = res.Logger()
+	}
+}
+
+// WithCacheImport makes Open import the given checkpoints from a cache
+// resource when no bookmark file exists yet. Each checkpoint is a
+// "topic:partition" pair which is looked up under the key prefix followed by
+// the checkpoint. It requires WithResources.
+func WithCacheImport(cacheName, keyPrefix string, checkpoints []string) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.importCache = cacheName
+		bm.importKeyPrefix = keyPrefix
+		bm.importCheckpoints = checkpoints
+	}
+}
+
+// parseCheckpoint splits a "topic:partition" checkpoint, see
+// ParseTopicPartition. Colons in topics are percent-encoded whereas
+// partitions can contain them, so the first colon is the separator.
+func parseCheckpoint(checkpoint string) (topic, partition string, err error) {
+	tp, err := ParseTopicPartition(checkpoint)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid checkpoint: %w", err)
+	}
+	return tp.Topic, tp.Partition, nil
+}
+
+// ImportFromCache reads the given "topic:partition" checkpoints from a cache
+// resource and stores them as bookmarks, replacing existing bookmarks of the
+// same topic and partition. A cached value is either a plain offset or a JSON
+// object with at least an offset field, such as a serialized bookmark.
+// Checkpoints missing from the cache are skipped. It returns the number of
+// imported bookmarks.
+func (bm *BookmarkManager) ImportFromCache(ctx context.Context, res *service.Resources, cacheName, keyPrefix string, checkpoints []string) (int, error) {
+	var imported []*Bookmark
+	var decodeErr error
+
+	if err := res.AccessCache(ctx, cacheName, func(c service.Cache) {
+		for _, checkpoint := range checkpoints {
+			topic, partition, err := parseCheckpoint(checkpoint)
+			if err != nil {
+				decodeErr = err
+				return
+			}
+
+			key := keyPrefix + bm.generateKey(topic, partition).String()
+			value, err := c.Get(ctx, key)
+			if errors.Is(err, service.ErrKeyNotFound) {
+				bm.log.Debugf("Checkpoint %s not found in cache %s", key, cacheName)
+				continue
+			}
+			if err != nil {
+				decodeErr = fmt.Errorf("failed to get checkpoint %s: %w", key, err)
+				return
+			}
+
+			b, err := decodeCacheCheckpoint(topic, partition, value)
+			if err != nil {
+				decodeErr = fmt.Errorf("failed to decode checkpoint %s: %w", key, err)
+				return
+			}
+			imported = append(imported, b)
+		}
+	}); err != nil {
+		return 0, fmt.Errorf("failed to access cache %s: %w", cacheName, err)
+	}
+	if decodeErr != nil {
+		return 0, decodeErr
+	}
+
+	for _, b := range imported {
+		if err := bm.AddBookmark(b); err != nil {
+			return 0, err
+		}
+	}
+	return len(imported), nil
+}
+
+// decodeCacheCheckpoint converts a cached checkpoint value into a bookmark
+func decodeCacheCheckpoint(topic, partition string, value []byte) (*Bookmark, error) {
+	trimmed := bytes.TrimSpace(value)
+	if offset, err := strconv.ParseInt(string(trimmed), 10, 64); err == nil {
+		return NewBookmark(topic, partition, offset)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	dec.UseNumber()
+
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil, errors.New("value is neither an offset nor a JSON object")
+	}
+
+	// The checkpoint identifies the bookmark, regardless of what the cached
+	// value claims
+	obj["topic"] = topic
+	obj["partition"] = partition
+	return parseBookmarkObject(obj)
+}
+
+// importFromCacheOnOpen runs the import configured with WithCacheImport when
+// the last load started without a bookmark file
+func (bm *BookmarkManager) importFromCacheOnOpen(ctx context.Context) error {
+	if bm.importCache == "" {
+		return nil
+	}
+	if bm.GetRecoveryReport().Source != "" {
+		return nil
+	}
+	if bm.res == nil {
+		return errors.New("importing from a cache requires resources")
+	}
+
+	n, err := bm.ImportFromCache(ctx, bm.res, bm.importCache, bm.importKeyPrefix, bm.importCheckpoints)
+	if err != nil {
+		return fmt.Errorf("failed to import bookmarks from cache: %w", err)
+	}
+
+	bm.log.Infof("Imported %d bookmarks from cache %s", n, bm.importCache)
+	if n == 0 {
+		return nil
+	}
+	return bm.SaveToFile(ctx)
+}
+
+// Formats of the checkpoints exported to a cache
+const (
+	CacheExportJSON   = "json"
+	CacheExportOffset = "offset"
+)
+
+// WithCacheExport mirrors every bookmark into a cache resource under the key
+// prefix followed by "topic:partition" whenever the bookmarks are saved, and
+// deletes the keys of removed bookmarks. Values are written as serialized
+// bookmarks for CacheExportJSON or as plain offsets for CacheExportOffset. It
+// requires WithResources.
+func WithCacheExport(cacheName, keyPrefix, format string) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.exportCache = cacheName
+		bm.exportKeyPrefix = keyPrefix
+		bm.exportFormat = format
+	}
+}
+
+// encodeCacheCheckpoint converts a bookmark into a cached checkpoint value
+func encodeCacheCheckpoint(b *Bookmark, format string) ([]byte, error) {
+	if format == CacheExportOffset {
+		return strconv.AppendInt(nil, b.Offset, 10), nil
+	}
+	return json.Marshal(b)
+}
+
+// exportToCache writes the bookmarks that changed since the last export to the
+// export cache and deletes the keys of bookmarks that no longer exist. The
+// cache is a secondary copy, so failures are logged rather than failing the
+// save. The caller must hold saveMutex.
+func (bm *BookmarkManager) exportToCache(bookmarks []*Bookmark) {
+	if bm.exportCache == "" || bm.res == nil || bm.readOnly {
+		return
+	}
+
+	current := make(map[string][]byte, len(bookmarks))
+	for _, b := range bookmarks {
+		value, err := encodeCacheCheckpoint(b, bm.exportFormat)
+		if err != nil {
+			bm.log.Warnf("Failed to encode bookmark %s/%s for cache %s: %v", b.Topic, b.Partition, bm.exportCache, err)
+			continue
+		}
+		current[bm.exportKeyPrefix+b.TopicPartition().String()] = value
+	}
+
+	ctx := context.Background()
+	if err := bm.res.AccessCache(ctx, bm.exportCache, func(c service.Cache) {
+		for key, value := range current {
+			if bytes.Equal(bm.exported[key], value) {
+				continue
+			}
+			if err := c.Set(ctx, key, value, nil); err != nil {
+				bm.log.Warnf("Failed to export checkpoint %s to cache %s: %v", key, bm.exportCache, err)
+				// Retried on the next save
+				current[key] = bm.exported[key]
+			}
+		}
+		for key := range bm.exported {
+			if _, exists := current[key]; exists {
+				continue
+			}
+			if err := c.Delete(ctx, key); err != nil && !errors.Is(err, service.ErrKeyNotFound) {
+				bm.log.Warnf("Failed to delete checkpoint %s from cache %s: %v", key, bm.exportCache, err)
+				current[key] = nil
+			}
+		}
+	}); err != nil {
+		bm.log.Warnf("Failed to access cache %s: %v", bm.exportCache, err)
+		return
+	}
+	bm.exported = current
+}
+
+// exportOnOpen populates the export cache with the loaded bookmarks
+func (bm *BookmarkManager) exportOnOpen() {
+	bm.saveMutex.Lock()
+	defer bm.saveMutex.Unlock()
+
+	bm.exportToCache(bm.GetAllBookmarks())
+}
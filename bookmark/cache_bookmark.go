@@ -0,0 +1,191 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	bcFieldFormat     = "format"
+	bcFieldDefaultTTL = "default_ttl"
+
+	// MetaCacheTTL is the bookmark metadata key holding the TTL a bookmark
+	// was written with by the bookmark_cache
+	MetaCacheTTL = "cache_ttl"
+)
+
+func bookmarkCacheSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Summary("Exposes the bookmarks of a bookmark file as a cache, keyed by `topic:partition`.").
+		Description(`
+This allows cache based components, such as the `+"`cache`"+` processor or deduplication, to read and write bookmarks. The topic of a key ends at its first colon, so partitions may contain colons. Values are written as plain offsets or as serialized bookmarks depending on `+"`format`"+`, and both are accepted when setting keys. Every change is saved to the bookmarks file immediately, unless `+"`auto_save_interval`"+` is set.
+
+A TTL is measured from the timestamp of the bookmark and stored in its `+"`cache_ttl`"+` metadata field, bookmarks older than their TTL are treated as missing. Bookmarks without a TTL, such as those written by other components, never expire unless a `+"`default_ttl`"+` is set.`).
+		Fields(
+			service.NewStringEnumField(bcFieldFormat, CacheExportOffset, CacheExportJSON).
+				Description("The format values are returned in, either the plain offset or the serialized bookmark.").
+				Default(CacheExportOffset),
+			service.NewDurationField(bcFieldDefaultTTL).
+				Description("The TTL of bookmarks without a TTL of their own.").
+				Example("24h").
+				Optional(),
+		).
+		Fields(BookmarkFileManagerConfigFields()...)
+}
+
+func init() {
+	service.MustRegisterCache("bookmark_cache", bookmarkCacheSpec(),
+		func(pConf *service.ParsedConfig, res *service.Resources) (service.Cache, error) {
+			return newBookmarkCacheFromParsed(pConf, res)
+		})
+}
+
+//------------------------------------------------------------------------------
+
+type bookmarkCache struct {
+	format     string
+	defaultTTL *time.Duration
+
+	bm *BookmarkManager
+
+	// writeMutex serializes writes, so that Add can check for an existing key
+	// without racing other writers
+	writeMutex sync.Mutex
+}
+
+func newBookmarkCacheFromParsed(pConf *service.ParsedConfig, res *service.Resources) (*bookmarkCache, error) {
+	c := &bookmarkCache{}
+
+	var err error
+	if c.format, err = pConf.FieldString(bcFieldFormat); err != nil {
+		return nil, err
+	}
+	if pConf.Contains(bcFieldDefaultTTL) {
+		ttl, err := pConf.FieldDuration(bcFieldDefaultTTL)
+		if err != nil {
+			return nil, err
+		}
+		c.defaultTTL = &ttl
+	}
+
+	if c.bm, err = BookmarkFileManagerFromParsed(pConf, WithResources(res)); err != nil {
+		return nil, err
+	}
+	if err := c.bm.Open(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to open bookmarks: %w", err)
+	}
+	return c, nil
+}
+
+// expired returns whether a bookmark outlived its TTL at now
+func (c *bookmarkCache) expired(b *Bookmark, now time.Time) bool {
+	ttl := c.defaultTTL
+	if d, ok := b.GetMetadataDuration(MetaCacheTTL); ok {
+		ttl = &d
+	}
+	return ttl != nil && now.After(b.Timestamp.Add(*ttl))
+}
+
+// lookup returns the unexpired bookmark of a key
+func (c *bookmarkCache) lookup(key string) (*Bookmark, error) {
+	topic, partition, err := parseCheckpoint(key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := c.bm.GetBookmark(topic, partition)
+	if errors.Is(err, ErrBookmarkNotFound) || (err == nil && c.expired(b, time.Now())) {
+		return nil, service.ErrKeyNotFound
+	}
+	return b, err
+}
+
+func (c *bookmarkCache) Get(ctx context.Context, key string) ([]byte, error) {
+	b, err := c.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	return encodeCacheCheckpoint(b, c.format)
+}
+
+// set stores a value under a key, the caller must hold writeMutex
+func (c *bookmarkCache) set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	topic, partition, err := parseCheckpoint(key)
+	if err != nil {
+		return err
+	}
+	b, err := decodeCacheCheckpoint(topic, partition, value)
+	if err != nil {
+		return fmt.Errorf("failed to decode value of %s: %w", key, err)
+	}
+	if ttl != nil {
+		b.Metadata[MetaCacheTTL] = ttl.String()
+	} else {
+		delete(b.Metadata, MetaCacheTTL)
+	}
+
+	if err := c.bm.AddBookmark(b); err != nil {
+		return err
+	}
+	return c.bm.RequestSave(ctx)
+}
+
+func (c *bookmarkCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	return c.set(ctx, key, value, ttl)
+}
+
+func (c *bookmarkCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	if _, err := c.lookup(key); err == nil {
+		return service.ErrKeyAlreadyExists
+	} else if !errors.Is(err, service.ErrKeyNotFound) {
+		return err
+	}
+	return c.set(ctx, key, value, ttl)
+}
+
+func (c *bookmarkCache) Delete(ctx context.Context, key string) error {
+	topic, partition, err := parseCheckpoint(key)
+	if err != nil {
+		return err
+	}
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	if err := c.bm.RemoveBookmark(topic, partition); err != nil {
+		if errors.Is(err, ErrBookmarkNotFound) {
+			return nil
+		}
+		return err
+	}
+	return c.bm.RequestSave(ctx)
+}
+
+func (c *bookmarkCache) Close(ctx context.Context) error {
+	return c.bm.Close(ctx)
+}
@@ -0,0 +1,209 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+)
+
+// FileVersion is the layout version written to bookmark files. Files of older
+// layouts are migrated when loaded, see RegisterFileMigration.
+const FileVersion = "1.1"
+
+// maxReadableMajorVersion is the newest layout that is known to be readable.
+// Files of newer layouts are still read as far as their fields are
+// understood.
+const maxReadableMajorVersion = 2
+
+// decodeBookmarkFile decodes a bookmark file of any known layout. The 2.0
+// layout differs from 1.0 by additional fields, which are ignored, integer
+// partitions and unix millisecond timestamps. Entries that can't be decoded
+// or are invalid are skipped and recorded in the report, so that a single
+// unreadable entry never discards the rest of the state. Files of older
+// layouts are migrated to FileVersion before decoding. A file that fails
+// its checksum is rejected as a whole. Compressed files are decompressed
+// first, and MessagePack files are decoded by decodeMsgpackFile.
+func decodeBookmarkFile(data []byte, report *RecoveryReport) (*BookmarkFile, error) {
+	return decodeFile(data, report, true)
+}
+
+// decodeUnverifiedFile decodes a bookmark file like decodeBookmarkFile
+// without verifying its checksum
+func decodeUnverifiedFile(data []byte, report *RecoveryReport) (*BookmarkFile, error) {
+	return decodeFile(data, report, false)
+}
+
+func decodeFile(data []byte, report *RecoveryReport, verify bool) (*BookmarkFile, error) {
+	data, err := decompressFile(data)
+	if err != nil {
+		return nil, err
+	}
+	if isMsgpackFile(data) {
+		return decodeMsgpackFile(data, report, verify)
+	}
+
+	type rawFile struct {
+		BookmarkFile
+		Bookmarks []json.RawMessage `json:"bookmarks"`
+	}
+	var raw rawFile
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	bookmarkFile := raw.BookmarkFile
+	if verify {
+		if err := verifyChecksum(data, bookmarkFile.Checksum); err != nil {
+			return nil, err
+		}
+	}
+
+	migrated, err := migrateFile(data, bookmarkFile.Version)
+	if err != nil {
+		return nil, err
+	}
+	if migrated != nil {
+		report.MigratedFrom = bookmarkFile.Version
+		if report.MigratedFrom == "" {
+			report.MigratedFrom = "1.0"
+		}
+		raw = rawFile{}
+		if err := json.Unmarshal(migrated, &raw); err != nil {
+			return nil, fmt.Errorf("failed to decode migrated file: %w", err)
+		}
+		bookmarkFile = raw.BookmarkFile
+	}
+	if major, ok := majorVersion(bookmarkFile.Version); ok && major > maxReadableMajorVersion {
+		report.problemf("file version %s is newer than the supported versions, only known fields are read", bookmarkFile.Version)
+	}
+
+	bookmarkFile.Bookmarks = make([]*Bookmark, 0, len(raw.Bookmarks))
+	for i, entry := range raw.Bookmarks {
+		var b *Bookmark
+		if err := json.Unmarshal(entry, &b); err != nil {
+			report.Skipped++
+			report.problemf("bookmark entry %d can't be decoded: %v", i, err)
+			continue
+		}
+		if b == nil {
+			report.Skipped++
+			report.problemf("bookmark entry %d is empty", i)
+			continue
+		}
+		if err := b.validate(); err != nil {
+			report.Skipped++
+			report.problemf("bookmark entry %d is invalid: %v", i, err)
+			continue
+		}
+		bookmarkFile.Bookmarks = append(bookmarkFile.Bookmarks, b)
+	}
+	return &bookmarkFile, nil
+}
+
+// majorVersion returns the major number of a version such as "1.0"
+func majorVersion(version string) (int, bool) {
+	major, _, _ := strings.Cut(version, ".")
+	n, err := strconv.Atoi(major)
+	return n, err == nil
+}
+
+// UnmarshalJSON decodes a bookmark, accepting partitions and offsets as
+// either strings or numbers, sentinel offsets by name, and timestamps as
+// either RFC 3339 strings or unix milliseconds
+func (b *Bookmark) UnmarshalJSON(data []byte) error {
+	type plainBookmark Bookmark
+	var raw struct {
+		plainBookmark
+		Partition json.RawMessage `json:"partition"`
+		Offset    json.RawMessage `json:"offset"`
+		Timestamp json.RawMessage `json:"timestamp"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*b = Bookmark(raw.plainBookmark)
+
+	partition, err := decodeLooseValue(raw.Partition)
+	if err != nil {
+		return fmt.Errorf("invalid partition: %w", err)
+	}
+	if partition != nil {
+		if b.Partition, err = partitionFromValue(partition); err != nil {
+			return err
+		}
+		b.Partition = NormalizePartition(b.Partition)
+	}
+
+	offset, err := decodeLooseValue(raw.Offset)
+	if err != nil {
+		return fmt.Errorf("invalid offset: %w", err)
+	}
+	switch o := offset.(type) {
+	case nil:
+	case string:
+		if b.Offset, err = ParseOffset(o); err != nil {
+			return err
+		}
+	default:
+		// Negative offsets are decoded so that validation reports them
+		if b.Offset, err = bloblang.ValueAsInt64(o); err != nil {
+			return fmt.Errorf("invalid offset: %v", err)
+		}
+	}
+
+	timestamp, err := decodeLooseValue(raw.Timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+	switch t := timestamp.(type) {
+	case nil:
+	case json.Number:
+		ms, err := t.Int64()
+		if err != nil {
+			return fmt.Errorf("invalid timestamp: %v", err)
+		}
+		b.Timestamp = time.UnixMilli(ms)
+	case string:
+		if b.Timestamp, err = time.Parse(time.RFC3339Nano, t); err != nil {
+			return fmt.Errorf("invalid timestamp: %v", err)
+		}
+	default:
+		return fmt.Errorf("invalid timestamp: unexpected %T", t)
+	}
+	return nil
+}
+
+// decodeLooseValue decodes a JSON scalar, keeping numbers as json.Number. A
+// missing or null value decodes as nil.
+func decodeLooseValue(data json.RawMessage) (any, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the codec the bookmark file is compressed with
+type Compression string
+
+// Compression codecs
+const (
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+// Magic numbers at the start of compressed files
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// WithCompression compresses the bookmark file, its backups and rotated
+// segments with the given codec. Loads detect the codec of a file, so that the
+// codec can be changed without converting existing files.
+func WithCompression(compression Compression) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.compression = compression
+	}
+}
+
+// The zstd encoder and decoder are safe for concurrent use and expensive to
+// create, so they are shared by all managers
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+func zstdCodec() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdOnce.Do(func() {
+		if zstdEncoder, zstdErr = zstd.NewWriter(nil); zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	return zstdEncoder, zstdDecoder, zstdErr
+}
+
+// compressFile compresses a serialized bookmark file with the configured codec
+func (bm *BookmarkManager) compressFile(data []byte) ([]byte, error) {
+	switch bm.compression {
+	case "", CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to compress bookmarks: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress bookmarks: %w", err)
+		}
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		enc, _, err := zstdCodec()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd codec: %w", err)
+		}
+		return enc.EncodeAll(data, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown compression: %s", bm.compression)
+	}
+}
+
+// decompressFile decompresses a bookmark file compressed with any of the
+// supported codecs, detected by its magic number. Uncompressed files are
+// returned as is.
+func decompressFile(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress gzip file: %w", err)
+		}
+		defer r.Close()
+		if data, err = io.ReadAll(r); err != nil {
+			return nil, fmt.Errorf("failed to decompress gzip file: %w", err)
+		}
+		return data, nil
+	case bytes.HasPrefix(data, zstdMagic):
+		_, dec, err := zstdCodec()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd codec: %w", err)
+		}
+		if data, err = dec.DecodeAll(data, nil); err != nil {
+			return nil, fmt.Errorf("failed to decompress zstd file: %w", err)
+		}
+		return data, nil
+	}
+	return data, nil
+}
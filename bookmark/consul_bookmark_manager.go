@@ -0,0 +1,675 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	consulDefaultAddress    = "http://127.0.0.1:8500"
+	consulDefaultPrefix     = "bookmarks/"
+	consulDefaultSessionTTL = 15 * time.Second
+)
+
+// ConsulBookmarkManagerConfig configures a ConsulBookmarkManager
+type ConsulBookmarkManagerConfig struct {
+	// Address of the Consul agent, defaults to CONSUL_HTTP_ADDR or
+	// http://127.0.0.1:8500
+	Address string
+
+	// Token is the ACL token, defaults to CONSUL_HTTP_TOKEN
+	Token string
+
+	Datacenter string
+
+	// Prefix is prepended to the keys, defaults to bookmarks/
+	Prefix string
+
+	// SessionTTL is how long the locks of an instance outlive it, defaults to
+	// 15 seconds
+	SessionTTL time.Duration
+
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// consulKV is a key of the Consul KV API
+type consulKV struct {
+	Key         string
+	Value       []byte
+	ModifyIndex uint64
+	Session     string
+}
+
+// consulVersion is the modify index and lock session of a key when it was
+// last seen. Acquiring and releasing locks changes the modify index, so the
+// session is current as long as the index is.
+type consulVersion struct {
+	index   uint64
+	session string
+}
+
+// consulTxnOp is a KV operation of a Consul transaction
+type consulTxnOp struct {
+	Verb    string
+	Key     string
+	Value   []byte `json:",omitempty"`
+	Index   uint64 `json:",omitempty"`
+	Session string `json:",omitempty"`
+}
+
+// ConsulBookmarkManager stores each bookmark as a key of the Consul KV store
+// under the prefix, keyed by "topic:partition", so that instances sharing a
+// Consul cluster can share bookmarks.
+//
+// Puts and deletes are conditional on the modify index of the key last read
+// or written by the manager, and fail with ErrBookmarkConflict when another
+// instance changed the bookmark in the meantime. Instances lock partitions
+// with Lock, which acquires the key of the bookmark with a session of the
+// instance that's released when the instance stops for longer than the
+// session TTL. Changing the bookmark of a partition locked by another
+// instance fails with ErrPartitionClaimed.
+type ConsulBookmarkManager struct {
+	client     *http.Client
+	address    string
+	token      string
+	datacenter string
+	prefix     string
+	sessionTTL time.Duration
+
+	// versions are the keys last seen
+	mutex    sync.Mutex
+	versions map[TopicPartition]consulVersion
+
+	sessionMutex sync.Mutex
+	session      string
+	locks        map[TopicPartition]struct{}
+	stopRenew    context.CancelFunc
+	renewDone    chan struct{}
+}
+
+var _ GroupBookmarkStore = (*ConsulBookmarkManager)(nil)
+
+// NewConsulBookmarkManager creates a Consul store and checks that the agent is
+// reachable
+func NewConsulBookmarkManager(ctx context.Context, conf ConsulBookmarkManagerConfig) (*ConsulBookmarkManager, error) {
+	if conf.Address == "" {
+		conf.Address = os.Getenv("CONSUL_HTTP_ADDR")
+	}
+	if conf.Address == "" {
+		conf.Address = consulDefaultAddress
+	}
+	if !strings.Contains(conf.Address, "://") {
+		conf.Address = "http://" + conf.Address
+	}
+	if conf.Token == "" {
+		conf.Token = os.Getenv("CONSUL_HTTP_TOKEN")
+	}
+	if conf.Prefix == "" {
+		conf.Prefix = consulDefaultPrefix
+	}
+	if conf.SessionTTL <= 0 {
+		conf.SessionTTL = consulDefaultSessionTTL
+	}
+	if conf.HTTPClient == nil {
+		conf.HTTPClient = http.DefaultClient
+	}
+
+	m := &ConsulBookmarkManager{
+		client:     conf.HTTPClient,
+		address:    strings.TrimSuffix(conf.Address, "/"),
+		token:      conf.Token,
+		datacenter: conf.Datacenter,
+		prefix:     strings.TrimPrefix(conf.Prefix, "/"),
+		sessionTTL: conf.SessionTTL,
+		versions:   make(map[TopicPartition]consulVersion),
+		locks:      make(map[TopicPartition]struct{}),
+	}
+	if _, err := m.do(ctx, http.MethodGet, "/v1/status/leader", nil, nil, nil); err != nil {
+		return nil, fmt.Errorf("failed to connect to consul: %w", err)
+	}
+	return m, nil
+}
+
+// do sends a request to the Consul HTTP API, decoding the response into out
+// when it's set, and returns the status code. Responses other than 200, 404
+// and 409 are errors.
+func (m *ConsulBookmarkManager) do(ctx context.Context, method, path string, query url.Values, body any, out any) (int, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	if m.datacenter != "" {
+		query.Set("dc", m.datacenter)
+	}
+
+	var reqBody io.Reader
+	if data, ok := body.([]byte); ok {
+		reqBody = bytes.NewReader(data)
+	} else if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, m.address+path+"?"+query.Encode(), reqBody)
+	if err != nil {
+		return 0, err
+	}
+	if m.token != "" {
+		req.Header.Set("X-Consul-Token", m.token)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusConflict:
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+			}
+		}
+		return resp.StatusCode, nil
+	case http.StatusNotFound:
+		return resp.StatusCode, nil
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode, fmt.Errorf("consul returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+}
+
+// key returns the KV key of the bookmark of a partition
+func (m *ConsulBookmarkManager) key(tp TopicPartition) string {
+	return m.prefix + tp.String()
+}
+
+// read returns the key of a bookmark, or nil when it doesn't exist
+func (m *ConsulBookmarkManager) read(ctx context.Context, tp TopicPartition) (*consulKV, error) {
+	var kvs []*consulKV
+	status, err := m.do(ctx, http.MethodGet, "/v1/kv/"+m.key(tp), nil, nil, &kvs)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound || len(kvs) == 0 {
+		m.seen(tp, nil)
+		return nil, nil
+	}
+	m.seen(tp, kvs[0])
+	return kvs[0], nil
+}
+
+// seen records the version of a key, where nil forgets the key
+func (m *ConsulBookmarkManager) seen(tp TopicPartition, kv *consulKV) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if kv == nil {
+		delete(m.versions, tp)
+		return
+	}
+	m.versions[tp] = consulVersion{index: kv.ModifyIndex, session: kv.Session}
+}
+
+// version returns the version of a key last seen
+func (m *ConsulBookmarkManager) version(tp TopicPartition) (consulVersion, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	v, exists := m.versions[tp]
+	return v, exists
+}
+
+// txn runs KV operations in a transaction, returning false when a check or
+// CAS operation failed and the transaction was rolled back
+func (m *ConsulBookmarkManager) txn(ctx context.Context, ops ...consulTxnOp) (bool, []*consulKV, error) {
+	body := make([]map[string]consulTxnOp, 0, len(ops))
+	for _, op := range ops {
+		body = append(body, map[string]consulTxnOp{"KV": op})
+	}
+	var result struct {
+		Results []struct {
+			KV *consulKV
+		}
+	}
+	status, err := m.do(ctx, http.MethodPut, "/v1/txn", nil, body, &result)
+	if err != nil {
+		return false, nil, err
+	}
+	if status != http.StatusOK {
+		return false, nil, nil
+	}
+	kvs := make([]*consulKV, 0, len(result.Results))
+	for _, r := range result.Results {
+		kvs = append(kvs, r.KV)
+	}
+	return true, kvs, nil
+}
+
+// locked returns the session of this instance if it holds the lock of a
+// partition
+func (m *ConsulBookmarkManager) locked(tp TopicPartition) (string, bool) {
+	m.sessionMutex.Lock()
+	defer m.sessionMutex.Unlock()
+
+	_, locked := m.locks[tp]
+	return m.session, locked
+}
+
+// change runs a write of the key of a bookmark, which is checked against the
+// lock of the partition when this instance holds it and against the modify
+// index last seen otherwise. The error of a failed check tells whether
+// another instance changed or locked the bookmark.
+func (m *ConsulBookmarkManager) change(ctx context.Context, tp TopicPartition, op consulTxnOp) error {
+	key := m.key(tp)
+	session, locked := m.locked(tp)
+	var check consulTxnOp
+	if locked {
+		check = consulTxnOp{Verb: "check-session", Key: key, Session: session}
+	} else if v, exists := m.version(tp); exists {
+		if v.session != "" && v.session != session {
+			return fmt.Errorf("%w: %s", ErrPartitionClaimed, tp)
+		}
+		check = consulTxnOp{Verb: "check-index", Key: key, Index: v.index}
+	} else {
+		check = consulTxnOp{Verb: "check-not-exists", Key: key}
+	}
+
+	ok, kvs, err := m.txn(ctx, check, op)
+	if err != nil {
+		return err
+	}
+	if ok {
+		m.seen(tp, written(op, kvs))
+		return nil
+	}
+
+	current, err := m.read(ctx, tp)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBookmarkConflict, tp)
+	}
+	if current != nil && current.Session != "" && current.Session != session {
+		return fmt.Errorf("%w: %s", ErrPartitionClaimed, tp)
+	}
+	return fmt.Errorf("%w: %s", ErrBookmarkConflict, tp)
+}
+
+// written returns the key written by the last operation of a transaction,
+// which is the last result as deletes and some checks have none, or nil when
+// the key was deleted
+func written(op consulTxnOp, kvs []*consulKV) *consulKV {
+	if op.Verb == "delete" || len(kvs) == 0 {
+		return nil
+	}
+	return kvs[len(kvs)-1]
+}
+
+// consulBookmark decodes the value of a key, returning nil for keys without a
+// bookmark such as those only holding a lock
+func consulBookmark(kv *consulKV) *Bookmark {
+	if kv == nil || len(kv.Value) == 0 {
+		return nil
+	}
+	var b *Bookmark
+	if err := json.Unmarshal(kv.Value, &b); err != nil || b == nil || b.validate() != nil {
+		return nil
+	}
+	if b.Metadata == nil {
+		b.Metadata = make(map[string]interface{})
+	}
+	return b
+}
+
+// Get returns the bookmark of a topic and partition in the default group
+func (m *ConsulBookmarkManager) Get(ctx context.Context, topic, partition string) (*Bookmark, error) {
+	return m.GetInGroup(ctx, "", topic, partition)
+}
+
+// GetInGroup returns the bookmark of a topic and partition in a group
+func (m *ConsulBookmarkManager) GetInGroup(ctx context.Context, group, topic, partition string) (*Bookmark, error) {
+	kv, err := m.read(ctx, NewGroupTopicPartition(group, topic, partition))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bookmark: %w", err)
+	}
+	b := consulBookmark(kv)
+	if b == nil {
+		return nil, notFoundError(group, topic, partition)
+	}
+	return b, nil
+}
+
+// Put adds or replaces a bookmark in a transaction that fails with
+// ErrBookmarkConflict when another instance changed the bookmark since the
+// manager last read or wrote it, and with ErrPartitionClaimed when another
+// instance locked its partition
+func (m *ConsulBookmarkManager) Put(ctx context.Context, bookmark *Bookmark) error {
+	if bookmark == nil {
+		return errors.New("bookmark cannot be nil")
+	}
+	if err := bookmark.validate(); err != nil {
+		return fmt.Errorf("invalid bookmark: %w", err)
+	}
+	value, err := json.Marshal(bookmark)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bookmark: %w", err)
+	}
+
+	tp := bookmark.TopicPartition()
+	if err := m.change(ctx, tp, consulTxnOp{Verb: "set", Key: m.key(tp), Value: value}); err != nil {
+		return fmt.Errorf("failed to put bookmark: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the bookmark of a topic and partition in the default group
+func (m *ConsulBookmarkManager) Delete(ctx context.Context, topic, partition string) error {
+	return m.DeleteInGroup(ctx, "", topic, partition)
+}
+
+// DeleteInGroup removes the bookmark of a topic and partition in a group,
+// failing like Put. Deleting the bookmark of a partition locked by this
+// instance releases the lock.
+func (m *ConsulBookmarkManager) DeleteInGroup(ctx context.Context, group, topic, partition string) error {
+	tp := NewGroupTopicPartition(group, topic, partition)
+	if _, exists := m.version(tp); !exists {
+		kv, err := m.read(ctx, tp)
+		if err != nil {
+			return fmt.Errorf("failed to delete bookmark: %w", err)
+		}
+		if consulBookmark(kv) == nil {
+			return notFoundError(group, topic, partition)
+		}
+	}
+
+	if err := m.change(ctx, tp, consulTxnOp{Verb: "delete", Key: m.key(tp)}); err != nil {
+		return fmt.Errorf("failed to delete bookmark: %w", err)
+	}
+	m.sessionMutex.Lock()
+	delete(m.locks, tp)
+	m.sessionMutex.Unlock()
+	return nil
+}
+
+// List returns all bookmarks sorted by topic and partition
+func (m *ConsulBookmarkManager) List(ctx context.Context) ([]*Bookmark, error) {
+	var kvs []*consulKV
+	if _, err := m.do(ctx, http.MethodGet, "/v1/kv/"+m.prefix, url.Values{"recurse": {"true"}}, nil, &kvs); err != nil {
+		return nil, fmt.Errorf("failed to list bookmarks: %w", err)
+	}
+
+	bookmarks := make([]*Bookmark, 0, len(kvs))
+	for _, kv := range kvs {
+		b := consulBookmark(kv)
+		if b == nil {
+			continue
+		}
+		m.seen(b.TopicPartition(), kv)
+		bookmarks = append(bookmarks, b)
+	}
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return lessBookmark(bookmarks[i], bookmarks[j])
+	})
+	return bookmarks, nil
+}
+
+// Flush does nothing, as every Put commits a transaction
+func (m *ConsulBookmarkManager) Flush(ctx context.Context) error {
+	return nil
+}
+
+// ensureSession creates the session of the instance if it has none and
+// renews it until it's destroyed. The caller must hold sessionMutex.
+func (m *ConsulBookmarkManager) ensureSession(ctx context.Context) error {
+	if m.session != "" {
+		return nil
+	}
+
+	var created struct {
+		ID string
+	}
+	_, err := m.do(ctx, http.MethodPut, "/v1/session/create", nil, map[string]string{
+		"Name":     "bookmarks",
+		"TTL":      m.sessionTTL.String(),
+		"Behavior": "release",
+	}, &created)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	m.session = created.ID
+
+	renewCtx, cancel := context.WithCancel(context.Background())
+	m.stopRenew, m.renewDone = cancel, make(chan struct{})
+	go m.renew(renewCtx, created.ID, m.renewDone)
+	return nil
+}
+
+// renew renews a session at half its TTL until ctx is done, dropping the
+// locks when the session was invalidated
+func (m *ConsulBookmarkManager) renew(ctx context.Context, session string, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(m.sessionTTL / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		status, err := m.do(ctx, http.MethodPut, "/v1/session/renew/"+session, nil, nil, nil)
+		if err != nil || status != http.StatusNotFound {
+			continue
+		}
+
+		m.sessionMutex.Lock()
+		if m.session == session {
+			m.session = ""
+			clear(m.locks)
+		}
+		m.sessionMutex.Unlock()
+		return
+	}
+}
+
+// Lock locks a partition for this instance until Unlock or Close, or until
+// the instance stops for longer than the session TTL. It returns false when
+// the partition is locked by another instance.
+func (m *ConsulBookmarkManager) Lock(ctx context.Context, topic, partition string) (bool, error) {
+	tp := NewTopicPartition(topic, partition)
+
+	current, err := m.read(ctx, tp)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock partition: %w", err)
+	}
+
+	m.sessionMutex.Lock()
+	defer m.sessionMutex.Unlock()
+
+	if err := m.ensureSession(ctx); err != nil {
+		return false, err
+	}
+	if current != nil && current.Session != "" {
+		if current.Session != m.session {
+			return false, nil
+		}
+		m.locks[tp] = struct{}{}
+		return true, nil
+	}
+
+	check := consulTxnOp{Verb: "check-not-exists", Key: m.key(tp)}
+	var value []byte
+	if current != nil {
+		check = consulTxnOp{Verb: "check-index", Key: m.key(tp), Index: current.ModifyIndex}
+		value = current.Value
+	}
+	ok, kvs, err := m.txn(ctx, check, consulTxnOp{Verb: "lock", Key: m.key(tp), Value: value, Session: m.session})
+	if err != nil {
+		return false, fmt.Errorf("failed to lock partition: %w", err)
+	}
+	if !ok {
+		return false, nil
+	}
+	m.seen(tp, written(consulTxnOp{Verb: "lock"}, kvs))
+	m.locks[tp] = struct{}{}
+	return true, nil
+}
+
+// Unlock releases the lock of a partition held by this instance
+func (m *ConsulBookmarkManager) Unlock(ctx context.Context, topic, partition string) error {
+	tp := NewTopicPartition(topic, partition)
+
+	m.sessionMutex.Lock()
+	defer m.sessionMutex.Unlock()
+
+	if _, locked := m.locks[tp]; !locked {
+		return nil
+	}
+	var kvs []*consulKV
+	if _, err := m.do(ctx, http.MethodGet, "/v1/kv/"+m.key(tp), nil, nil, &kvs); err != nil {
+		return fmt.Errorf("failed to unlock partition: %w", err)
+	}
+	if len(kvs) > 0 && kvs[0].Session == m.session {
+		ok, result, err := m.txn(ctx,
+			consulTxnOp{Verb: "check-index", Key: m.key(tp), Index: kvs[0].ModifyIndex},
+			consulTxnOp{Verb: "unlock", Key: m.key(tp), Value: kvs[0].Value, Session: m.session},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to unlock partition: %w", err)
+		}
+		if !ok {
+			return fmt.Errorf("failed to unlock partition: %w: %s", ErrBookmarkConflict, tp)
+		}
+		m.seen(tp, written(consulTxnOp{Verb: "unlock"}, result))
+	}
+	delete(m.locks, tp)
+	return nil
+}
+
+// Locks returns the partitions locked by this instance
+func (m *ConsulBookmarkManager) Locks() []TopicPartition {
+	m.sessionMutex.Lock()
+	defer m.sessionMutex.Unlock()
+
+	locks := make([]TopicPartition, 0, len(m.locks))
+	for tp := range m.locks {
+		locks = append(locks, tp)
+	}
+	sortTopicPartitions(locks)
+	return locks
+}
+
+// Close destroys the session of the instance, which releases its locks
+func (m *ConsulBookmarkManager) Close(ctx context.Context) error {
+	m.sessionMutex.Lock()
+	session, stop, done := m.session, m.stopRenew, m.renewDone
+	m.session, m.stopRenew, m.renewDone = "", nil, nil
+	clear(m.locks)
+	m.sessionMutex.Unlock()
+
+	if session == "" {
+		return nil
+	}
+	stop()
+	<-done
+	if _, err := m.do(ctx, http.MethodPut, "/v1/session/destroy/"+session, nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to destroy session: %w", err)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	cbmFieldSection    = "bookmarks_consul"
+	cbmFieldAddress    = "address"
+	cbmFieldToken      = "token"
+	cbmFieldDatacenter = "datacenter"
+	cbmFieldPrefix     = "prefix"
+	cbmFieldSessionTTL = "session_ttl"
+)
+
+// ConsulBookmarkManagerConfigFields returns the config fields of a Consul
+// bookmark store
+func ConsulBookmarkManagerConfigFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewObjectField(cbmFieldSection,
+			service.NewStringField(cbmFieldAddress).
+				Description("The address of the Consul agent, defaults to `CONSUL_HTTP_ADDR` or `http://127.0.0.1:8500`.").
+				Default(""),
+			service.NewStringField(cbmFieldToken).
+				Description("The ACL token, defaults to `CONSUL_HTTP_TOKEN`.").
+				Default("").
+				Secret(),
+			service.NewStringField(cbmFieldDatacenter).
+				Description("The datacenter to use, defaults to the datacenter of the agent.").
+				Default("").
+				Advanced(),
+			service.NewStringField(cbmFieldPrefix).
+				Description("The prefix of the keys bookmarks are stored under.").
+				Default(consulDefaultPrefix),
+			service.NewDurationField(cbmFieldSessionTTL).
+				Description("How long the partition locks of an instance outlive it.").
+				Default("15s").
+				Advanced(),
+		).Description("The Consul bookmarks store configuration"),
+	}
+}
+
+// ConsulBookmarkManagerFromParsed creates a Consul bookmark store from the
+// config fields defined by ConsulBookmarkManagerConfigFields
+func ConsulBookmarkManagerFromParsed(ctx context.Context, pConf *service.ParsedConfig) (*ConsulBookmarkManager, error) {
+	cConf := pConf.Namespace(cbmFieldSection)
+
+	var conf ConsulBookmarkManagerConfig
+
+	var err error
+	if conf.Address, err = cConf.FieldString(cbmFieldAddress); err != nil {
+		return nil, err
+	}
+	if conf.Token, err = cConf.FieldString(cbmFieldToken); err != nil {
+		return nil, err
+	}
+	if conf.Datacenter, err = cConf.FieldString(cbmFieldDatacenter); err != nil {
+		return nil, err
+	}
+	if conf.Prefix, err = cConf.FieldString(cbmFieldPrefix); err != nil {
+		return nil, err
+	}
+	if conf.SessionTTL, err = cConf.FieldDuration(cbmFieldSessionTTL); err != nil {
+		return nil, err
+	}
+
+	return NewConsulBookmarkManager(ctx, conf)
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+)
+
+// ExportToConsumerGroup commits the bookmark offsets of the group of the
+// manager as the offsets of a Kafka consumer group, so that lag tooling such
+// as rpk or Burrow sees the progress of the pipeline. It returns the number of
+// committed offsets. Bookmarks with sentinel offsets or partitions that
+// aren't Kafka partitions are skipped. Kafka only accepts these commits while
+// the consumer group has no active members.
+func (bm *BookmarkManager) ExportToConsumerGroup(ctx context.Context, admin *kadm.Client, group string) (int, error) {
+	if admin == nil {
+		return 0, errors.New("exporting to a consumer group requires a Kafka admin client")
+	}
+	if group == "" {
+		return 0, errors.New("consumer group cannot be empty")
+	}
+
+	var offsets kadm.Offsets
+	for _, b := range bm.GetBookmarksByGroup(bm.group) {
+		if b.IsSentinel() {
+			continue
+		}
+		partition, err := b.TopicPartition().KafkaPartition()
+		if err != nil {
+			continue
+		}
+		epoch := int32(-1)
+		if b.LeaderEpoch != nil {
+			epoch = *b.LeaderEpoch
+		}
+		// Committed offsets are the next offset to consume
+		offsets.Add(kadm.Offset{Topic: b.Topic, Partition: partition, At: b.Offset + 1, LeaderEpoch: epoch})
+	}
+	if len(offsets) == 0 {
+		return 0, nil
+	}
+
+	committed, err := admin.CommitOffsets(ctx, group, offsets)
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit offsets of consumer group %s: %w", group, err)
+	}
+	if err := committed.Error(); err != nil {
+		return 0, fmt.Errorf("failed to commit offsets of consumer group %s: %w", group, err)
+	}
+
+	count := 0
+	committed.Each(func(kadm.OffsetResponse) { count++ })
+	bm.log.Infof("Exported %d bookmarks to consumer group %s", count, group)
+	return count, nil
+}
+
+// ImportFromConsumerGroup sets the bookmarks of the group of the manager to
+// the committed offsets of a Kafka consumer group and returns the number of
+// imported offsets, keeping the metadata of existing bookmarks. Partitions
+// without a committed offset are skipped. The offsets are checked against the
+// regression policy as a whole, and none are imported when any of them is
+// rejected.
+func (bm *BookmarkManager) ImportFromConsumerGroup(ctx context.Context, admin *kadm.Client, group string) (int, error) {
+	if admin == nil {
+		return 0, errors.New("importing from a consumer group requires a Kafka admin client")
+	}
+	if group == "" {
+		return 0, errors.New("consumer group cannot be empty")
+	}
+
+	fetched, err := admin.FetchOffsets(ctx, group)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch offsets of consumer group %s: %w", group, err)
+	}
+	if err := fetched.Error(); err != nil {
+		return 0, fmt.Errorf("failed to fetch offsets of consumer group %s: %w", group, err)
+	}
+
+	now := time.Now()
+	var bookmarks []*Bookmark
+	fetched.Each(func(o kadm.OffsetResponse) {
+		if o.At < 0 {
+			return
+		}
+		tp := KafkaTopicPartition(o.Topic, o.Partition)
+		b := &Bookmark{Topic: tp.Topic, Partition: tp.Partition, Metadata: make(map[string]interface{})}
+		if existing, err := bm.GetBookmark(tp.Topic, tp.Partition); err == nil {
+			clone := *existing
+			b = &clone
+		}
+		b.Offset = resumeOffset(o.At)
+		b.Timestamp = now
+		if o.LeaderEpoch >= 0 {
+			epoch := o.LeaderEpoch
+			b.LeaderEpoch = &epoch
+		}
+		bookmarks = append(bookmarks, b)
+	})
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return lessBookmark(bookmarks[i], bookmarks[j])
+	})
+	if err := bm.AddBookmarks(bookmarks); err != nil {
+		return 0, fmt.Errorf("failed to import consumer group %s: %w", group, err)
+	}
+	bm.log.Infof("Imported %d bookmarks from consumer group %s", len(bookmarks), group)
+	return len(bookmarks), nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/checkpoint"
+)
+
+// coordinatorShards is the number of lock shards of a coordinator, so that
+// batches of different partitions are tracked and committed concurrently
+const coordinatorShards = 64
+
+// CommitFunc resolves a tracked batch once it has been processed, advancing
+// the bookmark of its partition when all batches before it are resolved too
+type CommitFunc func(ctx context.Context) error
+
+// CheckpointCoordinator tracks the batches of offsets in flight per partition
+// and only advances a bookmark to the highest offset of which it and all
+// offsets before it have been committed, so that acknowledgements arriving out
+// of order never move a bookmark past unprocessed messages
+type CheckpointCoordinator struct {
+	bm     *BookmarkManager
+	shards [coordinatorShards]coordinatorShard
+}
+
+// coordinatorShard holds the partitions of a coordinator hashed to it
+type coordinatorShard struct {
+	mut      sync.Mutex
+	trackers map[TopicPartition]*checkpoint.Uncapped[int64]
+	states   map[TopicPartition]*PartitionState
+}
+
+// NewCheckpointCoordinator creates a coordinator advancing the bookmarks of bm
+func NewCheckpointCoordinator(bm *BookmarkManager) *CheckpointCoordinator {
+	c := &CheckpointCoordinator{bm: bm}
+	for i := range c.shards {
+		c.shards[i].trackers = make(map[TopicPartition]*checkpoint.Uncapped[int64])
+		c.shards[i].states = make(map[TopicPartition]*PartitionState)
+	}
+	return c
+}
+
+// shard returns the shard of a partition of a group
+func (c *CheckpointCoordinator) shard(tp TopicPartition) *coordinatorShard {
+	return &c.shards[shardIndex(tp, coordinatorShards)]
+}
+
+// Track registers a batch of size messages of a partition ending at offset,
+// which must be tracked in the order the batches were read. The returned
+// function commits the batch.
+func (c *CheckpointCoordinator) Track(tp TopicPartition, offset, size int64) CommitFunc {
+	return c.track(tp, offset, size, nil)
+}
+
+// TrackState is Track for batches read from Kafka, where committed bookmarks
+// record the latest state of the partition, see UpdateOffsetState
+func (c *CheckpointCoordinator) TrackState(tp TopicPartition, offset, size int64, state PartitionState) CommitFunc {
+	return c.track(tp, offset, size, &state)
+}
+
+func (c *CheckpointCoordinator) track(tp TopicPartition, offset, size int64, state *PartitionState) CommitFunc {
+	s := c.shard(tp)
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if state != nil {
+		s.states[tp] = state
+	}
+	tracker, exists := s.trackers[tp]
+	if !exists {
+		tracker = checkpoint.NewUncapped[int64]()
+		s.trackers[tp] = tracker
+	}
+	release := tracker.Track(offset, size)
+
+	return func(ctx context.Context) error {
+		s.mut.Lock()
+		highest := release()
+		state := s.states[tp]
+		forgotten := s.trackers[tp] != tracker
+		s.mut.Unlock()
+		if highest == nil || forgotten {
+			return nil
+		}
+
+		advanced, err := c.bm.AdvanceOffset(tp, *highest, state)
+		if err != nil || !advanced {
+			return err
+		}
+		if err := c.bm.RequestSave(ctx); err != nil {
+			return fmt.Errorf("failed to save bookmarks: %w", err)
+		}
+		return nil
+	}
+}
+
+// Pending returns the number of messages of a partition in flight, including
+// those of committed batches that wait for earlier batches
+func (c *CheckpointCoordinator) Pending(tp TopicPartition) int64 {
+	s := c.shard(tp)
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if tracker, exists := s.trackers[tp]; exists {
+		return tracker.Pending()
+	}
+	return 0
+}
+
+// Forget stops tracking a partition, such as one that was revoked, so that
+// commits of its batches in flight no longer advance its bookmark
+func (c *CheckpointCoordinator) Forget(tp TopicPartition) {
+	s := c.shard(tp)
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	delete(s.trackers, tp)
+	delete(s.states, tp)
+}
+
+// AdvanceOffset moves the bookmark of a partition forward to offset, creating
+// it if needed, and records the latest state of the partition when state is
+// set, see UpdateOffsetState. The partition is of the group of tp, or of the
+// group of the manager when it has none. Offsets at or below the current
+// bookmark are ignored, the offset is compared and written under one lock,
+// and false is returned when the bookmark didn't change.
+func (bm *BookmarkManager) AdvanceOffset(tp TopicPartition, offset int64, state *PartitionState) (bool, error) {
+	if err := validateOffset(offset); err != nil {
+		return false, err
+	}
+	group := tp.Group
+	if group == "" {
+		group = bm.group
+	}
+	key := NewGroupTopicPartition(group, tp.Topic, tp.Partition)
+
+	created, err := NewBookmark(key.Topic, key.Partition, offset)
+	if err != nil {
+		return false, fmt.Errorf("invalid bookmark: %w", err)
+	}
+	created.Group = key.Group
+	if state != nil {
+		created.SetPartitionState(*state)
+	}
+	if err := bm.validateBookmark(created); err != nil {
+		return false, fmt.Errorf("invalid bookmark: %w", err)
+	}
+
+	defer bm.dispatchEvents()
+	bm.mutex.RLock()
+	defer bm.mutex.RUnlock()
+
+	shard := bm.bookmarks.shard(key)
+	shard.mut.Lock()
+	defer shard.mut.Unlock()
+
+	bookmark, exists := shard.bookmarks[key]
+	if !exists {
+		created.Version = nextVersion(nil)
+		ev, _ := changeEvent(nil, created)
+		bm.emit(ev)
+		shard.setLocked(key, created)
+		bm.dirty.markBookmark(key)
+		return true, nil
+	}
+	if bookmark.Offset >= offset {
+		return false, nil
+	}
+
+	var opts offsetOpts
+	if state != nil {
+		if err := checkLeaderEpoch(bookmark, created.LeaderEpoch); err != nil {
+			return false, err
+		}
+		if created.LeaderEpoch == nil {
+			created.LeaderEpoch = bookmark.LeaderEpoch
+		}
+		opts.update = func(b *Bookmark) {
+			b.LeaderEpoch, b.HighWatermark, b.Lag = created.LeaderEpoch, created.HighWatermark, created.Lag
+		}
+	}
+	if err := bm.applyOffsetLocked(key, bookmark, offset, time.Time{}, opts); err != nil {
+		return false, fmt.Errorf("failed to update bookmark: %w", err)
+	}
+	return true, nil
+}
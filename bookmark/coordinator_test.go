@@ -0,0 +1,255 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// benchmarkPartitions is the number of partitions the benchmarks update
+const benchmarkPartitions = 1024
+
+func newBenchmarkManager(b *testing.B) (*BookmarkManager, []string) {
+	b.Helper()
+
+	bm := NewBookmarkManager(filepath.Join(b.TempDir(), "bookmarks.json"))
+	partitions := make([]string, benchmarkPartitions)
+	for i := range partitions {
+		partitions[i] = strconv.Itoa(i)
+		require.NoError(b, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: partitions[i], Offset: 0}))
+	}
+	return bm, partitions
+}
+
+func BenchmarkUpdateOffset(b *testing.B) {
+	bm, partitions := newBenchmarkManager(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := bm.UpdateOffset("orders", partitions[i%len(partitions)], int64(i+1)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUpdateOffsetConcurrent(b *testing.B) {
+	bm, partitions := newBenchmarkManager(b)
+
+	var next atomic.Int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := next.Add(1)
+			if err := bm.UpdateOffset("orders", partitions[i%int64(len(partitions))], i); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func TestConcurrentUpdatesOfDifferentPartitions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	bm := NewBookmarkManager(path)
+
+	const partitions, updates = 64, 100
+	for p := 0; p < partitions; p++ {
+		require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: strconv.Itoa(p), Offset: 0}))
+	}
+
+	var wg sync.WaitGroup
+	for p := 0; p < partitions; p++ {
+		wg.Add(1)
+		go func(partition string) {
+			defer wg.Done()
+			for offset := int64(1); offset <= updates; offset++ {
+				assert.NoError(t, bm.UpdateOffset("orders", partition, offset))
+			}
+		}(strconv.Itoa(p))
+	}
+	// Saves and reads run alongside the updates
+	for i := 0; i < 10; i++ {
+		require.NoError(t, bm.SaveToFile(context.Background()))
+		assert.Len(t, bm.GetAllBookmarks(), partitions)
+	}
+	wg.Wait()
+
+	require.NoError(t, bm.SaveToFile(context.Background()))
+	assert.False(t, bm.IsDirty())
+
+	loaded := NewBookmarkManager(path)
+	require.NoError(t, loaded.LoadFromFile(context.Background()))
+	for _, b := range loaded.GetAllBookmarks() {
+		assert.Equal(t, int64(updates), b.Offset, b.Partition)
+		assert.Equal(t, int64(updates+1), b.Version, b.Partition)
+	}
+	assert.Equal(t, partitions, loaded.Count())
+}
+
+func TestCheckpointCoordinatorCommitsInOrder(t *testing.T) {
+	bm := NewBookmarkManager(filepath.Join(t.TempDir(), "bookmarks.json"))
+	c := NewCheckpointCoordinator(bm)
+	tp := NewTopicPartition("orders", "0")
+	ctx := context.Background()
+
+	first := c.Track(tp, 9, 10)
+	second := c.Track(tp, 19, 10)
+	third := c.Track(tp, 29, 10)
+	assert.Equal(t, int64(30), c.Pending(tp))
+
+	// Later batches wait for the first one
+	require.NoError(t, third(ctx))
+	require.NoError(t, second(ctx))
+	_, err := bm.GetBookmark("orders", "0")
+	assert.ErrorIs(t, err, ErrBookmarkNotFound)
+
+	require.NoError(t, first(ctx))
+	b, err := bm.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(29), b.Offset)
+	assert.Equal(t, int64(0), c.Pending(tp))
+	assert.False(t, bm.IsDirty())
+}
+
+func TestCheckpointCoordinatorRecordsPartitionState(t *testing.T) {
+	bm := NewBookmarkManager(filepath.Join(t.TempDir(), "bookmarks.json"))
+	c := NewCheckpointCoordinator(bm)
+	tp := KafkaTopicPartition("orders", 0)
+	ctx := context.Background()
+
+	require.NoError(t, c.TrackState(tp, 9, 10, PartitionState{LeaderEpoch: 3, HighWatermark: 15})(ctx))
+	require.NoError(t, c.TrackState(tp, 14, 5, PartitionState{LeaderEpoch: 3, HighWatermark: 20})(ctx))
+
+	b, err := bm.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(14), b.Offset)
+	require.NotNil(t, b.LeaderEpoch)
+	assert.Equal(t, int32(3), *b.LeaderEpoch)
+	assert.Equal(t, int64(20), b.HighWatermark)
+}
+
+func TestCheckpointCoordinatorNeverMovesBack(t *testing.T) {
+	bm := NewBookmarkManager(filepath.Join(t.TempDir(), "bookmarks.json"))
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 50}))
+	c := NewCheckpointCoordinator(bm)
+	tp := NewTopicPartition("orders", "0")
+
+	require.NoError(t, c.Track(tp, 9, 10)(context.Background()))
+
+	b, err := bm.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), b.Offset)
+	assert.Equal(t, int64(1), b.Version)
+}
+
+func TestCheckpointCoordinatorForget(t *testing.T) {
+	bm := NewBookmarkManager(filepath.Join(t.TempDir(), "bookmarks.json"))
+	c := NewCheckpointCoordinator(bm)
+	tp := NewTopicPartition("orders", "0")
+
+	commit := c.Track(tp, 9, 10)
+	c.Forget(tp)
+	assert.Equal(t, int64(0), c.Pending(tp))
+
+	require.NoError(t, commit(context.Background()))
+	assert.Equal(t, 0, bm.Count())
+}
+
+func TestCheckpointCoordinatorSeparatesGroups(t *testing.T) {
+	bm := NewBookmarkManager(filepath.Join(t.TempDir(), "bookmarks.json"))
+	c := NewCheckpointCoordinator(bm)
+	billing := NewGroupTopicPartition("billing", "orders", "0")
+	shipping := NewGroupTopicPartition("shipping", "orders", "0")
+	ctx := context.Background()
+
+	blocked := c.Track(billing, 9, 10)
+	require.NoError(t, c.Track(billing, 19, 10)(ctx))
+	require.NoError(t, c.Track(shipping, 4, 5)(ctx))
+	assert.Equal(t, int64(20), c.Pending(billing))
+	assert.Equal(t, int64(0), c.Pending(shipping))
+
+	_, err := bm.GetGroupBookmark("billing", "orders", "0")
+	assert.ErrorIs(t, err, ErrBookmarkNotFound)
+	b, err := bm.GetGroupBookmark("shipping", "orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), b.Offset)
+
+	require.NoError(t, blocked(ctx))
+	b, err = bm.GetGroupBookmark("billing", "orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(19), b.Offset)
+}
+
+func TestAdvanceOffset(t *testing.T) {
+	bm := NewBookmarkManager("", WithGroup("billing"))
+	tp := NewTopicPartition("orders", "0")
+
+	advanced, err := bm.AdvanceOffset(tp, 10, nil)
+	require.NoError(t, err)
+	assert.True(t, advanced)
+
+	advanced, err = bm.AdvanceOffset(tp, 10, nil)
+	require.NoError(t, err)
+	assert.False(t, advanced)
+
+	advanced, err = bm.AdvanceOffset(tp, 5, nil)
+	require.NoError(t, err)
+	assert.False(t, advanced)
+
+	advanced, err = bm.AdvanceOffset(tp, 12, nil)
+	require.NoError(t, err)
+	assert.True(t, advanced)
+
+	b, err := bm.GetGroupBookmark("billing", "orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), b.Offset)
+	assert.Equal(t, int64(2), b.Version)
+
+	_, err = bm.AdvanceOffset(tp, -5, nil)
+	assert.Error(t, err)
+}
+
+func TestAdvanceOffsetConcurrently(t *testing.T) {
+	bm := NewBookmarkManager("")
+	tp := NewTopicPartition("orders", "0")
+
+	const writers, updates = 8, 200
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				_, err := bm.AdvanceOffset(tp, int64(i*writers+w), nil)
+				assert.NoError(t, err)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	b, err := bm.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(updates*writers-1), b.Offset)
+}
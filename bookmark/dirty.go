@@ -0,0 +1,145 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// dirtySet tracks the bookmarks and sink positions changed since the state was
+// last saved or loaded. The changed bookmarks are sharded like the bookmarks,
+// so that marking them doesn't contend across partitions, and each shard and
+// the sinks are guarded by a mutex of their own. Saves hold the write lock of
+// the manager mutex while resetting the set, so no changes are marked
+// meanwhile.
+type dirtySet struct {
+	shards [bookmarkShardCount]dirtyShard
+
+	sinksMut sync.Mutex
+	sinks    map[string]struct{}
+
+	// generation counts the bookmark changes ever marked, so that read
+	// snapshots can tell whether they're current
+	generation atomic.Uint64
+}
+
+// dirtyShard holds the changed bookmarks of a shard
+type dirtyShard struct {
+	sync.Mutex
+	bookmarks map[TopicPartition]struct{}
+}
+
+func (d *dirtySet) markBookmark(key TopicPartition) {
+	shard := &d.shards[shardIndex(key, bookmarkShardCount)]
+	shard.Lock()
+	defer shard.Unlock()
+
+	if shard.bookmarks == nil {
+		shard.bookmarks = make(map[TopicPartition]struct{})
+	}
+	shard.bookmarks[key] = struct{}{}
+	d.generation.Add(1)
+}
+
+func (d *dirtySet) markSink(sink string) {
+	d.sinksMut.Lock()
+	defer d.sinksMut.Unlock()
+
+	if d.sinks == nil {
+		d.sinks = make(map[string]struct{})
+	}
+	d.sinks[sink] = struct{}{}
+}
+
+func (d *dirtySet) reset() {
+	for i := range d.shards {
+		d.shards[i].Lock()
+		d.shards[i].bookmarks = nil
+		d.shards[i].Unlock()
+	}
+
+	d.sinksMut.Lock()
+	d.sinks = nil
+	d.sinksMut.Unlock()
+}
+
+// len returns the number of changed bookmarks and sink positions
+func (d *dirtySet) len() int {
+	n := 0
+	for i := range d.shards {
+		d.shards[i].Lock()
+		n += len(d.shards[i].bookmarks)
+		d.shards[i].Unlock()
+	}
+
+	d.sinksMut.Lock()
+	defer d.sinksMut.Unlock()
+
+	return n + len(d.sinks)
+}
+
+// bookmarkKeys returns the changed topic-partitions sorted by topic and
+// partition
+func (d *dirtySet) bookmarkKeys() []TopicPartition {
+	var tps []TopicPartition
+	for i := range d.shards {
+		d.shards[i].Lock()
+		for tp := range d.shards[i].bookmarks {
+			tps = append(tps, tp)
+		}
+		d.shards[i].Unlock()
+	}
+	sortTopicPartitions(tps)
+	return tps
+}
+
+// sinkKeys returns the changed sinks sorted by name
+func (d *dirtySet) sinkKeys() []string {
+	d.sinksMut.Lock()
+	defer d.sinksMut.Unlock()
+
+	sinks := make([]string, 0, len(d.sinks))
+	for sink := range d.sinks {
+		sinks = append(sinks, sink)
+	}
+	sort.Strings(sinks)
+	return sinks
+}
+
+// IsDirty returns whether bookmarks or sink positions changed since they were
+// last saved or loaded
+func (bm *BookmarkManager) IsDirty() bool {
+	return bm.dirty.len() > 0
+}
+
+// DirtyBookmarks returns the topic-partitions of the bookmarks added, updated
+// or removed since the bookmarks were last saved or loaded, sorted by topic
+// and partition
+func (bm *BookmarkManager) DirtyBookmarks() []TopicPartition {
+	return bm.dirty.bookmarkKeys()
+}
+
+// SaveDirty saves the bookmarks only when they changed since they were last
+// saved or loaded, which avoids rewriting large bookmark files when nothing
+// changed
+func (bm *BookmarkManager) SaveDirty(ctx context.Context) error {
+	if !bm.IsDirty() {
+		return nil
+	}
+	return bm.SaveToFile(ctx)
+}
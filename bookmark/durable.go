@@ -0,0 +1,72 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// WithDurableWrites makes saves fsync the temporary file before it replaces
+// the bookmark file and the directory after, so that a saved state survives
+// a power failure. Without it the rename is atomic but the new content may
+// not have reached the disk yet.
+func WithDurableWrites(durable bool) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.durable = durable
+	}
+}
+
+// writeTempFile writes the temporary file of a save, syncing it to disk when
+// durable writes are enabled
+func (bm *BookmarkManager) writeTempFile(path string, data []byte) error {
+	if !bm.durable {
+		return os.WriteFile(path, data, 0644)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync: %w", err)
+	}
+	return f.Close()
+}
+
+// syncDir syncs a directory so that renames within it are durable when
+// durable writes are enabled. Windows doesn't support syncing directories and
+// persists renames with the file metadata instead.
+func (bm *BookmarkManager) syncDir(dir string) error {
+	if !bm.durable || runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open directory: %w", err)
+	}
+	if err := d.Sync(); err != nil {
+		d.Close()
+		return fmt.Errorf("failed to sync directory: %w", err)
+	}
+	return d.Close()
+}
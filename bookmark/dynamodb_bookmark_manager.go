@@ -0,0 +1,415 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	awsconfig "rpanda-connect-native-plugin-example/aws/config"
+)
+
+// Attributes of the items of the DynamoDB store
+const (
+	ddbAttrKey       = "key"
+	ddbAttrGroup     = "group"
+	ddbAttrTopic     = "topic"
+	ddbAttrPartition = "partition"
+	ddbAttrOffset    = "offset"
+	ddbAttrTimestamp = "timestamp"
+	ddbAttrMetadata  = "metadata"
+	ddbAttrVersion   = "version"
+)
+
+// ddbCreateTableTimeout bounds how long creating the table waits for it to
+// become active
+const ddbCreateTableTimeout = 2 * time.Minute
+
+// DynamoDBBookmarkManagerConfig configures a DynamoDBBookmarkManager
+type DynamoDBBookmarkManagerConfig struct {
+	Table string
+
+	// AWS holds the region, endpoint and credentials of the client
+	AWS aws.Config
+
+	// CreateTable creates the table with on-demand capacity when it doesn't
+	// exist
+	CreateTable bool
+}
+
+// DynamoDBBookmarkManager stores each bookmark as an item of a DynamoDB table
+// with the string partition key "key", which is "topic:partition". Items
+// carry a version attribute that every write increments, and writes are
+// conditional on the version last read or written by the manager, so that an
+// update based on a stale bookmark fails with ErrBookmarkConflict rather than
+// overwriting the update of another instance. Reads are strongly consistent.
+type DynamoDBBookmarkManager struct {
+	client *dynamodb.Client
+	table  string
+
+	// versions are the versions of the items last seen
+	mutex    sync.Mutex
+	versions map[TopicPartition]int64
+}
+
+var _ GroupBookmarkStore = (*DynamoDBBookmarkManager)(nil)
+
+// NewDynamoDBBookmarkManager creates a DynamoDB store, creating its table
+// first when configured
+func NewDynamoDBBookmarkManager(ctx context.Context, conf DynamoDBBookmarkManagerConfig) (*DynamoDBBookmarkManager, error) {
+	if conf.Table == "" {
+		return nil, errors.New("table must be set")
+	}
+
+	m := &DynamoDBBookmarkManager{
+		client:   dynamodb.NewFromConfig(conf.AWS),
+		table:    conf.Table,
+		versions: make(map[TopicPartition]int64),
+	}
+	if conf.CreateTable {
+		if err := m.ensureTable(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *DynamoDBBookmarkManager) ensureTable(ctx context.Context) error {
+	_, err := m.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: &m.table})
+	var notFound *types.ResourceNotFoundException
+	if err == nil || !errors.As(err, &notFound) {
+		return err
+	}
+
+	_, err = m.client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:   &m.table,
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String(ddbAttrKey), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String(ddbAttrKey), KeyType: types.KeyTypeHash},
+		},
+	})
+	var inUse *types.ResourceInUseException
+	if err != nil && !errors.As(err, &inUse) {
+		return fmt.Errorf("failed to create table %s: %w", m.table, err)
+	}
+	if err := dynamodb.NewTableExistsWaiter(m.client).Wait(ctx, &dynamodb.DescribeTableInput{TableName: &m.table}, ddbCreateTableTimeout); err != nil {
+		return fmt.Errorf("failed to wait for table %s: %w", m.table, err)
+	}
+	return nil
+}
+
+// seen records the version of an item, where zero forgets the item
+func (m *DynamoDBBookmarkManager) seen(tp TopicPartition, version int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if version == 0 {
+		delete(m.versions, tp)
+		return
+	}
+	m.versions[tp] = version
+}
+
+// version returns the version of an item last seen, zero when none was seen
+func (m *DynamoDBBookmarkManager) version(tp TopicPartition) int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.versions[tp]
+}
+
+// ddbVersionCondition returns the condition of writes expecting the given
+// version of an item, where zero expects no item
+func ddbVersionCondition(version int64) (*string, map[string]string, map[string]types.AttributeValue) {
+	if version == 0 {
+		return aws.String("attribute_not_exists(#k)"), map[string]string{"#k": ddbAttrKey}, nil
+	}
+	return aws.String("#v = :v"), map[string]string{"#v": ddbAttrVersion}, map[string]types.AttributeValue{
+		":v": &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)},
+	}
+}
+
+// Get returns the bookmark of a topic and partition in the default group
+func (m *DynamoDBBookmarkManager) Get(ctx context.Context, topic, partition string) (*Bookmark, error) {
+	return m.GetInGroup(ctx, "", topic, partition)
+}
+
+// GetInGroup returns the bookmark of a topic and partition in a group
+func (m *DynamoDBBookmarkManager) GetInGroup(ctx context.Context, group, topic, partition string) (*Bookmark, error) {
+	tp := NewGroupTopicPartition(group, topic, partition)
+	out, err := m.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      &m.table,
+		Key:            ddbKey(tp),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bookmark: %w", err)
+	}
+	if len(out.Item) == 0 {
+		m.seen(tp, 0)
+		return nil, notFoundError(group, topic, partition)
+	}
+
+	b, version, err := decodeDynamoDBItem(out.Item)
+	if err != nil {
+		return nil, err
+	}
+	m.seen(tp, version)
+	return b, nil
+}
+
+// Put adds or replaces a bookmark with a write that fails with
+// ErrBookmarkConflict when the bookmark was changed by another instance since
+// the manager last read or wrote it. The bookmark seen by the failed write is
+// remembered, so that the change can be retried after reading it again.
+func (m *DynamoDBBookmarkManager) Put(ctx context.Context, bookmark *Bookmark) error {
+	if bookmark == nil {
+		return errors.New("bookmark cannot be nil")
+	}
+	if err := bookmark.validate(); err != nil {
+		return fmt.Errorf("invalid bookmark: %w", err)
+	}
+
+	tp := bookmark.TopicPartition()
+	version := m.version(tp)
+	item, err := encodeDynamoDBItem(bookmark, version+1)
+	if err != nil {
+		return err
+	}
+
+	condition, names, values := ddbVersionCondition(version)
+	_, err = m.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                           &m.table,
+		Item:                                item,
+		ConditionExpression:                 condition,
+		ExpressionAttributeNames:            names,
+		ExpressionAttributeValues:           values,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err != nil {
+		return m.writeError(tp, "put", err)
+	}
+	m.seen(tp, version+1)
+	return nil
+}
+
+// Delete removes the bookmark of a topic and partition in the default group
+func (m *DynamoDBBookmarkManager) Delete(ctx context.Context, topic, partition string) error {
+	return m.DeleteInGroup(ctx, "", topic, partition)
+}
+
+// DeleteInGroup removes the bookmark of a topic and partition in a group,
+// failing with ErrBookmarkConflict like Put
+func (m *DynamoDBBookmarkManager) DeleteInGroup(ctx context.Context, group, topic, partition string) error {
+	tp := NewGroupTopicPartition(group, topic, partition)
+	version := m.version(tp)
+
+	input := &dynamodb.DeleteItemInput{
+		TableName:                           &m.table,
+		Key:                                 ddbKey(tp),
+		ConditionExpression:                 aws.String("attribute_exists(#k)"),
+		ExpressionAttributeNames:            map[string]string{"#k": ddbAttrKey},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	}
+	if version != 0 {
+		input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues = ddbVersionCondition(version)
+	}
+	if _, err := m.client.DeleteItem(ctx, input); err != nil {
+		return m.writeError(tp, "delete", err)
+	}
+	m.seen(tp, 0)
+	return nil
+}
+
+// writeError returns the error of a failed conditional write, remembering the
+// version of the item that failed the condition
+func (m *DynamoDBBookmarkManager) writeError(tp TopicPartition, op string, err error) error {
+	var failed *types.ConditionalCheckFailedException
+	if !errors.As(err, &failed) {
+		return fmt.Errorf("failed to %s bookmark: %w", op, err)
+	}
+	if len(failed.Item) == 0 {
+		m.seen(tp, 0)
+		if op == "delete" {
+			return fmt.Errorf("%w for topic: %s, partition: %s", ErrBookmarkNotFound, tp.Topic, tp.Partition)
+		}
+	} else if _, version, decodeErr := decodeDynamoDBItem(failed.Item); decodeErr == nil {
+		m.seen(tp, version)
+	}
+	return fmt.Errorf("failed to %s bookmark: %w: %s", op, ErrBookmarkConflict, tp)
+}
+
+// List returns all bookmarks sorted by topic and partition
+func (m *DynamoDBBookmarkManager) List(ctx context.Context) ([]*Bookmark, error) {
+	var bookmarks []*Bookmark
+	paginator := dynamodb.NewScanPaginator(m.client, &dynamodb.ScanInput{
+		TableName:      &m.table,
+		ConsistentRead: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list bookmarks: %w", err)
+		}
+		for _, item := range page.Items {
+			b, version, err := decodeDynamoDBItem(item)
+			if err != nil {
+				return nil, err
+			}
+			m.seen(b.TopicPartition(), version)
+			bookmarks = append(bookmarks, b)
+		}
+	}
+
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return lessBookmark(bookmarks[i], bookmarks[j])
+	})
+	return bookmarks, nil
+}
+
+// Flush does nothing, as every Put is written when it returns
+func (m *DynamoDBBookmarkManager) Flush(ctx context.Context) error {
+	return nil
+}
+
+func ddbKey(tp TopicPartition) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		ddbAttrKey: &types.AttributeValueMemberS{Value: tp.String()},
+	}
+}
+
+func encodeDynamoDBItem(b *Bookmark, version int64) (map[string]types.AttributeValue, error) {
+	item := ddbKey(b.TopicPartition())
+	item[ddbAttrTopic] = &types.AttributeValueMemberS{Value: b.Topic}
+	item[ddbAttrPartition] = &types.AttributeValueMemberS{Value: b.Partition}
+	item[ddbAttrOffset] = &types.AttributeValueMemberN{Value: strconv.FormatInt(b.Offset, 10)}
+	item[ddbAttrTimestamp] = &types.AttributeValueMemberS{Value: b.Timestamp.Format(time.RFC3339Nano)}
+	item[ddbAttrVersion] = &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)}
+	if b.Group != "" {
+		item[ddbAttrGroup] = &types.AttributeValueMemberS{Value: b.Group}
+	}
+	if len(b.Metadata) > 0 {
+		metadata, err := json.Marshal(b.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal metadata of %s/%s: %w", b.Topic, b.Partition, err)
+		}
+		item[ddbAttrMetadata] = &types.AttributeValueMemberS{Value: string(metadata)}
+	}
+	return item, nil
+}
+
+func decodeDynamoDBItem(item map[string]types.AttributeValue) (*Bookmark, int64, error) {
+	str := func(name string) string {
+		if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+			return v.Value
+		}
+		return ""
+	}
+	num := func(name string) (int64, error) {
+		v, ok := item[name].(*types.AttributeValueMemberN)
+		if !ok {
+			return 0, fmt.Errorf("missing attribute %s", name)
+		}
+		return strconv.ParseInt(v.Value, 10, 64)
+	}
+
+	key := str(ddbAttrKey)
+	b := &Bookmark{
+		Group:     str(ddbAttrGroup),
+		Topic:     str(ddbAttrTopic),
+		Partition: str(ddbAttrPartition),
+		Metadata:  make(map[string]interface{}),
+	}
+	var err error
+	if b.Offset, err = num(ddbAttrOffset); err != nil {
+		return nil, 0, fmt.Errorf("invalid bookmark %s: %w", key, err)
+	}
+	version, err := num(ddbAttrVersion)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid bookmark %s: %w", key, err)
+	}
+	if b.Timestamp, err = time.Parse(time.RFC3339Nano, str(ddbAttrTimestamp)); err != nil {
+		return nil, 0, fmt.Errorf("invalid timestamp of bookmark %s: %w", key, err)
+	}
+	if metadata := str(ddbAttrMetadata); metadata != "" {
+		if err := json.Unmarshal([]byte(metadata), &b.Metadata); err != nil {
+			return nil, 0, fmt.Errorf("invalid metadata of bookmark %s: %w", key, err)
+		}
+	}
+	return b, version, nil
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	dbmFieldSection     = "bookmarks_dynamodb"
+	dbmFieldTable       = "table"
+	dbmFieldCreateTable = "create_table"
+)
+
+// DynamoDBBookmarkManagerConfigFields returns the config fields of a DynamoDB
+// bookmark store, which include the region, endpoint and credentials of the
+// client
+func DynamoDBBookmarkManagerConfigFields() []*service.ConfigField {
+	fields := []*service.ConfigField{
+		service.NewStringField(dbmFieldTable).
+			Description("The table bookmarks are stored in, with the string partition key `key`."),
+		service.NewBoolField(dbmFieldCreateTable).
+			Description("Whether to create the table with on-demand capacity when it doesn't exist.").
+			Default(false).
+			Advanced(),
+	}
+	return []*service.ConfigField{
+		service.NewObjectField(dbmFieldSection, append(fields, awsconfig.SessionFields()...)...).
+			Description("The DynamoDB bookmarks store configuration"),
+	}
+}
+
+// DynamoDBBookmarkManagerFromParsed creates a DynamoDB bookmark store from the
+// config fields defined by DynamoDBBookmarkManagerConfigFields, where session
+// builds the AWS config from the session fields, such as aws.GetSession
+func DynamoDBBookmarkManagerFromParsed(ctx context.Context, pConf *service.ParsedConfig, session func(context.Context, *service.ParsedConfig, ...func(*config.LoadOptions) error) (aws.Config, error)) (*DynamoDBBookmarkManager, error) {
+	dConf := pConf.Namespace(dbmFieldSection)
+
+	var conf DynamoDBBookmarkManagerConfig
+
+	var err error
+	if conf.Table, err = dConf.FieldString(dbmFieldTable); err != nil {
+		return nil, err
+	}
+	if conf.CreateTable, err = dConf.FieldBool(dbmFieldCreateTable); err != nil {
+		return nil, err
+	}
+	if conf.AWS, err = session(ctx, dConf); err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return NewDynamoDBBookmarkManager(ctx, conf)
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrFencedLeaderEpoch is returned when an update was read at a leader epoch
+// older than the one of the bookmark, which means that it comes from a
+// consumer of a former partition leader
+var ErrFencedLeaderEpoch = errors.New("fenced leader epoch")
+
+// PartitionState is the state of a Kafka partition an offset was read at
+type PartitionState struct {
+	// LeaderEpoch is the leader epoch of the last record read, negative when
+	// the broker doesn't report it
+	LeaderEpoch int32
+
+	// HighWatermark is the offset following the last committed record of the
+	// partition
+	HighWatermark int64
+}
+
+// checkLeaderEpoch rejects an update of the previous bookmark, which is nil
+// for new bookmarks, read at an older leader epoch. Updates without an epoch
+// are always allowed.
+func checkLeaderEpoch(previous *Bookmark, epoch *int32) error {
+	if previous == nil || previous.LeaderEpoch == nil || epoch == nil || *epoch < 0 {
+		return nil
+	}
+	if *epoch < *previous.LeaderEpoch {
+		return fmt.Errorf("%w: bookmark %s/%s is at leader epoch %d, refusing update from epoch %d", ErrFencedLeaderEpoch, previous.Topic, previous.Partition, *previous.LeaderEpoch, *epoch)
+	}
+	return nil
+}
+
+// sameLeaderEpoch returns whether two leader epochs, nil when unknown, are
+// equal
+func sameLeaderEpoch(a, b *int32) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// partitionLag returns the number of records after the bookmarked offset up
+// to the high watermark, zero for sentinel offsets
+func partitionLag(offset, highWatermark int64) int64 {
+	if IsSentinelOffset(offset) {
+		return 0
+	}
+	return max(highWatermark-offset-1, 0)
+}
+
+// SetPartitionState sets the leader epoch, high watermark and lag of the
+// bookmark from the state of its partition
+func (b *Bookmark) SetPartitionState(state PartitionState) {
+	b.LeaderEpoch = nil
+	if state.LeaderEpoch >= 0 {
+		epoch := state.LeaderEpoch
+		b.LeaderEpoch = &epoch
+	}
+	b.HighWatermark = state.HighWatermark
+	b.Lag = partitionLag(b.Offset, state.HighWatermark)
+}
+
+// UpdateOffsetState updates the offset of an existing bookmark along with the
+// state of its partition, and sets its timestamp to the current time. Updates
+// read at an older leader epoch than the bookmark are rejected with
+// ErrFencedLeaderEpoch, and updates without an epoch keep the epoch of the
+// bookmark. Updates to the current offset only refresh the leader epoch, high
+// watermark and lag.
+func (bm *BookmarkManager) UpdateOffsetState(topic, partition string, offset int64, state PartitionState) error {
+	if err := validateOffset(offset); err != nil {
+		return err
+	}
+
+	defer bm.dispatchEvents()
+	bm.mutex.RLock()
+	defer bm.mutex.RUnlock()
+
+	key := bm.generateKey(topic, partition)
+	shard := bm.bookmarks.shard(key)
+	shard.mut.Lock()
+	defer shard.mut.Unlock()
+
+	bookmark, exists := shard.bookmarks[key]
+	if !exists {
+		return fmt.Errorf("%w for topic: %s, partition: %s", ErrBookmarkNotFound, topic, partition)
+	}
+
+	update := *bookmark
+	update.Offset = offset
+	update.SetPartitionState(state)
+	if err := checkLeaderEpoch(bookmark, update.LeaderEpoch); err != nil {
+		return err
+	}
+	if update.LeaderEpoch == nil {
+		update.LeaderEpoch = bookmark.LeaderEpoch
+	}
+	if bookmark.Offset == offset {
+		if bookmark.HighWatermark != update.HighWatermark || !sameLeaderEpoch(bookmark.LeaderEpoch, update.LeaderEpoch) {
+			bookmark.LeaderEpoch, bookmark.HighWatermark, bookmark.Lag = update.LeaderEpoch, update.HighWatermark, update.Lag
+			bm.dirty.markBookmark(key)
+		}
+		return nil
+	}
+	return bm.applyOffsetLocked(key, bookmark, offset, time.Time{}, offsetOpts{update: func(b *Bookmark) {
+		b.LeaderEpoch, b.HighWatermark, b.Lag = update.LeaderEpoch, update.HighWatermark, update.Lag
+	}})
+}
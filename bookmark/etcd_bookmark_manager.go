@@ -0,0 +1,651 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	etcdDefaultPrefix      = "/bookmarks/"
+	etcdDefaultLeaseTTL    = 10 * time.Second
+	etcdDefaultDialTimeout = 5 * time.Second
+
+	// etcdRetryInterval is how long watching waits before resuming after the
+	// watch failed
+	etcdRetryInterval = time.Second
+)
+
+// EtcdBookmarkManagerConfig configures an EtcdBookmarkManager
+type EtcdBookmarkManagerConfig struct {
+	Endpoints []string
+
+	// Prefix is prepended to the keys, defaults to /bookmarks/
+	Prefix string
+
+	// LeaseTTL is how long the claims of an instance outlive it, defaults to
+	// 10 seconds
+	LeaseTTL time.Duration
+
+	DialTimeout time.Duration
+	Username    string
+	Password    string
+	TLS         *tls.Config
+}
+
+// etcdEntry is a cached bookmark with the revision it was last changed in
+type etcdEntry struct {
+	bookmark *Bookmark
+	revision int64
+}
+
+// EtcdBookmarkManager stores bookmarks in etcd under <prefix>bookmarks/, keyed
+// by "topic:partition", so that several replicas of a connector can share
+// them. The bookmarks are read into memory on creation and kept current by
+// watching etcd, so that changes of other instances are seen locally and
+// delivered by Watch.
+//
+// Put and Delete compare the revision of the bookmark with the one last seen,
+// and fail with ErrBookmarkConflict when another instance changed it in the
+// meantime. Instances claim partitions with Claim, which is bound to a lease
+// of the instance that expires when it stops, and changing the bookmark of a
+// partition claimed by another instance fails with ErrPartitionClaimed.
+type EtcdBookmarkManager struct {
+	client      *clientv3.Client
+	bookmarkKey string
+	ownerKey    string
+	leaseTTL    time.Duration
+
+	mutex     sync.RWMutex
+	bookmarks map[TopicPartition]*etcdEntry
+	watchers  map[*watcher]struct{}
+
+	leaseMutex sync.Mutex
+	lease      clientv3.LeaseID
+	claims     map[TopicPartition]struct{}
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var _ GroupBookmarkStore = (*EtcdBookmarkManager)(nil)
+
+// NewEtcdBookmarkManager connects to etcd, loads the bookmarks stored under
+// the prefix and starts watching them
+func NewEtcdBookmarkManager(ctx context.Context, conf EtcdBookmarkManagerConfig) (*EtcdBookmarkManager, error) {
+	if len(conf.Endpoints) == 0 {
+		return nil, errors.New("at least one endpoint must be set")
+	}
+	if conf.Prefix == "" {
+		conf.Prefix = etcdDefaultPrefix
+	}
+	if conf.LeaseTTL <= 0 {
+		conf.LeaseTTL = etcdDefaultLeaseTTL
+	}
+	if conf.DialTimeout <= 0 {
+		conf.DialTimeout = etcdDefaultDialTimeout
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   conf.Endpoints,
+		DialTimeout: conf.DialTimeout,
+		Username:    conf.Username,
+		Password:    conf.Password,
+		TLS:         conf.TLS,
+		Context:     ctx,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	m := &EtcdBookmarkManager{
+		client:      client,
+		bookmarkKey: conf.Prefix + "bookmarks/",
+		ownerKey:    conf.Prefix + "owners/",
+		leaseTTL:    conf.LeaseTTL,
+		bookmarks:   make(map[TopicPartition]*etcdEntry),
+		claims:      make(map[TopicPartition]struct{}),
+		done:        make(chan struct{}),
+	}
+	revision, err := m.load(ctx)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to load bookmarks: %w", err)
+	}
+
+	var watchCtx context.Context
+	watchCtx, m.cancel = context.WithCancel(context.Background())
+	go m.run(watchCtx, revision)
+	return m, nil
+}
+
+// load replaces the cached bookmarks with those stored in etcd, returning the
+// revision they were read at
+func (m *EtcdBookmarkManager) load(ctx context.Context) (int64, error) {
+	resp, err := m.client.Get(ctx, m.bookmarkKey, clientv3.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+
+	bookmarks := make(map[TopicPartition]*etcdEntry, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		if b := decodeEtcdBookmark(kv.Value); b != nil {
+			bookmarks[b.TopicPartition()] = &etcdEntry{bookmark: b, revision: kv.ModRevision}
+		}
+	}
+
+	m.mutex.Lock()
+	m.bookmarks = bookmarks
+	m.mutex.Unlock()
+	return resp.Header.Revision, nil
+}
+
+// run watches the bookmarks from the revision after the given one, applying
+// the changes of other instances, until ctx is done. When the revisions to
+// resume from were compacted the bookmarks are loaded again.
+func (m *EtcdBookmarkManager) run(ctx context.Context, revision int64) {
+	defer close(m.done)
+
+	for ctx.Err() == nil {
+		watch := m.client.Watch(clientv3.WithRequireLeader(ctx), m.bookmarkKey,
+			clientv3.WithPrefix(), clientv3.WithPrevKV(), clientv3.WithRev(revision+1))
+		for resp := range watch {
+			if resp.CompactRevision != 0 {
+				if rev, err := m.load(ctx); err == nil {
+					revision = rev
+				}
+				break
+			}
+			if resp.Err() != nil {
+				break
+			}
+			m.apply(resp.Events)
+			revision = resp.Header.Revision
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(etcdRetryInterval):
+		}
+	}
+}
+
+// apply updates the cached bookmarks with watched changes, skipping those
+// already applied by Put and Delete
+func (m *EtcdBookmarkManager) apply(events []*clientv3.Event) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, ev := range events {
+		tp, err := ParseTopicPartition(strings.TrimPrefix(string(ev.Kv.Key), m.bookmarkKey))
+		if err != nil {
+			continue
+		}
+		existing := m.bookmarks[tp]
+		if existing != nil && existing.revision >= ev.Kv.ModRevision {
+			continue
+		}
+
+		if ev.Type == clientv3.EventTypeDelete {
+			if existing != nil {
+				delete(m.bookmarks, tp)
+				m.emit(removeEvent(existing.bookmark))
+			}
+			continue
+		}
+		b := decodeEtcdBookmark(ev.Kv.Value)
+		if b == nil {
+			continue
+		}
+		m.bookmarks[tp] = &etcdEntry{bookmark: b, revision: ev.Kv.ModRevision}
+		var previous *Bookmark
+		if existing != nil {
+			previous = existing.bookmark
+		}
+		if event, changed := changeEvent(previous, b); changed {
+			m.emit(event)
+		}
+	}
+}
+
+// emit delivers an event to the watchers without blocking. The caller must
+// hold mutex.
+func (m *EtcdBookmarkManager) emit(ev Event) {
+	for w := range m.watchers {
+		if !w.send(ev) {
+			delete(m.watchers, w)
+			close(w.events)
+		}
+	}
+}
+
+// Watch returns a channel receiving the change events selected by filter of
+// bookmarks made by this and other instances until ctx is done, like
+// BookmarkManager.Watch. Bookmarks loaded again after the watched revisions
+// were compacted aren't reported.
+func (m *EtcdBookmarkManager) Watch(ctx context.Context, filter WatchFilter) (<-chan Event, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	w := &watcher{events: make(chan Event, eventQueueSize), filter: filter}
+
+	m.mutex.Lock()
+	if m.watchers == nil {
+		m.watchers = make(map[*watcher]struct{})
+	}
+	m.watchers[w] = struct{}{}
+	m.mutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		if _, exists := m.watchers[w]; exists {
+			delete(m.watchers, w)
+			close(w.events)
+		}
+	}()
+	return w.events, nil
+}
+
+// ownerCompare returns the condition that a partition isn't claimed by
+// another instance
+func (m *EtcdBookmarkManager) ownerCompare(tp TopicPartition) clientv3.Cmp {
+	key := m.ownerKey + tp.String()
+
+	m.leaseMutex.Lock()
+	defer m.leaseMutex.Unlock()
+
+	if _, claimed := m.claims[tp]; claimed {
+		return clientv3.Compare(clientv3.Value(key), "=", m.leaseValue())
+	}
+	return clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+}
+
+// leaseValue is the value of owner keys of this instance. The caller must
+// hold leaseMutex.
+func (m *EtcdBookmarkManager) leaseValue() string {
+	return strconv.FormatInt(int64(m.lease), 16)
+}
+
+// change applies op to the key of a bookmark when the bookmark wasn't changed
+// since revision and the partition isn't claimed by another instance,
+// returning the revision of the change
+func (m *EtcdBookmarkManager) change(ctx context.Context, tp TopicPartition, revision int64, op clientv3.Op) (int64, error) {
+	key := m.bookmarkKey + tp.String()
+	resp, err := m.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", revision), m.ownerCompare(tp)).
+		Then(op).
+		Else(clientv3.OpGet(key), clientv3.OpGet(m.ownerKey+tp.String())).
+		Commit()
+	if err != nil {
+		return 0, err
+	}
+	if resp.Succeeded {
+		return resp.Header.Revision, nil
+	}
+
+	current, owner := resp.Responses[0].GetResponseRange(), resp.Responses[1].GetResponseRange()
+	if len(current.Kvs) == 0 || current.Kvs[0].ModRevision == revision {
+		if len(owner.Kvs) > 0 {
+			return 0, fmt.Errorf("%w: %s", ErrPartitionClaimed, tp)
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrBookmarkConflict, tp)
+}
+
+// Get returns the bookmark of a topic and partition in the default group
+func (m *EtcdBookmarkManager) Get(ctx context.Context, topic, partition string) (*Bookmark, error) {
+	return m.GetInGroup(ctx, "", topic, partition)
+}
+
+// GetInGroup returns the bookmark of a topic and partition in a group
+func (m *EtcdBookmarkManager) GetInGroup(ctx context.Context, group, topic, partition string) (*Bookmark, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	entry, exists := m.bookmarks[NewGroupTopicPartition(group, topic, partition)]
+	if !exists {
+		return nil, notFoundError(group, topic, partition)
+	}
+	return entry.bookmark, nil
+}
+
+// Put adds or replaces a bookmark in a transaction that fails with
+// ErrBookmarkConflict when another instance changed the bookmark since it was
+// last seen, and with ErrPartitionClaimed when another instance claimed its
+// partition
+func (m *EtcdBookmarkManager) Put(ctx context.Context, bookmark *Bookmark) error {
+	if bookmark == nil {
+		return errors.New("bookmark cannot be nil")
+	}
+	if err := bookmark.validate(); err != nil {
+		return fmt.Errorf("invalid bookmark: %w", err)
+	}
+	value, err := json.Marshal(bookmark)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bookmark: %w", err)
+	}
+
+	tp := bookmark.TopicPartition()
+
+	m.mutex.RLock()
+	var previous *Bookmark
+	var revision int64
+	if existing, exists := m.bookmarks[tp]; exists {
+		previous, revision = existing.bookmark, existing.revision
+	}
+	m.mutex.RUnlock()
+
+	changed, err := m.change(ctx, tp, revision, clientv3.OpPut(m.bookmarkKey+tp.String(), string(value)))
+	if err != nil {
+		return fmt.Errorf("failed to put bookmark: %w", err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if existing, exists := m.bookmarks[tp]; !exists || existing.revision < changed {
+		m.bookmarks[tp] = &etcdEntry{bookmark: bookmark, revision: changed}
+		if event, ok := changeEvent(previous, bookmark); ok {
+			m.emit(event)
+		}
+	}
+	return nil
+}
+
+// Delete removes the bookmark of a topic and partition in the default group
+func (m *EtcdBookmarkManager) Delete(ctx context.Context, topic, partition string) error {
+	return m.DeleteInGroup(ctx, "", topic, partition)
+}
+
+// DeleteInGroup removes the bookmark of a topic and partition in a group,
+// failing like Put when it was changed by or claimed by another instance
+func (m *EtcdBookmarkManager) DeleteInGroup(ctx context.Context, group, topic, partition string) error {
+	tp := NewGroupTopicPartition(group, topic, partition)
+
+	m.mutex.RLock()
+	existing, exists := m.bookmarks[tp]
+	m.mutex.RUnlock()
+	if !exists {
+		return notFoundError(group, topic, partition)
+	}
+
+	changed, err := m.change(ctx, tp, existing.revision, clientv3.OpDelete(m.bookmarkKey+tp.String()))
+	if err != nil {
+		return fmt.Errorf("failed to delete bookmark: %w", err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if current, exists := m.bookmarks[tp]; exists && current.revision < changed {
+		delete(m.bookmarks, tp)
+		m.emit(removeEvent(current.bookmark))
+	}
+	return nil
+}
+
+// List returns all bookmarks sorted by topic and partition
+func (m *EtcdBookmarkManager) List(ctx context.Context) ([]*Bookmark, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	bookmarks := make([]*Bookmark, 0, len(m.bookmarks))
+	for _, entry := range m.bookmarks {
+		bookmarks = append(bookmarks, entry.bookmark)
+	}
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return lessBookmark(bookmarks[i], bookmarks[j])
+	})
+	return bookmarks, nil
+}
+
+// Flush does nothing, as every Put commits a transaction
+func (m *EtcdBookmarkManager) Flush(ctx context.Context) error {
+	return nil
+}
+
+// ensureLease grants the lease of the instance if it has none and keeps it
+// alive until it's revoked. The caller must hold leaseMutex.
+func (m *EtcdBookmarkManager) ensureLease(ctx context.Context) error {
+	if m.lease != clientv3.NoLease {
+		return nil
+	}
+	resp, err := m.client.Grant(ctx, int64(m.leaseTTL/time.Second))
+	if err != nil {
+		return fmt.Errorf("failed to grant lease: %w", err)
+	}
+	keepAlive, err := m.client.KeepAlive(context.Background(), resp.ID)
+	if err != nil {
+		return fmt.Errorf("failed to keep lease alive: %w", err)
+	}
+	m.lease = resp.ID
+
+	go func(id clientv3.LeaseID) {
+		for range keepAlive {
+		}
+		// The lease expired or was revoked, taking the claims with it
+		m.leaseMutex.Lock()
+		defer m.leaseMutex.Unlock()
+
+		if m.lease == id {
+			m.lease = clientv3.NoLease
+			clear(m.claims)
+		}
+	}(resp.ID)
+	return nil
+}
+
+// Claim claims a partition for this instance until Release or Close, or until
+// the instance stops for longer than the lease TTL. It returns false when the
+// partition is claimed by another instance.
+func (m *EtcdBookmarkManager) Claim(ctx context.Context, topic, partition string) (bool, error) {
+	tp := NewTopicPartition(topic, partition)
+	key := m.ownerKey + tp.String()
+
+	m.leaseMutex.Lock()
+	defer m.leaseMutex.Unlock()
+
+	if err := m.ensureLease(ctx); err != nil {
+		return false, err
+	}
+	value := m.leaseValue()
+	resp, err := m.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, value, clientv3.WithLease(m.lease))).
+		Else(clientv3.OpGet(key)).
+		Commit()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim partition: %w", err)
+	}
+	if !resp.Succeeded {
+		owner := resp.Responses[0].GetResponseRange()
+		if len(owner.Kvs) == 0 || string(owner.Kvs[0].Value) != value {
+			return false, nil
+		}
+	}
+	m.claims[tp] = struct{}{}
+	return true, nil
+}
+
+// Release gives up the claim of a partition by this instance
+func (m *EtcdBookmarkManager) Release(ctx context.Context, topic, partition string) error {
+	tp := NewTopicPartition(topic, partition)
+	key := m.ownerKey + tp.String()
+
+	m.leaseMutex.Lock()
+	defer m.leaseMutex.Unlock()
+
+	if _, claimed := m.claims[tp]; !claimed {
+		return nil
+	}
+	_, err := m.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.Value(key), "=", m.leaseValue())).
+		Then(clientv3.OpDelete(key)).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("failed to release partition: %w", err)
+	}
+	delete(m.claims, tp)
+	return nil
+}
+
+// Claims returns the partitions claimed by this instance
+func (m *EtcdBookmarkManager) Claims() []TopicPartition {
+	m.leaseMutex.Lock()
+	defer m.leaseMutex.Unlock()
+
+	claims := make([]TopicPartition, 0, len(m.claims))
+	for tp := range m.claims {
+		claims = append(claims, tp)
+	}
+	sortTopicPartitions(claims)
+	return claims
+}
+
+// Close releases all claims, stops watching and closes the client
+func (m *EtcdBookmarkManager) Close(ctx context.Context) error {
+	var err error
+	m.leaseMutex.Lock()
+	if m.lease != clientv3.NoLease {
+		if _, revokeErr := m.client.Revoke(ctx, m.lease); revokeErr != nil {
+			err = fmt.Errorf("failed to revoke lease: %w", revokeErr)
+		}
+		m.lease = clientv3.NoLease
+		clear(m.claims)
+	}
+	m.leaseMutex.Unlock()
+
+	m.cancel()
+	<-m.done
+
+	m.mutex.Lock()
+	for w := range m.watchers {
+		close(w.events)
+	}
+	m.watchers = nil
+	m.mutex.Unlock()
+
+	if closeErr := m.client.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// decodeEtcdBookmark decodes a bookmark value, returning nil for values not
+// written by a bookmark manager
+func decodeEtcdBookmark(value []byte) *Bookmark {
+	var b *Bookmark
+	if err := json.Unmarshal(value, &b); err != nil || b == nil || b.validate() != nil {
+		return nil
+	}
+	if b.Metadata == nil {
+		b.Metadata = make(map[string]interface{})
+	}
+	return b
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	ebmFieldSection   = "bookmarks_etcd"
+	ebmFieldEndpoints = "endpoints"
+	ebmFieldPrefix    = "prefix"
+	ebmFieldLeaseTTL  = "lease_ttl"
+	ebmFieldUsername  = "username"
+	ebmFieldPassword  = "password"
+	ebmFieldTLS       = "tls"
+)
+
+// EtcdBookmarkManagerConfigFields returns the config fields of an etcd
+// bookmark store
+func EtcdBookmarkManagerConfigFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewObjectField(ebmFieldSection,
+			service.NewStringListField(ebmFieldEndpoints).
+				Description("A list of etcd endpoints to connect to.").
+				Example([]string{"localhost:2379"}),
+			service.NewStringField(ebmFieldPrefix).
+				Description("The prefix of the keys bookmarks and partition claims are stored under.").
+				Default(etcdDefaultPrefix),
+			service.NewDurationField(ebmFieldLeaseTTL).
+				Description("How long the partition claims of an instance outlive it.").
+				Default("10s").
+				Advanced(),
+			service.NewStringField(ebmFieldUsername).
+				Description("The user name to authenticate with.").
+				Default("").
+				Advanced(),
+			service.NewStringField(ebmFieldPassword).
+				Description("The password to authenticate with.").
+				Default("").
+				Secret().
+				Advanced(),
+			service.NewTLSToggledField(ebmFieldTLS),
+		).Description("The etcd bookmarks store configuration"),
+	}
+}
+
+// EtcdBookmarkManagerFromParsed creates an etcd bookmark store from the config
+// fields defined by EtcdBookmarkManagerConfigFields, tlsConf overrides the tls
+// field when it isn't nil
+func EtcdBookmarkManagerFromParsed(ctx context.Context, pConf *service.ParsedConfig, tlsConf *tls.Config) (*EtcdBookmarkManager, error) {
+	eConf := pConf.Namespace(ebmFieldSection)
+
+	conf := EtcdBookmarkManagerConfig{TLS: tlsConf}
+
+	var err error
+	if conf.Endpoints, err = eConf.FieldStringList(ebmFieldEndpoints); err != nil {
+		return nil, err
+	}
+	if conf.Prefix, err = eConf.FieldString(ebmFieldPrefix); err != nil {
+		return nil, err
+	}
+	if conf.LeaseTTL, err = eConf.FieldDuration(ebmFieldLeaseTTL); err != nil {
+		return nil, err
+	}
+	if conf.Username, err = eConf.FieldString(ebmFieldUsername); err != nil {
+		return nil, err
+	}
+	if conf.Password, err = eConf.FieldString(ebmFieldPassword); err != nil {
+		return nil, err
+	}
+	if conf.TLS == nil {
+		tlsConf, enabled, err := eConf.FieldTLSToggled(ebmFieldTLS)
+		if err != nil {
+			return nil, err
+		}
+		if enabled {
+			conf.TLS = tlsConf
+		}
+	}
+
+	return NewEtcdBookmarkManager(ctx, conf)
+}
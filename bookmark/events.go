@@ -0,0 +1,404 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"time"
+)
+
+// EventKind describes how a bookmark changed
+type EventKind string
+
+// Kinds of bookmark change events
+const (
+	EventCreated  EventKind = "created"
+	EventAdvanced EventKind = "advanced"
+	EventRewound  EventKind = "rewound"
+	EventRemoved  EventKind = "removed"
+	EventExpired  EventKind = "expired"
+)
+
+// Event describes a change of a bookmark. The previous offset is set for all
+// events but created.
+type Event struct {
+	Kind           EventKind `json:"kind"`
+	Group          string    `json:"group,omitempty"`
+	Topic          string    `json:"topic"`
+	Partition      string    `json:"partition"`
+	Offset         int64     `json:"offset"`
+	PreviousOffset *int64    `json:"previous_offset,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// EventSink receives bookmark change events. Events are delivered to each sink
+// from a dedicated goroutine in the order they occurred.
+type EventSink interface {
+	Connect(ctx context.Context) error
+	Publish(ctx context.Context, event Event) error
+	Close(ctx context.Context) error
+}
+
+const (
+	eventQueueSize      = 1024
+	eventPublishTimeout = 10 * time.Second
+)
+
+// WithEventSink adds a sink that receives the change events of bookmarks once
+// the manager is opened. Events are queued and dropped when the sink can't
+// keep up, so a slow sink never blocks bookmark updates.
+func WithEventSink(name string, sink EventSink) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.eventSinks = append(bm.eventSinks, &eventDispatcher{name: name, sink: sink, bm: bm})
+	}
+}
+
+// changeEvent returns the event for a bookmark replacing previous, which is
+// nil for new bookmarks. It returns false when the offset didn't change.
+func changeEvent(previous, bookmark *Bookmark) (Event, bool) {
+	ev := Event{
+		Kind:      EventCreated,
+		Group:     bookmark.Group,
+		Topic:     bookmark.Topic,
+		Partition: bookmark.Partition,
+		Offset:    bookmark.Offset,
+		Timestamp: bookmark.Timestamp,
+	}
+	if previous == nil {
+		return ev, true
+	}
+
+	switch {
+	case bookmark.Offset > previous.Offset:
+		ev.Kind = EventAdvanced
+	case bookmark.Offset < previous.Offset:
+		ev.Kind = EventRewound
+	default:
+		return ev, false
+	}
+	prevOffset := previous.Offset
+	ev.PreviousOffset = &prevOffset
+	return ev, true
+}
+
+// removeEvent returns the event for a removed bookmark
+func removeEvent(bookmark *Bookmark) Event {
+	prevOffset := bookmark.Offset
+	return Event{
+		Kind:           EventRemoved,
+		Group:          bookmark.Group,
+		Topic:          bookmark.Topic,
+		Partition:      bookmark.Partition,
+		Offset:         bookmark.Offset,
+		PreviousOffset: &prevOffset,
+		Timestamp:      time.Now(),
+	}
+}
+
+// expireEvent returns the event for a bookmark removed by expiry
+func expireEvent(bookmark *Bookmark) Event {
+	ev := removeEvent(bookmark)
+	ev.Kind = EventExpired
+	return ev
+}
+
+// hasEventListeners returns true if emitted events are delivered anywhere, so
+// that hot paths can skip building them
+func (bm *BookmarkManager) hasEventListeners() bool {
+	return len(bm.eventSinks) > 0 || bm.alerts != nil || bm.watching.Load() > 0 || len(bm.updateHooks) > 0 || bm.audit != nil
+}
+
+// WatchFilter selects the events of a watch. Empty fields match all events.
+type WatchFilter struct {
+	// Topics are path.Match patterns of the topics to watch, such as
+	// "orders-*"
+	Topics []string
+	// Kinds are the kinds of events to watch
+	Kinds []EventKind
+}
+
+// Validate returns an error if a topic pattern is malformed or a kind is
+// unknown
+func (f WatchFilter) Validate() error {
+	for _, pattern := range f.Topics {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid topic pattern %q: %w", pattern, err)
+		}
+	}
+	for _, kind := range f.Kinds {
+		switch kind {
+		case EventCreated, EventAdvanced, EventRewound, EventRemoved, EventExpired:
+		default:
+			return fmt.Errorf("unknown event kind: %s", kind)
+		}
+	}
+	return nil
+}
+
+// Matches returns true if the filter selects an event
+func (f WatchFilter) Matches(ev Event) bool {
+	if len(f.Kinds) > 0 && !slices.Contains(f.Kinds, ev.Kind) {
+		return false
+	}
+	if len(f.Topics) == 0 {
+		return true
+	}
+	for _, pattern := range f.Topics {
+		if matched, _ := path.Match(pattern, ev.Topic); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// watcher receives the events emitted while it's registered that match its
+// filter. Its channel is closed when it's cancelled, or when it falls behind.
+type watcher struct {
+	events chan Event
+	filter WatchFilter
+}
+
+// send queues an event matching the filter without blocking, returning false
+// when the receiver fell behind
+func (w *watcher) send(ev Event) bool {
+	if !w.filter.Matches(ev) {
+		return true
+	}
+	select {
+	case w.events <- ev:
+		return true
+	default:
+		return false
+	}
+}
+
+// watch registers a watcher buffering up to size events that match filter,
+// returning it and a function that cancels it
+func (bm *BookmarkManager) watch(size int, filter WatchFilter) (*watcher, func()) {
+	w := &watcher{events: make(chan Event, size), filter: filter}
+
+	bm.eventMutex.Lock()
+	if bm.watchers == nil {
+		bm.watchers = make(map[*watcher]struct{})
+	}
+	bm.watchers[w] = struct{}{}
+	bm.watching.Store(int64(len(bm.watchers)))
+	bm.eventMutex.Unlock()
+
+	return w, func() {
+		bm.eventMutex.Lock()
+		defer bm.eventMutex.Unlock()
+
+		if _, exists := bm.watchers[w]; exists {
+			delete(bm.watchers, w)
+			bm.watching.Store(int64(len(bm.watchers)))
+			close(w.events)
+		}
+	}
+}
+
+// Watch returns a channel receiving the change events of bookmarks selected by
+// filter, created, advanced, rewound, removed and expired, until ctx is done.
+// Events are delivered in the order they occurred and buffered up to a limit.
+// The channel is closed when ctx is done, or early when the receiver falls
+// behind and would block bookmark updates, in which case the receiver should
+// read the current bookmarks and watch again.
+func (bm *BookmarkManager) Watch(ctx context.Context, filter WatchFilter) (<-chan Event, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	w, cancel := bm.watch(eventQueueSize, filter)
+	go func() {
+		<-ctx.Done()
+		cancel()
+	}()
+	return w.events, nil
+}
+
+// emit queues an event for delivery once the manager is unlocked. The caller
+// must hold mutex and dispatch the queued events after releasing it, so that
+// events are delivered in the order the changes were made.
+func (bm *BookmarkManager) emit(ev Event) {
+	bm.eventMutex.Lock()
+	defer bm.eventMutex.Unlock()
+
+	bm.queuedEvents = append(bm.queuedEvents, ev)
+}
+
+// takeEvents returns the queued events and empties the queue
+func (bm *BookmarkManager) takeEvents() []Event {
+	bm.eventMutex.Lock()
+	defer bm.eventMutex.Unlock()
+
+	events := bm.queuedEvents
+	bm.queuedEvents = nil
+	return events
+}
+
+// dispatchEvents delivers the queued events. Only one goroutine delivers at a
+// time, events queued meanwhile, including by update hooks calling the
+// manager, are delivered by it before it returns.
+func (bm *BookmarkManager) dispatchEvents() {
+	for {
+		bm.eventMutex.Lock()
+		queued := len(bm.queuedEvents) > 0
+		bm.eventMutex.Unlock()
+		if !queued || !bm.dispatchMutex.TryLock() {
+			return
+		}
+		for events := bm.takeEvents(); len(events) > 0; events = bm.takeEvents() {
+			for _, ev := range events {
+				bm.deliver(ev)
+			}
+		}
+		bm.dispatchMutex.Unlock()
+	}
+}
+
+// deliver calls the update hooks and queues an event for all running sinks
+// and watchers without blocking
+func (bm *BookmarkManager) deliver(ev Event) {
+	for _, hook := range bm.updateHooks {
+		hook(ev)
+	}
+	if bm.alerts != nil {
+		bm.alerts.onEvent(ev)
+	}
+	if bm.audit != nil {
+		bm.audit.record(ev, bm.writer)
+	}
+
+	bm.eventMutex.Lock()
+	defer bm.eventMutex.Unlock()
+
+	for _, d := range bm.eventSinks {
+		d.enqueue(ev)
+	}
+	for w := range bm.watchers {
+		if !w.send(ev) {
+			delete(bm.watchers, w)
+			bm.watching.Store(int64(len(bm.watchers)))
+			close(w.events)
+		}
+	}
+}
+
+// startEventSinks connects all sinks and starts delivering events to them
+func (bm *BookmarkManager) startEventSinks(ctx context.Context) error {
+	for i, d := range bm.eventSinks {
+		if d.running() {
+			continue
+		}
+		if err := d.sink.Connect(ctx); err != nil {
+			for _, started := range bm.eventSinks[:i] {
+				started.stop(ctx)
+			}
+			return fmt.Errorf("failed to connect event sink %s: %w", d.name, err)
+		}
+		d.start()
+	}
+	return nil
+}
+
+// stopEventSinks delivers the queued events and closes all sinks
+func (bm *BookmarkManager) stopEventSinks(ctx context.Context) {
+	for _, d := range bm.eventSinks {
+		d.stop(ctx)
+	}
+}
+
+// eventDispatcher delivers queued events to a sink
+type eventDispatcher struct {
+	name string
+	sink EventSink
+	bm   *BookmarkManager
+
+	events  chan Event
+	done    chan struct{}
+	dropped int
+}
+
+func (d *eventDispatcher) running() bool {
+	d.bm.eventMutex.Lock()
+	defer d.bm.eventMutex.Unlock()
+
+	return d.events != nil
+}
+
+func (d *eventDispatcher) start() {
+	d.bm.eventMutex.Lock()
+	defer d.bm.eventMutex.Unlock()
+
+	d.events = make(chan Event, eventQueueSize)
+	d.done = make(chan struct{})
+	go d.run(d.events)
+}
+
+// enqueue queues an event if the dispatcher is running. The caller must hold
+// eventMutex.
+func (d *eventDispatcher) enqueue(ev Event) {
+	if d.events == nil {
+		return
+	}
+	select {
+	case d.events <- ev:
+	default:
+		d.dropped++
+	}
+}
+
+func (d *eventDispatcher) run(events <-chan Event) {
+	defer close(d.done)
+
+	for ev := range events {
+		d.bm.eventMutex.Lock()
+		dropped := d.dropped
+		d.dropped = 0
+		d.bm.eventMutex.Unlock()
+		if dropped > 0 {
+			d.bm.log.Warnf("Dropped %d bookmark events for sink %s as it couldn't keep up", dropped, d.name)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), eventPublishTimeout)
+		if err := d.sink.Publish(ctx, ev); err != nil {
+			d.bm.log.Warnf("Failed to publish bookmark event to sink %s: %v", d.name, err)
+		}
+		cancel()
+	}
+}
+
+// stop closes the queue, waits for queued events to be delivered and closes
+// the sink
+func (d *eventDispatcher) stop(ctx context.Context) {
+	d.bm.eventMutex.Lock()
+	events := d.events
+	d.events = nil
+	d.bm.eventMutex.Unlock()
+	if events == nil {
+		return
+	}
+
+	close(events)
+	select {
+	case <-d.done:
+	case <-ctx.Done():
+	}
+	if err := d.sink.Close(ctx); err != nil {
+		d.bm.log.Warnf("Failed to close event sink %s: %v", d.name, err)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// MQTTEventSinkConfig configures an MQTTEventSink
+type MQTTEventSinkConfig struct {
+	URLs        []string
+	TopicPrefix string
+	ClientID    string
+	User        string
+	Password    string
+	QoS         byte
+	Retained    bool
+}
+
+// MQTTEventSink publishes the state of each bookmark as JSON to the MQTT topic
+// "<prefix>/<topic>/<partition>". Messages are retained by default so that a
+// broker always holds the latest position of every partition, and removed
+// bookmarks clear their retained message.
+type MQTTEventSink struct {
+	conf   MQTTEventSinkConfig
+	client mqtt.Client
+}
+
+// NewMQTTEventSink creates a sink publishing to the MQTT brokers of conf
+func NewMQTTEventSink(conf MQTTEventSinkConfig) *MQTTEventSink {
+	return &MQTTEventSink{conf: conf}
+}
+
+// mqttTopicEscaper escapes the characters with a special meaning within MQTT
+// topic levels
+var mqttTopicEscaper = strings.NewReplacer("%", "%25", "/", "%2F", "+", "%2B", "#", "%23")
+
+// Topic returns the MQTT topic the state of a bookmark is published to
+func (s *MQTTEventSink) Topic(event Event) string {
+	return fmt.Sprintf("%s/%s/%s", s.conf.TopicPrefix, mqttTopicEscaper.Replace(event.Topic), mqttTopicEscaper.Replace(event.Partition))
+}
+
+// Connect connects to the MQTT brokers
+func (s *MQTTEventSink) Connect(ctx context.Context) error {
+	opts := mqtt.NewClientOptions().
+		SetClientID(s.conf.ClientID).
+		SetUsername(s.conf.User).
+		SetPassword(s.conf.Password).
+		SetAutoReconnect(true).
+		SetConnectTimeout(eventPublishTimeout)
+	for _, u := range s.conf.URLs {
+		opts.AddBroker(u)
+	}
+
+	client := mqtt.NewClient(opts)
+	if err := waitToken(ctx, client.Connect()); err != nil {
+		return err
+	}
+	s.client = client
+	return nil
+}
+
+// Publish publishes the bookmark state carried by an event
+func (s *MQTTEventSink) Publish(ctx context.Context, event Event) error {
+	if s.client == nil {
+		return errors.New("not connected")
+	}
+
+	var payload []byte
+	if event.Kind != EventRemoved && event.Kind != EventExpired {
+		var err error
+		if payload, err = json.Marshal(event); err != nil {
+			return err
+		}
+	}
+	return waitToken(ctx, s.client.Publish(s.Topic(event), s.conf.QoS, s.conf.Retained, payload))
+}
+
+// Close disconnects from the brokers
+func (s *MQTTEventSink) Close(ctx context.Context) error {
+	if s.client == nil {
+		return nil
+	}
+	quiesce := uint(time.Second / time.Millisecond)
+	if deadline, ok := ctx.Deadline(); ok {
+		quiesce = uint(time.Until(deadline) / time.Millisecond)
+	}
+	s.client.Disconnect(quiesce)
+	s.client = nil
+	return nil
+}
+
+// waitToken waits for an MQTT operation to complete or the context to end
+func waitToken(ctx context.Context, token mqtt.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSEventSink publishes bookmark events as JSON to the NATS subject
+// "<prefix>.<kind>.<topic>", so that subscribers can filter by event kind and
+// topic with subject wildcards
+type NATSEventSink struct {
+	urls          []string
+	subjectPrefix string
+
+	conn *nats.Conn
+}
+
+// NewNATSEventSink creates a sink publishing to the NATS servers at urls
+func NewNATSEventSink(urls []string, subjectPrefix string) *NATSEventSink {
+	return &NATSEventSink{
+		urls:          urls,
+		subjectPrefix: subjectPrefix,
+	}
+}
+
+// Connect connects to the NATS servers
+func (s *NATSEventSink) Connect(ctx context.Context) error {
+	conn, err := nats.Connect(strings.Join(s.urls, ","), nats.Name("bookmark_events"))
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+// Subject returns the subject an event is published to
+func (s *NATSEventSink) Subject(event Event) string {
+	return fmt.Sprintf("%s.%s.%s", s.subjectPrefix, event.Kind, event.Topic)
+}
+
+// Publish publishes an event
+func (s *NATSEventSink) Publish(ctx context.Context, event Event) error {
+	if s.conn == nil {
+		return errors.New("not connected")
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.conn.Publish(s.Subject(event), data)
+}
+
+// Close flushes pending events and closes the connection
+func (s *NATSEventSink) Close(ctx context.Context) error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.FlushWithContext(ctx)
+	s.conn.Close()
+	s.conn = nil
+	return err
+}
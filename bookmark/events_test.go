@@ -0,0 +1,63 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateHookCallsManager(t *testing.T) {
+	var bm *BookmarkManager
+	var events []Event
+	bm = NewBookmarkManager(filepath.Join(t.TempDir(), "bookmarks.json"), WithUpdateHook(func(ev Event) {
+		events = append(events, ev)
+		if ev.Topic != "orders" {
+			return
+		}
+		// follow the orders bookmark with a copy, which emits from the hook
+		if _, err := bm.GetBookmark("copy", ev.Partition); err != nil {
+			assert.NoError(t, bm.AddBookmark(&Bookmark{Topic: "copy", Partition: ev.Partition, Offset: ev.Offset}))
+			return
+		}
+		assert.NoError(t, bm.UpdateOffset("copy", ev.Partition, ev.Offset))
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 1}))
+		assert.NoError(t, bm.UpdateOffset("orders", "0", 2))
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("update hook calling the manager deadlocked")
+	}
+
+	copied, err := bm.GetBookmark("copy", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), copied.Offset)
+
+	var got []string
+	for _, ev := range events {
+		got = append(got, string(ev.Kind)+" "+ev.Topic)
+	}
+	assert.Equal(t, []string{"created orders", "created copy", "advanced orders", "advanced copy"}, got)
+}
@@ -0,0 +1,265 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExchangeFormat is a format bookmarks are exported to and imported from
+type ExchangeFormat string
+
+// Exchange formats
+const (
+	ExchangeJSON ExchangeFormat = "json"
+	ExchangeCSV  ExchangeFormat = "csv"
+	ExchangeYAML ExchangeFormat = "yaml"
+)
+
+// csvHeader are the columns of CSV exports. Imports require the topic,
+// partition and offset columns, in any order.
+var csvHeader = []string{"topic", "partition", "offset", "timestamp", "metadata", "group"}
+
+// yamlBookmark is a bookmark in YAML exports, where sentinel offsets are
+// written by name
+type yamlBookmark struct {
+	Group     string         `yaml:"group,omitempty"`
+	Topic     string         `yaml:"topic"`
+	Partition string         `yaml:"partition"`
+	Offset    any            `yaml:"offset"`
+	Timestamp time.Time      `yaml:"timestamp"`
+	Metadata  map[string]any `yaml:"metadata,omitempty"`
+}
+
+// Export writes all bookmarks to w sorted by topic and partition, as a JSON
+// array of bookmarks, as CSV with a header row and the metadata as a JSON
+// column, or as a YAML list. Sentinel offsets are written by name in CSV and
+// YAML.
+func (bm *BookmarkManager) Export(w io.Writer, format ExchangeFormat) error {
+	// The shared snapshot is sorted already, and unlike ForEach no lock is
+	// held while writing to w
+	bookmarks := bm.readSnapshot().bookmarks
+
+	switch format {
+	case ExchangeJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(bookmarks)
+	case ExchangeCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, b := range bookmarks {
+			metadata := ""
+			if len(b.Metadata) > 0 {
+				data, err := json.Marshal(b.Metadata)
+				if err != nil {
+					return fmt.Errorf("failed to marshal metadata of %s/%s: %w", b.Topic, b.Partition, err)
+				}
+				metadata = string(data)
+			}
+			if err := cw.Write([]string{b.Topic, b.Partition, FormatOffset(b.Offset), b.Timestamp.Format(time.RFC3339Nano), metadata, b.Group}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case ExchangeYAML:
+		entries := make([]yamlBookmark, 0, len(bookmarks))
+		for _, b := range bookmarks {
+			var offset any = b.Offset
+			if b.IsSentinel() {
+				offset = FormatOffset(b.Offset)
+			}
+			entries = append(entries, yamlBookmark{
+				Group:     b.Group,
+				Topic:     b.Topic,
+				Partition: b.Partition,
+				Offset:    offset,
+				Timestamp: b.Timestamp,
+				Metadata:  b.Metadata,
+			})
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(entries); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("unknown exchange format: %s", format)
+	}
+}
+
+// Import reads bookmarks in a format written by Export and adds them,
+// replacing the bookmarks of the same groups, topics and partitions, and
+// returns the number of imported bookmarks. JSON imports also accept a
+// bookmark file. Missing timestamps default to the current time. The
+// bookmarks are validated and checked against the regression policy as a
+// whole, and none are imported when any of them is rejected.
+func (bm *BookmarkManager) Import(r io.Reader, format ExchangeFormat) (int, error) {
+	var bookmarks []*Bookmark
+	var err error
+	switch format {
+	case ExchangeJSON:
+		bookmarks, err = importJSON(r)
+	case ExchangeCSV:
+		bookmarks, err = importCSV(r)
+	case ExchangeYAML:
+		bookmarks, err = importYAML(r)
+	default:
+		return 0, fmt.Errorf("unknown exchange format: %s", format)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	for _, b := range bookmarks {
+		if b.Timestamp.IsZero() {
+			b.Timestamp = now
+		}
+		if b.Metadata == nil {
+			b.Metadata = make(map[string]interface{})
+		}
+	}
+	if err := bm.AddBookmarks(bookmarks); err != nil {
+		return 0, err
+	}
+	return len(bookmarks), nil
+}
+
+func importJSON(r io.Reader) ([]*Bookmark, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var report RecoveryReport
+		bookmarkFile, err := decodeBookmarkFile(data, &report)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode bookmark file: %w", err)
+		}
+		if report.Skipped > 0 {
+			return nil, fmt.Errorf("bookmark file has invalid entries: %s", strings.Join(report.Problems, ", "))
+		}
+		return bookmarkFile.Bookmarks, nil
+	}
+
+	var bookmarks []*Bookmark
+	if err := json.Unmarshal(data, &bookmarks); err != nil {
+		return nil, fmt.Errorf("failed to decode bookmarks: %w", err)
+	}
+	for i, b := range bookmarks {
+		if b == nil {
+			return nil, fmt.Errorf("bookmark %d is empty", i)
+		}
+	}
+	return bookmarks, nil
+}
+
+func importCSV(r io.Reader) ([]*Bookmark, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range csvHeader[:3] {
+		if _, exists := columns[required]; !exists {
+			return nil, fmt.Errorf("missing column %q", required)
+		}
+	}
+	column := func(record []string, name string) string {
+		if i, exists := columns[name]; exists && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var bookmarks []*Bookmark
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return bookmarks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		b := &Bookmark{
+			Group:     column(record, "group"),
+			Topic:     column(record, "topic"),
+			Partition: NormalizePartition(column(record, "partition")),
+		}
+		if b.Offset, err = ParseOffset(column(record, "offset")); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if ts := column(record, "timestamp"); ts != "" {
+			if b.Timestamp, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+				return nil, fmt.Errorf("line %d: invalid timestamp: %w", line, err)
+			}
+		}
+		if metadata := column(record, "metadata"); metadata != "" {
+			if err := json.Unmarshal([]byte(metadata), &b.Metadata); err != nil {
+				return nil, fmt.Errorf("line %d: invalid metadata: %w", line, err)
+			}
+		}
+		bookmarks = append(bookmarks, b)
+	}
+}
+
+func importYAML(r io.Reader) ([]*Bookmark, error) {
+	var entries []yamlBookmark
+	if err := yaml.NewDecoder(r).Decode(&entries); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode bookmarks: %w", err)
+	}
+
+	bookmarks := make([]*Bookmark, 0, len(entries))
+	for i, entry := range entries {
+		offset, err := ParseOffset(fmt.Sprint(entry.Offset))
+		if err != nil {
+			return nil, fmt.Errorf("bookmark %d: %w", i, err)
+		}
+		bookmarks = append(bookmarks, &Bookmark{
+			Group:     entry.Group,
+			Topic:     entry.Topic,
+			Partition: NormalizePartition(entry.Partition),
+			Offset:    offset,
+			Timestamp: entry.Timestamp,
+			Metadata:  entry.Metadata,
+		})
+	}
+	return bookmarks, nil
+}
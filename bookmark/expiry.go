@@ -0,0 +1,120 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"time"
+)
+
+// MetadataExpireAfter is the metadata key of a duration, such as "72h", that
+// overrides the expiry of a single bookmark. A zero duration never expires.
+const MetadataExpireAfter = "expire_after"
+
+// WithExpiry expires bookmarks that haven't been updated for expireAfter,
+// such as those of deleted or repartitioned topics, and makes Open start
+// pruning them every interval, see PruneExpired. Zero expireAfter only
+// expires bookmarks with an expiry in their metadata, and zero interval
+// disables pruning in the background.
+func WithExpiry(expireAfter, interval time.Duration) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.expireAfter = expireAfter
+		bm.expiryInterval = interval
+	}
+}
+
+// expiryOf returns the expiry of a bookmark, zero if it never expires
+func (bm *BookmarkManager) expiryOf(b *Bookmark) time.Duration {
+	if d, ok := b.GetMetadataDuration(MetadataExpireAfter); ok {
+		return d
+	}
+	return bm.expireAfter
+}
+
+// PruneExpired removes the bookmarks that weren't updated within their expiry
+// and returns the number removed, emitting expired events for them. Removals
+// are saved with the next save.
+func (bm *BookmarkManager) PruneExpired() int {
+	now := time.Now()
+
+	defer bm.dispatchEvents()
+	bm.mutex.Lock()
+	defer bm.mutex.Unlock()
+
+	removed := 0
+	for _, b := range bm.bookmarks.all() {
+		expiry := bm.expiryOf(b)
+		if expiry <= 0 || now.Sub(b.Timestamp) < expiry {
+			continue
+		}
+		key := b.TopicPartition()
+		bm.emit(expireEvent(b))
+		bm.bookmarks.remove(key)
+		bm.dirty.markBookmark(key)
+		removed++
+	}
+	return removed
+}
+
+// expiryPruner is a running background pruner
+type expiryPruner struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startPruner starts pruning expired bookmarks in the background when an
+// interval is configured. The caller must hold lifecycleMutex.
+func (bm *BookmarkManager) startPruner() {
+	if bm.expiryInterval <= 0 || bm.pruner != nil {
+		return
+	}
+	p := &expiryPruner{stop: make(chan struct{}), done: make(chan struct{})}
+	bm.pruner = p
+	go bm.runPruner(p)
+}
+
+// stopPruner stops the background pruner. The caller must hold
+// lifecycleMutex.
+func (bm *BookmarkManager) stopPruner() {
+	if bm.pruner == nil {
+		return
+	}
+	close(bm.pruner.stop)
+	<-bm.pruner.done
+	bm.pruner = nil
+}
+
+func (bm *BookmarkManager) runPruner(p *expiryPruner) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(bm.expiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			removed := bm.PruneExpired()
+			if removed == 0 {
+				continue
+			}
+			bm.log.Infof("Pruned %d expired bookmarks", removed)
+			if err := bm.RequestSave(context.Background()); err != nil {
+				bm.log.Errorf("Failed to save pruned bookmarks: %v", err)
+			}
+		case <-p.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type fallbackStore struct {
+	name  string
+	store BookmarkStore
+	open  func(ctx context.Context) (ownedStore, error)
+}
+
+// WithFallbackStore adds a store Open loads the bookmarks from when there's no
+// bookmark file, so that a fresh node bootstraps its state from a remote copy
+// such as a mirror. Fallbacks are tried in the order they were added and the
+// first one holding bookmarks provides them, after which they are saved to
+// the bookmark file. Fallbacks that fail are skipped, but Open fails when none
+// holds bookmarks and any of them failed, rather than starting from scratch
+// while a copy may be unreachable. The store remains owned by the caller.
+func WithFallbackStore(name string, store BookmarkStore) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.fallbacks = append(bm.fallbacks, fallbackStore{name: name, store: store})
+	}
+}
+
+// withFallbackURL adds a fallback to the store a URL refers to, which is only
+// opened while loading from it
+func withFallbackURL(storeURL string) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.fallbacks = append(bm.fallbacks, fallbackStore{name: storeURL, open: func(ctx context.Context) (ownedStore, error) {
+			return openStoreURL(ctx, storeURL)
+		}})
+	}
+}
+
+// list returns the bookmarks of the fallback, opening and closing it when it
+// was added by URL
+func (f fallbackStore) list(ctx context.Context) ([]*Bookmark, error) {
+	if f.open == nil {
+		return f.store.List(ctx)
+	}
+	opened, err := f.open(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open: %w", err)
+	}
+	bookmarks, err := opened.store.List(ctx)
+	if cerr := opened.close(ctx); err == nil && cerr != nil {
+		return nil, fmt.Errorf("failed to close: %w", cerr)
+	}
+	return bookmarks, err
+}
+
+// loadFromFallbacksOnOpen loads the bookmarks from the first fallback holding
+// any when the last load started without a bookmark file
+func (bm *BookmarkManager) loadFromFallbacksOnOpen(ctx context.Context) error {
+	if len(bm.fallbacks) == 0 || bm.GetRecoveryReport().Source != "" {
+		return nil
+	}
+
+	var errs []error
+	for _, f := range bm.fallbacks {
+		bookmarks, err := f.list(ctx)
+		if err != nil {
+			bm.log.Warnf("Failed to load bookmarks from fallback %s: %v", f.name, err)
+			errs = append(errs, fmt.Errorf("fallback %s: %w", f.name, err))
+			continue
+		}
+		if len(bookmarks) == 0 {
+			continue
+		}
+
+		if err := bm.AddBookmarks(cloneBookmarks(bookmarks)); err != nil {
+			return fmt.Errorf("failed to add bookmarks of fallback %s: %w", f.name, err)
+		}
+		bm.mutex.Lock()
+		bm.recovery.Source = f.name
+		bm.recovery.Loaded = len(bookmarks)
+		bm.mutex.Unlock()
+
+		bm.log.Infof("Loaded %d bookmarks from fallback %s", len(bookmarks), f.name)
+		return bm.SaveToFile(ctx)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to load bookmarks from fallbacks: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -15,44 +15,220 @@
 package bookmark
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/redpanda-data/benthos/v4/public/service"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 )
 
 // BookmarkManager manages bookmarks with file-based persistence
 type BookmarkManager struct {
 	filePath  string
-	bookmarks map[string]*Bookmark // key: "topic:partition"
-	mutex     sync.RWMutex
+	bookmarks bookmarkShards
+	sinks     map[string]*SinkPosition
+	pending   map[string]*PendingCommit
+	recovery  RecoveryReport
+
+	// mutex is read locked by updates of single bookmarks, which also lock
+	// the shard of the bookmark, and write locked by operations spanning
+	// bookmarks, see bookmarkShards. It guards the other state above.
+	mutex sync.RWMutex
+
+	// saveMutex serializes file operations and guards the fields below
+	saveMutex     sync.Mutex
+	createdAt     time.Time
+	lastDigest    [sha256.Size]byte
+	lastUpdatedAt time.Time
+	lastWriter    *WriterIdentity
+	writerInfo    fs.FileInfo
+	exported      map[string][]byte
+	walEntries    int
+
+	writer        WriterIdentity
+	log           *service.Logger
+	res           *service.Resources
+	ownerLockHeld bool
+
+	// lifecycleMutex guards the number of components that opened the manager
+	lifecycleMutex sync.Mutex
+	openRefs       int
+
+	autoSaveMutex sync.Mutex
+	autoSave      *autoSaver
+	pruner        *expiryPruner
+
+	dirty    dirtySet
+	snapshot atomic.Pointer[readSnapshot]
+
+	// fileSize estimates the size of the bookmark file before compression
+	// for the file size limit
+	fileSize atomic.Int64
+
+	// eventMutex guards the events queued by changes and the queues of event
+	// sinks and watchers, which are counted by watching for lock-free checks.
+	// dispatchMutex is held by the goroutine delivering the queued events.
+	eventMutex    sync.Mutex
+	dispatchMutex sync.Mutex
+	queuedEvents  []Event
+	watchers      map[*watcher]struct{}
+	watching      atomic.Int64
+
+	// Options, these are set at construction and never modified
+	label          string
+	group          string
+	annotations    map[string]string
+	rotateMaxSize  int64
+	rotateMaxAge   time.Duration
+	maxFileSize    int64
+	backups        int
+	ignoreChecksum bool
+	durable        bool
+	compression    Compression
+	format         FileFormat
+	pretty         bool
+	indent         int
+	validateTopics bool
+	metadataSchema *MetadataSchema
+
+	regressionPolicy   RegressionPolicy
+	autoSaveInterval   time.Duration
+	autoSaveMaxUpdates int
+
+	expireAfter    time.Duration
+	expiryInterval time.Duration
+
+	walEnabled      bool
+	walCompactAfter int
+
+	maxTopicLength     int
+	maxPartitionLength int
+
+	maxTimestampFuture     time.Duration
+	maxTimestampPast       time.Duration
+	rejectSkewedTimestamps bool
+
+	ownerLockEnabled    bool
+	ownerLockStaleAfter time.Duration
+
+	fileLockEnabled bool
+	fileLockTimeout time.Duration
+
+	importCache       string
+	importKeyPrefix   string
+	importCheckpoints []string
+
+	exportCache     string
+	exportKeyPrefix string
+	exportFormat    string
+
+	partitionMetrics bool
+	readOnly         bool
+	metrics          *managerMetrics
+
+	tracerProvider trace.TracerProvider
+	tracer         trace.Tracer
+
+	beforeSaveHooks []BeforeSaveHook
+	afterLoadHooks  []AfterLoadHook
+	updateHooks     []UpdateHook
+
+	audit      *auditLog
+	eventSinks []*eventDispatcher
+	alerts     *webhookAlerter
+	lag        *lagReporter
+	mirrors    []*mirror
+	fallbacks  []fallbackStore
+	adminAPI   *adminServer
+	grpcAPI    *grpcServer
+
+	snapshotSchedule *snapshotScheduler
+
+	adminWriteLimit  rate.Limit
+	adminWriteBurst  int
+	adminTLS         *AdminTLSConfig
+	adminCORSOrigins []string
 }
 
-// BookmarkFile represents the structure saved to/loaded from file
+// ManagerOption configures optional behaviour of a BookmarkManager
+type ManagerOption func(*BookmarkManager)
+
+// BookmarkFile represents the structure saved to/loaded from file. Bookmarks
+// are ordered by topic and partition, sink positions by sink, and metadata
+// and annotation keys alphabetically. The checksum covers the bookmarks, sink
+// positions and pending commits and is verified on load. The writer is only
+// read from files saved by former versions, it's recorded in <path>.writer
+// instead.
 type BookmarkFile struct {
-	Version   string      `json:"version"`
-	CreatedAt time.Time   `json:"created_at"`
-	UpdatedAt time.Time   `json:"updated_at"`
-	Bookmarks []*Bookmark `json:"bookmarks"`
+	Version     string            `json:"version"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	Writer      *WriterIdentity   `json:"writer,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Checksum    string            `json:"checksum,omitempty"`
+	Bookmarks   []*Bookmark       `json:"bookmarks"`
+	Sinks       []*SinkPosition   `json:"sink_positions,omitempty"`
+	Pending     []*PendingCommit  `json:"pending_commits,omitempty"`
 }
 
 // NewBookmarkManager creates a new bookmark manager
-func NewBookmarkManager(filePath string) *BookmarkManager {
-	return &BookmarkManager{
-		filePath:  filePath,
-		bookmarks: make(map[string]*Bookmark),
+func NewBookmarkManager(filePath string, opts ...ManagerOption) *BookmarkManager {
+	bm := &BookmarkManager{
+		filePath:       filePath,
+		sinks:          make(map[string]*SinkPosition),
+		writer:         newWriterIdentity(),
+		annotations:    defaultAnnotations(),
+		pretty:         true,
+		indent:         2,
+		backups:        1,
+		validateTopics: true,
+
+		partitionMetrics: true,
+		regressionPolicy: RegressionAllow,
+
+		maxTopicLength:     249,
+		maxPartitionLength: 1024,
+
+		maxTimestampFuture: 24 * time.Hour,
+		maxTimestampPast:   20 * 365 * 24 * time.Hour,
+
+		adminWriteLimit: defaultAdminWriteLimit,
+		adminWriteBurst: defaultAdminWriteBurst,
+	}
+	for _, opt := range opts {
+		opt(bm)
+	}
+	// Every log line names the file it's about, and the label when shared
+	bm.log = bm.log.With("bookmark_file", bm.filePath)
+	if bm.label != "" {
+		bm.log = bm.log.With("bookmark_label", bm.label)
 	}
+	if bm.res != nil {
+		bm.metrics = newManagerMetrics(bm.res.Metrics())
+	}
+	bm.tracer = bm.newTracer()
+	return bm
 }
 
-// generateKey creates a unique key for topic-partition combination
-func (bm *BookmarkManager) generateKey(topic, partition string) string {
-	return fmt.Sprintf("%s:%s", topic, partition)
+// generateKey creates a unique key for topic-partition combination in the
+// group of the manager
+func (bm *BookmarkManager) generateKey(topic, partition string) TopicPartition {
+	return NewGroupTopicPartition(bm.group, topic, partition)
 }
 
 // AddBookmark adds or updates a bookmark
@@ -61,69 +237,116 @@ func (bm *BookmarkManager) AddBookmark(bookmark *Bookmark) error {
 		return errors.New("bookmark cannot be nil")
 	}
 
-	if err := bookmark.validate(); err != nil {
+	if err := bm.validateBookmark(bookmark); err != nil {
 		return fmt.Errorf("invalid bookmark: %w", err)
 	}
+	bm.assignGroup(bookmark)
 
-	bm.mutex.Lock()
-	defer bm.mutex.Unlock()
+	defer bm.dispatchEvents()
+	bm.mutex.RLock()
+	defer bm.mutex.RUnlock()
+
+	key := bookmark.TopicPartition()
+	shard := bm.bookmarks.shard(key)
+	shard.mut.Lock()
+	defer shard.mut.Unlock()
 
-	key := bm.generateKey(bookmark.Topic, bookmark.Partition)
-	bm.bookmarks[key] = bookmark
+	previous := shard.bookmarks[key]
+	err := bm.checkRegression(previous, bookmark.Offset)
+	if errors.Is(err, errRegressionIgnored) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkLeaderEpoch(previous, bookmark.LeaderEpoch); err != nil {
+		return err
+	}
+	if err := bm.checkMetadataBudget(previous, bookmark); err != nil {
+		return err
+	}
+	bookmark.Version = nextVersion(previous)
+	if ev, changed := changeEvent(previous, bookmark); changed {
+		bm.emit(ev)
+	}
+	shard.setLocked(key, bookmark)
+	bm.dirty.markBookmark(key)
 
 	return nil
 }
 
 // GetBookmark retrieves a bookmark by topic and partition
 func (bm *BookmarkManager) GetBookmark(topic, partition string) (*Bookmark, error) {
-	bm.mutex.RLock()
-	defer bm.mutex.RUnlock()
-
 	key := bm.generateKey(topic, partition)
-	bookmark, exists := bm.bookmarks[key]
+	bookmark, exists := bm.bookmarks.get(key)
 	if !exists {
-		return nil, fmt.Errorf("bookmark not found for topic: %s, partition: %s", topic, partition)
+		return nil, fmt.Errorf("%w for topic: %s, partition: %s", ErrBookmarkNotFound, topic, partition)
 	}
 
 	return bookmark, nil
 }
 
-// GetAllBookmarks returns all bookmarks
+// GetBookmarks retrieves the bookmarks of several topic-partitions at once,
+// returning the bookmarks found and the keys without a bookmark in the order
+// they were given
+func (bm *BookmarkManager) GetBookmarks(keys []TopicPartition) (map[TopicPartition]*Bookmark, []TopicPartition) {
+	found := make(map[TopicPartition]*Bookmark, len(keys))
+	var misses []TopicPartition
+	for _, tp := range keys {
+		if bookmark, exists := bm.bookmarks.get(bm.generateKey(tp.Topic, tp.Partition)); exists {
+			found[tp] = bookmark
+			continue
+		}
+		misses = append(misses, tp)
+	}
+	return found, misses
+}
+
+// GetAllBookmarks returns all bookmarks of all groups sorted by group, topic
+// and partition. The bookmarks are copies taken when they last changed, which
+// are shared between callers and must not be modified, so that frequent reads
+// don't contend with updates.
 func (bm *BookmarkManager) GetAllBookmarks() []*Bookmark {
+	return slices.Clone(bm.readSnapshot().bookmarks)
+}
+
+// ForEach calls fn for each bookmark in no particular order, stopping at and
+// returning the first error. Unlike GetAllBookmarks no slice is allocated,
+// but the bookmark is locked while fn runs, so fn must not modify it or call
+// methods of the manager.
+func (bm *BookmarkManager) ForEach(fn func(*Bookmark) error) error {
 	bm.mutex.RLock()
 	defer bm.mutex.RUnlock()
 
-	bookmarks := make([]*Bookmark, 0, len(bm.bookmarks))
-	for _, bookmark := range bm.bookmarks {
-		bookmarks = append(bookmarks, bookmark)
-	}
+	return bm.bookmarks.each(fn)
+}
+
+// sortedBookmarks returns all bookmarks sorted by topic, then by partition with
+// numeric partitions in numeric order, for consistent ordering. The caller
+// must hold the write lock of the mutex.
+func (bm *BookmarkManager) sortedBookmarks() []*Bookmark {
+	bookmarks := bm.bookmarks.all()
 
-	// Sort by topic, then by partition for consistent ordering
 	sort.Slice(bookmarks, func(i, j int) bool {
-		if bookmarks[i].Topic == bookmarks[j].Topic {
-			return bookmarks[i].Partition < bookmarks[j].Partition
-		}
-		return bookmarks[i].Topic < bookmarks[j].Topic
+		return lessBookmark(bookmarks[i], bookmarks[j])
 	})
 
 	return bookmarks
 }
 
-// GetBookmarksByTopic returns all bookmarks for a specific topic
+// GetBookmarksByTopic returns all bookmarks for a specific topic in the group
+// of the manager
 func (bm *BookmarkManager) GetBookmarksByTopic(topic string) []*Bookmark {
-	bm.mutex.RLock()
-	defer bm.mutex.RUnlock()
-
 	var bookmarks []*Bookmark
-	for _, bookmark := range bm.bookmarks {
-		if bookmark.Topic == topic {
+	for _, bookmark := range bm.bookmarks.all() {
+		if bookmark.Group == bm.group && bookmark.Topic == topic {
 			bookmarks = append(bookmarks, bookmark)
 		}
 	}
 
 	// Sort by partition
 	sort.Slice(bookmarks, func(i, j int) bool {
-		return bookmarks[i].Partition < bookmarks[j].Partition
+		return comparePartitions(bookmarks[i].Partition, bookmarks[j].Partition) < 0
 	})
 
 	return bookmarks
@@ -131,143 +354,512 @@ func (bm *BookmarkManager) GetBookmarksByTopic(topic string) []*Bookmark {
 
 // RemoveBookmark removes a bookmark by topic and partition
 func (bm *BookmarkManager) RemoveBookmark(topic, partition string) error {
+	defer bm.dispatchEvents()
 	bm.mutex.Lock()
 	defer bm.mutex.Unlock()
 
 	key := bm.generateKey(topic, partition)
-	if _, exists := bm.bookmarks[key]; !exists {
-		return fmt.Errorf("bookmark not found for topic: %s, partition: %s", topic, partition)
+	bookmark, exists := bm.bookmarks.get(key)
+	if !exists {
+		return fmt.Errorf("%w for topic: %s, partition: %s", ErrBookmarkNotFound, topic, partition)
 	}
 
-	delete(bm.bookmarks, key)
+	bm.emit(removeEvent(bookmark))
+	bm.bookmarks.remove(key)
+	bm.dirty.markBookmark(key)
 	return nil
 }
 
 // UpdateOffset updates the offset for an existing bookmark
-func (bm *BookmarkManager) UpdateOffset(topic, partition string, offset int) error {
-	bm.mutex.Lock()
-	defer bm.mutex.Unlock()
+func (bm *BookmarkManager) UpdateOffset(topic, partition string, offset int64) error {
+	return bm.UpdateOffsetAt(topic, partition, offset, time.Time{})
+}
+
+// UpdateOffsetAt updates the offset for an existing bookmark and sets its
+// timestamp, or the current time if timestamp is zero. Passing the timestamp
+// of a batch avoids reading the clock for every message. Updates to the
+// current offset are no-ops that leave the timestamp untouched.
+func (bm *BookmarkManager) UpdateOffsetAt(topic, partition string, offset int64, timestamp time.Time) error {
+	if err := validateOffset(offset); err != nil {
+		return err
+	}
+
+	defer bm.dispatchEvents()
+	bm.mutex.RLock()
+	defer bm.mutex.RUnlock()
 
 	key := bm.generateKey(topic, partition)
-	bookmark, exists := bm.bookmarks[key]
+	shard := bm.bookmarks.shard(key)
+	shard.mut.Lock()
+	defer shard.mut.Unlock()
+
+	bookmark, exists := shard.bookmarks[key]
 	if !exists {
-		return fmt.Errorf("bookmark not found for topic: %s, partition: %s", topic, partition)
+		return fmt.Errorf("%w for topic: %s, partition: %s", ErrBookmarkNotFound, topic, partition)
 	}
+	return bm.applyOffsetLocked(key, bookmark, offset, timestamp, offsetOpts{})
+}
+
+// offsetOpts tune how applyOffsetLocked updates a bookmark
+type offsetOpts struct {
+	// force moves the offset backwards regardless of the regression policy
+	force bool
+
+	// update applies further changes to the bookmark along with the offset
+	update func(b *Bookmark)
+}
 
-	if offset < 0 {
-		return errors.New("offset must be non-negative")
+// applyOffsetLocked moves the bookmark of key to offset and sets its
+// timestamp, or the current time if timestamp is zero, then bumps its version,
+// marks it dirty and emits the change. Updates to the current offset and
+// regressions dropped by the regression policy change nothing. The manager
+// must be write locked, or read locked along with the shard of key.
+func (bm *BookmarkManager) applyOffsetLocked(key TopicPartition, bookmark *Bookmark, offset int64, timestamp time.Time, opts offsetOpts) error {
+	if bookmark.Offset == offset {
+		return nil
+	}
+	if !opts.force {
+		err := bm.checkRegression(bookmark, offset)
+		if errors.Is(err, errRegressionIgnored) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
 	}
 
+	previousOffset := bookmark.Offset
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
 	bookmark.Offset = offset
-	bookmark.Timestamp = time.Now()
+	bookmark.Timestamp = timestamp
+	if opts.update != nil {
+		opts.update(bookmark)
+	}
+	bookmark.Version++
+	bm.dirty.markBookmark(key)
+	if bm.hasEventListeners() {
+		ev, _ := changeEvent(&Bookmark{Offset: previousOffset}, bookmark)
+		bm.emit(ev)
+	}
+	return nil
+}
+
+// RecordSinkPosition records the last record successfully produced to a sink
+func (bm *BookmarkManager) RecordSinkPosition(sink string, sequence int64, id string) error {
+	p, err := NewSinkPosition(sink, sequence, id)
+	if err != nil {
+		return fmt.Errorf("invalid sink position: %w", err)
+	}
+
+	bm.mutex.Lock()
+	defer bm.mutex.Unlock()
 
+	bm.sinks[sink] = p
+	bm.dirty.markSink(sink)
 	return nil
 }
 
-// Count returns the number of bookmarks
-func (bm *BookmarkManager) Count() int {
+// GetSinkPosition retrieves the last recorded position of a sink
+func (bm *BookmarkManager) GetSinkPosition(sink string) (*SinkPosition, error) {
 	bm.mutex.RLock()
 	defer bm.mutex.RUnlock()
 
-	return len(bm.bookmarks)
+	p, exists := bm.sinks[sink]
+	if !exists {
+		return nil, fmt.Errorf("sink position not found for sink: %s", sink)
+	}
+
+	return p, nil
 }
 
-// Clear removes all bookmarks
-func (bm *BookmarkManager) Clear() {
+// GetAllSinkPositions returns the positions of all sinks sorted by sink name
+func (bm *BookmarkManager) GetAllSinkPositions() []*SinkPosition {
+	bm.mutex.RLock()
+	defer bm.mutex.RUnlock()
+
+	return bm.sortedSinkPositions()
+}
+
+// RemoveSinkPosition removes the recorded position of a sink
+func (bm *BookmarkManager) RemoveSinkPosition(sink string) error {
 	bm.mutex.Lock()
 	defer bm.mutex.Unlock()
 
-	bm.bookmarks = make(map[string]*Bookmark)
+	if _, exists := bm.sinks[sink]; !exists {
+		return fmt.Errorf("sink position not found for sink: %s", sink)
+	}
+
+	delete(bm.sinks, sink)
+	bm.dirty.markSink(sink)
+	return nil
 }
 
-// SaveToFile saves all bookmarks to the specified file
-func (bm *BookmarkManager) SaveToFile() error {
+// IsDelivered returns true if the record with the given sequence has already
+// been produced to the sink, which allows idempotent outputs to skip it
+func (bm *BookmarkManager) IsDelivered(sink string, sequence int64) bool {
 	bm.mutex.RLock()
 	defer bm.mutex.RUnlock()
 
+	p, exists := bm.sinks[sink]
+	return exists && p.Delivered(sequence)
+}
+
+func (bm *BookmarkManager) sortedSinkPositions() []*SinkPosition {
+	sinks := make([]*SinkPosition, 0, len(bm.sinks))
+	for _, p := range bm.sinks {
+		sinks = append(sinks, p)
+	}
+	sort.Slice(sinks, func(i, j int) bool {
+		return sinks[i].Sink < sinks[j].Sink
+	})
+	return sinks
+}
+
+// Count returns the number of bookmarks
+func (bm *BookmarkManager) Count() int {
+	return bm.bookmarks.len()
+}
+
+// Clear removes all bookmarks
+func (bm *BookmarkManager) Clear() {
+	defer bm.dispatchEvents()
+	bm.mutex.Lock()
+	defer bm.mutex.Unlock()
+
+	for _, bookmark := range bm.sortedBookmarks() {
+		bm.emit(removeEvent(bookmark))
+		bm.dirty.markBookmark(bookmark.TopicPartition())
+	}
+	bm.bookmarks.reset()
+}
+
+// SaveToFile saves all bookmarks to the specified file, or appends the
+// changes to the write-ahead log when enabled. File writes can't be
+// interrupted, so ctx is checked before each step of the save.
+func (bm *BookmarkManager) SaveToFile(ctx context.Context) error {
+	return bm.save(ctx, false)
+}
+
+func (bm *BookmarkManager) save(ctx context.Context, compact bool) error {
+	if bm.readOnly {
+		bm.skipSave(compact)
+		return nil
+	}
+
+	ctx, span := bm.startSpan(ctx, "save", attribute.Bool("bookmark.compact", compact))
+	started := time.Now()
+	err := bm.saveToFile(ctx, compact)
+	if err == nil && bm.audit != nil {
+		err = bm.audit.flush()
+	}
+	bm.recordSave(started, err)
+	endSpan(span, err)
+
+	log := bm.log.With("compact", compact, "duration", time.Since(started))
+	if err != nil {
+		log.Warnf("Failed to save bookmarks: %v", err)
+	} else {
+		log.Debugf("Saved bookmarks")
+	}
+	if bm.alerts != nil {
+		bm.alerts.onSave(err)
+	}
+	return err
+}
+
+func (bm *BookmarkManager) saveToFile(ctx context.Context, compact bool) error {
+	bm.saveMutex.Lock()
+	defer bm.saveMutex.Unlock()
+
+	unlock, err := bm.lockFile(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Updates are held off until the state is written, so that the changes
+	// marked dirty are exactly those saved
+	bm.mutex.Lock()
+	defer bm.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Changes are appended to the write-ahead log until it's due for
+	// compaction into the snapshot
+	if bm.walDue(compact) {
+		return bm.appendWAL()
+	}
+
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(bm.filePath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
+	// A rotated file starts a new segment
+	now := time.Now()
+	rotate, err := bm.rotationDue(now)
+	if err != nil {
+		return err
+	}
+	createdAt := bm.createdAt
+	if rotate || createdAt.IsZero() {
+		createdAt = now
+	}
+
+	// Bookmarks and sink positions are sorted, the update time only changes
+	// along with the state and the writer is recorded in a file of its own,
+	// so that saving an identical state produces a byte-identical file, also
+	// from another process or after a restart. ForEach would visit them in
+	// map order, and the snapshot of GetAllBookmarks can't be built while the
+	// lock is held.
+	bookmarks := bm.sortedBookmarks()
+	sinks := bm.sortedSinkPositions()
+
+	digest, err := stateDigest(bookmarks, sinks)
+	if err != nil {
+		return err
+	}
+	updatedAt := now
+	if digest == bm.lastDigest && !bm.lastUpdatedAt.IsZero() {
+		updatedAt = bm.lastUpdatedAt
+	}
+
 	// Prepare bookmark file structure
 	bookmarkFile := BookmarkFile{
-		Version:   "1.0",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Bookmarks: make([]*Bookmark, 0, len(bm.bookmarks)),
+		Version:     FileVersion,
+		CreatedAt:   createdAt,
+		UpdatedAt:   updatedAt,
+		Annotations: bm.annotations,
+		Bookmarks:   bookmarks,
 	}
-
-	// Convert map to slice for JSON serialization
-	for _, bookmark := range bm.bookmarks {
-		bookmarkFile.Bookmarks = append(bookmarkFile.Bookmarks, bookmark)
+	if len(sinks) > 0 {
+		bookmarkFile.Sinks = sinks
+	}
+	if len(bm.pending) > 0 {
+		bookmarkFile.Pending = bm.sortedPendingCommits()
 	}
 
-	// Sort bookmarks for consistent file output
-	sort.Slice(bookmarkFile.Bookmarks, func(i, j int) bool {
-		if bookmarkFile.Bookmarks[i].Topic == bookmarkFile.Bookmarks[j].Topic {
-			return bookmarkFile.Bookmarks[i].Partition < bookmarkFile.Bookmarks[j].Partition
-		}
-		return bookmarkFile.Bookmarks[i].Topic < bookmarkFile.Bookmarks[j].Topic
-	})
+	if err := bm.runBeforeSaveHooks(ctx, &bookmarkFile); err != nil {
+		return err
+	}
 
-	// Marshal to JSON with indentation
-	data, err := json.MarshalIndent(bookmarkFile, "", "  ")
+	data, err := bm.marshalFile(bookmarkFile)
 	if err != nil {
 		return fmt.Errorf("failed to marshal bookmarks: %w", err)
 	}
 
+	if bm.maxFileSize > 0 && int64(len(data)) > bm.maxFileSize {
+		if data, err = bm.trimToBudget(bookmarkFile, len(data)); err != nil {
+			return err
+		}
+	}
+	bm.fileSize.Store(int64(len(data)))
+	if data, err = bm.compressFile(data); err != nil {
+		return err
+	}
+
+	// Marshalling large bookmark sets takes a while, the file is left
+	// untouched if the caller gave up in the meantime
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	bm.checkConcurrentWriter()
+	bm.refreshOwnerLock()
+
+	// Move the active file into a segment when it's due for rotation
+	if rotate {
+		if err := bm.rotate(now); err != nil {
+			return err
+		}
+	}
+
 	// Write to temporary file first, then rename (atomic operation)
 	tempFile := bm.filePath + ".tmp"
-	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+	if err := bm.writeTempFile(tempFile, data); err != nil {
+		os.Remove(tempFile)
 		return fmt.Errorf("failed to write temporary file: %w", err)
 	}
 
+	// The replaced file becomes the newest backup unless it holds the same
+	// state, a rotated file is kept as a segment instead
+	if !rotate && digest != bm.lastDigest {
+		if err := bm.rotateBackups(now); err != nil {
+			os.Remove(tempFile)
+			return err
+		}
+	}
+
 	if err := os.Rename(tempFile, bm.filePath); err != nil {
 		os.Remove(tempFile) // Clean up temp file
 		return fmt.Errorf("failed to rename temporary file: %w", err)
 	}
+	if err := bm.syncDir(dir); err != nil {
+		return err
+	}
 
-	return nil
+	bm.createdAt = createdAt
+	bm.lastDigest = digest
+	bm.lastUpdatedAt = updatedAt
+	bm.dirty.reset()
+	if err := bm.recordWriter(); err != nil {
+		bm.log.Warnf("Failed to record the writer of bookmark file %s: %v", bm.filePath, err)
+	}
+
+	bm.exportToCache(bookmarks)
+	return bm.truncateWAL()
 }
 
-// LoadFromFile loads bookmarks from the specified file
-func (bm *BookmarkManager) LoadFromFile() error {
-	bm.mutex.Lock()
-	defer bm.mutex.Unlock()
+// WithFormatting sets whether the bookmark file is written as indented JSON
+// and the indent width used, compact JSON is written when pretty is false
+func WithFormatting(pretty bool, indent int) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.pretty = pretty
+		bm.indent = indent
+	}
+}
 
-	// Check if file exists
-	if _, err := os.Stat(bm.filePath); errors.Is(err, fs.ErrNotExist) {
-		// File doesn't exist, start with empty bookmarks
-		return nil
+// WithLogger sets the logger used to report operational events of the manager
+func WithLogger(log *service.Logger) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.log = log
 	}
+}
 
-	// Read file
-	data, err := os.ReadFile(bm.filePath)
+// marshalFile serializes the bookmark file using the configured format and
+// formatting along with its checksum
+func (bm *BookmarkManager) marshalFile(bookmarkFile BookmarkFile) ([]byte, error) {
+	if bm.format == FileFormatMsgpack {
+		return marshalMsgpackFile(bookmarkFile)
+	}
+
+	checksum, err := fileChecksum(bookmarkFile.Bookmarks, bookmarkFile.Sinks, bookmarkFile.Pending)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return nil, err
 	}
+	bookmarkFile.Checksum = checksum
 
-	// Parse JSON
-	var bookmarkFile BookmarkFile
-	if err := json.Unmarshal(data, &bookmarkFile); err != nil {
-		return fmt.Errorf("failed to unmarshal bookmarks: %w", err)
+	if !bm.pretty {
+		return json.Marshal(bookmarkFile)
 	}
+	return json.MarshalIndent(bookmarkFile, "", strings.Repeat(" ", bm.indent))
+}
 
-	// Clear existing bookmarks and load from file
-	bm.bookmarks = make(map[string]*Bookmark)
+// LoadFromFile loads bookmarks from the specified file. Invalid entries are
+// skipped and the newest backup or rotated segment is used when the file is
+// missing or can't be decoded, the outcome is available from
+// GetRecoveryReport. A file that fails its checksum, such as a file edited by
+// hand, fails the load with ErrChecksumMismatch rather than rolling back to a
+// backup, see WithIgnoreChecksum.
+func (bm *BookmarkManager) LoadFromFile(ctx context.Context) error {
+	ctx, span := bm.startSpan(ctx, "load")
+	started := time.Now()
+	err := bm.loadFromFile(ctx)
+	if err == nil {
+		err = bm.runAfterLoadHooks(ctx)
+	}
+	bm.recordLoad(err)
+	endSpan(span, err)
+
+	log := bm.log.With("duration", time.Since(started))
+	if err != nil {
+		log.Warnf("Failed to load bookmarks: %v", err)
+	} else {
+		log.Debugf("Loaded %d bookmarks", bm.Count())
+	}
+	return err
+}
+
+func (bm *BookmarkManager) loadFromFile(ctx context.Context) error {
+	bm.saveMutex.Lock()
+	defer bm.saveMutex.Unlock()
+
+	unlock, err := bm.lockFile(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	bm.mutex.Lock()
+	defer bm.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	report := RecoveryReport{LoadedAt: time.Now()}
+	var bookmarkFile *BookmarkFile
+
+	// Check if file exists
+	if _, err := os.Stat(bm.filePath); errors.Is(err, fs.ErrNotExist) {
+		// A rotation interrupted between moving the active file and writing
+		// its successor leaves only segments behind
+		if bookmarkFile = bm.readBackupFile(&report); bookmarkFile == nil {
+			if _, err := os.Stat(bm.walPath()); !bm.walEnabled || err != nil {
+				// File doesn't exist, start with empty bookmarks
+				bm.recovery = report
+				bm.logRecoveryReport(report)
+				return nil
+			}
+			// The write-ahead log of a manager that never compacted
+			bookmarkFile = &BookmarkFile{}
+		}
+	} else {
+		// Read file
+		data, err := os.ReadFile(bm.filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
 
-	// Validate and add each bookmark
-	for _, bookmark := range bookmarkFile.Bookmarks {
-		if err := bookmark.validate(); err != nil {
-			return fmt.Errorf("invalid bookmark in file: %w", err)
+		// Parse JSON
+		bm.fileSize.Store(int64(len(data)))
+		bookmarkFile, err = decodeBookmarkFile(data, &report)
+		if errors.Is(err, ErrChecksumMismatch) {
+			if !bm.ignoreChecksum {
+				return fmt.Errorf("failed to verify bookmarks, restore a backup or run bookmarkctl resign to keep changes made by hand: %w", err)
+			}
+			bm.log.Warnf("Ignoring the checksum of %s: %v", bm.filePath, err)
+			report.problemf("%s: %v", bm.filePath, err)
+			bookmarkFile, err = decodeUnverifiedFile(data, &report)
+		}
+		if err != nil {
+			report.problemf("failed to decode %s: %v", bm.filePath, err)
+			if bookmarkFile = bm.readBackupFile(&report); bookmarkFile == nil {
+				return fmt.Errorf("failed to unmarshal bookmarks: %w", err)
+			}
+		} else {
+			report.Source = bm.filePath
 		}
+	}
+
+	// Clear existing bookmarks and load from file
+	bm.applyBookmarkFile(bookmarkFile, &report)
+
+	replayed, err := bm.replayWAL(&report)
+	if err != nil {
+		return err
+	}
+	if replayed && report.Source == "" {
+		report.Source = bm.walPath()
+	}
 
-		key := bm.generateKey(bookmark.Topic, bookmark.Partition)
-		bm.bookmarks[key] = bookmark
+	if bm.lastDigest, err = stateDigest(bm.sortedBookmarks(), bm.sortedSinkPositions()); err != nil {
+		return err
 	}
+	bm.lastUpdatedAt = bookmarkFile.UpdatedAt
+	bm.lastWriter = bookmarkFile.Writer
+	if writer, err := bm.readFileWriter(); err == nil && writer != nil {
+		bm.lastWriter = writer
+	}
+	bm.dirty.reset()
 
+	bm.recovery = report
+	bm.logRecoveryReport(report)
 	return nil
 }
 
@@ -284,17 +876,936 @@ func (bm *BookmarkManager) GetFilePath() string {
 
 // String returns a string representation of the manager
 func (bm *BookmarkManager) String() string {
-	bm.mutex.RLock()
-	defer bm.mutex.RUnlock()
-
-	return fmt.Sprintf("BookmarkManager{filePath: %s, bookmarks: %d}", bm.filePath, len(bm.bookmarks))
+	return fmt.Sprintf("BookmarkManager{filePath: %s, bookmarks: %d}", bm.filePath, bm.bookmarks.len())
 }
 
+const (
+	bfmFieldSection        = "bookmarks_file"
+	bfmFieldPath           = "path"
+	bfmFieldLabel          = "label"
+	bfmFieldGroup          = "group"
+	bfmFieldMetaSchema     = "metadata_schema"
+	bfmFieldRotation       = "rotation"
+	bfmFieldRotationSize   = "max_size"
+	bfmFieldRotationMaxAge = "max_age"
+	bfmFieldMaxFileSize    = "max_file_size"
+	bfmFieldBackups        = "backups"
+	bfmFieldBackupCount    = "count"
+	bfmFieldDurable        = "durable"
+	bfmFieldCompression    = "compression"
+	bfmFieldFormat         = "format"
+	bfmFieldAutoSave       = "auto_save_interval"
+	bfmFieldAutoSaveMax    = "auto_save_max_updates"
+	bfmFieldWAL            = "wal"
+	bfmFieldWALEnabled     = "enabled"
+	bfmFieldWALCompact     = "compact_after"
+	bfmFieldPretty         = "pretty"
+	bfmFieldIndent         = "indent"
+	bfmFieldAnnotations    = "annotations"
+	bfmFieldValidateTopics = "validate_topic_names"
+	bfmFieldMaxTopicLen    = "max_topic_length"
+	bfmFieldMaxPartLen     = "max_partition_length"
+	bfmFieldOwnerLock      = "owner_lock"
+	bfmFieldOwnerLockOn    = "enabled"
+	bfmFieldOwnerLockStale = "stale_after"
+	bfmFieldFileLock       = "file_lock"
+	bfmFieldFileLockOn     = "enabled"
+	bfmFieldFileLockWait   = "lock_timeout"
+	bfmFieldCacheImport    = "import_from_cache"
+	bfmFieldCacheResource  = "resource"
+	bfmFieldCacheKeyPrefix = "key_prefix"
+	bfmFieldCacheCheckpts  = "checkpoints"
+	bfmFieldOnRegression   = "on_regression"
+	bfmFieldTimestampCheck = "timestamp_tolerance"
+	bfmFieldMaxFuture      = "max_future"
+	bfmFieldMaxPast        = "max_past"
+	bfmFieldReject         = "reject"
+	bfmFieldCacheExport    = "export_to_cache"
+	bfmFieldCacheFormat    = "format"
+	bfmFieldEvents         = "events"
+	bfmFieldEventsNATS     = "nats"
+	bfmFieldNATSURLs       = "urls"
+	bfmFieldNATSPrefix     = "subject_prefix"
+	bfmFieldEventsMQTT     = "mqtt"
+	bfmFieldMQTTURLs       = "urls"
+	bfmFieldMQTTPrefix     = "topic_prefix"
+	bfmFieldMQTTClientID   = "client_id"
+	bfmFieldMQTTUser       = "user"
+	bfmFieldMQTTPassword   = "password"
+	bfmFieldMQTTQoS        = "qos"
+	bfmFieldMQTTRetained   = "retained"
+	bfmFieldAlerts         = "alerts"
+	bfmFieldAlertsURL      = "webhook_url"
+	bfmFieldAlertsHeaders  = "headers"
+	bfmFieldAlertsStale    = "stale_after"
+	bfmFieldAlertsInterval = "check_interval"
+	bfmFieldAlertsRegress  = "on_regression"
+	bfmFieldAlertsSaveFail = "on_save_failure"
+	bfmFieldAdminAPI       = "admin_api"
+	bfmFieldAdminAddress   = "address"
+	bfmFieldAdminWriteRate = "write_limit"
+	bfmFieldAdminBurst     = "write_burst"
+	bfmFieldAdminTLS       = "tls"
+	bfmFieldAdminCert      = "cert_file"
+	bfmFieldAdminKey       = "key_file"
+	bfmFieldAdminClientCA  = "client_ca_file"
+	bfmFieldAdminCORS      = "cors_origins"
+	bfmFieldGRPCAPI        = "grpc_api"
+	bfmFieldGRPCAddress    = "address"
+	bfmFieldPartMetrics    = "partition_metrics"
+	bfmFieldExpiry         = "expiry"
+	bfmFieldExpireAfter    = "expire_after"
+	bfmFieldExpiryInterval = "check_interval"
+	bfmFieldSnapSchedule   = "snapshot_schedule"
+	bfmFieldSnapCron       = "cron"
+	bfmFieldSnapKeep       = "keep"
+	bfmFieldSnapUploadURL  = "upload_url"
+	bfmFieldAudit          = "audit"
+	bfmFieldAuditEnabled   = "enabled"
+	bfmFieldAuditRetention = "retention"
+	bfmFieldLag            = "lag"
+	bfmFieldLagBrokers     = "seed_brokers"
+	bfmFieldLagTopics      = "topics"
+	bfmFieldLagInterval    = "interval"
+	bfmFieldLagThreshold   = "alert_threshold"
+	bfmFieldMirrors        = "mirrors"
+	bfmFieldFallbacks      = "fallbacks"
+	bfmFieldStore          = "store"
+	bfmFieldReadOnly       = "read_only"
+)
+
 func BookmarkFileManagerConfigFields() []*service.ConfigField {
 	return []*service.ConfigField{
-		service.NewObjectField("bookmarks_file",
-			service.NewStringField("path").
-				Description("The bookmark path.").
-				Description("The file based bookmarks manager configuration")),
+		service.NewObjectField(bfmFieldSection,
+			service.NewStringField(bfmFieldPath).
+				Description("The path of the bookmark file.").
+				Example("./bookmarks.json").
+				LintRule(`root = if this.trim() == "" { [ "the bookmark path must not be empty" ] }`),
+			service.NewStringField(bfmFieldLabel).
+				Description("A label that shares a single bookmark manager between all components of the config using it, which prevents separate managers from racing on one file. The manager is created from the configuration of the first component, opened by the first component to start and flushed and closed by the last component to stop. Components sharing a label must use the same path.").
+				Default("").
+				Advanced(),
+			service.NewStringField(bfmFieldGroup).
+				Description("The consumer group the bookmarks of the component belong to, so that components reading the same topics keep independent bookmarks in one bookmark file. Components sharing a label use the group of the first component. Leave empty for the default group.").
+				Default("").
+				Advanced(),
+			service.NewStringField(bfmFieldMetaSchema).
+				Description("A JSON schema the metadata of every bookmark must match, bookmarks with metadata that doesn't match it are rejected. Leave empty to accept any metadata.").
+				Default("").
+				Example(`{"type":"object","properties":{"etag":{"type":"string"}},"required":["etag"]}`).
+				Advanced(),
+			service.NewObjectField(bfmFieldRotation,
+				service.NewIntField(bfmFieldRotationSize).
+					Description("The size in bytes the active file can reach before it is rotated into a new segment. Set to 0 to disable size based rotation.").
+					Default(0),
+				service.NewStringField(bfmFieldRotationMaxAge).
+					Description("The period of time the active file can be written to before it is rotated into a new segment. Leave empty to disable age based rotation.").
+					Default("").
+					Example("24h"),
+			).
+				Description("Rotates the active bookmark file into numbered segments listed in an index file (`<path>.segments.json`), allowing checkpoint history to be archived externally.").
+				Advanced(),
+			service.NewIntField(bfmFieldMaxFileSize).
+				Description("The maximum size in bytes of the serialized bookmark file before compression. Bookmarks carrying metadata that would grow the file past the limit are rejected with an error. When the file exceeds it anyway the bookmark metadata is dropped from the file with a warning, and if the file is still too large the save is refused with an error. Set to 0 to disable the limit.").
+				Default(0).
+				Advanced(),
+			service.NewObjectField(bfmFieldBackups,
+				service.NewIntField(bfmFieldBackupCount).
+					Description("The number of backups to keep. Set to 0 to disable automatic backups.").
+					Default(1),
+			).
+				Description("Moves the bookmark file into a timestamped backup (`<path>.bak.<timestamp>`) on every save that replaces it and keeps the newest backups. When the bookmark file is missing or truncated the newest valid backup is loaded instead, and a backup can be restored to roll back the bookmarks. A bookmark file that fails its checksum, such as a file edited by hand, fails to load instead of rolling back, run `bookmarkctl resign` to keep the edit or restore a backup.").
+				Advanced(),
+			service.NewBoolField(bfmFieldDurable).
+				Description("Whether saves fsync the new bookmark file before it replaces the old one, and its directory after, so that saved bookmarks survive a power failure at the cost of slower saves.").
+				Default(false).
+				Advanced(),
+			service.NewStringEnumField(bfmFieldCompression, string(CompressionNone), string(CompressionGzip), string(CompressionZstd)).
+				Description("The codec the bookmark file is compressed with, which shrinks large bookmark sets considerably. The codec of a file is detected when loading, so that the codec can be changed at any time. The write-ahead log is never compressed.").
+				Default(string(CompressionNone)).
+				Advanced(),
+			service.NewStringEnumField(bfmFieldFormat, string(FileFormatJSON), string(FileFormatMsgpack)).
+				Description("The encoding of the bookmark file. MessagePack files are smaller and faster to save and load for large partition counts, but can't be read or edited by hand. The format of a file is detected when loading, so that the format can be changed at any time.").
+				Default(string(FileFormatJSON)).
+				Advanced(),
+			service.NewStringField(bfmFieldAutoSave).
+				Description("The interval at which changed bookmarks are saved in the background, and once more when the component stops. Components then no longer save the bookmark file after every update, which trades replaying the updates of at most one interval after a crash for far fewer writes. Leave empty to save after every update.").
+				Default("").
+				Example("5s").
+				Advanced(),
+			service.NewIntField(bfmFieldAutoSaveMax).
+				Description("Saves early, before the next `auto_save_interval`, once this many bookmarks changed since the last save. Zero only saves at the interval.").
+				Default(0).
+				Advanced(),
+			service.NewObjectField(bfmFieldWAL,
+				service.NewBoolField(bfmFieldWALEnabled).
+					Description("Whether to append changed bookmarks to a write-ahead log (`<path>.wal`) instead of rewriting the bookmark file on every save. The bookmark file becomes a snapshot the log is replayed on top of when loading.").
+					Default(false),
+				service.NewIntField(bfmFieldWALCompact).
+					Description("The number of log entries after which the state is compacted into the snapshot and the log truncated. The size of truncated logs is counted as `bookmark_wal_reclaimed_bytes`.").
+					Default(1000),
+			).
+				Description("Persists bookmark changes to an append-only log, which is synced on every save and survives partial writes, and periodically compacts it into the bookmark file. Rotation and the file size budget apply when compacting.").
+				Advanced(),
+			service.NewBoolField(bfmFieldPretty).
+				Description("Whether to write the bookmark file as indented JSON. Compact JSON is smaller and faster to write for large bookmark sets.").
+				Default(true).
+				Advanced(),
+			service.NewIntField(bfmFieldIndent).
+				Description("The number of spaces used to indent the bookmark file when `pretty` is enabled.").
+				Default(2).
+				Advanced(),
+			service.NewStringMapField(bfmFieldAnnotations).
+				Description("Annotations written to the bookmark file, telling operators inspecting the file which pipeline instance owns it. The `hostname` and `version` annotations are added automatically and can be overridden. Values can reference environment variables with the `${VAR}` syntax.").
+				Default(map[string]any{}).
+				Example(map[string]any{"pipeline": "${PIPELINE_NAME}", "team": "payments"}).
+				Advanced(),
+			service.NewBoolField(bfmFieldValidateTopics).
+				Description("Whether to reject bookmarks with topics that are not valid Kafka topic names (alphanumerics, `.`, `_` and `-`, at most 249 characters).").
+				Default(true).
+				Advanced(),
+			service.NewIntField(bfmFieldMaxTopicLen).
+				Description("The maximum length in bytes of a bookmark topic. Set to 0 to disable the limit.").
+				Default(249).
+				Advanced(),
+			service.NewIntField(bfmFieldMaxPartLen).
+				Description("The maximum length in bytes of a bookmark partition. The default matches the maximum length of an S3 object key. Set to 0 to disable the limit.").
+				Default(1024).
+				Advanced(),
+			service.NewStringEnumField(bfmFieldOnRegression, string(RegressionAllow), string(RegressionWarn), string(RegressionReject), string(RegressionIgnore)).
+				Description("How updates that move the offset of a bookmark backwards are handled, either allowed, allowed with a warning, rejected with an error, or logged and ignored. `reject` and `ignore` enforce strictly monotonic offsets, so that a retried batch can't rewind committed progress. Restores and forced resets, such as `bookmarkctl reset-to-timestamp --force`, rewind bookmarks regardless of the policy.").
+				Default(string(RegressionAllow)).
+				Advanced(),
+			service.NewObjectField(bfmFieldTimestampCheck,
+				service.NewStringField(bfmFieldMaxFuture).
+					Description("How far in the future a bookmark timestamp may lie. Leave empty to disable the check.").
+					Default("24h"),
+				service.NewStringField(bfmFieldMaxPast).
+					Description("How far in the past a bookmark timestamp may lie. Leave empty to disable the check.").
+					Default("175200h"),
+				service.NewBoolField(bfmFieldReject).
+					Description("Whether to reject bookmarks with timestamps outside the tolerance rather than logging a warning.").
+					Default(false),
+			).
+				Description("Sanity checks of bookmark timestamps, which catch upstream confusion of seconds and milliseconds.").
+				Advanced(),
+			service.NewObjectField(bfmFieldOwnerLock,
+				service.NewBoolField(bfmFieldOwnerLockOn).
+					Description("Whether to create an owner lock file (`<path>.lock`) when the component starts, preventing other components from using the same bookmark file until it is closed.").
+					Default(false),
+				service.NewDurationField(bfmFieldOwnerLockStale).
+					Description("The period after which a lock that hasn't been refreshed by a save is considered stale and taken over. Locks of processes that no longer run on the same host are always taken over.").
+					Default("5m"),
+			).
+				Description("Guards the bookmark file against use by multiple components at the same time.").
+				Advanced(),
+			service.NewObjectField(bfmFieldFileLock,
+				service.NewBoolField(bfmFieldFileLockOn).
+					Description("Whether to take an advisory lock on `<path>.flock` around every save and load, so that processes sharing the bookmark file don't clobber each other's writes. Uses `flock` on Unix and `LockFileEx` on Windows.").
+					Default(false),
+				service.NewDurationField(bfmFieldFileLockWait).
+					Description("How long to wait for a lock held by another process before the save or load fails.").
+					Default("10s"),
+			).
+				Description("Serializes file operations across processes with an operating system file lock, which is released when its process dies.").
+				Advanced(),
+			service.NewObjectField(bfmFieldCacheImport,
+				service.NewStringField(bfmFieldCacheResource).
+					Description("The cache resource to import checkpoints from. Leave empty to disable the import.").
+					Default(""),
+				service.NewStringField(bfmFieldCacheKeyPrefix).
+					Description("A prefix prepended to each checkpoint to form its cache key.").
+					Default("").
+					Example("offsets_"),
+				service.NewStringListField(bfmFieldCacheCheckpts).
+					Description("The checkpoints to import, each in the form `topic:partition`. Cached values are either a plain offset or a JSON object with at least an `offset` field.").
+					Default([]any{}).
+					Example([]any{"orders:0", "orders:1"}),
+			).
+				Description("Imports checkpoints from a cache resource when the component starts without a bookmark file, easing migration from cache based checkpointing.").
+				Advanced(),
+			service.NewObjectField(bfmFieldCacheExport,
+				service.NewStringField(bfmFieldCacheResource).
+					Description("The cache resource to mirror bookmarks into. Leave empty to disable the export.").
+					Default(""),
+				service.NewStringField(bfmFieldCacheKeyPrefix).
+					Description("A prefix prepended to the `topic:partition` of each bookmark to form its cache key.").
+					Default("").
+					Example("offsets_"),
+				service.NewStringEnumField(bfmFieldCacheFormat, CacheExportJSON, CacheExportOffset).
+					Description("Whether to write each bookmark as a JSON object or as a plain offset.").
+					Default(CacheExportJSON),
+			).
+				Description("Mirrors the bookmarks into a cache resource whenever they are saved, so that components reading checkpoints from a cache keep working during a transition period.").
+				Advanced(),
+			service.NewObjectField(bfmFieldEvents,
+				service.NewObjectField(bfmFieldEventsNATS,
+					service.NewStringListField(bfmFieldNATSURLs).
+						Description("The URLs of the NATS servers to publish to. Leave empty to disable publishing to NATS.").
+						Default([]any{}).
+						Example([]any{"nats://127.0.0.1:4222"}),
+					service.NewStringField(bfmFieldNATSPrefix).
+						Description("The subject prefix, events are published to `<subject_prefix>.<kind>.<topic>`.").
+						Default("bookmarks"),
+				).
+					Description("Publishes bookmark events as JSON to NATS subjects."),
+				service.NewObjectField(bfmFieldEventsMQTT,
+					service.NewStringListField(bfmFieldMQTTURLs).
+						Description("The URLs of the MQTT brokers to publish to. Leave empty to disable publishing to MQTT.").
+						Default([]any{}).
+						Example([]any{"tcp://localhost:1883"}),
+					service.NewStringField(bfmFieldMQTTPrefix).
+						Description("The topic prefix, the state of each bookmark is published to `<topic_prefix>/<topic>/<partition>` where `/`, `+`, `#` and `%` within the topic and partition are percent-encoded.").
+						Default("bookmarks"),
+					service.NewStringField(bfmFieldMQTTClientID).
+						Description("An identifier for the client connection.").
+						Default(""),
+					service.NewStringField(bfmFieldMQTTUser).
+						Description("A username to connect with.").
+						Default(""),
+					service.NewStringField(bfmFieldMQTTPassword).
+						Description("A password to connect with.").
+						Default("").
+						Secret(),
+					service.NewIntField(bfmFieldMQTTQoS).
+						Description("The QoS level to publish with.").
+						Default(1),
+					service.NewBoolField(bfmFieldMQTTRetained).
+						Description("Whether to publish retained messages, so that the broker holds the latest position of every partition. Removed bookmarks clear their retained message.").
+						Default(true),
+				).
+					Description("Publishes the state of each bookmark as JSON to MQTT topics."),
+			).
+				Description("Publishers of bookmark change events. The kinds of events are `created`, `advanced`, `rewound`, `removed` and `expired`, and events are dropped rather than delaying the pipeline when a publisher can't keep up.").
+				Advanced(),
+			service.NewObjectField(bfmFieldAlerts,
+				service.NewStringField(bfmFieldAlertsURL).
+					Description("The URL alerts are posted to as JSON. Leave empty to disable alerts.").
+					Default(""),
+				service.NewStringMapField(bfmFieldAlertsHeaders).
+					Description("Headers added to each alert request.").
+					Default(map[string]any{}),
+				service.NewStringField(bfmFieldAlertsStale).
+					Description("Raises an alert for bookmarks that haven't been updated for this period. Leave empty to disable the rule.").
+					Default("").
+					Example("30m"),
+				service.NewDurationField(bfmFieldAlertsInterval).
+					Description("How often bookmarks are checked for staleness.").
+					Default("1m"),
+				service.NewBoolField(bfmFieldAlertsRegress).
+					Description("Whether to raise an alert when the offset of a bookmark moves backwards.").
+					Default(true),
+				service.NewBoolField(bfmFieldAlertsSaveFail).
+					Description("Whether to raise an alert when saving the bookmark file fails. Consecutive failures raise a single alert.").
+					Default(true),
+			).
+				Description("Posts alerts to a webhook when checkpointing goes wrong, for routing to incident tools such as PagerDuty or Slack.").
+				Advanced(),
+			service.NewObjectField(bfmFieldAdminAPI,
+				service.NewStringField(bfmFieldAdminAddress).
+					Description("The address to serve the admin API on. Leave empty to disable the API.").
+					Default("").
+					Example("127.0.0.1:4196"),
+				service.NewFloatField(bfmFieldAdminWriteRate).
+					Description("The number of `PUT` and `DELETE` requests per second each client address may make, rejecting those above it with `429 Too Many Requests`. Set to `0` to disable the limit.").
+					Default(1),
+				service.NewIntField(bfmFieldAdminBurst).
+					Description("The number of `PUT` and `DELETE` requests a client address may make at once before the write limit applies.").
+					Default(10),
+				service.NewObjectField(bfmFieldAdminTLS,
+					service.NewStringField(bfmFieldAdminCert).
+						Description("The PEM certificate file to serve the API with. Leave empty to serve plain HTTP.").
+						Default(""),
+					service.NewStringField(bfmFieldAdminKey).
+						Description("The PEM private key file of the certificate.").
+						Default(""),
+					service.NewStringField(bfmFieldAdminClientCA).
+						Description("A PEM file of the CAs client certificates are verified against. When set, clients must present a certificate signed by one of them.").
+						Default(""),
+				).
+					Description("Serves the API over TLS when a certificate is set."),
+				service.NewStringListField(bfmFieldAdminCORS).
+					Description("The origins of web pages allowed to call the API from browsers, such as internal dashboards. Use `*` to allow all origins.").
+					Default([]any{}).
+					Example([]any{"https://dashboard.internal"}),
+			).
+				Description("Serves an HTTP API while the component runs, for listing bookmarks with `GET /bookmarks`, inspecting, setting and removing them with `GET`, `PUT` and `DELETE` on `/bookmarks/<topic>/<partition>`, and checking health with `GET /health`. The `group` query parameter selects the consumer group of the bookmarks, which defaults to the group of the component. The list is paged with the `limit` parameter and the `cursor` returned in the `X-Next-Cursor` header, and sorted by descending lag with `sort=lag`. `DELETE /bookmarks` removes all bookmarks of the group, or of the `topic` parameter, and removals require the `confirm=true` query parameter. A `PUT` body sets the `offset`, which is a number or `latest`, `earliest` or `none`, and optionally the `metadata` and `timestamp`. Without client certificates the API has no authentication, so bind it to an address only operators can reach.").
+				Advanced(),
+			service.NewObjectField(bfmFieldGRPCAPI,
+				service.NewStringField(bfmFieldGRPCAddress).
+					Description("The address to serve the gRPC API on. Leave empty to disable the API.").
+					Default("").
+					Example("127.0.0.1:4197"),
+			).
+				Description("Serves the `bookmark.v1.BookmarkService` gRPC API while the component runs, with `GetBookmark`, `SetBookmark`, `ListBookmarks` and a streaming `Watch` of bookmark changes, for orchestration tools managing offsets programmatically. The API has no authentication, so bind it to an address only trusted clients can reach.").
+				Advanced(),
+			service.NewObjectField(bfmFieldExpiry,
+				service.NewStringField(bfmFieldExpireAfter).
+					Description("Removes bookmarks that haven't been updated for the given period, such as those of deleted or repartitioned topics. Leave empty to keep bookmarks forever. A bookmark with an `expire_after` metadata key expires after that duration instead, where `0s` never expires.").
+					Default("").
+					Example("720h"),
+				service.NewDurationField(bfmFieldExpiryInterval).
+					Description("How often expired bookmarks are pruned.").
+					Default("10m"),
+			).
+				Description("Prunes stale bookmarks so that they don't accumulate in the bookmark file.").
+				Advanced(),
+			service.NewObjectField(bfmFieldSnapSchedule,
+				service.NewStringField(bfmFieldSnapCron).
+					Description("A cron expression of the times to take named snapshots at, named `scheduled-<UTC time>`. Leave empty to disable scheduled snapshots.").
+					Default("").
+					Example("0 * * * *").
+					Example("@daily"),
+				service.NewIntField(bfmFieldSnapKeep).
+					Description("The number of scheduled snapshots to keep, the oldest are deleted beyond it. Set to `0` to keep all.").
+					Default(24),
+				service.NewStringField(bfmFieldSnapUploadURL).
+					Description("A store URL to copy every scheduled snapshot to, an `s3://`, `gs://` or `azblob://` object where `{name}` is replaced by the name of the snapshot, or a `kafka://<broker>[,<broker>...]/<topic>` store. Leave empty to keep snapshots local.").
+					Default("").
+					Example("s3://backups/bookmarks/{name}.json"),
+			).
+				Description("Takes named snapshots on a schedule, giving predictable restore points for `bookmarkctl snapshot restore`.").
+				Advanced(),
+			service.NewObjectField(bfmFieldAudit,
+				service.NewBoolField(bfmFieldAuditEnabled).
+					Description("Whether to record every bookmark change in an append-only audit log next to the bookmark file.").
+					Default(false),
+				service.NewDurationField(bfmFieldAuditRetention).
+					Description("How long audit entries are kept. Zero keeps them forever.").
+					Default("720h"),
+			).
+				Description("Records when, by which writer and from which offset to which offset every bookmark changed in `<path>.audit`, one JSON entry per line, for proving when offsets were rewritten.").
+				Advanced(),
+			service.NewObjectField(bfmFieldLag,
+				service.NewStringListField(bfmFieldLagBrokers).
+					Description("The seed brokers of the Kafka cluster the bookmarks refer to. Leave empty to disable lag reporting.").
+					Default([]string{}),
+				service.NewStringListField(bfmFieldLagTopics).
+					Description("The topics whose lag is reported. Leave empty to report all bookmarked topics.").
+					Default([]string{}),
+				service.NewDurationField(bfmFieldLagInterval).
+					Description("How often the end offsets of the partitions are queried.").
+					Default("30s"),
+				service.NewIntField(bfmFieldLagThreshold).
+					Description("Logs a warning, and posts an alert when alerts are configured, for partitions lagging at least this many records. Zero disables the alert.").
+					Default(0),
+			).
+				Description("Reports how many records each bookmarked Kafka partition is behind the end of the partition as the `bookmark_lag` gauge.").
+				Advanced(),
+			service.NewStringListField(bfmFieldMirrors).
+				Description("URLs of stores the bookmarks are replicated to in the background, blob objects such as `s3://bucket/bookmarks.json?region=eu-west-1` or compacted Kafka topics such as `kafka://localhost:9092/bookmarks`, so that saves run at the speed of the local file while the mirrors keep a durable copy. The mirrors are reconciled on open and caught up on close.").
+				Example([]string{"s3://bucket/bookmarks.json"}).
+				Default([]string{}).
+				Advanced(),
+			service.NewStringListField(bfmFieldFallbacks).
+				Description("URLs of stores the bookmarks are loaded from on open when the bookmark file doesn't exist, in the given order, such as the mirrors of other nodes, so that a fresh node bootstraps from a remote copy. The URLs take the same forms as `mirrors`. The first store holding bookmarks provides them, and opening fails when none does and any of them is unreachable.").
+				Example([]string{"s3://bucket/bookmarks.json", "kafka://localhost:9092/bookmarks"}).
+				Default([]string{}).
+				Advanced(),
+			storeConfigField(),
+			service.NewBoolField(bfmFieldPartMetrics).
+				Description("Whether to record the offset of every bookmark as the `bookmark_offset` gauge labelled by topic and partition. Disable it when partitions are unbounded, such as the object keys bookmarked by the S3 input.").
+				Default(true).
+				Advanced(),
+			service.NewBoolField(bfmFieldReadOnly).
+				Description("Whether the bookmarks are only loaded and never written, for shadow deployments validating a pipeline against production. Changes are kept in memory, and saves log them and count them as `bookmark_read_only_skipped_writes_total` instead of writing the bookmark file. The owner lock, cache exports, mirrors and audit log are skipped as well.").
+				Default(false).
+				Advanced(),
+		).
+			Description("The file based bookmarks manager configuration.").
+			LintRule(`root = if this.read_only.or(false) && this.mirrors.or([]).length() > 0 { [ "mirrors are never written to while read_only is set" ] }`),
+	}
+}
+
+// BookmarkFileManagerFromParsed creates a bookmark manager from the config
+// fields defined by BookmarkFileManagerConfigFields, extraOpts are applied on
+// top of the parsed options. Labelled configs return the manager shared under
+// the label.
+func BookmarkFileManagerFromParsed(pConf *service.ParsedConfig, extraOpts ...ManagerOption) (*BookmarkManager, error) {
+	label, err := pConf.Namespace(bfmFieldSection).FieldString(bfmFieldLabel)
+	if err != nil {
+		return nil, err
+	}
+	if label != "" {
+		return sharedManagerFromParsed(label, pConf, extraOpts)
+	}
+	return newBookmarkFileManagerFromParsed(pConf, extraOpts...)
+}
+
+func newBookmarkFileManagerFromParsed(pConf *service.ParsedConfig, extraOpts ...ManagerOption) (*BookmarkManager, error) {
+	bConf := pConf.Namespace(bfmFieldSection)
+
+	filePath, err := bConf.FieldString(bfmFieldPath)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(filePath) == "" {
+		return nil, errors.New("bookmark path must not be empty")
+	}
+
+	var opts []ManagerOption
+
+	group, err := bConf.FieldString(bfmFieldGroup)
+	if err != nil {
+		return nil, err
+	}
+	if group != "" {
+		opts = append(opts, WithGroup(group))
+	}
+
+	metaSchema, err := bConf.FieldString(bfmFieldMetaSchema)
+	if err != nil {
+		return nil, err
+	}
+	if metaSchema != "" {
+		schema, err := NewMetadataSchema(metaSchema)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithMetadataSchema(schema))
+	}
+
+	rConf := bConf.Namespace(bfmFieldRotation)
+	maxSize, err := rConf.FieldInt(bfmFieldRotationSize)
+	if err != nil {
+		return nil, err
+	}
+	var maxAge time.Duration
+	if maxAgeStr, _ := rConf.FieldString(bfmFieldRotationMaxAge); maxAgeStr != "" {
+		if maxAge, err = time.ParseDuration(maxAgeStr); err != nil {
+			return nil, fmt.Errorf("failed to parse rotation max age: %w", err)
+		}
+	}
+	if maxSize > 0 || maxAge > 0 {
+		opts = append(opts, WithRotation(int64(maxSize), maxAge))
+	}
+
+	maxFileSize, err := bConf.FieldInt(bfmFieldMaxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	if maxFileSize > 0 {
+		opts = append(opts, WithMaxFileSize(int64(maxFileSize)))
+	}
+
+	backups, err := bConf.FieldInt(bfmFieldBackups, bfmFieldBackupCount)
+	if err != nil {
+		return nil, err
+	}
+	if backups < 0 {
+		return nil, errors.New("backup count must not be negative")
+	}
+	opts = append(opts, WithBackups(backups))
+
+	durable, err := bConf.FieldBool(bfmFieldDurable)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithDurableWrites(durable))
+
+	compression, err := bConf.FieldString(bfmFieldCompression)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithCompression(Compression(compression)))
+
+	format, err := bConf.FieldString(bfmFieldFormat)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithFileFormat(FileFormat(format)))
+
+	if autoSaveStr, _ := bConf.FieldString(bfmFieldAutoSave); autoSaveStr != "" {
+		interval, err := time.ParseDuration(autoSaveStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse auto-save interval: %w", err)
+		}
+		if interval <= 0 {
+			return nil, errors.New("auto-save interval must be positive")
+		}
+		opts = append(opts, WithAutoSave(interval))
+
+		maxUpdates, err := bConf.FieldInt(bfmFieldAutoSaveMax)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithAutoSaveMaxUpdates(maxUpdates))
+	}
+
+	wConf := bConf.Namespace(bfmFieldWAL)
+	walEnabled, err := wConf.FieldBool(bfmFieldWALEnabled)
+	if err != nil {
+		return nil, err
+	}
+	if walEnabled {
+		compactAfter, err := wConf.FieldInt(bfmFieldWALCompact)
+		if err != nil {
+			return nil, err
+		}
+		if compactAfter <= 0 {
+			return nil, errors.New("write-ahead log compact_after must be positive")
+		}
+		opts = append(opts, WithWAL(compactAfter))
+	}
+
+	pretty, err := bConf.FieldBool(bfmFieldPretty)
+	if err != nil {
+		return nil, err
+	}
+	indent, err := bConf.FieldInt(bfmFieldIndent)
+	if err != nil {
+		return nil, err
+	}
+	if indent < 0 {
+		return nil, errors.New("indent must be a non-negative integer")
+	}
+	opts = append(opts, WithFormatting(pretty, indent))
+
+	annotations, err := bConf.FieldStringMap(bfmFieldAnnotations)
+	if err != nil {
+		return nil, err
+	}
+	if len(annotations) > 0 {
+		opts = append(opts, WithAnnotations(annotations))
+	}
+
+	validateTopics, err := bConf.FieldBool(bfmFieldValidateTopics)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithTopicValidation(validateTopics))
+
+	maxTopicLength, err := bConf.FieldInt(bfmFieldMaxTopicLen)
+	if err != nil {
+		return nil, err
+	}
+	maxPartitionLength, err := bConf.FieldInt(bfmFieldMaxPartLen)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithFieldLimits(maxTopicLength, maxPartitionLength))
+
+	onRegression, err := bConf.FieldString(bfmFieldOnRegression)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithRegressionPolicy(RegressionPolicy(onRegression)))
+
+	tConf := bConf.Namespace(bfmFieldTimestampCheck)
+	var maxFuture, maxPast time.Duration
+	if maxFutureStr, _ := tConf.FieldString(bfmFieldMaxFuture); maxFutureStr != "" {
+		if maxFuture, err = time.ParseDuration(maxFutureStr); err != nil {
+			return nil, fmt.Errorf("failed to parse timestamp tolerance max future: %w", err)
+		}
+	}
+	if maxPastStr, _ := tConf.FieldString(bfmFieldMaxPast); maxPastStr != "" {
+		if maxPast, err = time.ParseDuration(maxPastStr); err != nil {
+			return nil, fmt.Errorf("failed to parse timestamp tolerance max past: %w", err)
+		}
+	}
+	rejectSkewed, err := tConf.FieldBool(bfmFieldReject)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithTimestampTolerance(maxFuture, maxPast, rejectSkewed))
+
+	lConf := bConf.Namespace(bfmFieldOwnerLock)
+	lockEnabled, err := lConf.FieldBool(bfmFieldOwnerLockOn)
+	if err != nil {
+		return nil, err
+	}
+	if lockEnabled {
+		staleAfter, err := lConf.FieldDuration(bfmFieldOwnerLockStale)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithOwnerLock(staleAfter))
+	}
+
+	flConf := bConf.Namespace(bfmFieldFileLock)
+	fileLockEnabled, err := flConf.FieldBool(bfmFieldFileLockOn)
+	if err != nil {
+		return nil, err
+	}
+	if fileLockEnabled {
+		lockTimeout, err := flConf.FieldDuration(bfmFieldFileLockWait)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithFileLock(lockTimeout))
+	}
+
+	iConf := bConf.Namespace(bfmFieldCacheImport)
+	importCache, err := iConf.FieldString(bfmFieldCacheResource)
+	if err != nil {
+		return nil, err
+	}
+	if importCache != "" {
+		keyPrefix, err := iConf.FieldString(bfmFieldCacheKeyPrefix)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints, err := iConf.FieldStringList(bfmFieldCacheCheckpts)
+		if err != nil {
+			return nil, err
+		}
+		for _, checkpoint := range checkpoints {
+			if _, _, err := parseCheckpoint(checkpoint); err != nil {
+				return nil, err
+			}
+		}
+		opts = append(opts, WithCacheImport(importCache, keyPrefix, checkpoints))
+	}
+
+	eConf := bConf.Namespace(bfmFieldCacheExport)
+	exportCache, err := eConf.FieldString(bfmFieldCacheResource)
+	if err != nil {
+		return nil, err
 	}
+	if exportCache != "" {
+		keyPrefix, err := eConf.FieldString(bfmFieldCacheKeyPrefix)
+		if err != nil {
+			return nil, err
+		}
+		format, err := eConf.FieldString(bfmFieldCacheFormat)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithCacheExport(exportCache, keyPrefix, format))
+	}
+
+	evConf := bConf.Namespace(bfmFieldEvents)
+	natsConf := evConf.Namespace(bfmFieldEventsNATS)
+	natsURLs, err := natsConf.FieldStringList(bfmFieldNATSURLs)
+	if err != nil {
+		return nil, err
+	}
+	if len(natsURLs) > 0 {
+		subjectPrefix, err := natsConf.FieldString(bfmFieldNATSPrefix)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithEventSink("nats", NewNATSEventSink(natsURLs, subjectPrefix)))
+	}
+
+	mqttConf := evConf.Namespace(bfmFieldEventsMQTT)
+	mqttURLs, err := mqttConf.FieldStringList(bfmFieldMQTTURLs)
+	if err != nil {
+		return nil, err
+	}
+	if len(mqttURLs) > 0 {
+		conf := MQTTEventSinkConfig{URLs: mqttURLs}
+		if conf.TopicPrefix, err = mqttConf.FieldString(bfmFieldMQTTPrefix); err != nil {
+			return nil, err
+		}
+		if conf.ClientID, err = mqttConf.FieldString(bfmFieldMQTTClientID); err != nil {
+			return nil, err
+		}
+		if conf.User, err = mqttConf.FieldString(bfmFieldMQTTUser); err != nil {
+			return nil, err
+		}
+		if conf.Password, err = mqttConf.FieldString(bfmFieldMQTTPassword); err != nil {
+			return nil, err
+		}
+		qos, err := mqttConf.FieldInt(bfmFieldMQTTQoS)
+		if err != nil {
+			return nil, err
+		}
+		if qos < 0 || qos > 2 {
+			return nil, fmt.Errorf("mqtt qos must be 0, 1 or 2, got %d", qos)
+		}
+		conf.QoS = byte(qos)
+		if conf.Retained, err = mqttConf.FieldBool(bfmFieldMQTTRetained); err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithEventSink("mqtt", NewMQTTEventSink(conf)))
+	}
+
+	aConf := bConf.Namespace(bfmFieldAlerts)
+	alertsURL, err := aConf.FieldString(bfmFieldAlertsURL)
+	if err != nil {
+		return nil, err
+	}
+	if alertsURL != "" {
+		conf := WebhookAlertConfig{URL: alertsURL}
+		if conf.Headers, err = aConf.FieldStringMap(bfmFieldAlertsHeaders); err != nil {
+			return nil, err
+		}
+		if staleStr, _ := aConf.FieldString(bfmFieldAlertsStale); staleStr != "" {
+			if conf.StaleAfter, err = time.ParseDuration(staleStr); err != nil {
+				return nil, fmt.Errorf("failed to parse alerts stale after: %w", err)
+			}
+		}
+		if conf.CheckInterval, err = aConf.FieldDuration(bfmFieldAlertsInterval); err != nil {
+			return nil, err
+		}
+		if conf.StaleAfter > 0 && conf.CheckInterval <= 0 {
+			return nil, errors.New("alerts check interval must be positive")
+		}
+		if conf.OnRegression, err = aConf.FieldBool(bfmFieldAlertsRegress); err != nil {
+			return nil, err
+		}
+		if conf.OnSaveFailure, err = aConf.FieldBool(bfmFieldAlertsSaveFail); err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithWebhookAlerts(conf))
+	}
+
+	adminConf := bConf.Namespace(bfmFieldAdminAPI)
+	adminAddress, err := adminConf.FieldString(bfmFieldAdminAddress)
+	if err != nil {
+		return nil, err
+	}
+	if adminAddress != "" {
+		writeLimit, err := adminConf.FieldFloat(bfmFieldAdminWriteRate)
+		if err != nil {
+			return nil, err
+		}
+		writeBurst, err := adminConf.FieldInt(bfmFieldAdminBurst)
+		if err != nil {
+			return nil, err
+		}
+		if writeLimit < 0 || writeBurst <= 0 {
+			return nil, errors.New("admin API write limit must not be negative and write burst must be positive")
+		}
+		opts = append(opts, WithAdminAPI(adminAddress), WithAdminWriteLimit(rate.Limit(writeLimit), writeBurst))
+
+		tlsConf := adminConf.Namespace(bfmFieldAdminTLS)
+		var conf AdminTLSConfig
+		if conf.CertFile, err = tlsConf.FieldString(bfmFieldAdminCert); err != nil {
+			return nil, err
+		}
+		if conf.KeyFile, err = tlsConf.FieldString(bfmFieldAdminKey); err != nil {
+			return nil, err
+		}
+		if conf.ClientCAFile, err = tlsConf.FieldString(bfmFieldAdminClientCA); err != nil {
+			return nil, err
+		}
+		if conf.CertFile != "" || conf.KeyFile != "" || conf.ClientCAFile != "" {
+			opts = append(opts, WithAdminTLS(conf))
+		}
+
+		origins, err := adminConf.FieldStringList(bfmFieldAdminCORS)
+		if err != nil {
+			return nil, err
+		}
+		if len(origins) > 0 {
+			opts = append(opts, WithAdminCORS(origins...))
+		}
+	}
+
+	grpcAddress, err := bConf.Namespace(bfmFieldGRPCAPI).FieldString(bfmFieldGRPCAddress)
+	if err != nil {
+		return nil, err
+	}
+	if grpcAddress != "" {
+		opts = append(opts, WithGRPCAPI(grpcAddress))
+	}
+
+	expiryConf := bConf.Namespace(bfmFieldExpiry)
+	if expireStr, _ := expiryConf.FieldString(bfmFieldExpireAfter); expireStr != "" {
+		expireAfter, err := time.ParseDuration(expireStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse expire after: %w", err)
+		}
+		interval, err := expiryConf.FieldDuration(bfmFieldExpiryInterval)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithExpiry(expireAfter, interval))
+	}
+
+	snapConf := bConf.Namespace(bfmFieldSnapSchedule)
+	snapCron, err := snapConf.FieldString(bfmFieldSnapCron)
+	if err != nil {
+		return nil, err
+	}
+	if snapCron != "" {
+		if _, err := cron.ParseStandard(snapCron); err != nil {
+			return nil, fmt.Errorf("failed to parse snapshot schedule: %w", err)
+		}
+		conf := SnapshotScheduleConfig{Cron: snapCron}
+		if conf.Keep, err = snapConf.FieldInt(bfmFieldSnapKeep); err != nil {
+			return nil, err
+		}
+		if conf.UploadURL, err = snapConf.FieldString(bfmFieldSnapUploadURL); err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithSnapshotSchedule(conf))
+	}
+
+	auditConf := bConf.Namespace(bfmFieldAudit)
+	auditEnabled, err := auditConf.FieldBool(bfmFieldAuditEnabled)
+	if err != nil {
+		return nil, err
+	}
+	if auditEnabled {
+		retention, err := auditConf.FieldDuration(bfmFieldAuditRetention)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithAuditLog(retention))
+	}
+
+	lagConf := bConf.Namespace(bfmFieldLag)
+	lagBrokers, err := lagConf.FieldStringList(bfmFieldLagBrokers)
+	if err != nil {
+		return nil, err
+	}
+	if len(lagBrokers) > 0 {
+		conf := LagReporterConfig{SeedBrokers: lagBrokers}
+		if conf.Topics, err = lagConf.FieldStringList(bfmFieldLagTopics); err != nil {
+			return nil, err
+		}
+		if conf.Interval, err = lagConf.FieldDuration(bfmFieldLagInterval); err != nil {
+			return nil, err
+		}
+		if conf.Interval <= 0 {
+			return nil, errors.New("lag interval must be positive")
+		}
+		threshold, err := lagConf.FieldInt(bfmFieldLagThreshold)
+		if err != nil {
+			return nil, err
+		}
+		conf.AlertThreshold = int64(threshold)
+		opts = append(opts, WithLagReporter(conf))
+	}
+
+	mirrors, err := bConf.FieldStringList(bfmFieldMirrors)
+	if err != nil {
+		return nil, err
+	}
+	for _, url := range mirrors {
+		opts = append(opts, withMirrorURL(url))
+	}
+
+	fallbacks, err := bConf.FieldStringList(bfmFieldFallbacks)
+	if err != nil {
+		return nil, err
+	}
+	for _, url := range fallbacks {
+		opts = append(opts, withFallbackURL(url))
+	}
+
+	storeOpt, err := remoteStoreFromParsed(bConf)
+	if err != nil {
+		return nil, err
+	}
+	if storeOpt != nil {
+		opts = append(opts, storeOpt)
+	}
+
+	partitionMetrics, err := bConf.FieldBool(bfmFieldPartMetrics)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithPartitionMetrics(partitionMetrics))
+
+	readOnly, err := bConf.FieldBool(bfmFieldReadOnly)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithReadOnly(readOnly))
+
+	return NewBookmarkManager(filePath, append(opts, extraOpts...)...), nil
 }
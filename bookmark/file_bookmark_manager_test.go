@@ -0,0 +1,151 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertBookmarks asserts that two lists of bookmarks hold the same keys,
+// offsets, timestamps and metadata in the same order
+func assertBookmarks(t *testing.T, expected, actual []*Bookmark) {
+	t.Helper()
+
+	require.Len(t, actual, len(expected))
+	for i, e := range expected {
+		a := actual[i]
+		assert.Equal(t, e.TopicPartition(), a.TopicPartition())
+		assert.Equal(t, e.Offset, a.Offset, "offset of %s", e.TopicPartition())
+		assert.True(t, e.Timestamp.Equal(a.Timestamp), "timestamp of %s: %v != %v", e.TopicPartition(), e.Timestamp, a.Timestamp)
+		assert.Equal(t, len(e.Metadata), len(a.Metadata), "metadata of %s", e.TopicPartition())
+		for k, v := range e.Metadata {
+			assert.EqualValues(t, v, a.Metadata[k], "metadata %s of %s", k, e.TopicPartition())
+		}
+	}
+}
+
+func testBookmarks(t *testing.T, bm *BookmarkManager) {
+	t.Helper()
+
+	ts := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+	for _, b := range []*Bookmark{
+		{Topic: "orders", Partition: "0", Offset: 42, Timestamp: ts, Metadata: map[string]any{"source": "test"}},
+		{Topic: "orders", Partition: "1", Offset: OffsetEarliest, Timestamp: ts},
+		{Topic: "bucket", Partition: "path/to/object.json", Offset: 1, Timestamp: ts},
+		{Group: "audit", Topic: "orders", Partition: "0", Offset: 7, Timestamp: ts},
+	} {
+		require.NoError(t, bm.AddBookmark(b))
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	tests := []struct {
+		format      FileFormat
+		compression Compression
+	}{
+		{FileFormatJSON, CompressionNone},
+		{FileFormatJSON, CompressionGzip},
+		{FileFormatJSON, CompressionZstd},
+		{FileFormatMsgpack, CompressionNone},
+		{FileFormatMsgpack, CompressionGzip},
+		{FileFormatMsgpack, CompressionZstd},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.format)+"/"+string(test.compression), func(t *testing.T) {
+			ctx := context.Background()
+			path := filepath.Join(t.TempDir(), "bookmarks.json")
+			opts := []ManagerOption{WithFileFormat(test.format), WithCompression(test.compression)}
+
+			bm := NewBookmarkManager(path, opts...)
+			testBookmarks(t, bm)
+			require.NoError(t, bm.SaveToFile(ctx))
+
+			loaded := NewBookmarkManager(path, opts...)
+			require.NoError(t, loaded.LoadFromFile(ctx))
+			assertBookmarks(t, bm.GetAllBookmarks(), loaded.GetAllBookmarks())
+		})
+	}
+}
+
+func TestWALReplayAndCompact(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+
+	bm := NewBookmarkManager(path, WithWAL(100))
+	testBookmarks(t, bm)
+	require.NoError(t, bm.SaveToFile(ctx))
+	require.NoError(t, bm.UpdateOffset("orders", "0", 43))
+	require.NoError(t, bm.RemoveBookmark("bucket", "path/to/object.json"))
+	require.NoError(t, bm.SaveToFile(ctx))
+
+	info, err := os.Stat(path + ".wal")
+	require.NoError(t, err)
+	assert.Positive(t, info.Size())
+
+	replayed := NewBookmarkManager(path, WithWAL(100))
+	require.NoError(t, replayed.LoadFromFile(ctx))
+	assertBookmarks(t, bm.GetAllBookmarks(), replayed.GetAllBookmarks())
+	_, err = replayed.GetBookmark("bucket", "path/to/object.json")
+	assert.ErrorIs(t, err, ErrBookmarkNotFound)
+
+	require.NoError(t, replayed.Compact(ctx))
+	if info, err := os.Stat(path + ".wal"); err == nil {
+		assert.Zero(t, info.Size())
+	}
+
+	compacted := NewBookmarkManager(path)
+	require.NoError(t, compacted.LoadFromFile(ctx))
+	assertBookmarks(t, bm.GetAllBookmarks(), compacted.GetAllBookmarks())
+}
+
+func TestGroupsShareAPartition(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+
+	bm := NewBookmarkManager(path, WithGroup("billing"))
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 10}))
+	require.NoError(t, bm.AddBookmark(&Bookmark{Group: "audit", Topic: "orders", Partition: "0", Offset: 20}))
+	require.NoError(t, bm.UpdateOffset("orders", "0", 11))
+	require.NoError(t, bm.SaveToFile(ctx))
+
+	loaded := NewBookmarkManager(path, WithGroup("audit"))
+	require.NoError(t, loaded.LoadFromFile(ctx))
+
+	tests := []struct {
+		group  string
+		offset int64
+	}{
+		{"billing", 11},
+		{"audit", 20},
+	}
+	for _, test := range tests {
+		b, err := loaded.GetGroupBookmark(test.group, "orders", "0")
+		require.NoError(t, err)
+		assert.Equal(t, test.offset, b.Offset, "offset of group %s", test.group)
+	}
+
+	b, err := loaded.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), b.Offset)
+	assert.Equal(t, []string{"audit", "billing"}, loaded.GetGroups())
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrBookmarkFileBusy is returned by saves and loads when another process
+// holds the file lock for longer than the lock timeout
+var ErrBookmarkFileBusy = errors.New("bookmark file is locked by another process")
+
+// fileLockRetryInterval is the interval at which a held file lock is retried
+const fileLockRetryInterval = 50 * time.Millisecond
+
+// WithFileLock takes an advisory lock on <path>.flock around every save and
+// load, exclusive for saves and shared for loads, so that processes using the
+// same bookmark file don't interleave their reads and writes. A lock held by
+// another process is retried for up to timeout. Unlike WithOwnerLock the lock
+// is only held for the duration of a file operation, and it's released by the
+// operating system when a process dies.
+func WithFileLock(timeout time.Duration) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.fileLockEnabled = true
+		bm.fileLockTimeout = timeout
+	}
+}
+
+// fileLockPath returns the path of the file the advisory lock is taken on. The
+// bookmark file itself can't be locked as saves replace it.
+func (bm *BookmarkManager) fileLockPath() string {
+	return bm.filePath + ".flock"
+}
+
+// lockFile takes the file lock when enabled and returns the function
+// releasing it. The caller must hold saveMutex.
+func (bm *BookmarkManager) lockFile(ctx context.Context, exclusive bool) (func(), error) {
+	if !bm.fileLockEnabled {
+		return func() {}, nil
+	}
+
+	path := bm.fileLockPath()
+	if exclusive {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if errors.Is(err, fs.ErrNotExist) && !exclusive {
+		// Nothing to read while the directory doesn't exist
+		return func() {}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(bm.fileLockTimeout)
+	for {
+		locked, err := tryLockFile(f, exclusive)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%w: %s is still held after %s", ErrBookmarkFileBusy, path, bm.fileLockTimeout)
+		}
+
+		select {
+		case <-time.After(fileLockRetryInterval):
+		case <-ctx.Done():
+			f.Close()
+			return nil, ctx.Err()
+		}
+	}
+
+	return func() {
+		if err := unlockFile(f); err != nil {
+			bm.log.Warnf("Failed to unlock %s: %v", path, err)
+		}
+		f.Close()
+	}, nil
+}
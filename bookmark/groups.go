@@ -0,0 +1,99 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"sort"
+)
+
+// WithGroup sets the consumer group of the manager, so that consumers reading
+// the same topics keep independent bookmarks in one bookmark file. Methods
+// that address bookmarks by topic and partition operate on the bookmarks of
+// the group, and added bookmarks without a group are assigned to it. The
+// bookmarks of other groups are kept as they are loaded and saved. The
+// default group is empty.
+func WithGroup(group string) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.group = group
+	}
+}
+
+// GetGroup returns the consumer group of the manager
+func (bm *BookmarkManager) GetGroup() string {
+	return bm.group
+}
+
+// assignGroup assigns a bookmark without a group to the group of the manager
+func (bm *BookmarkManager) assignGroup(b *Bookmark) {
+	if b.Group == "" {
+		b.Group = bm.group
+	}
+}
+
+// GetGroupBookmark retrieves the bookmark of a topic and partition in a group
+func (bm *BookmarkManager) GetGroupBookmark(group, topic, partition string) (*Bookmark, error) {
+	bookmark, exists := bm.bookmarks.get(NewGroupTopicPartition(group, topic, partition))
+	if !exists {
+		return nil, notFoundError(group, topic, partition)
+	}
+	return bookmark, nil
+}
+
+// RemoveGroupBookmark removes the bookmark of a topic and partition in a group
+func (bm *BookmarkManager) RemoveGroupBookmark(group, topic, partition string) error {
+	defer bm.dispatchEvents()
+	bm.mutex.Lock()
+	defer bm.mutex.Unlock()
+
+	key := NewGroupTopicPartition(group, topic, partition)
+	bookmark, exists := bm.bookmarks.get(key)
+	if !exists {
+		return notFoundError(group, topic, partition)
+	}
+
+	bm.emit(removeEvent(bookmark))
+	bm.bookmarks.remove(key)
+	bm.dirty.markBookmark(key)
+	return nil
+}
+
+// GetBookmarksByGroup returns all bookmarks of a group sorted by topic and
+// partition
+func (bm *BookmarkManager) GetBookmarksByGroup(group string) []*Bookmark {
+	var bookmarks []*Bookmark
+	for _, bookmark := range bm.bookmarks.all() {
+		if bookmark.Group == group {
+			bookmarks = append(bookmarks, bookmark)
+		}
+	}
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return lessBookmark(bookmarks[i], bookmarks[j])
+	})
+	return bookmarks
+}
+
+// GetGroups returns the groups with bookmarks, sorted
+func (bm *BookmarkManager) GetGroups() []string {
+	seen := make(map[string]struct{})
+	for _, bookmark := range bm.bookmarks.all() {
+		seen[bookmark.Group] = struct{}{}
+	}
+	groups := make([]string, 0, len(seen))
+	for group := range seen {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups
+}
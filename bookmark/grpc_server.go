@@ -0,0 +1,251 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"rpanda-connect-native-plugin-example/bookmark/bookmarkpb"
+)
+
+// WithGRPCAPI serves the BookmarkService gRPC API defined in
+// bookmarkpb/bookmark.proto on the given address while the manager is open.
+// Like the admin API it has no authentication.
+func WithGRPCAPI(address string) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.grpcAPI = &grpcServer{address: address, bm: bm}
+	}
+}
+
+// NewGRPCService returns an implementation of the BookmarkService backed by
+// bm, for registering on a gRPC server of the application. Changes are saved
+// like those of components and are subject to the regression policy.
+func NewGRPCService(bm *BookmarkManager) bookmarkpb.BookmarkServiceServer {
+	return &grpcService{bm: bm}
+}
+
+type grpcService struct {
+	bookmarkpb.UnimplementedBookmarkServiceServer
+	bm *BookmarkManager
+
+	// stopping ends the watch streams when closed, nil for services
+	// registered by the application
+	stopping <-chan struct{}
+}
+
+func (s *grpcService) GetBookmark(_ context.Context, req *bookmarkpb.GetBookmarkRequest) (*bookmarkpb.Bookmark, error) {
+	bookmark, err := s.bm.GetBookmark(req.GetTopic(), req.GetPartition())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return bookmarkToProto(bookmark)
+}
+
+func (s *grpcService) SetBookmark(ctx context.Context, req *bookmarkpb.SetBookmarkRequest) (*bookmarkpb.Bookmark, error) {
+	timestamp := time.Now()
+	if req.GetTimestamp() != nil {
+		timestamp = req.GetTimestamp().AsTime()
+	}
+	var metadata map[string]interface{}
+	if req.GetMetadata() != nil {
+		metadata = req.GetMetadata().AsMap()
+	} else if existing, err := s.bm.GetBookmark(req.GetTopic(), req.GetPartition()); err == nil {
+		metadata = existing.Metadata
+	}
+
+	bookmark, err := NewBookmarkWithTimestamp(req.GetTopic(), req.GetPartition(), req.GetOffset(), timestamp, metadata)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.bm.AddBookmark(bookmark); err != nil {
+		return nil, grpcError(err)
+	}
+	if err := s.bm.RequestSave(ctx); err != nil {
+		return nil, status.Errorf(codes.Internal, "bookmark was set but failed to save: %v", err)
+	}
+	s.bm.log.Infof("gRPC API set bookmark %s/%s to offset %s", bookmark.Topic, bookmark.Partition, FormatOffset(bookmark.Offset))
+	return bookmarkToProto(bookmark)
+}
+
+func (s *grpcService) ListBookmarks(_ context.Context, req *bookmarkpb.ListBookmarksRequest) (*bookmarkpb.ListBookmarksResponse, error) {
+	var bookmarks []*Bookmark
+	if req.GetTopic() != "" {
+		bookmarks = s.bm.GetBookmarksByTopic(req.GetTopic())
+	} else {
+		bookmarks = s.bm.GetAllBookmarks()
+	}
+
+	resp := &bookmarkpb.ListBookmarksResponse{Bookmarks: make([]*bookmarkpb.Bookmark, 0, len(bookmarks))}
+	for _, b := range bookmarks {
+		pb, err := bookmarkToProto(b)
+		if err != nil {
+			return nil, err
+		}
+		resp.Bookmarks = append(resp.Bookmarks, pb)
+	}
+	return resp, nil
+}
+
+func (s *grpcService) Watch(req *bookmarkpb.WatchRequest, stream grpc.ServerStreamingServer[bookmarkpb.BookmarkEvent]) error {
+	filter := WatchFilter{Topics: req.GetTopicPatterns()}
+	if req.GetTopic() != "" {
+		filter.Topics = append(filter.Topics, req.GetTopic())
+	}
+	for _, kind := range req.GetKinds() {
+		filter.Kinds = append(filter.Kinds, eventKindsFromProto[kind])
+	}
+	if err := filter.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	w, cancel := s.bm.watch(eventQueueSize, filter)
+	defer cancel()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.stopping:
+			return status.Error(codes.Unavailable, "bookmark manager is closing")
+		case ev, open := <-w.events:
+			if !open {
+				return status.Error(codes.ResourceExhausted, "watch fell behind the bookmark changes")
+			}
+			if err := stream.Send(eventToProto(ev)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// grpcError converts err to a status with the code matching its cause
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrBookmarkNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrOffsetRegression), errors.Is(err, ErrFencedLeaderEpoch):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+func bookmarkToProto(b *Bookmark) (*bookmarkpb.Bookmark, error) {
+	metadata, err := structpb.NewStruct(b.Metadata)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to convert metadata of %s/%s: %v", b.Topic, b.Partition, err)
+	}
+	return &bookmarkpb.Bookmark{
+		Topic:     b.Topic,
+		Partition: b.Partition,
+		Offset:    b.Offset,
+		Timestamp: timestamppb.New(b.Timestamp),
+		Metadata:  metadata,
+	}, nil
+}
+
+var eventKindsToProto = map[EventKind]bookmarkpb.EventKind{
+	EventCreated:  bookmarkpb.EventKind_EVENT_KIND_CREATED,
+	EventAdvanced: bookmarkpb.EventKind_EVENT_KIND_ADVANCED,
+	EventRewound:  bookmarkpb.EventKind_EVENT_KIND_REWOUND,
+	EventRemoved:  bookmarkpb.EventKind_EVENT_KIND_REMOVED,
+	EventExpired:  bookmarkpb.EventKind_EVENT_KIND_EXPIRED,
+}
+
+var eventKindsFromProto = map[bookmarkpb.EventKind]EventKind{
+	bookmarkpb.EventKind_EVENT_KIND_CREATED:  EventCreated,
+	bookmarkpb.EventKind_EVENT_KIND_ADVANCED: EventAdvanced,
+	bookmarkpb.EventKind_EVENT_KIND_REWOUND:  EventRewound,
+	bookmarkpb.EventKind_EVENT_KIND_REMOVED:  EventRemoved,
+	bookmarkpb.EventKind_EVENT_KIND_EXPIRED:  EventExpired,
+}
+
+func eventToProto(ev Event) *bookmarkpb.BookmarkEvent {
+	return &bookmarkpb.BookmarkEvent{
+		Kind:           eventKindsToProto[ev.Kind],
+		Topic:          ev.Topic,
+		Partition:      ev.Partition,
+		Offset:         ev.Offset,
+		PreviousOffset: ev.PreviousOffset,
+		Timestamp:      timestamppb.New(ev.Timestamp),
+	}
+}
+
+type grpcServer struct {
+	address string
+	bm      *BookmarkManager
+
+	mut      sync.Mutex
+	server   *grpc.Server
+	stopping chan struct{}
+}
+
+func (g *grpcServer) start() error {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	if g.server != nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", g.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on gRPC API address %s: %w", g.address, err)
+	}
+
+	g.server = grpc.NewServer()
+	g.stopping = make(chan struct{})
+	bookmarkpb.RegisterBookmarkServiceServer(g.server, &grpcService{bm: g.bm, stopping: g.stopping})
+	go func(server *grpc.Server) {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			g.bm.log.Errorf("gRPC API stopped: %v", err)
+		}
+	}(g.server)
+	g.bm.log.Infof("Serving the bookmark gRPC API on %s", listener.Addr())
+	return nil
+}
+
+// close ends the watch streams and stops the server gracefully, cancelling the
+// requests still in flight once ctx is done
+func (g *grpcServer) close(ctx context.Context) {
+	g.mut.Lock()
+	server, stopping := g.server, g.stopping
+	g.server, g.stopping = nil, nil
+	g.mut.Unlock()
+	if server == nil {
+		return
+	}
+	close(stopping)
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"fmt"
+	"maps"
+)
+
+// BeforeSaveHook is called with the bookmark file about to be written and may
+// modify it, such as to drop bookmarks or add annotations. Returning an error
+// fails the save and leaves the bookmark file untouched.
+type BeforeSaveHook func(ctx context.Context, file *BookmarkFile) error
+
+// AfterLoadHook is called with the bookmarks after a successful load.
+// Returning an error fails the load.
+type AfterLoadHook func(ctx context.Context, bookmarks []*Bookmark) error
+
+// UpdateHook is called with the event of every bookmark change
+type UpdateHook func(event Event)
+
+// WithBeforeSaveHook adds a hook called before every save that writes the
+// bookmark file, in the order hooks were added. The hook receives copies of
+// the bookmarks, so changes only affect the written file and not the state of
+// the manager. Saves that only append to the write-ahead log don't call it.
+func WithBeforeSaveHook(hook BeforeSaveHook) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.beforeSaveHooks = append(bm.beforeSaveHooks, hook)
+	}
+}
+
+// WithAfterLoadHook adds a hook called after every load, in the order hooks
+// were added. The hook receives copies of the loaded bookmarks. The loaded
+// bookmarks are kept when a hook fails, and the first error is returned by
+// the load.
+func WithAfterLoadHook(hook AfterLoadHook) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.afterLoadHooks = append(bm.afterLoadHooks, hook)
+	}
+}
+
+// WithUpdateHook adds a hook called for every bookmark change, which unlike
+// event sinks and watchers never misses an event. Hooks are called in the
+// order of the changes after the manager is unlocked, so they may call its
+// methods. A hook usually runs before the call making the change returns, but
+// may run on another goroutine that is delivering events at the same time, so
+// it should return quickly.
+func WithUpdateHook(hook UpdateHook) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.updateHooks = append(bm.updateHooks, hook)
+	}
+}
+
+// runBeforeSaveHooks passes the file to all before save hooks. The caller must
+// hold the mutex.
+func (bm *BookmarkManager) runBeforeSaveHooks(ctx context.Context, file *BookmarkFile) error {
+	if len(bm.beforeSaveHooks) == 0 {
+		return nil
+	}
+	file.Bookmarks = cloneBookmarks(file.Bookmarks)
+	file.Annotations = maps.Clone(file.Annotations)
+	for _, hook := range bm.beforeSaveHooks {
+		if err := hook(ctx, file); err != nil {
+			return fmt.Errorf("before save hook failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// runAfterLoadHooks passes the loaded bookmarks to all after load hooks
+func (bm *BookmarkManager) runAfterLoadHooks(ctx context.Context) error {
+	if len(bm.afterLoadHooks) == 0 {
+		return nil
+	}
+	bookmarks := cloneBookmarks(bm.GetAllBookmarks())
+	for _, hook := range bm.afterLoadHooks {
+		if err := hook(ctx, bookmarks); err != nil {
+			return fmt.Errorf("after load hook failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// cloneBookmarks returns copies of the bookmarks and their metadata
+func cloneBookmarks(bookmarks []*Bookmark) []*Bookmark {
+	clones := make([]*Bookmark, len(bookmarks))
+	for i, b := range bookmarks {
+		clone := *b
+		clone.Metadata = maps.Clone(b.Metadata)
+		clones[i] = &clone
+	}
+	return clones
+}
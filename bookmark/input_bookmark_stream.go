@@ -0,0 +1,234 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	bsiFieldPath         = "path"
+	bsiFieldPollInterval = "poll_interval"
+	bsiFieldEmitCurrent  = "emit_current"
+
+	// MetaEvent is the metadata key of the event kind set by the
+	// bookmark_stream input
+	MetaEvent = "bookmark_event"
+)
+
+// EventSnapshot is the kind of the events emitted by the bookmark_stream input
+// for the bookmarks present when it starts
+const EventSnapshot EventKind = "snapshot"
+
+func bookmarkStreamInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Summary("Streams the bookmarks of a bookmark file, followed by every subsequent change, as messages.").
+		Description(`
+The bookmark file is polled for changes, and each change is emitted as a JSON event with the fields `+"`kind`, `topic`, `partition`, `offset`, `previous_offset` and `timestamp`"+`. The kinds are `+"`snapshot`"+` for the bookmarks present on start, followed by `+"`created`, `advanced`, `rewound` and `removed`"+`. This allows checkpoint state to be shipped into a warehouse or monitoring topic with regular outputs.
+
+The file is only read, so the input can run alongside the component writing the bookmarks, including in a different process.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- bookmark_event
+- bookmark_topic
+- bookmark_partition
+- bookmark_offset
+- bookmark_timestamp
+`+"```"+``).
+		Fields(
+			service.NewStringField(bsiFieldPath).
+				Description("The path of the bookmark file to stream."),
+			service.NewDurationField(bsiFieldPollInterval).
+				Description("How often the bookmark file is checked for changes.").
+				Default("1s"),
+			service.NewBoolField(bsiFieldEmitCurrent).
+				Description("Whether to emit a snapshot of the current bookmarks on start, otherwise only changes are emitted.").
+				Default(true),
+		)
+}
+
+func init() {
+	service.MustRegisterInput("bookmark_stream", bookmarkStreamInputSpec(),
+		func(pConf *service.ParsedConfig, res *service.Resources) (service.Input, error) {
+			return newBookmarkStreamInputFromParsed(pConf, res)
+		})
+}
+
+//------------------------------------------------------------------------------
+
+type bookmarkStreamInput struct {
+	path         string
+	pollInterval time.Duration
+	emitCurrent  bool
+	log          *service.Logger
+
+	polled    bool
+	lastMod   time.Time
+	lastSize  int64
+	bookmarks map[TopicPartition]*Bookmark
+	pending   []Event
+}
+
+func newBookmarkStreamInputFromParsed(pConf *service.ParsedConfig, res *service.Resources) (*bookmarkStreamInput, error) {
+	i := &bookmarkStreamInput{
+		log:       res.Logger(),
+		bookmarks: make(map[TopicPartition]*Bookmark),
+	}
+
+	var err error
+	if i.path, err = pConf.FieldString(bsiFieldPath); err != nil {
+		return nil, err
+	}
+	if i.pollInterval, err = pConf.FieldDuration(bsiFieldPollInterval); err != nil {
+		return nil, err
+	}
+	if i.pollInterval <= 0 {
+		return nil, errors.New("poll interval must be positive")
+	}
+	if i.emitCurrent, err = pConf.FieldBool(bsiFieldEmitCurrent); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (i *bookmarkStreamInput) Connect(ctx context.Context) error {
+	return nil
+}
+
+// poll reads the bookmark file when it changed and queues an event for each
+// bookmark that differs from the previous read
+func (i *bookmarkStreamInput) poll() error {
+	info, err := os.Stat(i.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Not written yet, which is an empty set of bookmarks
+		i.diff(nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if i.polled && info.ModTime().Equal(i.lastMod) && info.Size() == i.lastSize {
+		return nil
+	}
+
+	data, err := os.ReadFile(i.path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	bookmarkFile, err := decodeBookmarkFile(data, &RecoveryReport{})
+	if err != nil {
+		// The file is replaced atomically, so this is a corrupted file rather
+		// than a partial write
+		return fmt.Errorf("failed to unmarshal bookmarks: %w", err)
+	}
+
+	i.lastMod, i.lastSize = info.ModTime(), info.Size()
+	i.diff(bookmarkFile.Bookmarks)
+	return nil
+}
+
+func (i *bookmarkStreamInput) diff(bookmarks []*Bookmark) {
+	first := !i.polled
+	i.polled = true
+
+	current := make(map[TopicPartition]*Bookmark, len(bookmarks))
+	for _, b := range bookmarks {
+		if b == nil || b.validate() != nil {
+			continue
+		}
+		key := b.TopicPartition()
+		current[key] = b
+
+		if first {
+			if i.emitCurrent {
+				ev, _ := changeEvent(nil, b)
+				ev.Kind = EventSnapshot
+				i.pending = append(i.pending, ev)
+			}
+			continue
+		}
+		if ev, changed := changeEvent(i.bookmarks[key], b); changed {
+			i.pending = append(i.pending, ev)
+		}
+	}
+
+	var removed []*Bookmark
+	for key, b := range i.bookmarks {
+		if _, exists := current[key]; !exists {
+			removed = append(removed, b)
+		}
+	}
+	sort.Slice(removed, func(x, y int) bool {
+		return lessBookmark(removed[x], removed[y])
+	})
+	for _, b := range removed {
+		i.pending = append(i.pending, removeEvent(b))
+	}
+	i.bookmarks = current
+}
+
+func (i *bookmarkStreamInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	for len(i.pending) == 0 {
+		if i.polled {
+			select {
+			case <-time.After(i.pollInterval):
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			}
+		}
+		if err := i.poll(); err != nil {
+			i.log.Errorf("Failed to poll bookmark file %s: %v", i.path, err)
+			i.polled = true
+		}
+	}
+
+	ev := i.pending[0]
+	i.pending = i.pending[1:]
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	msg := service.NewMessage(data)
+	msg.MetaSetMut(MetaEvent, string(ev.Kind))
+	msg.MetaSetMut(MetaTopic, ev.Topic)
+	msg.MetaSetMut(MetaPartition, ev.Partition)
+	msg.MetaSetMut(MetaOffset, ev.Offset)
+	msg.MetaSetMut(MetaTimestamp, ev.Timestamp.Format(time.RFC3339))
+
+	return msg, func(ctx context.Context, err error) error {
+		return nil
+	}, nil
+}
+
+func (i *bookmarkStreamInput) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// checksumPrefix names the algorithm of the checksum of a bookmark file
+const checksumPrefix = "sha256:"
+
+// ErrChecksumMismatch is returned when the content of a bookmark file doesn't
+// match its checksum, which is the case for files edited by hand
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// WithIgnoreChecksum loads bookmark files that fail their checksum, such as
+// files edited by hand, with a warning instead of failing the load. The next
+// save signs the file again. It's meant for accepting a deliberate edit once,
+// as bookmarkctl resign does, rather than for running pipelines.
+func WithIgnoreChecksum() ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.ignoreChecksum = true
+	}
+}
+
+// fileChecksum returns the checksum of the bookmarks, sink positions and
+// pending commits of a file, which is a SHA-256 hash of their compact JSON
+// encoding. Sink positions and pending commits are only part of the hash when
+// present, as they're omitted from the file otherwise.
+func fileChecksum(bookmarks []*Bookmark, sinks []*SinkPosition, pending []*PendingCommit) (string, error) {
+	h := sha256.New()
+	data, err := json.Marshal(bookmarks)
+	if err != nil {
+		return "", err
+	}
+	h.Write(data)
+	if len(sinks) > 0 {
+		if data, err = json.Marshal(sinks); err != nil {
+			return "", err
+		}
+		h.Write(data)
+	}
+	if len(pending) > 0 {
+		if data, err = json.Marshal(pending); err != nil {
+			return "", err
+		}
+		h.Write(data)
+	}
+	return checksumPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyChecksum checks the checksum of a bookmark file against its content
+// as written, so that decoding leniency can't hide a modification. Files
+// without a checksum, such as files written by older versions, are accepted.
+func verifyChecksum(data []byte, checksum string) error {
+	if checksum == "" {
+		return nil
+	}
+	if !strings.HasPrefix(checksum, checksumPrefix) {
+		return fmt.Errorf("unsupported checksum %q", checksum)
+	}
+
+	var raw struct {
+		Bookmarks json.RawMessage `json:"bookmarks"`
+		Sinks     json.RawMessage `json:"sink_positions"`
+		Pending   json.RawMessage `json:"pending_commits"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw.Bookmarks); err != nil {
+		return err
+	}
+	for _, section := range []json.RawMessage{raw.Sinks, raw.Pending} {
+		if len(section) == 0 {
+			continue
+		}
+		if err := json.Compact(&buf, section); err != nil {
+			return err
+		}
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	if actual := checksumPrefix + hex.EncodeToString(sum[:]); actual != checksum {
+		return fmt.Errorf("%w, the file records %s but its content hashes to %s", ErrChecksumMismatch, checksum, actual)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// savedTwice returns the path of a bookmark file saved at offset 20, whose
+// backup holds offset 10
+func savedTwice(t *testing.T) string {
+	t.Helper()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	bm := NewBookmarkManager(path)
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 10}))
+	require.NoError(t, bm.SaveToFile(ctx))
+	require.NoError(t, bm.UpdateOffset("orders", "0", 20))
+	require.NoError(t, bm.SaveToFile(ctx))
+
+	backups, err := bm.ListBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	return path
+}
+
+// editFile replaces the offset 20 in a bookmark file as a hand edit would
+func editFile(t *testing.T, path string, offset string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"offset": 20`)
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(string(data), `"offset": 20`, `"offset": `+offset, 1)), 0644))
+}
+
+func TestChecksumMismatchFailsLoad(t *testing.T) {
+	path := savedTwice(t)
+	editFile(t, path, "30")
+
+	bm := NewBookmarkManager(path)
+	err := bm.LoadFromFile(context.Background())
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.Zero(t, bm.Count(), "the backup must not be loaded instead")
+}
+
+func TestUndecodableFileFallsBackToBackup(t *testing.T) {
+	path := savedTwice(t)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data[:len(data)/2], 0644))
+
+	bm := NewBookmarkManager(path)
+	require.NoError(t, bm.LoadFromFile(context.Background()))
+
+	b, err := bm.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), b.Offset)
+	assert.True(t, bm.GetRecoveryReport().RecoveredFromBackup)
+}
+
+func TestIgnoreChecksumResignsFile(t *testing.T) {
+	ctx := context.Background()
+	path := savedTwice(t)
+	editFile(t, path, "30")
+
+	bm := NewBookmarkManager(path, WithIgnoreChecksum())
+	require.NoError(t, bm.LoadFromFile(ctx))
+	b, err := bm.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(30), b.Offset)
+	report := bm.GetRecoveryReport()
+	assert.False(t, report.RecoveredFromBackup)
+	require.Len(t, report.Problems, 1)
+	assert.Contains(t, report.Problems[0], "checksum mismatch")
+	require.NoError(t, bm.Compact(ctx))
+
+	loaded := NewBookmarkManager(path)
+	require.NoError(t, loaded.LoadFromFile(ctx))
+	b, err = loaded.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(30), b.Offset)
+}
+
+func TestFileWithoutChecksumLoads(t *testing.T) {
+	ctx := context.Background()
+	path := savedTwice(t)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(string(data), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.Contains(line, `"checksum"`) {
+			kept = append(kept, line)
+		}
+	}
+	require.Len(t, kept, len(lines)-1)
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(kept, "\n")), 0644))
+
+	bm := NewBookmarkManager(path)
+	require.NoError(t, bm.LoadFromFile(ctx))
+	b, err := bm.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), b.Offset)
+}
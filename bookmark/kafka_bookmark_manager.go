@@ -0,0 +1,377 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// KafkaBookmarkManagerConfig configures a KafkaBookmarkManager
+type KafkaBookmarkManagerConfig struct {
+	SeedBrokers []string
+	Topic       string
+
+	// Partitions and ReplicationFactor are used when the topic doesn't exist
+	// yet, -1 uses the broker defaults
+	Partitions        int32
+	ReplicationFactor int16
+
+	// ClientOpts are applied to the clients, for example for TLS and SASL
+	ClientOpts []kgo.Opt
+}
+
+// KafkaBookmarkManager stores bookmarks in a compacted topic keyed by
+// "[group/]topic:partition" as returned by TopicPartition.String, similar to
+// __consumer_offsets, which keeps the offset
+// state inside the cluster. The topic is read into memory on creation, Put and
+// Delete return once the record is acknowledged, one write at a time.
+type KafkaBookmarkManager struct {
+	topic  string
+	client *kgo.Client
+
+	// mutex guards the bookmarks and orders the records produced for them
+	mutex     sync.RWMutex
+	bookmarks map[TopicPartition]*Bookmark
+}
+
+var _ GroupBookmarkStore = (*KafkaBookmarkManager)(nil)
+
+// NewKafkaBookmarkManager creates the compacted topic if it doesn't exist and
+// loads the bookmarks stored in it
+func NewKafkaBookmarkManager(ctx context.Context, conf KafkaBookmarkManagerConfig) (*KafkaBookmarkManager, error) {
+	if conf.Topic == "" {
+		return nil, errors.New("topic must be set")
+	}
+
+	opts := append([]kgo.Opt{
+		kgo.SeedBrokers(conf.SeedBrokers...),
+		kgo.DefaultProduceTopic(conf.Topic),
+	}, conf.ClientOpts...)
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	m := &KafkaBookmarkManager{
+		topic:     conf.Topic,
+		client:    client,
+		bookmarks: make(map[TopicPartition]*Bookmark),
+	}
+	if err := m.ensureTopic(ctx, conf.Partitions, conf.ReplicationFactor); err != nil {
+		client.Close()
+		return nil, err
+	}
+	if err := m.load(ctx, conf); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to load bookmarks: %w", err)
+	}
+	return m, nil
+}
+
+func (m *KafkaBookmarkManager) ensureTopic(ctx context.Context, partitions int32, replicationFactor int16) error {
+	compact := "compact"
+	_, err := kadm.NewClient(m.client).CreateTopic(ctx, partitions, replicationFactor, map[string]*string{
+		"cleanup.policy": &compact,
+	}, m.topic)
+	if err != nil && !errors.Is(err, kerr.TopicAlreadyExists) {
+		return fmt.Errorf("failed to create topic %s: %w", m.topic, err)
+	}
+	return nil
+}
+
+// kafkaLoadIdleTimeout is how long loading waits for further records of
+// partitions not read up to their end yet. Records below the end that never
+// arrive are transaction markers or were removed by compaction.
+const kafkaLoadIdleTimeout = 2 * time.Second
+
+// load consumes the topic from the start up to the current high watermarks
+func (m *KafkaBookmarkManager) load(ctx context.Context, conf KafkaBookmarkManagerConfig) error {
+	adm := kadm.NewClient(m.client)
+	starts, err := adm.ListStartOffsets(ctx, m.topic)
+	if err != nil {
+		return err
+	}
+	if err := starts.Error(); err != nil {
+		return err
+	}
+	ends, err := adm.ListEndOffsets(ctx, m.topic)
+	if err != nil {
+		return err
+	}
+	if err := ends.Error(); err != nil {
+		return err
+	}
+
+	remaining := make(map[int32]int64)
+	consume := make(map[int32]kgo.Offset)
+	ends.Each(func(o kadm.ListedOffset) {
+		start, _ := starts.Lookup(o.Topic, o.Partition)
+		if o.Offset > start.Offset {
+			remaining[o.Partition] = o.Offset
+			consume[o.Partition] = kgo.NewOffset().At(start.Offset)
+		}
+	})
+	if len(remaining) == 0 {
+		return nil
+	}
+
+	opts := append([]kgo.Opt{
+		kgo.SeedBrokers(conf.SeedBrokers...),
+		kgo.ConsumePartitions(map[string]map[int32]kgo.Offset{m.topic: consume}),
+		kgo.FetchMaxWait(kafkaLoadIdleTimeout / 4),
+	}, conf.ClientOpts...)
+	consumer, err := kgo.NewClient(opts...)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	for len(remaining) > 0 {
+		pollCtx, cancel := context.WithTimeout(ctx, kafkaLoadIdleTimeout)
+		fetches := consumer.PollFetches(pollCtx)
+		idle := pollCtx.Err() != nil
+		cancel()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if idle && fetches.NumRecords() == 0 {
+			// Every record left to read before the end is a transaction
+			// marker or a compacted record, which aren't delivered
+			return nil
+		}
+		for _, err := range fetches.Errors() {
+			if !errors.Is(err.Err, context.DeadlineExceeded) {
+				return err.Err
+			}
+		}
+		fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+			for _, r := range p.Records {
+				m.apply(r)
+			}
+			end, exists := remaining[p.Partition]
+			if !exists {
+				return
+			}
+			next := p.HighWatermark
+			if len(p.Records) > 0 {
+				next = p.Records[len(p.Records)-1].Offset + 1
+			}
+			if next >= end || p.HighWatermark <= next {
+				delete(remaining, p.Partition)
+			}
+		})
+	}
+	return nil
+}
+
+// apply updates the in-memory state with a record of the topic, where an
+// empty value is a tombstone
+func (m *KafkaBookmarkManager) apply(r *kgo.Record) {
+	if len(r.Value) == 0 {
+		if tp, err := ParseTopicPartition(string(r.Key)); err == nil {
+			delete(m.bookmarks, tp)
+		}
+		return
+	}
+
+	var b *Bookmark
+	if err := json.Unmarshal(r.Value, &b); err != nil || b == nil || b.validate() != nil {
+		// Skip records not written by a bookmark manager
+		return
+	}
+	if b.Metadata == nil {
+		b.Metadata = make(map[string]interface{})
+	}
+	m.bookmarks[b.TopicPartition()] = b
+}
+
+// produce writes a record and waits for it to be acknowledged. The caller must
+// hold mutex, so that records of a key are produced in the order of the
+// updates.
+func (m *KafkaBookmarkManager) produce(ctx context.Context, key string, value []byte) error {
+	if err := m.client.ProduceSync(ctx, &kgo.Record{Key: []byte(key), Value: value}).FirstErr(); err != nil {
+		return fmt.Errorf("failed to produce bookmark: %w", err)
+	}
+	return nil
+}
+
+// Get returns the bookmark of a topic and partition in the default group
+func (m *KafkaBookmarkManager) Get(ctx context.Context, topic, partition string) (*Bookmark, error) {
+	return m.GetInGroup(ctx, "", topic, partition)
+}
+
+// GetInGroup returns the bookmark of a topic and partition in a group
+func (m *KafkaBookmarkManager) GetInGroup(ctx context.Context, group, topic, partition string) (*Bookmark, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	b, exists := m.bookmarks[NewGroupTopicPartition(group, topic, partition)]
+	if !exists {
+		return nil, notFoundError(group, topic, partition)
+	}
+	return b, nil
+}
+
+// Put adds or replaces a bookmark, returning once the record is acknowledged.
+// The bookmark is left unchanged when producing fails.
+func (m *KafkaBookmarkManager) Put(ctx context.Context, bookmark *Bookmark) error {
+	if bookmark == nil {
+		return errors.New("bookmark cannot be nil")
+	}
+	if err := bookmark.validate(); err != nil {
+		return fmt.Errorf("invalid bookmark: %w", err)
+	}
+	value, err := json.Marshal(bookmark)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bookmark: %w", err)
+	}
+
+	tp := bookmark.TopicPartition()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.produce(ctx, tp.String(), value); err != nil {
+		return err
+	}
+	m.bookmarks[tp] = bookmark
+	return nil
+}
+
+// Delete removes the bookmark of a topic and partition in the default group
+func (m *KafkaBookmarkManager) Delete(ctx context.Context, topic, partition string) error {
+	return m.DeleteInGroup(ctx, "", topic, partition)
+}
+
+// DeleteInGroup removes the bookmark of a topic and partition in a group by
+// producing a tombstone, returning once it's acknowledged
+func (m *KafkaBookmarkManager) DeleteInGroup(ctx context.Context, group, topic, partition string) error {
+	tp := NewGroupTopicPartition(group, topic, partition)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.bookmarks[tp]; !exists {
+		return notFoundError(group, topic, partition)
+	}
+	if err := m.produce(ctx, tp.String(), nil); err != nil {
+		return err
+	}
+	delete(m.bookmarks, tp)
+	return nil
+}
+
+// List returns all bookmarks sorted by topic and partition
+func (m *KafkaBookmarkManager) List(ctx context.Context) ([]*Bookmark, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	bookmarks := make([]*Bookmark, 0, len(m.bookmarks))
+	for _, b := range m.bookmarks {
+		bookmarks = append(bookmarks, b)
+	}
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return lessBookmark(bookmarks[i], bookmarks[j])
+	})
+	return bookmarks, nil
+}
+
+// Flush waits for all produced records to be acknowledged. Put and Delete
+// already wait for theirs, so there is nothing to wait for after they return.
+func (m *KafkaBookmarkManager) Flush(ctx context.Context) error {
+	if err := m.client.Flush(ctx); err != nil {
+		return fmt.Errorf("failed to flush bookmarks: %w", err)
+	}
+	return nil
+}
+
+// Close flushes pending records and closes the client
+func (m *KafkaBookmarkManager) Close(ctx context.Context) error {
+	err := m.Flush(ctx)
+	m.client.Close()
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	kbmFieldSection     = "bookmarks_kafka"
+	kbmFieldSeedBrokers = "seed_brokers"
+	kbmFieldTopic       = "topic"
+	kbmFieldPartitions  = "partitions"
+	kbmFieldReplication = "replication_factor"
+)
+
+// KafkaBookmarkManagerConfigFields returns the config fields of a Kafka
+// bookmark store
+func KafkaBookmarkManagerConfigFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewObjectField(kbmFieldSection,
+			service.NewStringListField(kbmFieldSeedBrokers).
+				Description("A list of broker addresses to connect to.").
+				Example([]string{"localhost:9092"}),
+			service.NewStringField(kbmFieldTopic).
+				Description("The compacted topic bookmarks are stored in, keyed by `topic:partition` prefixed by `<group>/` for bookmarks of a consumer group.").
+				Default("__bookmarks"),
+			service.NewIntField(kbmFieldPartitions).
+				Description("The number of partitions of the topic when it is created, -1 uses the broker default.").
+				Default(1).
+				Advanced(),
+			service.NewIntField(kbmFieldReplication).
+				Description("The replication factor of the topic when it is created, -1 uses the broker default.").
+				Default(-1).
+				Advanced(),
+		).Description("The Kafka bookmarks store configuration"),
+	}
+}
+
+// KafkaBookmarkManagerFromParsed creates a Kafka bookmark store from the
+// config fields defined by KafkaBookmarkManagerConfigFields
+func KafkaBookmarkManagerFromParsed(ctx context.Context, pConf *service.ParsedConfig, clientOpts ...kgo.Opt) (*KafkaBookmarkManager, error) {
+	kConf := pConf.Namespace(kbmFieldSection)
+
+	conf := KafkaBookmarkManagerConfig{ClientOpts: clientOpts}
+
+	var err error
+	if conf.SeedBrokers, err = kConf.FieldStringList(kbmFieldSeedBrokers); err != nil {
+		return nil, err
+	}
+	if conf.Topic, err = kConf.FieldString(kbmFieldTopic); err != nil {
+		return nil, err
+	}
+	partitions, err := kConf.FieldInt(kbmFieldPartitions)
+	if err != nil {
+		return nil, err
+	}
+	conf.Partitions = int32(partitions)
+	replication, err := kConf.FieldInt(kbmFieldReplication)
+	if err != nil {
+		return nil, err
+	}
+	conf.ReplicationFactor = int16(replication)
+
+	return NewKafkaBookmarkManager(ctx, conf)
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func kafkaBookmarkRecord(t *testing.T, b *Bookmark) *kgo.Record {
+	t.Helper()
+
+	value, err := json.Marshal(b)
+	require.NoError(t, err)
+	return &kgo.Record{Key: []byte(b.TopicPartition().String()), Value: value}
+}
+
+func TestKafkaBookmarkManagerApply(t *testing.T) {
+	m := &KafkaBookmarkManager{bookmarks: make(map[TopicPartition]*Bookmark)}
+
+	m.apply(kafkaBookmarkRecord(t, &Bookmark{Topic: "orders", Partition: "0", Offset: 5}))
+	m.apply(kafkaBookmarkRecord(t, &Bookmark{Topic: "orders", Partition: "0", Offset: 9}))
+	m.apply(kafkaBookmarkRecord(t, &Bookmark{Group: "billing", Topic: "orders", Partition: "0", Offset: 3}))
+	m.apply(kafkaBookmarkRecord(t, &Bookmark{Topic: "orders", Partition: "1", Offset: 7}))
+
+	// Records not written by a bookmark manager are skipped
+	m.apply(&kgo.Record{Key: []byte("orders:2"), Value: []byte("not json")})
+	m.apply(&kgo.Record{Key: []byte("orders:3"), Value: []byte(`{"topic":"","partition":"3"}`)})
+
+	// Tombstones remove bookmarks
+	m.apply(&kgo.Record{Key: []byte("orders:1")})
+
+	bookmarks, err := m.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, bookmarks, 2)
+	assert.Equal(t, NewGroupTopicPartition("", "orders", "0"), bookmarks[0].TopicPartition())
+	assert.Equal(t, int64(9), bookmarks[0].Offset)
+	assert.NotNil(t, bookmarks[0].Metadata)
+	assert.Equal(t, NewGroupTopicPartition("billing", "orders", "0"), bookmarks[1].TopicPartition())
+	assert.Equal(t, int64(3), bookmarks[1].Offset)
+
+	b, err := m.GetInGroup(t.Context(), "billing", "orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), b.Offset)
+	_, err = m.Get(t.Context(), "orders", "1")
+	assert.ErrorIs(t, err, ErrBookmarkNotFound)
+	assert.ErrorIs(t, m.Delete(t.Context(), "orders", "1"), ErrBookmarkNotFound)
+}
@@ -0,0 +1,291 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// MetricLag is the gauge of the lag of every bookmarked partition, labelled
+// by topic and partition, recorded by the lag reporter
+const MetricLag = "bookmark_lag"
+
+// lagQueryTimeout bounds how long a lag report waits for the end offsets
+const lagQueryTimeout = 10 * time.Second
+
+// PartitionLag is the number of records a bookmark is behind the end of its
+// partition
+type PartitionLag struct {
+	Topic      string    `json:"topic"`
+	Partition  string    `json:"partition"`
+	Offset     int64     `json:"offset"`
+	EndOffset  int64     `json:"end_offset"`
+	Lag        int64     `json:"lag"`
+	MeasuredAt time.Time `json:"measured_at"`
+}
+
+// LagReporterConfig configures the cluster the lag reporter queries and how
+// often it does
+type LagReporterConfig struct {
+	SeedBrokers []string
+	ClientOpts  []kgo.Opt
+
+	// Topics are the topics whose lag is reported, all bookmarked topics when
+	// empty
+	Topics   []string
+	Interval time.Duration
+
+	// AlertThreshold raises an alert for partitions lagging at least the
+	// given number of records. Zero disables the alert.
+	AlertThreshold int64
+}
+
+// WithLagReporter makes Open start a lag reporter that queries the end
+// offsets of the bookmarked partitions every interval, records their lag as
+// the bookmark_lag gauge and makes it available through GetLag. Partitions
+// crossing the alert threshold are logged, and alert the webhook once until
+// they catch up when alerts are configured.
+func WithLagReporter(conf LagReporterConfig) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.lag = &lagReporter{conf: conf, bm: bm}
+	}
+}
+
+type lagReporter struct {
+	conf LagReporterConfig
+	bm   *BookmarkManager
+
+	// mut guards the fields below, client, stop and done are also guarded by
+	// the lifecycleMutex of the manager
+	mut        sync.Mutex
+	ends       map[TopicPartition]int64
+	measuredAt time.Time
+	alerted    map[TopicPartition]struct{}
+
+	client *kgo.Client
+	gauge  *service.MetricGauge
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func (r *lagReporter) start() error {
+	if r.client != nil {
+		return nil
+	}
+	if len(r.conf.SeedBrokers) == 0 {
+		return errors.New("lag reporter requires seed brokers")
+	}
+	if r.conf.Interval <= 0 {
+		return errors.New("lag reporter interval must be positive")
+	}
+	client, err := kgo.NewClient(append([]kgo.Opt{kgo.SeedBrokers(r.conf.SeedBrokers...)}, r.conf.ClientOpts...)...)
+	if err != nil {
+		return fmt.Errorf("failed to create lag reporter client: %w", err)
+	}
+	if r.bm.res != nil && r.gauge == nil {
+		r.gauge = r.bm.res.Metrics().NewGauge(MetricLag, "topic", "partition")
+	}
+
+	r.client = client
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.run(kadm.NewClient(client), r.stop, r.done)
+	return nil
+}
+
+func (r *lagReporter) close() {
+	if r.client == nil {
+		return
+	}
+	// Closing the client aborts a report in flight
+	close(r.stop)
+	r.client.Close()
+	<-r.done
+	r.client = nil
+}
+
+func (r *lagReporter) run(admin *kadm.Client, stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(r.conf.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.report(admin); err != nil {
+			r.bm.log.Warnf("Failed to report lag: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// report queries the end offsets of the topics and records the lag of their
+// bookmarks
+func (r *lagReporter) report(admin *kadm.Client) error {
+	topics := r.conf.Topics
+	if len(topics) == 0 {
+		topics = r.bm.bookmarkedTopics()
+	}
+	if len(topics) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lagQueryTimeout)
+	defer cancel()
+
+	listed, err := admin.ListEndOffsets(ctx, topics...)
+	if err != nil {
+		return fmt.Errorf("failed to list end offsets: %w", err)
+	}
+	ends := make(map[TopicPartition]int64)
+	listed.Each(func(o kadm.ListedOffset) {
+		if o.Err != nil {
+			r.bm.log.Debugf("Failed to list end offset of %s/%d: %v", o.Topic, o.Partition, o.Err)
+			return
+		}
+		ends[KafkaTopicPartition(o.Topic, o.Partition)] = o.Offset
+	})
+
+	r.mut.Lock()
+	r.ends, r.measuredAt = ends, time.Now()
+	r.mut.Unlock()
+
+	for _, topic := range topics {
+		for _, l := range r.bm.GetLag(topic) {
+			if r.gauge != nil {
+				r.gauge.Set(l.Lag, l.Topic, l.Partition)
+			}
+			r.checkThreshold(l)
+		}
+	}
+	return nil
+}
+
+// checkThreshold alerts once for a partition crossing the alert threshold
+// until it catches up again
+func (r *lagReporter) checkThreshold(l PartitionLag) {
+	if r.conf.AlertThreshold <= 0 {
+		return
+	}
+	key := NewTopicPartition(l.Topic, l.Partition)
+
+	r.mut.Lock()
+	if l.Lag < r.conf.AlertThreshold {
+		delete(r.alerted, key)
+		r.mut.Unlock()
+		return
+	}
+	if _, exists := r.alerted[key]; exists {
+		r.mut.Unlock()
+		return
+	}
+	if r.alerted == nil {
+		r.alerted = make(map[TopicPartition]struct{})
+	}
+	r.alerted[key] = struct{}{}
+	r.mut.Unlock()
+
+	message := fmt.Sprintf("Bookmark %s/%s is %d records behind the end of its partition", l.Topic, l.Partition, l.Lag)
+	r.bm.log.Warn(message)
+	if r.bm.alerts != nil {
+		offset := l.Offset
+		r.bm.alerts.raise(Alert{
+			Rule:      AlertRuleLag,
+			Message:   message,
+			Topic:     l.Topic,
+			Partition: l.Partition,
+			Offset:    &offset,
+		})
+	}
+}
+
+// stopLagReporter stops the lag reporter when configured
+func (bm *BookmarkManager) stopLagReporter() {
+	if bm.lag != nil {
+		bm.lag.close()
+	}
+}
+
+// endOffset returns the last end offset reported for a partition
+func (r *lagReporter) endOffset(tp TopicPartition) (int64, time.Time, bool) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	end, exists := r.ends[tp]
+	return end, r.measuredAt, exists
+}
+
+// GetLag returns the lag of the bookmarks of a topic in the group of the
+// manager, sorted by partition. The end offsets are those last queried by the
+// lag reporter, or the high watermarks recorded on the bookmarks without one.
+// Partitions without a known end offset are left out.
+func (bm *BookmarkManager) GetLag(topic string) []PartitionLag {
+	var lags []PartitionLag
+	for _, b := range bm.GetBookmarksByTopic(topic) {
+		if l, known := bm.lagOf(b); known {
+			lags = append(lags, l)
+		}
+	}
+	return lags
+}
+
+// lagOf returns the lag of a bookmark of any group, false when the end offset
+// of its partition is unknown
+func (bm *BookmarkManager) lagOf(b *Bookmark) (PartitionLag, bool) {
+	l := PartitionLag{Topic: b.Topic, Partition: b.Partition, Offset: b.Offset}
+	var exists bool
+	if bm.lag != nil {
+		l.EndOffset, l.MeasuredAt, exists = bm.lag.endOffset(NewTopicPartition(b.Topic, b.Partition))
+	}
+	if !exists {
+		if b.HighWatermark <= 0 {
+			return l, false
+		}
+		l.EndOffset, l.MeasuredAt = b.HighWatermark, b.Timestamp
+	}
+	l.Lag = partitionLag(b.Offset, l.EndOffset)
+	return l, true
+}
+
+// bookmarkedTopics returns the topics with bookmarks in the group of the
+// manager, sorted by name
+func (bm *BookmarkManager) bookmarkedTopics() []string {
+	seen := make(map[string]struct{})
+	for _, b := range bm.bookmarks.all() {
+		if b.Group == bm.group {
+			seen[b.Topic] = struct{}{}
+		}
+	}
+
+	topics := make([]string, 0, len(seen))
+	for topic := range seen {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
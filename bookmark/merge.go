@@ -0,0 +1,128 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DiffKind describes how a bookmark differs between two managers
+type DiffKind string
+
+// Kinds of bookmark differences
+const (
+	DiffOnlyOurs   DiffKind = "only_ours"
+	DiffOnlyTheirs DiffKind = "only_theirs"
+	DiffChanged    DiffKind = "changed"
+)
+
+// BookmarkDiff is a bookmark that differs between two managers, ours is nil
+// when only the other manager has it and theirs when only this one does
+type BookmarkDiff struct {
+	Kind      DiffKind  `json:"kind"`
+	Group     string    `json:"group,omitempty"`
+	Topic     string    `json:"topic"`
+	Partition string    `json:"partition"`
+	Ours      *Bookmark `json:"ours,omitempty"`
+	Theirs    *Bookmark `json:"theirs,omitempty"`
+}
+
+// MergeStrategy decides which bookmark a merge keeps when both managers have
+// one for the same partition
+type MergeStrategy string
+
+// Merge strategies
+const (
+	// MergeMaxOffset keeps the bookmark with the higher offset
+	MergeMaxOffset MergeStrategy = "max-offset"
+
+	// MergeNewestTimestamp keeps the bookmark with the newer timestamp
+	MergeNewestTimestamp MergeStrategy = "newest-timestamp"
+
+	// MergePreferOurs keeps the bookmark of the manager merged into
+	MergePreferOurs MergeStrategy = "prefer-ours"
+)
+
+// Diff compares the bookmarks of all groups with those of another manager,
+// for example the bookmark file of a parallel instance, and returns those
+// that exist in only one of them or differ in offset, timestamp or metadata,
+// sorted by group, topic and partition
+func (bm *BookmarkManager) Diff(other *BookmarkManager) []BookmarkDiff {
+	return diffBookmarks(bm.GetAllBookmarks(), other.GetAllBookmarks())
+}
+
+func diffBookmarks(ours, theirs []*Bookmark) []BookmarkDiff {
+	theirsByKey := make(map[TopicPartition]*Bookmark, len(theirs))
+	for _, b := range theirs {
+		theirsByKey[b.TopicPartition()] = b
+	}
+
+	var diffs []BookmarkDiff
+	for _, b := range ours {
+		key := b.TopicPartition()
+		t, exists := theirsByKey[key]
+		delete(theirsByKey, key)
+		switch {
+		case !exists:
+			diffs = append(diffs, BookmarkDiff{Kind: DiffOnlyOurs, Group: key.Group, Topic: key.Topic, Partition: key.Partition, Ours: b})
+		case !sameBookmark(b, t):
+			diffs = append(diffs, BookmarkDiff{Kind: DiffChanged, Group: key.Group, Topic: key.Topic, Partition: key.Partition, Ours: b, Theirs: t})
+		}
+	}
+	for key, t := range theirsByKey {
+		diffs = append(diffs, BookmarkDiff{Kind: DiffOnlyTheirs, Group: key.Group, Topic: key.Topic, Partition: key.Partition, Theirs: t})
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		a, b := diffs[i], diffs[j]
+		return lessBookmark(&Bookmark{Group: a.Group, Topic: a.Topic, Partition: a.Partition}, &Bookmark{Group: b.Group, Topic: b.Topic, Partition: b.Partition})
+	})
+	return diffs
+}
+
+// Merge adopts the bookmarks of another manager, for example after failing
+// over between environments, and returns the number of adopted bookmarks.
+// Bookmarks only the other manager has are always adopted, and the strategy
+// decides between the bookmarks both have. Sentinel offsets compare below all
+// other offsets under MergeMaxOffset. The adopted bookmarks are checked
+// against the regression policy as a whole like AddBookmarks, and the
+// bookmarks are not saved.
+func (bm *BookmarkManager) Merge(other *BookmarkManager, strategy MergeStrategy) (int, error) {
+	switch strategy {
+	case MergeMaxOffset, MergeNewestTimestamp, MergePreferOurs:
+	default:
+		return 0, fmt.Errorf("unknown merge strategy: %s", strategy)
+	}
+
+	var adopted []*Bookmark
+	for _, d := range bm.Diff(other) {
+		switch {
+		case d.Kind == DiffOnlyOurs:
+			continue
+		case d.Kind == DiffOnlyTheirs,
+			strategy == MergeMaxOffset && d.Theirs.Offset > d.Ours.Offset,
+			strategy == MergeNewestTimestamp && d.Theirs.Timestamp.After(d.Ours.Timestamp):
+			adopted = append(adopted, d.Theirs)
+		}
+	}
+	if len(adopted) == 0 {
+		return 0, nil
+	}
+	if err := bm.AddBookmarks(cloneBookmarks(adopted)); err != nil {
+		return 0, fmt.Errorf("failed to merge bookmarks: %w", err)
+	}
+	return len(adopted), nil
+}
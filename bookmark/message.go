@@ -0,0 +1,82 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	// Metadata keys set by ToMessage
+	MetaTopic     = "bookmark_topic"
+	MetaPartition = "bookmark_partition"
+	MetaOffset    = "bookmark_offset"
+	MetaTimestamp = "bookmark_timestamp"
+)
+
+// ToMessage converts bookmark to a message with a structured payload and
+// metadata describing the bookmark position
+func (b *Bookmark) ToMessage() *service.Message {
+	msg := service.NewMessage(nil)
+	msg.SetStructured(b.ToDict())
+
+	msg.MetaSetMut(MetaTopic, b.Topic)
+	msg.MetaSetMut(MetaPartition, b.Partition)
+	msg.MetaSetMut(MetaOffset, b.Offset)
+	msg.MetaSetMut(MetaTimestamp, b.Timestamp.Format(time.RFC3339))
+
+	return msg
+}
+
+// FromMessage creates a bookmark from a message payload, falling back to the
+// bookmark metadata when the payload is empty
+func FromMessage(msg *service.Message) (*Bookmark, error) {
+	if msg == nil {
+		return nil, errors.New("message cannot be nil")
+	}
+
+	data, err := msg.AsBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message payload: %w", err)
+	}
+	if len(data) > 0 {
+		return FromJSON(string(data))
+	}
+
+	topic, _ := msg.MetaGet(MetaTopic)
+	partition, _ := msg.MetaGet(MetaPartition)
+
+	offsetStr, exists := msg.MetaGet(MetaOffset)
+	if !exists {
+		return nil, errors.New("invalid or missing offset")
+	}
+	offset, err := ParseOffset(offsetStr)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Now()
+	if tsStr, exists := msg.MetaGet(MetaTimestamp); exists {
+		if timestamp, err = time.Parse(time.RFC3339, tsStr); err != nil {
+			return nil, fmt.Errorf("invalid timestamp format: %v", err)
+		}
+	}
+
+	return NewBookmarkWithTimestamp(topic, partition, offset, timestamp, nil)
+}
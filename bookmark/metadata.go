@@ -0,0 +1,156 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// MetadataSchema is a compiled JSON schema that the metadata of bookmarks is
+// validated against
+type MetadataSchema struct {
+	schema *gojsonschema.Schema
+}
+
+// NewMetadataSchema compiles a JSON schema document for validating the
+// metadata of bookmarks
+func NewMetadataSchema(schema string) (*MetadataSchema, error) {
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile metadata schema: %w", err)
+	}
+	return &MetadataSchema{schema: compiled}, nil
+}
+
+// Validate returns an error describing every violation of the schema by the
+// metadata
+func (s *MetadataSchema) Validate(metadata map[string]interface{}) error {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	result, err := s.schema.Validate(gojsonschema.NewGoLoader(metadata))
+	if err != nil {
+		return fmt.Errorf("failed to validate metadata: %w", err)
+	}
+	if result.Valid() {
+		return nil
+	}
+	problems := make([]string, 0, len(result.Errors()))
+	for _, e := range result.Errors() {
+		problems = append(problems, e.String())
+	}
+	return fmt.Errorf("metadata doesn't match the schema: %s", strings.Join(problems, "; "))
+}
+
+// WithMetadataSchema validates the metadata of every added bookmark against
+// the schema, rejecting those that don't match it
+func WithMetadataSchema(schema *MetadataSchema) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.metadataSchema = schema
+	}
+}
+
+// GetMetadataString returns a string metadata value, and false when the key
+// is missing or the value isn't a string
+func (b *Bookmark) GetMetadataString(key string) (string, bool) {
+	s, ok := b.Metadata[key].(string)
+	return s, ok
+}
+
+// GetMetadataInt returns an integer metadata value, and false when the key is
+// missing or the value isn't an integer. Numbers decoded from JSON are
+// accepted when they are integral.
+func (b *Bookmark) GetMetadataInt(key string) (int64, bool) {
+	return asInt64(b.Metadata[key])
+}
+
+// asInt64 returns an integer of any integer type, or a number decoded from
+// JSON when it's integral
+func asInt64(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v), true
+		}
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return int64(v), true
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// GetMetadataFloat returns a numeric metadata value, and false when the key
+// is missing or the value isn't a number
+func (b *Bookmark) GetMetadataFloat(key string) (float64, bool) {
+	switch v := b.Metadata[key].(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f, true
+		}
+	}
+	if i, ok := b.GetMetadataInt(key); ok {
+		return float64(i), true
+	}
+	return 0, false
+}
+
+// GetMetadataBool returns a boolean metadata value, and false when the key is
+// missing or the value isn't a boolean
+func (b *Bookmark) GetMetadataBool(key string) (bool, bool) {
+	v, ok := b.Metadata[key].(bool)
+	return v, ok
+}
+
+// GetMetadataDuration returns a metadata value holding a duration string such
+// as "72h", and false when the key is missing or the value isn't a duration
+func (b *Bookmark) GetMetadataDuration(key string) (time.Duration, bool) {
+	s, ok := b.GetMetadataString(key)
+	if !ok {
+		return 0, false
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// Metrics of the bookmark operations, recorded when the manager is created
+// with WithResources
+const (
+	MetricSaves             = "bookmark_saves_total"
+	MetricSaveErrors        = "bookmark_save_errors_total"
+	MetricSaveLatency       = "bookmark_save_latency_ns"
+	MetricLastSaveTimestamp = "bookmark_last_save_timestamp_seconds"
+	MetricLoads             = "bookmark_loads_total"
+	MetricLoadErrors        = "bookmark_load_errors_total"
+	MetricCount             = "bookmark_count"
+	MetricOffset            = "bookmark_offset"
+
+	MetricReadOnlySkippedWrites = "bookmark_read_only_skipped_writes_total"
+	MetricWALReclaimedBytes     = "bookmark_wal_reclaimed_bytes"
+)
+
+// WithPartitionMetrics sets whether the offset of every bookmark is recorded
+// as a gauge labelled by topic and partition, which is enabled by default.
+// Disable it for bookmarks with unbounded partitions, such as the object keys
+// of S3 buckets.
+func WithPartitionMetrics(enabled bool) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.partitionMetrics = enabled
+	}
+}
+
+type managerMetrics struct {
+	saves       *service.MetricCounter
+	saveErrors  *service.MetricCounter
+	saveLatency *service.MetricTimer
+	lastSave    *service.MetricGauge
+	loads       *service.MetricCounter
+	loadErrors  *service.MetricCounter
+	count       *service.MetricGauge
+	offset      *service.MetricGauge
+
+	skippedWrites *service.MetricCounter
+	walReclaimed  *service.MetricCounter
+}
+
+func newManagerMetrics(m *service.Metrics) *managerMetrics {
+	return &managerMetrics{
+		saves:       m.NewCounter(MetricSaves),
+		saveErrors:  m.NewCounter(MetricSaveErrors),
+		saveLatency: m.NewTimer(MetricSaveLatency),
+		lastSave:    m.NewGauge(MetricLastSaveTimestamp),
+		loads:       m.NewCounter(MetricLoads),
+		loadErrors:  m.NewCounter(MetricLoadErrors),
+		count:       m.NewGauge(MetricCount),
+		offset:      m.NewGauge(MetricOffset, "topic", "partition"),
+
+		skippedWrites: m.NewCounter(MetricReadOnlySkippedWrites),
+		walReclaimed:  m.NewCounter(MetricWALReclaimedBytes),
+	}
+}
+
+// recordSave records the outcome of a save that started at the given time.
+// The gauges are only updated by successful saves, so that a stalled offset
+// gauge or last save timestamp reveals stalled commits.
+func (bm *BookmarkManager) recordSave(started time.Time, err error) {
+	if bm.metrics == nil {
+		return
+	}
+	bm.metrics.saveLatency.Timing(time.Since(started).Nanoseconds())
+	if err != nil {
+		bm.metrics.saveErrors.Incr(1)
+		return
+	}
+	bm.metrics.saves.Incr(1)
+	bm.metrics.lastSave.Set(time.Now().Unix())
+	bm.recordBookmarkGauges()
+}
+
+// recordLoad records the outcome of a load
+func (bm *BookmarkManager) recordLoad(err error) {
+	if bm.metrics == nil {
+		return
+	}
+	if err != nil {
+		bm.metrics.loadErrors.Incr(1)
+		return
+	}
+	bm.metrics.loads.Incr(1)
+	bm.recordBookmarkGauges()
+}
+
+func (bm *BookmarkManager) recordBookmarkGauges() {
+	bookmarks := bm.readSnapshot().bookmarks
+
+	bm.metrics.count.Set(int64(len(bookmarks)))
+	if !bm.partitionMetrics {
+		return
+	}
+	for _, b := range bookmarks {
+		bm.metrics.offset.Set(b.Offset, b.Topic, b.Partition)
+	}
+}
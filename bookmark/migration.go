@@ -0,0 +1,118 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// FileMigration upgrades the document of a JSON bookmark file from one layout
+// version to the next. Numbers in the document are json.Number so that
+// offsets keep their precision, and the version field is set by the caller.
+type FileMigration func(doc map[string]any) error
+
+type fileMigrationStep struct {
+	to      string
+	migrate FileMigration
+}
+
+var fileMigrations = struct {
+	sync.RWMutex
+	steps map[string]fileMigrationStep
+}{steps: map[string]fileMigrationStep{}}
+
+// RegisterFileMigration registers the migration of bookmark files from layout
+// version from to version to. Loads apply the chain of migrations from the
+// version of a file until no migration is registered for the resulting
+// version, which is normally FileVersion. Files without a version are
+// migrated as version 1.0.
+func RegisterFileMigration(from, to string, migrate FileMigration) error {
+	if from == "" || to == "" || from == to {
+		return fmt.Errorf("invalid migration from version %q to %q", from, to)
+	}
+	if migrate == nil {
+		return errors.New("migration cannot be nil")
+	}
+
+	fileMigrations.Lock()
+	defer fileMigrations.Unlock()
+
+	if existing, exists := fileMigrations.steps[from]; exists {
+		return fmt.Errorf("a migration from version %s to %s is already registered", from, existing.to)
+	}
+	fileMigrations.steps[from] = fileMigrationStep{to: to, migrate: migrate}
+	return nil
+}
+
+func init() {
+	// 1.1 allows sentinel offsets and adds the checksum, both of which 1.0
+	// files simply don't use
+	if err := RegisterFileMigration("1.0", "1.1", func(map[string]any) error { return nil }); err != nil {
+		panic(err)
+	}
+}
+
+// fileMigrationChain returns the migrations to apply to a file of the given
+// version, if any
+func fileMigrationChain(version string) ([]fileMigrationStep, error) {
+	if version == "" {
+		version = "1.0"
+	}
+
+	fileMigrations.RLock()
+	defer fileMigrations.RUnlock()
+
+	var chain []fileMigrationStep
+	seen := map[string]struct{}{}
+	for {
+		step, exists := fileMigrations.steps[version]
+		if !exists {
+			return chain, nil
+		}
+		if _, looped := seen[version]; looped {
+			return nil, fmt.Errorf("migrations of version %s form a cycle", version)
+		}
+		seen[version] = struct{}{}
+		chain = append(chain, step)
+		version = step.to
+	}
+}
+
+// migrateFile applies the registered migrations to a JSON bookmark file of the
+// given version. It returns nil when the file needs no migration.
+func migrateFile(data []byte, version string) ([]byte, error) {
+	chain, err := fileMigrationChain(version)
+	if err != nil || len(chain) == 0 {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	for _, step := range chain {
+		if err := step.migrate(doc); err != nil {
+			return nil, fmt.Errorf("failed to migrate file to version %s: %w", step.to, err)
+		}
+		doc["version"] = step.to
+	}
+	return json.Marshal(doc)
+}
@@ -0,0 +1,450 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// mirrorRetryInterval is how long replication to a failing mirror waits
+	// before retrying
+	mirrorRetryInterval = 5 * time.Second
+
+	// mirrorCloseTimeout bounds the final replication of Close
+	mirrorCloseTimeout = 30 * time.Second
+)
+
+// MirrorStatus describes the replication to a mirror
+type MirrorStatus struct {
+	Name             string    `json:"name"`
+	Pending          int       `json:"pending"`
+	LastReplicatedAt time.Time `json:"last_replicated_at,omitempty"`
+	LastError        string    `json:"last_error,omitempty"`
+}
+
+// ReconcileResult counts the bookmarks reconciling changed in a mirror
+type ReconcileResult struct {
+	Mirror  string `json:"mirror,omitempty"`
+	Updated int    `json:"updated"`
+	Removed int    `json:"removed"`
+}
+
+// WithMirror replicates the bookmarks to a further store while the manager is
+// open. Changes are applied to the manager synchronously and copied to the
+// mirror in the background, a changed bookmark is replicated once however
+// often it changed in the meantime, and the mirror is flushed after every
+// batch. Open reconciles the mirror with the loaded bookmarks, and Close
+// waits for the pending changes to be replicated. A mirror that fails is
+// retried with the changes kept pending, so it lags but never blocks the
+// manager. Mirrors hold the bookmarks of all groups, but deletions of
+// bookmarks of a group only reach stores implementing GroupBookmarkStore.
+// The store remains owned by the caller.
+func WithMirror(name string, store BookmarkStore) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.addMirror(&mirror{name: name, store: store})
+	}
+}
+
+// withMirrorURL adds a mirror to the store a URL refers to, which is opened by
+// Open and closed by Close
+func withMirrorURL(storeURL string) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.addMirror(&mirror{name: storeURL, open: func(ctx context.Context) (ownedStore, error) {
+			return openStoreURL(ctx, storeURL)
+		}})
+	}
+}
+
+func (bm *BookmarkManager) addMirror(m *mirror) {
+	m.bm = bm
+	m.pending = make(map[TopicPartition]struct{})
+	m.kick = make(chan struct{}, 1)
+	bm.mirrors = append(bm.mirrors, m)
+	bm.updateHooks = append(bm.updateHooks, m.onEvent)
+}
+
+type mirror struct {
+	name  string
+	store BookmarkStore
+	open  func(ctx context.Context) (ownedStore, error)
+	bm    *BookmarkManager
+
+	// mut guards the fields below
+	mut              sync.Mutex
+	pending          map[TopicPartition]struct{}
+	lastReplicatedAt time.Time
+	lastErr          error
+
+	// replicateMut serializes replications, and opened, stop and done are
+	// guarded by the lifecycleMutex of the manager
+	replicateMut sync.Mutex
+	kick         chan struct{}
+	opened       *ownedStore
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+// onEvent records a changed bookmark as pending. It's an update hook, so it
+// runs once the manager is unlocked and must not block.
+func (m *mirror) onEvent(ev Event) {
+	m.mut.Lock()
+	m.pending[NewGroupTopicPartition(ev.Group, ev.Topic, ev.Partition)] = struct{}{}
+	m.mut.Unlock()
+
+	select {
+	case m.kick <- struct{}{}:
+	default:
+	}
+}
+
+// target returns the store replicated to
+func (m *mirror) target() BookmarkStore {
+	if m.opened != nil {
+		return m.opened.store
+	}
+	return m.store
+}
+
+func (m *mirror) start(ctx context.Context) error {
+	if m.done != nil {
+		return nil
+	}
+	if m.open != nil && m.opened == nil {
+		opened, err := m.open(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to open mirror %s: %w", m.name, err)
+		}
+		m.opened = &opened
+	}
+
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go m.run()
+	return nil
+}
+
+// run reconciles the mirror and then replicates pending changes until stopped
+func (m *mirror) run() {
+	defer close(m.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-m.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	synced := false
+	for {
+		var err error
+		if !synced {
+			if _, err = m.reconcile(ctx); err == nil {
+				synced = true
+			}
+		} else {
+			err = m.replicate(ctx)
+		}
+
+		var retry <-chan time.Time
+		wait := m.kick
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			m.bm.log.Warnf("Failed to replicate bookmarks to mirror %s, retrying in %v: %v", m.name, mirrorRetryInterval, err)
+			retry, wait = time.After(mirrorRetryInterval), nil
+		}
+		select {
+		case <-wait:
+		case <-retry:
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// replicate copies the pending bookmarks to the mirror and flushes it. The
+// bookmarks are pending again when it fails.
+func (m *mirror) replicate(ctx context.Context) error {
+	m.replicateMut.Lock()
+	defer m.replicateMut.Unlock()
+
+	m.mut.Lock()
+	pending := m.pending
+	m.pending = make(map[TopicPartition]struct{})
+	m.mut.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	keys := make([]TopicPartition, 0, len(pending))
+	for tp := range pending {
+		keys = append(keys, tp)
+	}
+	err := m.apply(ctx, keys)
+	m.record(err, pending)
+	return err
+}
+
+func (m *mirror) apply(ctx context.Context, keys []TopicPartition) error {
+	store := m.target()
+	found, missing := m.bm.copyBookmarks(keys)
+	for _, b := range found {
+		if err := store.Put(ctx, b); err != nil {
+			return fmt.Errorf("failed to put bookmark %s/%s: %w", b.Topic, b.Partition, err)
+		}
+	}
+	for _, tp := range missing {
+		if _, err := deleteFromStore(ctx, store, tp); err != nil && !errors.Is(err, ErrBookmarkNotFound) {
+			return fmt.Errorf("failed to delete bookmark %s/%s: %w", tp.Topic, tp.Partition, err)
+		}
+	}
+	if err := store.Flush(ctx); err != nil {
+		return fmt.Errorf("failed to flush: %w", err)
+	}
+	return nil
+}
+
+// copyBookmarks returns copies of the bookmarks of keys, which stores may keep,
+// and the keys without a bookmark
+func (bm *BookmarkManager) copyBookmarks(keys []TopicPartition) ([]*Bookmark, []TopicPartition) {
+	var found []*Bookmark
+	var missing []TopicPartition
+	for _, tp := range keys {
+		if b, exists := bm.bookmarks.clone(tp); exists {
+			found = append(found, b)
+			continue
+		}
+		missing = append(missing, tp)
+	}
+	return found, missing
+}
+
+// reconcile makes the mirror match the manager, which replicates all pending
+// bookmarks
+func (m *mirror) reconcile(ctx context.Context) (ReconcileResult, error) {
+	m.replicateMut.Lock()
+	defer m.replicateMut.Unlock()
+
+	m.mut.Lock()
+	pending := m.pending
+	m.pending = make(map[TopicPartition]struct{})
+	m.mut.Unlock()
+
+	result, err := Reconcile(ctx, m.bm, m.target())
+	result.Mirror = m.name
+	m.record(err, pending)
+	return result, err
+}
+
+// record updates the status after a replication, keeping its bookmarks
+// pending when it failed
+func (m *mirror) record(err error, pending map[TopicPartition]struct{}) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.lastErr = err
+	if err != nil {
+		for tp := range pending {
+			m.pending[tp] = struct{}{}
+		}
+		return
+	}
+	m.lastReplicatedAt = time.Now()
+}
+
+// close stops the replication, replicates the pending bookmarks one last time
+// and closes a store opened by start
+func (m *mirror) close(ctx context.Context) {
+	if m.done != nil {
+		close(m.stop)
+		<-m.done
+		m.stop, m.done = nil, nil
+	}
+	if m.target() == nil {
+		return
+	}
+
+	if err := m.replicate(ctx); err != nil {
+		m.bm.log.Errorf("Failed to replicate bookmarks to mirror %s on close: %v", m.name, err)
+	}
+	if m.opened != nil {
+		if err := m.opened.close(ctx); err != nil {
+			m.bm.log.Warnf("Failed to close mirror %s: %v", m.name, err)
+		}
+		m.opened = nil
+	}
+}
+
+func (m *mirror) status() MirrorStatus {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	s := MirrorStatus{Name: m.name, Pending: len(m.pending), LastReplicatedAt: m.lastReplicatedAt}
+	if m.lastErr != nil {
+		s.LastError = m.lastErr.Error()
+	}
+	return s
+}
+
+// startMirrors starts replicating to the configured mirrors
+func (bm *BookmarkManager) startMirrors(ctx context.Context) error {
+	if bm.readOnly {
+		return nil
+	}
+	for i, m := range bm.mirrors {
+		if err := m.start(ctx); err != nil {
+			for _, started := range bm.mirrors[:i] {
+				started.close(ctx)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// stopMirrors stops replicating to the configured mirrors once the pending
+// bookmarks are replicated
+func (bm *BookmarkManager) stopMirrors(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, mirrorCloseTimeout)
+	defer cancel()
+
+	for _, m := range bm.mirrors {
+		m.close(ctx)
+	}
+}
+
+// MirrorStatus returns the replication status of every mirror
+func (bm *BookmarkManager) MirrorStatus() []MirrorStatus {
+	statuses := make([]MirrorStatus, 0, len(bm.mirrors))
+	for _, m := range bm.mirrors {
+		statuses = append(statuses, m.status())
+	}
+	return statuses
+}
+
+// ReconcileMirrors makes every mirror match the bookmarks of the manager right
+// away rather than waiting for the background replication, which repairs
+// mirrors changed by others or restored from elsewhere
+func (bm *BookmarkManager) ReconcileMirrors(ctx context.Context) ([]ReconcileResult, error) {
+	bm.lifecycleMutex.Lock()
+	defer bm.lifecycleMutex.Unlock()
+
+	results := make([]ReconcileResult, 0, len(bm.mirrors))
+	var errs []error
+	for _, m := range bm.mirrors {
+		if m.target() == nil {
+			errs = append(errs, fmt.Errorf("mirror %s is not open", m.name))
+			continue
+		}
+		result, err := m.reconcile(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to reconcile mirror %s: %w", m.name, err))
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
+
+// Reconcile makes mirror hold the same bookmarks as primary, putting those
+// that are missing or differ and deleting those primary doesn't have, and
+// flushes it
+func Reconcile(ctx context.Context, primary, mirror BookmarkStore) (ReconcileResult, error) {
+	var result ReconcileResult
+
+	want, err := primary.List(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to list primary bookmarks: %w", err)
+	}
+	have, err := mirror.List(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to list mirror bookmarks: %w", err)
+	}
+
+	existing := make(map[TopicPartition]*Bookmark, len(have))
+	for _, b := range have {
+		existing[b.TopicPartition()] = b
+	}
+	for _, b := range want {
+		tp := b.TopicPartition()
+		current, exists := existing[tp]
+		delete(existing, tp)
+		if exists && sameBookmark(current, b) {
+			continue
+		}
+		if err := mirror.Put(ctx, b); err != nil {
+			return result, fmt.Errorf("failed to put bookmark %s/%s: %w", b.Topic, b.Partition, err)
+		}
+		result.Updated++
+	}
+
+	extra := make([]TopicPartition, 0, len(existing))
+	for tp := range existing {
+		extra = append(extra, tp)
+	}
+	sort.Slice(extra, func(i, j int) bool { return extra[i].String() < extra[j].String() })
+	for _, tp := range extra {
+		deleted, err := deleteFromStore(ctx, mirror, tp)
+		if err != nil && !errors.Is(err, ErrBookmarkNotFound) {
+			return result, fmt.Errorf("failed to delete bookmark %s/%s: %w", tp.Topic, tp.Partition, err)
+		}
+		if deleted {
+			result.Removed++
+		}
+	}
+
+	if err := mirror.Flush(ctx); err != nil {
+		return result, fmt.Errorf("failed to flush mirror: %w", err)
+	}
+	return result, nil
+}
+
+// deleteFromStore removes the bookmark of a key from a store and reports
+// whether the store could address it, which stores that don't implement
+// GroupBookmarkStore can't for the bookmarks of a group
+func deleteFromStore(ctx context.Context, store BookmarkStore, tp TopicPartition) (bool, error) {
+	if tp.Group == "" {
+		return true, store.Delete(ctx, tp.Topic, tp.Partition)
+	}
+	if groupStore, ok := store.(GroupBookmarkStore); ok {
+		return true, groupStore.DeleteInGroup(ctx, tp.Group, tp.Topic, tp.Partition)
+	}
+	return false, nil
+}
+
+// sameBookmark reports whether two bookmarks have the same position and
+// metadata, comparing the metadata by its JSON encoding as stores that decode
+// it from JSON turn numbers into floats
+func sameBookmark(a, b *Bookmark) bool {
+	if a.Offset != b.Offset || !a.Timestamp.Equal(b.Timestamp) || a.Group != b.Group || a.Topic != b.Topic || a.Partition != b.Partition {
+		return false
+	}
+	if len(a.Metadata) == 0 && len(b.Metadata) == 0 {
+		return true
+	}
+	aData, aErr := json.Marshal(a.Metadata)
+	bData, bErr := json.Marshal(b.Metadata)
+	return aErr == nil && bErr == nil && string(aData) == string(bData)
+}
@@ -0,0 +1,341 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// Layout of mmap bookmark files. The header holds the magic and the record
+// size, followed by fixed-size records with the offset and timestamp at
+// 8-byte aligned positions so that they can be written atomically in place.
+const (
+	mmapMagic         = "BKMMAP01"
+	mmapHeaderSize    = 64
+	mmapRecordSize    = 512
+	mmapRecordKeySize = mmapRecordSize - mmapRecordKey
+	mmapInitialSlots  = 1024
+
+	mmapRecordOffset    = 0
+	mmapRecordTimestamp = 8
+	mmapRecordUsed      = 16
+	mmapRecordTopicLen  = 20
+	mmapRecordPartLen   = 22
+	mmapRecordKey       = 24
+)
+
+// ErrStoreClosed is returned by operations of a bookmark store that was closed,
+// or lost its mapping to a failure
+var ErrStoreClosed = errors.New("bookmark store is closed")
+
+// MmapBookmarkManager persists bookmarks as fixed-size records of a memory
+// mapped file, where updating the offset of an existing bookmark is a write to
+// the mapping rather than a rewrite of the file. It suits very high
+// commit frequencies. Only the topic, partition, offset and timestamp are
+// stored, groups and metadata are dropped, and the topic and partition
+// together can't exceed 488 bytes. Changes reach the file when the operating
+// system writes back the mapping, or once Flush returns. The file must only
+// be opened by one process at a time.
+type MmapBookmarkManager struct {
+	path string
+	file *os.File
+
+	// mut guards the mapping and the slots. Reads hold its read lock and
+	// writes its write lock, so that the offset and timestamp of a record
+	// are always read and written together. A nil mapping is closed.
+	mut   sync.RWMutex
+	data  []byte
+	slots map[TopicPartition]int
+	free  []int
+}
+
+var _ BookmarkStore = (*MmapBookmarkManager)(nil)
+
+// NewMmapBookmarkManager opens or creates a mmap bookmark file and maps it
+func NewMmapBookmarkManager(path string) (*MmapBookmarkManager, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bookmark file: %w", err)
+	}
+	m := &MmapBookmarkManager{path: path, file: file}
+	if err := m.open(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// open maps the file, initializing the header of new files, and indexes the
+// records in use
+func (m *MmapBookmarkManager) open() error {
+	info, err := m.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat bookmark file: %w", err)
+	}
+	size := info.Size()
+	if size == 0 {
+		size = mmapHeaderSize + mmapInitialSlots*mmapRecordSize
+		if err := m.file.Truncate(size); err != nil {
+			return fmt.Errorf("failed to allocate bookmark file: %w", err)
+		}
+	}
+	if size < mmapHeaderSize || (size-mmapHeaderSize)%mmapRecordSize != 0 {
+		return fmt.Errorf("bookmark file %s has an invalid size of %d bytes", m.path, size)
+	}
+	if m.data, err = mapFile(m.file, int(size)); err != nil {
+		return fmt.Errorf("failed to map bookmark file: %w", err)
+	}
+
+	if info.Size() == 0 {
+		copy(m.data, mmapMagic)
+		binary.LittleEndian.PutUint32(m.data[8:], mmapRecordSize)
+	}
+	if string(m.data[:len(mmapMagic)]) != mmapMagic {
+		_ = unmapFile(m.data)
+		return fmt.Errorf("%s is not a mmap bookmark file", m.path)
+	}
+	if recordSize := binary.LittleEndian.Uint32(m.data[8:]); recordSize != mmapRecordSize {
+		_ = unmapFile(m.data)
+		return fmt.Errorf("bookmark file %s has an unsupported record size of %d bytes", m.path, recordSize)
+	}
+
+	m.slots = make(map[TopicPartition]int)
+	for slot := m.capacity() - 1; slot >= 0; slot-- {
+		if atomic.LoadUint32(m.word32(slot, mmapRecordUsed)) == 0 {
+			m.free = append(m.free, slot)
+			continue
+		}
+		m.slots[m.recordKey(slot)] = slot
+	}
+	return nil
+}
+
+// capacity returns the number of record slots of the mapping
+func (m *MmapBookmarkManager) capacity() int {
+	return (len(m.data) - mmapHeaderSize) / mmapRecordSize
+}
+
+func (m *MmapBookmarkManager) record(slot int) []byte {
+	start := mmapHeaderSize + slot*mmapRecordSize
+	return m.data[start : start+mmapRecordSize]
+}
+
+func (m *MmapBookmarkManager) word64(slot, field int) *int64 {
+	return (*int64)(unsafe.Pointer(&m.record(slot)[field]))
+}
+
+func (m *MmapBookmarkManager) word32(slot, field int) *uint32 {
+	return (*uint32)(unsafe.Pointer(&m.record(slot)[field]))
+}
+
+// recordKey decodes the key of the record in a slot
+func (m *MmapBookmarkManager) recordKey(slot int) TopicPartition {
+	r := m.record(slot)
+	topicLen := int(binary.LittleEndian.Uint16(r[mmapRecordTopicLen:]))
+	partLen := int(binary.LittleEndian.Uint16(r[mmapRecordPartLen:]))
+	key := r[mmapRecordKey:]
+	return TopicPartition{Topic: string(key[:topicLen]), Partition: string(key[topicLen : topicLen+partLen])}
+}
+
+// readRecord decodes the bookmark in a slot
+func (m *MmapBookmarkManager) readRecord(slot int) *Bookmark {
+	key := m.recordKey(slot)
+	return &Bookmark{
+		Topic:     key.Topic,
+		Partition: key.Partition,
+		Offset:    atomic.LoadInt64(m.word64(slot, mmapRecordOffset)),
+		Timestamp: time.Unix(0, atomic.LoadInt64(m.word64(slot, mmapRecordTimestamp))),
+		Metadata:  make(map[string]interface{}),
+	}
+}
+
+// Get returns the bookmark of a topic and partition
+func (m *MmapBookmarkManager) Get(ctx context.Context, topic, partition string) (*Bookmark, error) {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	if m.data == nil {
+		return nil, ErrStoreClosed
+	}
+	slot, exists := m.slots[NewTopicPartition(topic, partition)]
+	if !exists {
+		return nil, fmt.Errorf("%w for topic: %s, partition: %s", ErrBookmarkNotFound, topic, partition)
+	}
+	return m.readRecord(slot), nil
+}
+
+// Put adds or replaces a bookmark. Updates of existing bookmarks write the
+// offset and timestamp in place.
+func (m *MmapBookmarkManager) Put(ctx context.Context, bookmark *Bookmark) error {
+	if bookmark == nil {
+		return errors.New("bookmark cannot be nil")
+	}
+	if err := bookmark.validate(); err != nil {
+		return fmt.Errorf("invalid bookmark: %w", err)
+	}
+	key := NewTopicPartition(bookmark.Topic, bookmark.Partition)
+
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.data == nil {
+		return ErrStoreClosed
+	}
+	slot, exists := m.slots[key]
+	if !exists {
+		if len(key.Topic)+len(key.Partition) > mmapRecordKeySize {
+			return fmt.Errorf("topic and partition of %s/%s exceed %d bytes", key.Topic, key.Partition, mmapRecordKeySize)
+		}
+		if len(m.free) == 0 {
+			if err := m.grow(); err != nil {
+				return err
+			}
+		}
+		slot = m.free[len(m.free)-1]
+		m.free = m.free[:len(m.free)-1]
+
+		r := m.record(slot)
+		binary.LittleEndian.PutUint16(r[mmapRecordTopicLen:], uint16(len(key.Topic)))
+		binary.LittleEndian.PutUint16(r[mmapRecordPartLen:], uint16(len(key.Partition)))
+		clear(r[mmapRecordKey:])
+		n := copy(r[mmapRecordKey:], key.Topic)
+		copy(r[mmapRecordKey+n:], key.Partition)
+	}
+	atomic.StoreInt64(m.word64(slot, mmapRecordOffset), bookmark.Offset)
+	atomic.StoreInt64(m.word64(slot, mmapRecordTimestamp), bookmark.Timestamp.UnixNano())
+	// Marked in use last, so that a crash never leaves a partial record
+	atomic.StoreUint32(m.word32(slot, mmapRecordUsed), 1)
+	m.slots[key] = slot
+	return nil
+}
+
+// grow doubles the slots of the file and remaps it, mapping the file at its
+// former size again when it fails. The caller must hold the write lock.
+func (m *MmapBookmarkManager) grow() error {
+	capacity := m.capacity()
+	oldSize := len(m.data)
+	size := mmapHeaderSize + 2*capacity*mmapRecordSize
+	if err := syncMapping(m.data); err != nil {
+		return fmt.Errorf("failed to sync bookmark file: %w", err)
+	}
+	if err := unmapFile(m.data); err != nil {
+		return fmt.Errorf("failed to unmap bookmark file: %w", err)
+	}
+	m.data = nil
+
+	data, err := m.growFile(size)
+	if err != nil {
+		// Left closed if even the former size can't be mapped again
+		remapped, remapErr := mapFile(m.file, oldSize)
+		if remapErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to map bookmark file again: %w", remapErr))
+		}
+		m.data = remapped
+		return err
+	}
+	m.data = data
+	for slot := 2*capacity - 1; slot >= capacity; slot-- {
+		m.free = append(m.free, slot)
+	}
+	return nil
+}
+
+// growFile extends the file to size bytes and maps it
+func (m *MmapBookmarkManager) growFile(size int) ([]byte, error) {
+	if err := m.file.Truncate(int64(size)); err != nil {
+		return nil, fmt.Errorf("failed to grow bookmark file: %w", err)
+	}
+	data, err := mapFile(m.file, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map bookmark file: %w", err)
+	}
+	return data, nil
+}
+
+// Delete removes the bookmark of a topic and partition
+func (m *MmapBookmarkManager) Delete(ctx context.Context, topic, partition string) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.data == nil {
+		return ErrStoreClosed
+	}
+	key := NewTopicPartition(topic, partition)
+	slot, exists := m.slots[key]
+	if !exists {
+		return fmt.Errorf("%w for topic: %s, partition: %s", ErrBookmarkNotFound, topic, partition)
+	}
+	atomic.StoreUint32(m.word32(slot, mmapRecordUsed), 0)
+	delete(m.slots, key)
+	m.free = append(m.free, slot)
+	return nil
+}
+
+// List returns all bookmarks sorted by topic and partition
+func (m *MmapBookmarkManager) List(ctx context.Context) ([]*Bookmark, error) {
+	m.mut.RLock()
+	if m.data == nil {
+		m.mut.RUnlock()
+		return nil, ErrStoreClosed
+	}
+	bookmarks := make([]*Bookmark, 0, len(m.slots))
+	for _, slot := range m.slots {
+		bookmarks = append(bookmarks, m.readRecord(slot))
+	}
+	m.mut.RUnlock()
+
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return lessBookmark(bookmarks[i], bookmarks[j])
+	})
+	return bookmarks, nil
+}
+
+// Flush writes the mapping back to the file and waits until it's durable
+func (m *MmapBookmarkManager) Flush(ctx context.Context) error {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	if m.data == nil {
+		return ErrStoreClosed
+	}
+	if err := syncMapping(m.data); err != nil {
+		return fmt.Errorf("failed to sync bookmark file: %w", err)
+	}
+	return nil
+}
+
+// Close flushes and unmaps the file
+func (m *MmapBookmarkManager) Close() error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.data == nil {
+		return nil
+	}
+	syncErr := syncMapping(m.data)
+	unmapErr := unmapFile(m.data)
+	m.data = nil
+	closeErr := m.file.Close()
+	return errors.Join(syncErr, unmapErr, closeErr)
+}
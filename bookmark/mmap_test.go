@@ -0,0 +1,116 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build unix
+
+package bookmark
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMmapBookmarkManagerRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bookmarks.mmap")
+
+	m, err := NewMmapBookmarkManager(path)
+	require.NoError(t, err)
+
+	// More bookmarks than initial slots, so that the file grows
+	const count = mmapInitialSlots + 10
+	ts := time.Unix(1700000000, 0)
+	for p := 0; p < count; p++ {
+		require.NoError(t, m.Put(ctx, &Bookmark{Topic: "orders", Partition: strconv.Itoa(p), Offset: int64(p), Timestamp: ts}))
+	}
+	require.NoError(t, m.Put(ctx, &Bookmark{Topic: "orders", Partition: "0", Offset: 42, Timestamp: ts.Add(time.Second)}))
+	require.NoError(t, m.Delete(ctx, "orders", "1"))
+	assert.ErrorIs(t, m.Delete(ctx, "orders", "1"), ErrBookmarkNotFound)
+	require.NoError(t, m.Close())
+
+	m, err = NewMmapBookmarkManager(path)
+	require.NoError(t, err)
+	defer m.Close()
+
+	bookmarks, err := m.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, bookmarks, count-1)
+
+	b, err := m.Get(ctx, "orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), b.Offset)
+	assert.True(t, ts.Add(time.Second).Equal(b.Timestamp))
+
+	b, err = m.Get(ctx, "orders", strconv.Itoa(count-1))
+	require.NoError(t, err)
+	assert.Equal(t, int64(count-1), b.Offset)
+
+	_, err = m.Get(ctx, "orders", "1")
+	assert.ErrorIs(t, err, ErrBookmarkNotFound)
+}
+
+func TestMmapBookmarkManagerClosed(t *testing.T) {
+	ctx := context.Background()
+	m, err := NewMmapBookmarkManager(filepath.Join(t.TempDir(), "bookmarks.mmap"))
+	require.NoError(t, err)
+	require.NoError(t, m.Put(ctx, &Bookmark{Topic: "orders", Partition: "0", Offset: 1, Timestamp: time.Now()}))
+	require.NoError(t, m.Close())
+
+	_, err = m.Get(ctx, "orders", "0")
+	assert.ErrorIs(t, err, ErrStoreClosed)
+	assert.ErrorIs(t, m.Put(ctx, &Bookmark{Topic: "orders", Partition: "0", Offset: 2, Timestamp: time.Now()}), ErrStoreClosed)
+	assert.ErrorIs(t, m.Put(ctx, &Bookmark{Topic: "orders", Partition: "1", Offset: 2, Timestamp: time.Now()}), ErrStoreClosed)
+	assert.ErrorIs(t, m.Delete(ctx, "orders", "0"), ErrStoreClosed)
+	_, err = m.List(ctx)
+	assert.ErrorIs(t, err, ErrStoreClosed)
+	assert.ErrorIs(t, m.Flush(ctx), ErrStoreClosed)
+	assert.NoError(t, m.Close())
+}
+
+func TestMmapBookmarkManagerUpdatesOffsetAndTimestampTogether(t *testing.T) {
+	ctx := context.Background()
+	m, err := NewMmapBookmarkManager(filepath.Join(t.TempDir(), "bookmarks.mmap"))
+	require.NoError(t, err)
+	defer m.Close()
+	require.NoError(t, m.Put(ctx, &Bookmark{Topic: "orders", Partition: "0", Offset: 1, Timestamp: time.Unix(0, 1)}))
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := int64(1); i <= 500; i++ {
+				offset := i*4 + int64(w)
+				assert.NoError(t, m.Put(ctx, &Bookmark{Topic: "orders", Partition: "0", Offset: offset, Timestamp: time.Unix(0, offset)}))
+			}
+		}(w)
+	}
+	for i := 0; i < 500; i++ {
+		b, err := m.Get(ctx, "orders", "0")
+		require.NoError(t, err)
+		require.Equal(t, b.Offset, b.Timestamp.UnixNano())
+	}
+	wg.Wait()
+
+	b, err := m.Get(ctx, "orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, b.Offset, b.Timestamp.UnixNano())
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// FileFormat is the encoding of the bookmark file
+type FileFormat string
+
+// File formats
+const (
+	FileFormatJSON    FileFormat = "json"
+	FileFormatMsgpack FileFormat = "msgpack"
+)
+
+// WithFileFormat sets the encoding of the bookmark file, its backups and
+// rotated segments. Loads detect the format of a file, so that the format can
+// be changed without converting existing files. JSON is the default.
+func WithFileFormat(format FileFormat) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.format = format
+	}
+}
+
+// isMsgpackFile returns whether data starts with a MessagePack map, which a
+// JSON file never does as it starts with a brace or whitespace
+func isMsgpackFile(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	b := data[0]
+	return (b >= 0x80 && b <= 0x8f) || b == 0xde || b == 0xdf
+}
+
+// marshalMsgpack encodes v with the field names of the JSON encoding and
+// sorted map keys, so that equal states produce identical files
+func marshalMsgpack(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.SetSortMapKeys(true)
+	enc.UseCompactInts(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func unmarshalMsgpack(data []byte, v any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// msgpackChecksum returns the checksum of the bookmarks, sink positions and
+// pending commits of a MessagePack file, which hashes their encoding as
+// fileChecksum does for JSON files
+func msgpackChecksum(bookmarks, sinks, pending []byte) string {
+	h := sha256.New()
+	h.Write(bookmarks)
+	h.Write(sinks)
+	h.Write(pending)
+	return checksumPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// marshalMsgpackFile encodes a bookmark file as MessagePack along with its
+// checksum
+func marshalMsgpackFile(bookmarkFile BookmarkFile) ([]byte, error) {
+	bookmarks, err := marshalMsgpack(bookmarkFile.Bookmarks)
+	if err != nil {
+		return nil, err
+	}
+	var sinks []byte
+	if len(bookmarkFile.Sinks) > 0 {
+		if sinks, err = marshalMsgpack(bookmarkFile.Sinks); err != nil {
+			return nil, err
+		}
+	}
+	var pending []byte
+	if len(bookmarkFile.Pending) > 0 {
+		if pending, err = marshalMsgpack(bookmarkFile.Pending); err != nil {
+			return nil, err
+		}
+	}
+	bookmarkFile.Checksum = msgpackChecksum(bookmarks, sinks, pending)
+	return marshalMsgpack(bookmarkFile)
+}
+
+// decodeMsgpackFile decodes a MessagePack bookmark file, skipping invalid
+// entries like decodeBookmarkFile. The checksum is only verified if verify is
+// set.
+func decodeMsgpackFile(data []byte, report *RecoveryReport, verify bool) (*BookmarkFile, error) {
+	var raw struct {
+		Version     string             `json:"version"`
+		CreatedAt   time.Time          `json:"created_at"`
+		UpdatedAt   time.Time          `json:"updated_at"`
+		Writer      *WriterIdentity    `json:"writer"`
+		Annotations map[string]string  `json:"annotations"`
+		Checksum    string             `json:"checksum"`
+		Bookmarks   msgpack.RawMessage `json:"bookmarks"`
+		Sinks       msgpack.RawMessage `json:"sink_positions"`
+		Pending     msgpack.RawMessage `json:"pending_commits"`
+	}
+	if err := unmarshalMsgpack(data, &raw); err != nil {
+		return nil, err
+	}
+	if verify && raw.Checksum != "" {
+		if actual := msgpackChecksum(raw.Bookmarks, raw.Sinks, raw.Pending); actual != raw.Checksum {
+			return nil, fmt.Errorf("%w, the file records %s but its content hashes to %s", ErrChecksumMismatch, raw.Checksum, actual)
+		}
+	}
+
+	bookmarkFile := BookmarkFile{
+		Version:     raw.Version,
+		CreatedAt:   raw.CreatedAt,
+		UpdatedAt:   raw.UpdatedAt,
+		Writer:      raw.Writer,
+		Annotations: raw.Annotations,
+		Checksum:    raw.Checksum,
+	}
+	if major, ok := majorVersion(bookmarkFile.Version); ok && major > maxReadableMajorVersion {
+		report.problemf("file version %s is newer than the supported versions, only known fields are read", bookmarkFile.Version)
+	}
+	if len(raw.Pending) > 0 {
+		if err := unmarshalMsgpack(raw.Pending, &bookmarkFile.Pending); err != nil {
+			return nil, fmt.Errorf("invalid pending commits: %w", err)
+		}
+	}
+	if len(raw.Sinks) > 0 {
+		if err := unmarshalMsgpack(raw.Sinks, &bookmarkFile.Sinks); err != nil {
+			return nil, fmt.Errorf("invalid sink positions: %w", err)
+		}
+	}
+
+	var entries []msgpack.RawMessage
+	if len(raw.Bookmarks) > 0 {
+		if err := unmarshalMsgpack(raw.Bookmarks, &entries); err != nil {
+			return nil, fmt.Errorf("invalid bookmarks: %w", err)
+		}
+	}
+	bookmarkFile.Bookmarks = make([]*Bookmark, 0, len(entries))
+	for i, entry := range entries {
+		var b *Bookmark
+		if err := unmarshalMsgpack(entry, &b); err != nil {
+			report.Skipped++
+			report.problemf("bookmark entry %d can't be decoded: %v", i, err)
+			continue
+		}
+		if b == nil {
+			report.Skipped++
+			report.problemf("bookmark entry %d is empty", i)
+			continue
+		}
+		b.Partition = NormalizePartition(b.Partition)
+		if err := b.validate(); err != nil {
+			report.Skipped++
+			report.problemf("bookmark entry %d is invalid: %v", i, err)
+			continue
+		}
+		bookmarkFile.Bookmarks = append(bookmarkFile.Bookmarks, b)
+	}
+	return &bookmarkFile, nil
+}
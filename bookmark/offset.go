@@ -0,0 +1,85 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sentinel offsets mark a position that consumers resolve against the source
+// rather than a processed offset, following the special offsets of the Kafka
+// ListOffsets API. OffsetNone means there is no position and the consumer
+// falls back to its own default.
+const (
+	OffsetLatest   int64 = -1
+	OffsetEarliest int64 = -2
+	OffsetNone     int64 = -3
+)
+
+var sentinelOffsetNames = map[int64]string{
+	OffsetLatest:   "latest",
+	OffsetEarliest: "earliest",
+	OffsetNone:     "none",
+}
+
+// IsSentinelOffset returns whether offset is one of the sentinel offsets
+func IsSentinelOffset(offset int64) bool {
+	_, exists := sentinelOffsetNames[offset]
+	return exists
+}
+
+// IsSentinel returns whether the bookmark holds a sentinel offset
+func (b *Bookmark) IsSentinel() bool {
+	return IsSentinelOffset(b.Offset)
+}
+
+// validateOffset accepts non-negative offsets and the sentinel offsets
+func validateOffset(offset int64) error {
+	if offset < 0 && !IsSentinelOffset(offset) {
+		return errors.New("offset must be a non-negative integer or a sentinel offset")
+	}
+	return nil
+}
+
+// ParseOffset parses a decimal offset or the name of a sentinel offset, which
+// is one of "earliest", "latest" and "none"
+func ParseOffset(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	for offset, name := range sentinelOffsetNames {
+		if strings.EqualFold(s, name) {
+			return offset, nil
+		}
+	}
+	offset, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset %q", s)
+	}
+	if err := validateOffset(offset); err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
+
+// FormatOffset formats an offset in decimal, or as the name of a sentinel
+// offset
+func FormatOffset(offset int64) string {
+	if name, exists := sentinelOffsetNames[offset]; exists {
+		return name
+	}
+	return strconv.FormatInt(offset, 10)
+}
@@ -0,0 +1,301 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// ErrBookmarkFileLocked is returned by Open when the bookmark file is owned by
+// another live manager
+var ErrBookmarkFileLocked = errors.New("bookmark file is locked by another owner")
+
+// ownerLock is the content of the owner lock file
+type ownerLock struct {
+	Owner      WriterIdentity `json:"owner"`
+	AcquiredAt time.Time      `json:"acquired_at"`
+}
+
+// WithOwnerLock enables an owner lock file next to the bookmark file which is
+// created by Open and removed by Close. The lock is refreshed on every save and
+// considered stale once it hasn't been refreshed for staleAfter, or when its
+// owner process no longer runs on this host.
+func WithOwnerLock(staleAfter time.Duration) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.ownerLockEnabled = true
+		bm.ownerLockStaleAfter = staleAfter
+	}
+}
+
+// ownerLockPath returns the path of the owner lock file
+func (bm *BookmarkManager) ownerLockPath() string {
+	return bm.filePath + ".lock"
+}
+
+// Open acquires the owner lock when enabled, loads the bookmarks from file or
+// else from the fallback stores, runs the configured cache import, populates
+// the export cache, reports the resume points, starts delivering events to the
+// event sinks and alerts to the alert webhook, and starts the mirror
+// replication, the admin and gRPC APIs, the expiry pruner, the snapshot
+// schedule, the lag reporter and the auto-save when configured
+func (bm *BookmarkManager) Open(ctx context.Context) error {
+	bm.lifecycleMutex.Lock()
+	defer bm.lifecycleMutex.Unlock()
+
+	// A shared manager is opened once by the first component
+	if bm.openRefs > 0 {
+		bm.openRefs++
+		return nil
+	}
+
+	if bm.ownerLockEnabled {
+		if err := bm.acquireOwnerLock(); err != nil {
+			return err
+		}
+	}
+	if err := bm.LoadFromFile(ctx); err != nil {
+		_ = bm.releaseOwnerLock()
+		return err
+	}
+	if err := bm.loadFromFallbacksOnOpen(ctx); err != nil {
+		_ = bm.releaseOwnerLock()
+		return err
+	}
+	if err := bm.importFromCacheOnOpen(ctx); err != nil {
+		_ = bm.releaseOwnerLock()
+		return err
+	}
+	bm.exportOnOpen()
+	bm.reportResumePoints()
+	if bm.lag != nil {
+		if err := bm.lag.start(); err != nil {
+			_ = bm.releaseOwnerLock()
+			return err
+		}
+	}
+	if err := bm.startMirrors(ctx); err != nil {
+		bm.stopLagReporter()
+		_ = bm.releaseOwnerLock()
+		return err
+	}
+	if err := bm.startAPIs(); err != nil {
+		bm.stopMirrors(ctx)
+		bm.stopLagReporter()
+		_ = bm.releaseOwnerLock()
+		return err
+	}
+	if err := bm.startEventSinks(ctx); err != nil {
+		bm.stopAPIs(ctx)
+		bm.stopMirrors(ctx)
+		bm.stopLagReporter()
+		_ = bm.releaseOwnerLock()
+		return err
+	}
+	if err := bm.startSnapshotSchedule(); err != nil {
+		bm.stopEventSinks(ctx)
+		bm.stopAPIs(ctx)
+		bm.stopMirrors(ctx)
+		bm.stopLagReporter()
+		_ = bm.releaseOwnerLock()
+		return err
+	}
+	if bm.alerts != nil {
+		bm.alerts.start()
+	}
+	bm.startPruner()
+	if bm.autoSaveInterval > 0 {
+		// The auto-save runs until Close rather than for the lifetime of ctx
+		if err := bm.StartAutoSave(context.Background(), bm.autoSaveInterval); err != nil {
+			return err
+		}
+	}
+	bm.openRefs = 1
+	return nil
+}
+
+// Close stops the APIs, pruner, snapshot schedule, lag reporter, auto-save, mirrors, event sinks
+// and alerts, and releases the owner lock acquired by Open. A shared manager is
+// only closed by the last component that opened it, which also saves the
+// bookmarks once for all of them.
+func (bm *BookmarkManager) Close(ctx context.Context) error {
+	bm.lifecycleMutex.Lock()
+	defer bm.lifecycleMutex.Unlock()
+
+	if bm.openRefs > 1 {
+		bm.openRefs--
+		return nil
+	}
+
+	// Stopped first so that changes made through the API make the final save
+	bm.stopAPIs(ctx)
+	bm.stopPruner()
+	bm.stopSnapshotSchedule()
+	bm.stopLagReporter()
+	saveErr := bm.StopAutoSave()
+	if bm.label != "" {
+		if bm.openRefs > 0 && saveErr == nil {
+			saveErr = bm.SaveToFile(ctx)
+		}
+		bm.unregisterShared()
+	}
+	bm.openRefs = 0
+	bm.stopMirrors(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, eventPublishTimeout)
+	defer cancel()
+
+	bm.stopEventSinks(ctx)
+	if bm.alerts != nil {
+		bm.alerts.close(ctx)
+	}
+	if err := bm.releaseOwnerLock(); err != nil {
+		return err
+	}
+	if saveErr != nil {
+		return fmt.Errorf("failed to save bookmarks: %w", saveErr)
+	}
+	return nil
+}
+
+func (bm *BookmarkManager) acquireOwnerLock() error {
+	bm.saveMutex.Lock()
+	defer bm.saveMutex.Unlock()
+
+	if bm.ownerLockHeld || bm.readOnly {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(bm.filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := json.Marshal(ownerLock{Owner: bm.writer, AcquiredAt: time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal owner lock: %w", err)
+	}
+
+	// Try twice, the second attempt follows the removal of a stale lock
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(bm.ownerLockPath(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, werr := f.Write(data)
+			if cerr := f.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				os.Remove(bm.ownerLockPath())
+				return fmt.Errorf("failed to write owner lock: %w", werr)
+			}
+			bm.ownerLockHeld = true
+			return nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("failed to create owner lock: %w", err)
+		}
+
+		current, stale, err := bm.inspectOwnerLock()
+		if err != nil {
+			return err
+		}
+		if !stale {
+			return fmt.Errorf("%w: %s is owned by %s since %s", ErrBookmarkFileLocked, bm.filePath, current.Owner, current.AcquiredAt.Format(time.RFC3339))
+		}
+
+		bm.log.Warnf("Removing stale owner lock of bookmark file %s held by %s", bm.filePath, current.Owner)
+		if err := os.Remove(bm.ownerLockPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove stale owner lock: %w", err)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrBookmarkFileLocked, bm.filePath)
+}
+
+// inspectOwnerLock reads the current owner lock and determines whether it's
+// stale. An unreadable lock is considered stale as it was likely left behind
+// by a crash during its creation.
+func (bm *BookmarkManager) inspectOwnerLock() (*ownerLock, bool, error) {
+	info, err := os.Stat(bm.ownerLockPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return &ownerLock{}, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to stat owner lock: %w", err)
+	}
+
+	var current ownerLock
+	data, err := os.ReadFile(bm.ownerLockPath())
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read owner lock: %w", err)
+	}
+	if err := json.Unmarshal(data, &current); err != nil {
+		return &current, true, nil
+	}
+
+	if bm.ownerLockStaleAfter > 0 && time.Since(info.ModTime()) > bm.ownerLockStaleAfter {
+		return &current, true, nil
+	}
+	if current.Owner.Host == bm.writer.Host && !processAlive(current.Owner.PID) {
+		return &current, true, nil
+	}
+	return &current, false, nil
+}
+
+// refreshOwnerLock marks a held owner lock as alive. The caller must hold
+// saveMutex.
+func (bm *BookmarkManager) refreshOwnerLock() {
+	if !bm.ownerLockHeld {
+		return
+	}
+	now := time.Now()
+	if err := os.Chtimes(bm.ownerLockPath(), now, now); err != nil {
+		bm.log.Warnf("Failed to refresh owner lock of bookmark file %s: %v", bm.filePath, err)
+	}
+}
+
+func (bm *BookmarkManager) releaseOwnerLock() error {
+	bm.saveMutex.Lock()
+	defer bm.saveMutex.Unlock()
+
+	if !bm.ownerLockHeld {
+		return nil
+	}
+	bm.ownerLockHeld = false
+
+	if err := os.Remove(bm.ownerLockPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove owner lock: %w", err)
+	}
+	return nil
+}
+
+// processAlive returns false only when it's certain that no process with the
+// given PID exists on this host
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return !errors.Is(err, os.ErrProcessDone) && !errors.Is(err, syscall.ESRCH)
+}
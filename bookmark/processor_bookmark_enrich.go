@@ -0,0 +1,102 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func bookmarkEnrichProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Summary("Attaches the current bookmark of the topic and partition of each message as metadata.").
+		Description(`
+The topic and partition of each message are resolved with Bloblang mappings and the offset and timestamp of their bookmark are added as the metadata fields ` + "`bookmark_offset`" + ` and ` + "`bookmark_timestamp`" + ` (RFC3339). This allows annotating messages with their lag behind the bookmark, or routing them conditionally, for example with ` + "`metadata(\"kafka_offset\").number() - metadata(\"bookmark_offset\")`" + `.
+
+Messages without a bookmark pass through without the metadata fields, and messages where a mapping fails are flagged with an error and passed through. The bookmarks file is read when the processor starts, unless the bookmark manager is shared through a ` + "`label`" + `, in which case the bookmarks of the other components are followed live.`).
+		Fields(topicPartitionMappingFields()...).
+		Fields(BookmarkFileManagerConfigFields()...)
+}
+
+func init() {
+	service.MustRegisterBatchProcessor("bookmark_enrich", bookmarkEnrichProcessorSpec(),
+		func(pConf *service.ParsedConfig, res *service.Resources) (service.BatchProcessor, error) {
+			return newBookmarkEnrichProcessorFromParsed(pConf, res)
+		})
+}
+
+//------------------------------------------------------------------------------
+
+type bookmarkEnrichProcessor struct {
+	position positionMappings
+
+	bm  *BookmarkManager
+	log *service.Logger
+}
+
+func newBookmarkEnrichProcessorFromParsed(pConf *service.ParsedConfig, res *service.Resources) (*bookmarkEnrichProcessor, error) {
+	p := &bookmarkEnrichProcessor{log: res.Logger()}
+
+	var err error
+	if p.position, err = topicPartitionMappingsFromParsed(pConf); err != nil {
+		return nil, err
+	}
+
+	if p.bm, err = BookmarkFileManagerFromParsed(pConf, WithResources(res)); err != nil {
+		return nil, err
+	}
+	// As with bookmark_filter a shared manager is opened, otherwise the file is
+	// only read
+	if p.bm.label != "" {
+		err = p.bm.Open(context.Background())
+	} else {
+		err = p.bm.LoadFromFile(context.Background())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bookmarks: %w", err)
+	}
+	return p, nil
+}
+
+func (p *bookmarkEnrichProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	for i, msg := range batch {
+		topic, partition, err := p.position.resolveTopicPartition(batch, i)
+		if err != nil {
+			p.log.Errorf("Failed to resolve message position: %v", err)
+			msg.SetError(err)
+			continue
+		}
+
+		b, err := p.bm.GetBookmark(topic, partition)
+		if err != nil {
+			continue
+		}
+		msg.MetaSetMut(MetaOffset, b.Offset)
+		msg.MetaSetMut(MetaTimestamp, b.Timestamp.Format(time.RFC3339))
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (p *bookmarkEnrichProcessor) Close(ctx context.Context) error {
+	if p.bm.label != "" {
+		return p.bm.Close(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	bfpFieldAction = "action"
+
+	bfpActionDrop = "drop"
+	bfpActionFlag = "flag"
+
+	// MetaProcessed is the metadata key set by the bookmark_filter processor
+	// to flag messages at or below their bookmark
+	MetaProcessed = "bookmark_processed"
+)
+
+func bookmarkFilterProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Summary("Drops or flags messages at or below the offset of their bookmark, which have already been processed.").
+		Description(`
+The topic, partition and offset of each message are resolved with Bloblang mappings and compared against the bookmarks loaded from the bookmarks file when the processor starts. Messages at or below the bookmarked offset are dropped, or flagged with the metadata field ` + "`bookmark_processed`" + ` set to ` + "`true`" + ` (and ` + "`false`" + ` for other messages), giving idempotent re-consumption when inputs overlap after a failover.
+
+The bookmarks file is only read, so it is typically shared with a ` + "`bookmark_set`" + ` processor placed after the processing steps that commit the bookmarks. Messages without a bookmark pass through, and messages where a mapping fails are flagged with an error and passed through.`).
+		Fields(positionMappingFields()...).
+		Fields(
+			service.NewStringEnumField(bfpFieldAction, bfpActionDrop, bfpActionFlag).
+				Description("Whether to drop already processed messages or to flag them with metadata.").
+				Default(bfpActionDrop),
+		).
+		Fields(BookmarkFileManagerConfigFields()...)
+}
+
+func init() {
+	service.MustRegisterBatchProcessor("bookmark_filter", bookmarkFilterProcessorSpec(),
+		func(pConf *service.ParsedConfig, res *service.Resources) (service.BatchProcessor, error) {
+			return newBookmarkFilterProcessorFromParsed(pConf, res)
+		})
+}
+
+//------------------------------------------------------------------------------
+
+type bookmarkFilterProcessor struct {
+	position positionMappings
+	drop     bool
+
+	bm  *BookmarkManager
+	log *service.Logger
+}
+
+func newBookmarkFilterProcessorFromParsed(pConf *service.ParsedConfig, res *service.Resources) (*bookmarkFilterProcessor, error) {
+	p := &bookmarkFilterProcessor{log: res.Logger()}
+
+	var err error
+	if p.position, err = positionMappingsFromParsed(pConf); err != nil {
+		return nil, err
+	}
+	action, err := pConf.FieldString(bfpFieldAction)
+	if err != nil {
+		return nil, err
+	}
+	p.drop = action == bfpActionDrop
+
+	if p.bm, err = BookmarkFileManagerFromParsed(pConf, WithResources(res)); err != nil {
+		return nil, err
+	}
+	// A shared manager is opened to follow the bookmarks of the other
+	// components live. Otherwise the file is only read, the owner lock, cache
+	// import and event sinks belong to the component writing the bookmarks.
+	if p.bm.label != "" {
+		err = p.bm.Open(context.Background())
+	} else {
+		err = p.bm.LoadFromFile(context.Background())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bookmarks: %w", err)
+	}
+	return p, nil
+}
+
+func (p *bookmarkFilterProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	filtered := batch[:0:0]
+	for i, msg := range batch {
+		topic, partition, offset, err := p.position.resolve(batch, i)
+		if err != nil {
+			p.log.Errorf("Failed to resolve message position: %v", err)
+			msg.SetError(err)
+			filtered = append(filtered, msg)
+			continue
+		}
+
+		processed := false
+		if b, err := p.bm.GetBookmark(topic, partition); err == nil {
+			processed = offset <= b.Offset
+		}
+
+		if processed && p.drop {
+			continue
+		}
+		if !p.drop {
+			msg.MetaSetMut(MetaProcessed, processed)
+		}
+		filtered = append(filtered, msg)
+	}
+
+	if len(filtered) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{filtered}, nil
+}
+
+func (p *bookmarkFilterProcessor) Close(ctx context.Context) error {
+	if p.bm.label != "" {
+		return p.bm.Close(ctx)
+	}
+	return nil
+}
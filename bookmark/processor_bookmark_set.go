@@ -0,0 +1,275 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	bspFieldTopic     = "topic"
+	bspFieldPartition = "partition"
+	bspFieldOffset    = "offset"
+	bspFieldTimestamp = "timestamp"
+)
+
+func bookmarkSetProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Summary("Stores a bookmark for each message, where the topic, partition and offset are extracted from the message with Bloblang mappings.").
+		Description(`
+The bookmarks of a batch are saved to the bookmarks file once the whole batch has been processed. Messages are passed through unchanged, and messages where a mapping fails are flagged with an error and do not update any bookmark. Existing bookmarks keep their metadata, only their offsets and timestamps are updated.
+
+By default the mappings read the ` + "`kafka_topic`, `kafka_partition`, `kafka_offset` and `kafka_timestamp_ms`" + ` metadata set by the Kafka inputs, so no mapping config is needed when consuming from Kafka or Redpanda.`).
+		Fields(positionMappingFields()...).
+		Fields(
+			service.NewBloblangField(bspFieldTimestamp).
+				Description("A Bloblang mapping that resolves the bookmark timestamp. Numbers are interpreted as unix milliseconds and strings as RFC 3339 timestamps. When the mapping resolves to `null` the current time is used.").
+				Default(`metadata("kafka_timestamp_ms")`).
+				Example(`this.source.created_at`),
+		).
+		Fields(BookmarkFileManagerConfigFields()...)
+}
+
+func init() {
+	service.MustRegisterBatchProcessor("bookmark_set", bookmarkSetProcessorSpec(),
+		func(pConf *service.ParsedConfig, res *service.Resources) (service.BatchProcessor, error) {
+			return newBookmarkSetProcessorFromParsed(pConf, res)
+		})
+}
+
+//------------------------------------------------------------------------------
+
+// topicPartitionMappingFields returns the mapping fields resolving the topic
+// and partition of a message, which default to the kafka_* metadata
+func topicPartitionMappingFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewBloblangField(bspFieldTopic).
+			Description("A Bloblang mapping that resolves the bookmark topic.").
+			Default(`metadata("kafka_topic")`).
+			Example(`this.source.stream`),
+		service.NewBloblangField(bspFieldPartition).
+			Description("A Bloblang mapping that resolves the bookmark partition. Numeric values are converted to strings.").
+			Default(`metadata("kafka_partition")`).
+			Example(`this.source.shard`),
+	}
+}
+
+// positionMappingFields returns the mapping fields resolving the topic,
+// partition and offset of a message, which default to the kafka_* metadata
+func positionMappingFields() []*service.ConfigField {
+	return append(topicPartitionMappingFields(),
+		service.NewBloblangField(bspFieldOffset).
+			Description("A Bloblang mapping that resolves the bookmark offset, which must be a non-negative integer or one of the sentinel offsets `earliest`, `latest` and `none`.").
+			Default(`metadata("kafka_offset")`).
+			Example(`this.source.sequence`),
+	)
+}
+
+// positionMappings resolve the position of a message within its source
+type positionMappings struct {
+	topic     *bloblang.Executor
+	partition *bloblang.Executor
+	offset    *bloblang.Executor
+}
+
+// topicPartitionMappingsFromParsed parses the fields of
+// topicPartitionMappingFields, leaving the offset mapping unset
+func topicPartitionMappingsFromParsed(pConf *service.ParsedConfig) (m positionMappings, err error) {
+	if m.topic, err = pConf.FieldBloblang(bspFieldTopic); err != nil {
+		return
+	}
+	m.partition, err = pConf.FieldBloblang(bspFieldPartition)
+	return
+}
+
+func positionMappingsFromParsed(pConf *service.ParsedConfig) (m positionMappings, err error) {
+	if m, err = topicPartitionMappingsFromParsed(pConf); err != nil {
+		return
+	}
+	m.offset, err = pConf.FieldBloblang(bspFieldOffset)
+	return
+}
+
+// resolveTopicPartition executes the topic and partition mappings against a
+// message of a batch
+func (m positionMappings) resolveTopicPartition(batch service.MessageBatch, i int) (topic, partition string, err error) {
+	topicV, err := queryMessageValue(batch, i, m.topic)
+	if err != nil {
+		return "", "", fmt.Errorf("topic mapping failed: %w", err)
+	}
+	topic, ok := topicV.(string)
+	if !ok {
+		return "", "", fmt.Errorf("topic mapping returned a non-string value: %T", topicV)
+	}
+
+	partitionV, err := queryMessageValue(batch, i, m.partition)
+	if err != nil {
+		return "", "", fmt.Errorf("partition mapping failed: %w", err)
+	}
+	if partition, err = partitionFromValue(partitionV); err != nil {
+		return "", "", err
+	}
+	return topic, partition, nil
+}
+
+// resolve executes the mappings against a message of a batch
+func (m positionMappings) resolve(batch service.MessageBatch, i int) (topic, partition string, offset int64, err error) {
+	if topic, partition, err = m.resolveTopicPartition(batch, i); err != nil {
+		return "", "", 0, err
+	}
+
+	offsetV, err := queryMessageValue(batch, i, m.offset)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("offset mapping failed: %w", err)
+	}
+	if offset, err = offsetFromValue(offsetV); err != nil {
+		return "", "", 0, err
+	}
+	return topic, partition, offset, nil
+}
+
+type bookmarkSetProcessor struct {
+	position  positionMappings
+	timestamp *bloblang.Executor
+
+	bm  *BookmarkManager
+	log *service.Logger
+}
+
+func newBookmarkSetProcessorFromParsed(pConf *service.ParsedConfig, res *service.Resources) (*bookmarkSetProcessor, error) {
+	p := &bookmarkSetProcessor{log: res.Logger()}
+
+	var err error
+	if p.position, err = positionMappingsFromParsed(pConf); err != nil {
+		return nil, err
+	}
+	if p.timestamp, err = pConf.FieldBloblang(bspFieldTimestamp); err != nil {
+		return nil, err
+	}
+
+	if p.bm, err = BookmarkFileManagerFromParsed(pConf, WithResources(res)); err != nil {
+		return nil, err
+	}
+	if err := p.bm.Open(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to open bookmarks: %w", err)
+	}
+	return p, nil
+}
+
+// queryMessageValue executes a mapping against a message of a batch and
+// returns the raw result, where non-structured results are returned as strings
+// and null results as nil
+func queryMessageValue(batch service.MessageBatch, i int, exec *bloblang.Executor) (any, error) {
+	res, err := batch.BloblangExecutor(exec).Query(i)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, bloblang.ErrRootDeleted
+	}
+	if res.HasStructured() {
+		return res.AsStructured()
+	}
+	data, err := res.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	// A null result loses its structure and is serialized as JSON
+	if string(data) == "null" {
+		return nil, nil
+	}
+	return string(data), nil
+}
+
+func (p *bookmarkSetProcessor) bookmarkFromMessage(batch service.MessageBatch, i int) (*Bookmark, error) {
+	topic, partition, offset, err := p.position.resolve(batch, i)
+	if err != nil {
+		return nil, err
+	}
+
+	timestampV, err := queryMessageValue(batch, i, p.timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("timestamp mapping failed: %w", err)
+	}
+	timestamp, err := timestampFromValue(timestampV)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBookmarkWithTimestamp(topic, partition, offset, timestamp, nil)
+}
+
+// timestampFromValue converts a unix milliseconds number or a timestamp string
+// into a time, falling back to the current time for null values
+func timestampFromValue(v any) (time.Time, error) {
+	if v == nil {
+		return time.Now(), nil
+	}
+	if _, isString := v.(string); !isString {
+		if ms, err := bloblang.ValueAsInt64(v); err == nil {
+			return time.UnixMilli(ms), nil
+		}
+	}
+	t, err := bloblang.ValueAsTimestamp(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp: %v", err)
+	}
+	return t, nil
+}
+
+// setBookmark updates the offset and timestamp of an existing bookmark, keeping
+// its metadata, or adds the bookmark when it doesn't exist
+func (p *bookmarkSetProcessor) setBookmark(b *Bookmark) error {
+	err := p.bm.UpdateOffsetAt(b.Topic, b.Partition, b.Offset, b.Timestamp)
+	if errors.Is(err, ErrBookmarkNotFound) {
+		return p.bm.AddBookmark(b)
+	}
+	return err
+}
+
+func (p *bookmarkSetProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	updated := 0
+	for i, msg := range batch {
+		b, err := p.bookmarkFromMessage(batch, i)
+		if err == nil {
+			err = p.setBookmark(b)
+		}
+		if err != nil {
+			p.log.Errorf("Failed to set bookmark: %v", err)
+			msg.SetError(err)
+			continue
+		}
+		updated++
+	}
+
+	if updated > 0 {
+		if err := p.bm.RequestSave(ctx); err != nil {
+			return nil, fmt.Errorf("failed to save bookmarks: %w", err)
+		}
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (p *bookmarkSetProcessor) Close(ctx context.Context) error {
+	return p.bm.Close(ctx)
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// MaxQueryRows is the number of rows a query returns at most
+const MaxQueryRows = 10000
+
+// QueryResult holds the columns and rows returned by a query, and whether the
+// rows were truncated to MaxQueryRows
+type QueryResult struct {
+	Columns   []string `json:"columns"`
+	Rows      [][]any  `json:"rows"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+// Query runs a read-only SQL query against a snapshot of the bookmarks, for
+// ad-hoc analysis such as:
+//
+//	SELECT topic, count(*), max(timestamp) FROM bookmarks GROUP BY topic
+//
+// The snapshot holds the tables bookmarks (grp, topic, partition, offset,
+// timestamp, metadata) and sink_positions (sink, sequence, id, timestamp),
+// where grp is the consumer group of the bookmark and empty for the default
+// group. Timestamps are RFC 3339 strings in UTC, which sort chronologically, and
+// metadata is a JSON object usable with the SQLite JSON functions. Queries
+// can't write to the snapshot or attach other databases, and return up to
+// MaxQueryRows rows.
+func (bm *BookmarkManager) Query(ctx context.Context, query string) (*QueryResult, error) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// Every connection has its own in-memory database
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := bm.loadQuerySnapshot(ctx, conn); err != nil {
+		return nil, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+		return nil, err
+	}
+	// Otherwise queries could read the databases of the host
+	if _, err := sqlite.Limit(conn, sqlite3.SQLITE_LIMIT_ATTACHED, 0); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &QueryResult{Rows: [][]any{}}
+	if result.Columns, err = rows.Columns(); err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		if len(result.Rows) == MaxQueryRows {
+			result.Truncated = true
+			break
+		}
+		row := make([]any, len(result.Columns))
+		ptrs := make([]any, len(row))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[i] = string(b)
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, rows.Err()
+}
+
+// queryTimeFormat is a fixed width UTC timestamp format, so that timestamps
+// compare chronologically as strings
+const queryTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+func (bm *BookmarkManager) loadQuerySnapshot(ctx context.Context, db *sql.Conn) error {
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE bookmarks (grp TEXT NOT NULL, topic TEXT NOT NULL, partition TEXT NOT NULL, offset INTEGER NOT NULL, timestamp TEXT NOT NULL, metadata TEXT NOT NULL, PRIMARY KEY (grp, topic, partition));
+CREATE TABLE sink_positions (sink TEXT PRIMARY KEY, sequence INTEGER NOT NULL, id TEXT NOT NULL, timestamp TEXT NOT NULL);
+`); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO bookmarks VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if err := bm.ForEach(func(b *Bookmark) error {
+		metadata, err := json.Marshal(b.Metadata)
+		if err != nil {
+			return err
+		}
+		_, err = stmt.ExecContext(ctx, b.Group, b.Topic, b.Partition, b.Offset, b.Timestamp.UTC().Format(queryTimeFormat), string(metadata))
+		return err
+	}); err != nil {
+		return err
+	}
+	for _, p := range bm.GetAllSinkPositions() {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO sink_positions VALUES (?, ?, ?, ?)",
+			p.Sink, p.Sequence, p.ID, p.Timestamp.UTC().Format(queryTimeFormat)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
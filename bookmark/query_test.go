@@ -0,0 +1,78 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery(t *testing.T) {
+	bm := NewBookmarkManager("")
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 10}))
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "1", Offset: 5}))
+	require.NoError(t, bm.AddBookmark(&Bookmark{Group: "audit", Topic: "orders", Partition: "0", Offset: 20}))
+
+	tests := []struct {
+		name      string
+		query     string
+		columns   []string
+		rows      [][]any
+		truncated bool
+		err       bool
+	}{
+		{
+			name:    "groups share a partition",
+			query:   "SELECT grp, offset FROM bookmarks WHERE topic = 'orders' AND partition = '0' ORDER BY grp",
+			columns: []string{"grp", "offset"},
+			rows:    [][]any{{"", int64(10)}, {"audit", int64(20)}},
+		},
+		{
+			name:    "aggregates",
+			query:   "SELECT topic, count(*), max(offset) FROM bookmarks WHERE grp = '' GROUP BY topic",
+			columns: []string{"topic", "count(*)", "max(offset)"},
+			rows:    [][]any{{"orders", int64(2), int64(10)}},
+		},
+		{
+			name:      "truncates rows",
+			query:     "WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n LIMIT 20000) SELECT x FROM n",
+			columns:   []string{"x"},
+			truncated: true,
+		},
+		{name: "rejects writes", query: "DELETE FROM bookmarks", err: true},
+		{name: "rejects attaching databases", query: "ATTACH 'other.db' AS other", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := bm.Query(context.Background(), test.query)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.columns, result.Columns)
+			assert.Equal(t, test.truncated, result.Truncated)
+			if test.truncated {
+				assert.Len(t, result.Rows, MaxQueryRows)
+				return
+			}
+			assert.Equal(t, test.rows, result.Rows)
+		})
+	}
+}
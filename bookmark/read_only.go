@@ -0,0 +1,73 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"errors"
+)
+
+// ErrReadOnly is returned by operations that exist only to write files, such
+// as backups and snapshots, when the manager is read-only
+var ErrReadOnly = errors.New("bookmark manager is read-only")
+
+// WithReadOnly sets whether the manager is read-only, for shadow deployments
+// validating a pipeline against the bookmarks of production. A read-only
+// manager loads the bookmarks and changes them in memory as usual, but saves
+// only log and count the changes they would have written, as the
+// bookmark_read_only_skipped_writes_total counter, and discard them. The owner
+// lock, write-ahead log truncation, cache exports, mirrors and audit log are
+// skipped as well, so that the files and stores of production stay untouched.
+func WithReadOnly(enabled bool) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.readOnly = enabled
+	}
+}
+
+// ReadOnly returns whether the manager is read-only
+func (bm *BookmarkManager) ReadOnly() bool {
+	return bm.readOnly
+}
+
+// skipSave records what a save of a read-only manager would have written and
+// forgets the changes, so that the next save only reports newer ones
+func (bm *BookmarkManager) skipSave(compact bool) {
+	bm.saveMutex.Lock()
+	defer bm.saveMutex.Unlock()
+
+	bm.mutex.Lock()
+	keys := bm.dirty.bookmarkKeys()
+	changes := bm.dirty.len()
+	log := bm.log.With("compact", compact)
+	for _, tp := range keys {
+		if b, exists := bm.bookmarks.get(tp); exists {
+			log.Debugf("Read-only, not saving bookmark %s/%s at offset %s", tp.Topic, tp.Partition, FormatOffset(b.Offset))
+		} else {
+			log.Debugf("Read-only, not saving the removal of bookmark %s/%s", tp.Topic, tp.Partition)
+		}
+	}
+	bm.dirty.reset()
+	bm.mutex.Unlock()
+
+	if bm.audit != nil {
+		bm.audit.discard()
+	}
+	if changes == 0 {
+		return
+	}
+	log.Infof("Read-only, skipped saving %d changes", changes)
+	if bm.metrics != nil {
+		bm.metrics.skippedWrites.Incr(int64(changes))
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"sort"
+)
+
+// readSnapshot is an immutable copy of all bookmarks, sorted like
+// sortedBookmarks, shared by readers until a bookmark changes
+type readSnapshot struct {
+	generation uint64
+	bookmarks  []*Bookmark
+}
+
+// readSnapshot returns the current snapshot of the bookmarks, building it when
+// bookmarks changed since the last one was built. The read lock is only held
+// while the bookmarks are copied, sorting happens without it so that large
+// reads don't stall writers.
+func (bm *BookmarkManager) readSnapshot() *readSnapshot {
+	if snap := bm.snapshot.Load(); snap != nil && snap.generation == bm.dirty.generation.Load() {
+		return snap
+	}
+
+	bm.mutex.RLock()
+	snap := &readSnapshot{
+		generation: bm.dirty.generation.Load(),
+		bookmarks:  bm.bookmarks.clones(),
+	}
+	bm.mutex.RUnlock()
+
+	sort.Slice(snap.bookmarks, func(i, j int) bool {
+		return lessBookmark(snap.bookmarks[i], snap.bookmarks[j])
+	})
+	bm.snapshot.Store(snap)
+	return snap
+}
@@ -0,0 +1,196 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RecoveryReport describes the state the manager resumed from during the last
+// load
+type RecoveryReport struct {
+	LoadedAt            time.Time `json:"loaded_at"`
+	Source              string    `json:"source,omitempty"`
+	Loaded              int       `json:"loaded"`
+	Repaired            int       `json:"repaired"`
+	Skipped             int       `json:"skipped"`
+	Replayed            int       `json:"replayed,omitempty"`
+	MigratedFrom        string    `json:"migrated_from,omitempty"`
+	RecoveredFromBackup bool      `json:"recovered_from_backup"`
+	Problems            []string  `json:"problems,omitempty"`
+}
+
+// String returns a one line summary of the report
+func (r RecoveryReport) String() string {
+	if r.Source == "" {
+		return "no bookmark file found, starting with empty bookmarks"
+	}
+	s := fmt.Sprintf("loaded %d entries from %s (repaired: %d, skipped: %d, recovered from backup: %t)",
+		r.Loaded, r.Source, r.Repaired, r.Skipped, r.RecoveredFromBackup)
+	if r.Replayed > 0 {
+		s += fmt.Sprintf(", replayed %d write-ahead log entries", r.Replayed)
+	}
+	if r.MigratedFrom != "" {
+		s += fmt.Sprintf(", migrated from version %s", r.MigratedFrom)
+	}
+	return s
+}
+
+// GetRecoveryReport returns the report of the last load
+func (bm *BookmarkManager) GetRecoveryReport() RecoveryReport {
+	bm.mutex.RLock()
+	defer bm.mutex.RUnlock()
+
+	report := bm.recovery
+	report.Problems = append([]string(nil), report.Problems...)
+	return report
+}
+
+func (r *RecoveryReport) problemf(format string, args ...any) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// logRecoveryReport logs the report, at warning level if the loaded state is
+// not exactly what was last saved
+func (bm *BookmarkManager) logRecoveryReport(report RecoveryReport) {
+	log := bm.log.With(
+		"source", report.Source,
+		"loaded", report.Loaded,
+		"repaired", report.Repaired,
+		"skipped", report.Skipped,
+		"recovered_from_backup", report.RecoveredFromBackup,
+	)
+	if report.Repaired == 0 && report.Skipped == 0 && !report.RecoveredFromBackup {
+		log.Infof("Bookmark recovery: %s", report)
+		return
+	}
+	log.Warnf("Bookmark recovery: %s", report)
+	for _, p := range report.Problems {
+		log.Warnf("Bookmark recovery: %s", p)
+	}
+}
+
+// readBackupFile reads the newest backup, or else the newest rotated segment,
+// which are the most recent complete copies of the state other than the
+// active file. It returns a nil file when there's no usable copy.
+func (bm *BookmarkManager) readBackupFile(report *RecoveryReport) *BookmarkFile {
+	if bookmarkFile := bm.readBackups(report); bookmarkFile != nil {
+		return bookmarkFile
+	}
+
+	index, err := bm.readSegmentIndex()
+	if err != nil {
+		report.problemf("failed to read segment index: %v", err)
+		return nil
+	}
+
+	for i := len(index.Segments) - 1; i >= 0; i-- {
+		path := filepath.Join(filepath.Dir(bm.filePath), index.Segments[i].File)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			report.problemf("failed to read segment %s: %v", path, err)
+			continue
+		}
+
+		bookmarkFile, err := decodeBookmarkFile(data, report)
+		if err != nil {
+			report.problemf("failed to unmarshal segment %s: %v", path, err)
+			continue
+		}
+
+		report.Source = path
+		report.RecoveredFromBackup = true
+		return bookmarkFile
+	}
+	return nil
+}
+
+// applyBookmarkFile replaces the state of the manager with the content of a
+// file returned by decodeBookmarkFile. Entries that can be repaired are fixed
+// up and counted, invalid sink positions are skipped. The caller must hold
+// saveMutex and mutex.
+func (bm *BookmarkManager) applyBookmarkFile(bookmarkFile *BookmarkFile, report *RecoveryReport) {
+	bm.bookmarks.reset()
+	bm.createdAt = bookmarkFile.CreatedAt
+	bm.dirty.generation.Add(1)
+
+	for _, bookmark := range bookmarkFile.Bookmarks {
+		repaired := false
+		if bookmark.Metadata == nil {
+			bookmark.Metadata = make(map[string]interface{})
+			repaired = true
+		}
+		if bookmark.Timestamp.IsZero() {
+			bookmark.Timestamp = bookmarkFile.UpdatedAt
+			repaired = true
+		}
+
+		key := bookmark.TopicPartition()
+		if existing, exists := bm.bookmarks.get(key); exists {
+			// Keep the furthest position of duplicated entries
+			report.Repaired++
+			report.problemf("duplicate bookmark for %s/%s, kept offset %d", bookmark.Topic, bookmark.Partition, max(existing.Offset, bookmark.Offset))
+			if existing.Offset >= bookmark.Offset {
+				continue
+			}
+			bm.bookmarks.set(key, bookmark)
+			continue
+		}
+		if repaired {
+			report.Repaired++
+		}
+		bm.bookmarks.set(key, bookmark)
+		report.Loaded++
+	}
+
+	bm.pending = make(map[string]*PendingCommit)
+	for i, c := range bookmarkFile.Pending {
+		if c == nil || c.ID == "" {
+			report.Skipped++
+			report.problemf("pending commit entry %d is invalid", i)
+			continue
+		}
+		bm.pending[c.ID] = c
+	}
+
+	bm.sinks = make(map[string]*SinkPosition)
+	for i, p := range bookmarkFile.Sinks {
+		if p == nil {
+			report.Skipped++
+			report.problemf("sink position entry %d is empty", i)
+			continue
+		}
+		if err := p.validate(); err != nil {
+			report.Skipped++
+			report.problemf("sink position entry %d is invalid: %v", i, err)
+			continue
+		}
+		if existing, exists := bm.sinks[p.Sink]; exists {
+			report.Repaired++
+			report.problemf("duplicate sink position for %s, kept sequence %d", p.Sink, max(existing.Sequence, p.Sequence))
+			if existing.Sequence >= p.Sequence {
+				continue
+			}
+			bm.sinks[p.Sink] = p
+			continue
+		}
+		bm.sinks[p.Sink] = p
+		report.Loaded++
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrOffsetRegression is returned when an update would move the offset of a
+// bookmark backwards under the reject regression policy
+var ErrOffsetRegression = errors.New("offset regression")
+
+// errRegressionIgnored is returned by checkRegression for updates that are
+// dropped under the ignore regression policy, which callers skip without
+// failing
+var errRegressionIgnored = errors.New("offset regression ignored")
+
+// RegressionPolicy determines how updates that move the offset of a bookmark
+// backwards are handled
+type RegressionPolicy string
+
+// Regression policies. Reject and ignore keep offsets strictly monotonic, the
+// former failing updates that move an offset backwards and the latter
+// logging and dropping them, so that a retried batch can't rewind committed
+// progress.
+const (
+	RegressionAllow  RegressionPolicy = "allow"
+	RegressionWarn   RegressionPolicy = "warn"
+	RegressionReject RegressionPolicy = "reject"
+	RegressionIgnore RegressionPolicy = "ignore"
+)
+
+// WithRegressionPolicy sets how updates moving an offset backwards are
+// handled, for all updates of the manager including imports. Regressions are
+// allowed by default. ForceSetOffset, Restore and forced resets of
+// ResetTopic move offsets backwards regardless of the policy.
+func WithRegressionPolicy(policy RegressionPolicy) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.regressionPolicy = policy
+	}
+}
+
+// checkRegression applies the regression policy to an update of the previous
+// bookmark, which is nil for new bookmarks, to the given offset. Updates from
+// or to a sentinel offset are deliberate resets and always allowed.
+func (bm *BookmarkManager) checkRegression(previous *Bookmark, offset int64) error {
+	if previous == nil || offset >= previous.Offset || previous.IsSentinel() || IsSentinelOffset(offset) {
+		return nil
+	}
+
+	switch bm.regressionPolicy {
+	case RegressionReject:
+		return fmt.Errorf("%w: bookmark %s/%s is at offset %d, refusing to move back to %d", ErrOffsetRegression, previous.Topic, previous.Partition, previous.Offset, offset)
+	case RegressionIgnore:
+		bm.log.Warnf("Ignored update moving bookmark %s/%s back from offset %d to %d", previous.Topic, previous.Partition, previous.Offset, offset)
+		return errRegressionIgnored
+	case RegressionWarn:
+		bm.log.Warnf("Bookmark %s/%s moved back from offset %d to %d", previous.Topic, previous.Partition, previous.Offset, offset)
+	}
+	return nil
+}
+
+// ForceSetOffset sets the offset of a bookmark regardless of the regression
+// policy and leader epoch, creating the bookmark when it's missing, and sets
+// its timestamp to the current time. It's meant for deliberate rewinds, such
+// as replaying a partition, under a policy that otherwise rejects them.
+func (bm *BookmarkManager) ForceSetOffset(topic, partition string, offset int64) error {
+	key := bm.generateKey(topic, partition)
+	now := time.Now()
+	created := &Bookmark{
+		Group:     key.Group,
+		Topic:     key.Topic,
+		Partition: key.Partition,
+		Offset:    offset,
+		Timestamp: now,
+		Metadata:  make(map[string]interface{}),
+		Version:   nextVersion(nil),
+	}
+	if err := bm.validateBookmark(created); err != nil {
+		return fmt.Errorf("invalid bookmark: %w", err)
+	}
+
+	defer bm.dispatchEvents()
+	bm.mutex.Lock()
+	defer bm.mutex.Unlock()
+
+	bookmark, exists := bm.bookmarks.get(key)
+	if !exists {
+		ev, _ := changeEvent(nil, created)
+		bm.emit(ev)
+		bm.bookmarks.set(key, created)
+		bm.dirty.markBookmark(key)
+		return nil
+	}
+	if offset < bookmark.Offset {
+		bm.log.Infof("Forced bookmark %s/%s back from offset %d to %d", topic, partition, bookmark.Offset, offset)
+	}
+	return bm.applyOffsetLocked(key, bookmark, offset, now, offsetOpts{force: true})
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegressionPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RegressionPolicy
+		from   int64
+		to     int64
+		err    error
+		stored int64
+	}{
+		{name: "allow moves back", policy: RegressionAllow, from: 10, to: 5, stored: 5},
+		{name: "warn moves back", policy: RegressionWarn, from: 10, to: 5, stored: 5},
+		{name: "reject fails", policy: RegressionReject, from: 10, to: 5, err: ErrOffsetRegression, stored: 10},
+		{name: "ignore drops", policy: RegressionIgnore, from: 10, to: 5, stored: 10},
+		{name: "reject moves forward", policy: RegressionReject, from: 10, to: 15, stored: 15},
+		{name: "reject resets to a sentinel", policy: RegressionReject, from: 10, to: OffsetEarliest, stored: OffsetEarliest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bm := NewBookmarkManager("", WithRegressionPolicy(test.policy))
+			require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: test.from}))
+
+			err := bm.UpdateOffset("orders", "0", test.to)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			b, err := bm.GetBookmark("orders", "0")
+			require.NoError(t, err)
+			assert.Equal(t, test.stored, b.Offset)
+		})
+	}
+}
+
+func TestForceSetOffsetIgnoresPolicy(t *testing.T) {
+	bm := NewBookmarkManager("", WithRegressionPolicy(RegressionReject))
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 10}))
+
+	require.NoError(t, bm.ForceSetOffset("orders", "0", 5))
+	b, err := bm.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), b.Offset)
+	assert.Equal(t, int64(2), b.Version)
+}
+
+func TestResetTopicAppliesPolicy(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		policy   RegressionPolicy
+		strategy ResetStrategy
+		err      error
+		reset    int
+		stored   [2]int64
+	}{
+		{name: "reject fails", policy: RegressionReject, strategy: ResetToOffset(8), err: ErrOffsetRegression, stored: [2]int64{10, 5}},
+		{name: "ignore keeps rewound partitions", policy: RegressionIgnore, strategy: ResetToOffset(8), reset: 1, stored: [2]int64{10, 7}},
+		{name: "allow moves back", policy: RegressionAllow, strategy: ResetToOffset(8), reset: 2, stored: [2]int64{7, 7}},
+		{name: "reject resets to earliest", policy: RegressionReject, strategy: ResetToEarliest(), reset: 2, stored: [2]int64{OffsetEarliest, OffsetEarliest}},
+		{name: "forced reset ignores policy", policy: RegressionReject, strategy: ResetStrategy{Kind: ResetOffset, Offset: 8, Force: true}, reset: 2, stored: [2]int64{7, 7}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bm := NewBookmarkManager("", WithRegressionPolicy(test.policy))
+			require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 10}))
+			require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "1", Offset: 5}))
+
+			reset, err := bm.ResetTopic(ctx, "orders", test.strategy)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.reset, reset)
+
+			for i, partition := range []string{"0", "1"} {
+				b, err := bm.GetBookmark("orders", partition)
+				require.NoError(t, err)
+				assert.Equal(t, test.stored[i], b.Offset, "offset of partition %s", partition)
+			}
+		})
+	}
+}
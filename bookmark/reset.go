@@ -0,0 +1,193 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+)
+
+// ResetKind is how a reset moves the bookmarks of a topic
+type ResetKind string
+
+// Kinds of resets
+const (
+	ResetEarliest  ResetKind = "earliest"
+	ResetLatest    ResetKind = "latest"
+	ResetOffset    ResetKind = "offset"
+	ResetTimestamp ResetKind = "timestamp"
+)
+
+// ResetStrategy describes where the partitions of a topic resume after a
+// reset. Offset is the offset consumption resumes at for ResetOffset, and
+// timestamp resets resume at the first records produced at or after
+// Timestamp, which are looked up with Admin. Forced resets move offsets
+// backwards regardless of the regression policy.
+type ResetStrategy struct {
+	Kind      ResetKind
+	Offset    int64
+	Timestamp time.Time
+	Admin     *kadm.Client
+	Force     bool
+}
+
+// ResetToEarliest resumes partitions at their earliest offset
+func ResetToEarliest() ResetStrategy {
+	return ResetStrategy{Kind: ResetEarliest}
+}
+
+// ResetToLatest resumes partitions at their end
+func ResetToLatest() ResetStrategy {
+	return ResetStrategy{Kind: ResetLatest}
+}
+
+// ResetToOffset resumes partitions at the given offset
+func ResetToOffset(offset int64) ResetStrategy {
+	return ResetStrategy{Kind: ResetOffset, Offset: offset}
+}
+
+// ResetToTimestamp resumes partitions at the first records produced at or
+// after a time, looking up their offsets with the given admin client
+func ResetToTimestamp(at time.Time, admin *kadm.Client) ResetStrategy {
+	return ResetStrategy{Kind: ResetTimestamp, Timestamp: at, Admin: admin}
+}
+
+// ResetTopic moves the bookmarks of a topic in the group of the manager by
+// the given strategy and returns the number of bookmarks reset. Earliest,
+// latest and offset resets apply to the existing bookmarks of the topic,
+// while timestamp resets apply to every partition of the topic in the
+// cluster, creating missing bookmarks. Partitions without records after the
+// time resume at their end. Moving offsets backwards is subject to the
+// regression policy unless the strategy is forced, rejected resets change no
+// bookmark and ignored partitions keep theirs. The changes are saved like any
+// other change.
+func (bm *BookmarkManager) ResetTopic(ctx context.Context, topic string, strategy ResetStrategy) (int, error) {
+	if topic == "" {
+		return 0, errors.New("topic cannot be empty")
+	}
+
+	var offsets map[string]int64
+	switch strategy.Kind {
+	case ResetEarliest:
+		offsets = bm.topicOffsets(topic, OffsetEarliest)
+	case ResetLatest:
+		offsets = bm.topicOffsets(topic, OffsetLatest)
+	case ResetOffset:
+		if strategy.Offset < 0 {
+			return 0, fmt.Errorf("reset offset cannot be negative: %d", strategy.Offset)
+		}
+		offsets = bm.topicOffsets(topic, resumeOffset(strategy.Offset))
+	case ResetTimestamp:
+		resolved, err := ResolveOffsetsAt(ctx, strategy.Admin, strategy.Timestamp, topic)
+		if err != nil {
+			return 0, err
+		}
+		offsets = make(map[string]int64, len(resolved))
+		for tp, offset := range resolved {
+			offsets[tp.Partition] = offset
+		}
+	default:
+		return 0, fmt.Errorf("unknown reset strategy: %s", strategy.Kind)
+	}
+
+	partitions := make([]string, 0, len(offsets))
+	for partition := range offsets {
+		partitions = append(partitions, partition)
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		return comparePartitions(partitions[i], partitions[j]) < 0
+	})
+
+	defer bm.dispatchEvents()
+	bm.mutex.Lock()
+	defer bm.mutex.Unlock()
+
+	if !strategy.Force {
+		kept := partitions[:0]
+		for _, partition := range partitions {
+			previous, _ := bm.bookmarks.get(bm.generateKey(topic, partition))
+			err := bm.checkRegression(previous, offsets[partition])
+			if errors.Is(err, errRegressionIgnored) {
+				continue
+			}
+			if err != nil {
+				return 0, fmt.Errorf("failed to reset topic %s: %w", topic, err)
+			}
+			kept = append(kept, partition)
+		}
+		partitions = kept
+	}
+
+	now := time.Now()
+	for _, partition := range partitions {
+		key := bm.generateKey(topic, partition)
+		previous, _ := bm.bookmarks.get(key)
+		if strategy.Force && previous != nil && offsets[partition] < previous.Offset {
+			bm.log.Infof("Forced bookmark %s/%s back from offset %d to %d", topic, partition, previous.Offset, offsets[partition])
+		}
+		reset := &Bookmark{Group: key.Group, Topic: key.Topic, Partition: key.Partition, Metadata: make(map[string]interface{})}
+		if previous != nil {
+			clone := *previous
+			reset = &clone
+		}
+		reset.Offset = offsets[partition]
+		reset.Timestamp = now
+		reset.Version = nextVersion(previous)
+
+		if ev, changed := changeEvent(previous, reset); changed {
+			bm.emit(ev)
+		}
+		bm.bookmarks.set(key, reset)
+		bm.dirty.markBookmark(key)
+	}
+	bm.log.Infof("Reset %d bookmarks of topic %s to %s", len(partitions), topic, strategy)
+	return len(partitions), nil
+}
+
+// String describes where the strategy resumes partitions
+func (s ResetStrategy) String() string {
+	switch s.Kind {
+	case ResetOffset:
+		return fmt.Sprintf("offset %d", s.Offset)
+	case ResetTimestamp:
+		return s.Timestamp.UTC().Format(time.RFC3339)
+	default:
+		return string(s.Kind)
+	}
+}
+
+// topicOffsets returns the given offset for every bookmarked partition of a
+// topic in the group of the manager
+func (bm *BookmarkManager) topicOffsets(topic string, offset int64) map[string]int64 {
+	offsets := make(map[string]int64)
+	for _, b := range bm.GetBookmarksByTopic(topic) {
+		offsets[b.Partition] = offset
+	}
+	return offsets
+}
+
+// resumeOffset returns the bookmark offset that resumes consumption at next,
+// as bookmarks hold the last processed offset
+func resumeOffset(next int64) int64 {
+	if next <= 0 {
+		return OffsetEarliest
+	}
+	return next - 1
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetTopic(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		strategy ResetStrategy
+		offset   int64
+	}{
+		{name: "earliest", strategy: ResetToEarliest(), offset: OffsetEarliest},
+		{name: "latest", strategy: ResetToLatest(), offset: OffsetLatest},
+		{name: "offset resumes at the offset", strategy: ResetToOffset(5), offset: 4},
+		{name: "offset zero resumes at the start", strategy: ResetToOffset(0), offset: OffsetEarliest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bm := NewBookmarkManager("")
+			require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 10}))
+			require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "1", Offset: 20}))
+			require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "payments", Partition: "0", Offset: 30}))
+			require.NoError(t, bm.AddBookmark(&Bookmark{Group: "audit", Topic: "orders", Partition: "0", Offset: 40}))
+
+			reset, err := bm.ResetTopic(ctx, "orders", test.strategy)
+			require.NoError(t, err)
+			assert.Equal(t, 2, reset)
+
+			for _, partition := range []string{"0", "1"} {
+				b, err := bm.GetBookmark("orders", partition)
+				require.NoError(t, err)
+				assert.Equal(t, test.offset, b.Offset, "offset of partition %s", partition)
+				assert.Equal(t, int64(2), b.Version, "version of partition %s", partition)
+			}
+			other, err := bm.GetBookmark("payments", "0")
+			require.NoError(t, err)
+			assert.Equal(t, int64(30), other.Offset)
+			audit, err := bm.GetGroupBookmark("audit", "orders", "0")
+			require.NoError(t, err)
+			assert.Equal(t, int64(40), audit.Offset)
+		})
+	}
+}
+
+func TestResetTopicKeepsMetadataAndSaves(t *testing.T) {
+	bm := NewBookmarkManager(filepath.Join(t.TempDir(), "bookmarks.json"))
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 10, Metadata: map[string]any{"source": "test"}}))
+	require.NoError(t, bm.SaveToFile(context.Background()))
+
+	_, err := bm.ResetTopic(context.Background(), "orders", ResetToOffset(3))
+	require.NoError(t, err)
+
+	b, err := bm.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, "test", b.Metadata["source"])
+	assert.Contains(t, bm.DirtyBookmarks(), b.TopicPartition())
+}
+
+func TestResetTopicEmitsEvents(t *testing.T) {
+	var events []Event
+	bm := NewBookmarkManager("", WithUpdateHook(func(ev Event) {
+		events = append(events, ev)
+	}))
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 10}))
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "1", Offset: 2}))
+	events = nil
+
+	_, err := bm.ResetTopic(context.Background(), "orders", ResetToOffset(6))
+	require.NoError(t, err)
+
+	require.Len(t, events, 2)
+	assert.Equal(t, EventRewound, events[0].Kind)
+	assert.Equal(t, "0", events[0].Partition)
+	assert.Equal(t, EventAdvanced, events[1].Kind)
+	assert.Equal(t, "1", events[1].Partition)
+}
+
+func TestResetTopicErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		topic    string
+		strategy ResetStrategy
+		err      string
+	}{
+		{name: "empty topic", strategy: ResetToEarliest(), err: "topic cannot be empty"},
+		{name: "negative offset", topic: "orders", strategy: ResetToOffset(-1), err: "reset offset cannot be negative"},
+		{name: "unknown strategy", topic: "orders", strategy: ResetStrategy{Kind: "sideways"}, err: "unknown reset strategy"},
+		{name: "timestamp without admin", topic: "orders", strategy: ResetToTimestamp(time.Now(), nil), err: "requires a Kafka admin client"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bm := NewBookmarkManager("")
+			require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 10}))
+
+			_, err := bm.ResetTopic(ctx, test.topic, test.strategy)
+			assert.ErrorContains(t, err, test.err)
+
+			b, err := bm.GetBookmark("orders", "0")
+			require.NoError(t, err)
+			assert.Equal(t, int64(10), b.Offset)
+		})
+	}
+}
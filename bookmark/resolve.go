@@ -0,0 +1,118 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+)
+
+// ResolveOffsetsAt looks up the bookmark offsets that resume every partition
+// of the topics at the first record produced at or after a time. Partitions
+// without records after the time resume at their end, and partitions without
+// any records at their earliest offset. The keys have no group.
+func ResolveOffsetsAt(ctx context.Context, admin *kadm.Client, at time.Time, topics ...string) (map[TopicPartition]int64, error) {
+	if admin == nil {
+		return nil, errors.New("resolving timestamps requires a Kafka admin client")
+	}
+	if len(topics) == 0 {
+		return nil, errors.New("at least one topic must be specified")
+	}
+	listed, err := admin.ListOffsetsAfterMilli(ctx, at.UnixMilli(), topics...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets: %w", err)
+	}
+
+	offsets := make(map[TopicPartition]int64)
+	for _, topic := range topics {
+		if len(listed[topic]) == 0 {
+			return nil, fmt.Errorf("topic %s has no partitions", topic)
+		}
+		for _, o := range listed[topic] {
+			if o.Err != nil {
+				return nil, fmt.Errorf("failed to list offset of %s/%d: %w", topic, o.Partition, o.Err)
+			}
+			offsets[KafkaTopicPartition(topic, o.Partition)] = resumeOffset(o.Offset)
+		}
+	}
+	return offsets, nil
+}
+
+// SeedFromTimestamp creates bookmarks in the group of the manager that resume
+// the partitions of the topics at the first records produced at or after a
+// time, and returns the number of bookmarks created. Partitions that already
+// have a bookmark keep it, use ResetTopic to move those.
+func (bm *BookmarkManager) SeedFromTimestamp(ctx context.Context, admin *kadm.Client, at time.Time, topics ...string) (int, error) {
+	resolved, err := ResolveOffsetsAt(ctx, admin, at, topics...)
+	if err != nil {
+		return 0, err
+	}
+	seeded, err := bm.SeedBookmarks(resolved)
+	if err != nil {
+		return 0, err
+	}
+	if seeded > 0 {
+		bm.log.Infof("Seeded %d bookmarks from %s", seeded, at.UTC().Format(time.RFC3339))
+	}
+	return seeded, nil
+}
+
+// SeedBookmarks creates bookmarks in the group of the manager at the given
+// offsets for the partitions without one, and returns the number of bookmarks
+// created. Existing bookmarks are kept. Seeded bookmarks are saved with the
+// next save.
+func (bm *BookmarkManager) SeedBookmarks(offsets map[TopicPartition]int64) (int, error) {
+	keys := make([]TopicPartition, 0, len(offsets))
+	for tp, offset := range offsets {
+		if err := validateOffset(offset); err != nil {
+			return 0, fmt.Errorf("invalid offset for %s/%s: %w", tp.Topic, tp.Partition, err)
+		}
+		keys = append(keys, tp)
+	}
+	sortTopicPartitions(keys)
+
+	defer bm.dispatchEvents()
+	bm.mutex.Lock()
+	defer bm.mutex.Unlock()
+
+	now := time.Now()
+	seeded := 0
+	for _, tp := range keys {
+		key := bm.generateKey(tp.Topic, tp.Partition)
+		if _, exists := bm.bookmarks.get(key); exists {
+			continue
+		}
+		b := &Bookmark{
+			Group:     key.Group,
+			Topic:     key.Topic,
+			Partition: key.Partition,
+			Offset:    offsets[tp],
+			Timestamp: now,
+			Metadata:  make(map[string]interface{}),
+			Version:   nextVersion(nil),
+		}
+		if ev, changed := changeEvent(nil, b); changed {
+			bm.emit(ev)
+		}
+		bm.bookmarks.set(key, b)
+		bm.dirty.markBookmark(key)
+		seeded++
+	}
+	return seeded, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeedBookmarks(t *testing.T) {
+	bm := NewBookmarkManager("", WithGroup("billing"))
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 10, Metadata: map[string]any{"source": "test"}}))
+
+	seeded, err := bm.SeedBookmarks(map[TopicPartition]int64{
+		KafkaTopicPartition("orders", 0): 3,
+		KafkaTopicPartition("orders", 1): 4,
+		KafkaTopicPartition("orders", 2): OffsetEarliest,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, seeded)
+
+	existing, err := bm.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), existing.Offset)
+	assert.Equal(t, "test", existing.Metadata["source"])
+
+	for partition, offset := range map[string]int64{"1": 4, "2": OffsetEarliest} {
+		b, err := bm.GetGroupBookmark("billing", "orders", partition)
+		require.NoError(t, err)
+		assert.Equal(t, offset, b.Offset, "offset of partition %s", partition)
+		assert.Equal(t, int64(1), b.Version, "version of partition %s", partition)
+	}
+
+	again, err := bm.SeedBookmarks(map[TopicPartition]int64{KafkaTopicPartition("orders", 1): 9})
+	require.NoError(t, err)
+	assert.Zero(t, again)
+}
+
+func TestSeedBookmarksRejectsInvalidOffsets(t *testing.T) {
+	bm := NewBookmarkManager("")
+
+	_, err := bm.SeedBookmarks(map[TopicPartition]int64{
+		KafkaTopicPartition("orders", 0): 3,
+		KafkaTopicPartition("orders", 1): -42,
+	})
+	assert.ErrorContains(t, err, "invalid offset for orders/1")
+	assert.Zero(t, bm.Count())
+}
+
+func TestResolveOffsetsAtErrors(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := ResolveOffsetsAt(ctx, nil, time.Now(), "orders")
+	assert.ErrorContains(t, err, "requires a Kafka admin client")
+
+	_, err = NewBookmarkManager("").SeedFromTimestamp(ctx, nil, time.Now(), "orders")
+	assert.ErrorContains(t, err, "requires a Kafka admin client")
+}
+
+func TestResumeOffset(t *testing.T) {
+	for next, offset := range map[int64]int64{-1: OffsetEarliest, 0: OffsetEarliest, 1: 0, 42: 41} {
+		assert.Equal(t, offset, resumeOffset(next), "resuming at %d", next)
+	}
+}
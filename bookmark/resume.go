@@ -0,0 +1,89 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"time"
+)
+
+// Metrics reported by Open describing where a restarted pipeline resumes
+const (
+	MetricResumeOffset     = "bookmark_resume_offset"
+	MetricResumeAgeSeconds = "bookmark_resume_age_seconds"
+	MetricResumeCatchUp    = "bookmark_resume_catch_up_seconds"
+)
+
+// ResumePoint is the position a partition resumes from, along with the time
+// passed since it was last updated
+type ResumePoint struct {
+	Topic     string        `json:"topic"`
+	Partition string        `json:"partition"`
+	Offset    int64         `json:"offset"`
+	Age       time.Duration `json:"age"`
+}
+
+// ResumePoints returns the resume point of every bookmark, sorted by topic and
+// partition
+func (bm *BookmarkManager) ResumePoints() []ResumePoint {
+	now := time.Now()
+	bookmarks := bm.GetAllBookmarks()
+
+	points := make([]ResumePoint, 0, len(bookmarks))
+	for _, b := range bookmarks {
+		points = append(points, ResumePoint{
+			Topic:     b.Topic,
+			Partition: b.Partition,
+			Offset:    b.Offset,
+			Age:       max(now.Sub(b.Timestamp), 0),
+		})
+	}
+	return points
+}
+
+// reportResumePoints logs and records metrics of the resume points after a
+// load. The catch-up estimate is the age of the stalest resume point, which is
+// the backlog of time a restarted pipeline has to work through at least.
+func (bm *BookmarkManager) reportResumePoints() {
+	points := bm.ResumePoints()
+
+	var stalest *ResumePoint
+	for i, p := range points {
+		bm.log.Debugf("Resuming %s/%s from offset %d, last updated %s ago", p.Topic, p.Partition, p.Offset, p.Age.Truncate(time.Second))
+		if stalest == nil || p.Age > stalest.Age {
+			stalest = &points[i]
+		}
+	}
+	if stalest != nil {
+		bm.log.Infof("Resuming %d partitions, the furthest behind is %s/%s at offset %d, last updated %s ago",
+			len(points), stalest.Topic, stalest.Partition, stalest.Offset, stalest.Age.Truncate(time.Second))
+	}
+
+	if bm.res == nil {
+		return
+	}
+	metrics := bm.res.Metrics()
+	offsetGauge := metrics.NewGauge(MetricResumeOffset, "topic", "partition")
+	ageGauge := metrics.NewGauge(MetricResumeAgeSeconds, "topic", "partition")
+	for _, p := range points {
+		offsetGauge.Set(p.Offset, p.Topic, p.Partition)
+		ageGauge.Set(int64(p.Age/time.Second), p.Topic, p.Partition)
+	}
+
+	var catchUp time.Duration
+	if stalest != nil {
+		catchUp = stalest.Age
+	}
+	metrics.NewGauge(MetricResumeCatchUp).Set(int64(catchUp / time.Second))
+}
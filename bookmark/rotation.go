@@ -0,0 +1,149 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Segment describes a rotated bookmark file
+type Segment struct {
+	Sequence  int       `json:"sequence"`
+	File      string    `json:"file"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	RotatedAt time.Time `json:"rotated_at"`
+}
+
+// SegmentIndex represents the index of rotated segments saved next to the
+// active bookmark file
+type SegmentIndex struct {
+	Segments []Segment `json:"segments"`
+}
+
+// WithRotation enables rotation of the active bookmark file once it reaches
+// maxSize bytes or has been active for maxAge. A zero value disables the
+// respective threshold.
+func WithRotation(maxSize int64, maxAge time.Duration) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.rotateMaxSize = maxSize
+		bm.rotateMaxAge = maxAge
+	}
+}
+
+// segmentIndexPath returns the path of the segment index file
+func (bm *BookmarkManager) segmentIndexPath() string {
+	return bm.filePath + ".segments.json"
+}
+
+// GetSegments returns the rotated segments of the bookmark file, oldest first
+func (bm *BookmarkManager) GetSegments() ([]Segment, error) {
+	index, err := bm.readSegmentIndex()
+	if err != nil {
+		return nil, err
+	}
+	return index.Segments, nil
+}
+
+func (bm *BookmarkManager) readSegmentIndex() (*SegmentIndex, error) {
+	var index SegmentIndex
+
+	data, err := os.ReadFile(bm.segmentIndexPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return &index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read segment index: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal segment index: %w", err)
+	}
+	return &index, nil
+}
+
+// rotationDue checks whether the active file has exceeded one of the rotation
+// thresholds. The caller must hold saveMutex.
+func (bm *BookmarkManager) rotationDue(now time.Time) (bool, error) {
+	if bm.rotateMaxSize <= 0 && bm.rotateMaxAge <= 0 {
+		return false, nil
+	}
+
+	info, err := os.Stat(bm.filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to stat bookmark file: %w", err)
+	}
+
+	sizeExceeded := bm.rotateMaxSize > 0 && info.Size() >= bm.rotateMaxSize
+	ageExceeded := bm.rotateMaxAge > 0 && !bm.createdAt.IsZero() && now.Sub(bm.createdAt) >= bm.rotateMaxAge
+	return sizeExceeded || ageExceeded, nil
+}
+
+// rotate moves the active file into a new segment and records it in the
+// segment index. The caller must hold saveMutex.
+func (bm *BookmarkManager) rotate(now time.Time) error {
+	info, err := os.Stat(bm.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to stat bookmark file: %w", err)
+	}
+
+	index, err := bm.readSegmentIndex()
+	if err != nil {
+		return err
+	}
+
+	sequence := 1
+	if n := len(index.Segments); n > 0 {
+		sequence = index.Segments[n-1].Sequence + 1
+	}
+
+	segmentPath := fmt.Sprintf("%s.%06d", bm.filePath, sequence)
+	if err := os.Rename(bm.filePath, segmentPath); err != nil {
+		return fmt.Errorf("failed to rotate bookmark file: %w", err)
+	}
+
+	index.Segments = append(index.Segments, Segment{
+		Sequence:  sequence,
+		File:      filepath.Base(segmentPath),
+		Size:      info.Size(),
+		CreatedAt: bm.createdAt,
+		RotatedAt: now,
+	})
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal segment index: %w", err)
+	}
+
+	tempFile := bm.segmentIndexPath() + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write temporary segment index: %w", err)
+	}
+	if err := os.Rename(tempFile, bm.segmentIndexPath()); err != nil {
+		os.Remove(tempFile) // Clean up temp file
+		return fmt.Errorf("failed to rename temporary segment index: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// segmentOffset returns the offset of orders/0 in a rotated segment
+func segmentOffset(t *testing.T, path string, segment Segment) int64 {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(path), segment.File))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), segment.Size, "size of segment %d", segment.Sequence)
+	bookmarkFile, err := decodeBookmarkFile(data, &RecoveryReport{})
+	require.NoError(t, err)
+	require.Len(t, bookmarkFile.Bookmarks, 1)
+	return bookmarkFile.Bookmarks[0].Offset
+}
+
+func TestRotationBySize(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	bm := NewBookmarkManager(path, WithRotation(1, 0))
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 1}))
+	require.NoError(t, bm.SaveToFile(ctx))
+	for offset := int64(2); offset <= 3; offset++ {
+		require.NoError(t, bm.UpdateOffset("orders", "0", offset))
+		require.NoError(t, bm.SaveToFile(ctx))
+	}
+
+	segments, err := bm.GetSegments()
+	require.NoError(t, err)
+	require.Len(t, segments, 2)
+	for i, segment := range segments {
+		assert.Equal(t, i+1, segment.Sequence)
+		assert.Equal(t, fmt.Sprintf("bookmarks.json.%06d", i+1), segment.File)
+		assert.Equal(t, int64(i+1), segmentOffset(t, path, segment))
+		assert.False(t, segment.RotatedAt.Before(segment.CreatedAt))
+	}
+
+	// Rotated files become segments rather than backups
+	backups, err := bm.ListBackups()
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	loaded := NewBookmarkManager(path)
+	require.NoError(t, loaded.LoadFromFile(ctx))
+	b, err := loaded.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), b.Offset)
+}
+
+func TestRotationByAge(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	bm := NewBookmarkManager(path, WithRotation(0, time.Hour))
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 1}))
+	require.NoError(t, bm.SaveToFile(ctx))
+	require.NoError(t, bm.UpdateOffset("orders", "0", 2))
+	require.NoError(t, bm.SaveToFile(ctx))
+
+	segments, err := bm.GetSegments()
+	require.NoError(t, err)
+	assert.Empty(t, segments, "a young file must not rotate")
+
+	created := bm.createdAt.Add(-2 * time.Hour)
+	bm.createdAt = created
+	require.NoError(t, bm.UpdateOffset("orders", "0", 3))
+	require.NoError(t, bm.SaveToFile(ctx))
+
+	segments, err = bm.GetSegments()
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	assert.True(t, segments[0].CreatedAt.Equal(created))
+	assert.Equal(t, int64(2), segmentOffset(t, path, segments[0]))
+	assert.True(t, bm.createdAt.After(created), "the active file restarts its age")
+}
+
+func TestRotationDisabled(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	bm := NewBookmarkManager(path)
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 1}))
+	require.NoError(t, bm.SaveToFile(ctx))
+	require.NoError(t, bm.UpdateOffset("orders", "0", 2))
+	require.NoError(t, bm.SaveToFile(ctx))
+
+	segments, err := bm.GetSegments()
+	require.NoError(t, err)
+	assert.Empty(t, segments)
+}
+
+func TestLoadFallsBackToNewestSegment(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	bm := NewBookmarkManager(path, WithRotation(1, 0), WithBackups(0))
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 1}))
+	require.NoError(t, bm.SaveToFile(ctx))
+	require.NoError(t, bm.UpdateOffset("orders", "0", 2))
+	require.NoError(t, bm.SaveToFile(ctx))
+
+	// A rotation interrupted before the successor of the active file was
+	// written leaves only segments behind
+	require.NoError(t, bm.UpdateOffset("orders", "0", 3))
+	require.NoError(t, bm.SaveToFile(ctx))
+	require.NoError(t, os.Remove(path))
+
+	loaded := NewBookmarkManager(path, WithRotation(1, 0), WithBackups(0))
+	require.NoError(t, loaded.LoadFromFile(ctx))
+	b, err := loaded.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), b.Offset)
+	assert.True(t, loaded.GetRecoveryReport().RecoveredFromBackup)
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"hash/fnv"
+	"maps"
+	"sync"
+)
+
+// bookmarkShardCount is the number of shards the bookmarks are split into
+const bookmarkShardCount = 64
+
+// bookmarkShards holds the bookmarks split into shards by topic-partition,
+// each behind a lock of its own, so that updates of different partitions
+// don't contend. Updates of a single bookmark hold the read lock of the
+// manager mutex and the lock of its shard, operations spanning all bookmarks
+// hold the write lock of the manager mutex, which keeps the former out. A map
+// is only ever accessed with the lock of its shard held.
+type bookmarkShards [bookmarkShardCount]bookmarkShard
+
+// bookmarkShard holds the bookmarks hashed to it
+type bookmarkShard struct {
+	mut       sync.Mutex
+	bookmarks map[TopicPartition]*Bookmark
+}
+
+// shardIndex returns the shard of a topic-partition among count shards
+func shardIndex(tp TopicPartition, count uint32) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(tp.Group))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(tp.Topic))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(tp.Partition))
+	return h.Sum32() % count
+}
+
+// shard returns the shard of key, which the caller must lock
+func (s *bookmarkShards) shard(key TopicPartition) *bookmarkShard {
+	return &s[shardIndex(key, bookmarkShardCount)]
+}
+
+// get returns the bookmark of key
+func (s *bookmarkShards) get(key TopicPartition) (*Bookmark, bool) {
+	shard := s.shard(key)
+	shard.mut.Lock()
+	defer shard.mut.Unlock()
+
+	b, exists := shard.bookmarks[key]
+	return b, exists
+}
+
+// clone returns a copy of the bookmark of key, which the caller may keep
+func (s *bookmarkShards) clone(key TopicPartition) (*Bookmark, bool) {
+	shard := s.shard(key)
+	shard.mut.Lock()
+	defer shard.mut.Unlock()
+
+	b, exists := shard.bookmarks[key]
+	if !exists {
+		return nil, false
+	}
+	return cloneBookmarks([]*Bookmark{b})[0], true
+}
+
+// set stores the bookmark of key
+func (s *bookmarkShards) set(key TopicPartition, b *Bookmark) {
+	shard := s.shard(key)
+	shard.mut.Lock()
+	defer shard.mut.Unlock()
+
+	shard.setLocked(key, b)
+}
+
+// remove deletes the bookmark of key
+func (s *bookmarkShards) remove(key TopicPartition) {
+	shard := s.shard(key)
+	shard.mut.Lock()
+	defer shard.mut.Unlock()
+
+	delete(shard.bookmarks, key)
+}
+
+// len returns the number of bookmarks
+func (s *bookmarkShards) len() int {
+	n := 0
+	for i := range s {
+		s[i].mut.Lock()
+		n += len(s[i].bookmarks)
+		s[i].mut.Unlock()
+	}
+	return n
+}
+
+// all returns all bookmarks in no particular order. Their fields may only be
+// read while the manager mutex is write locked, see clones otherwise.
+func (s *bookmarkShards) all() []*Bookmark {
+	var bookmarks []*Bookmark
+	for i := range s {
+		s[i].mut.Lock()
+		for _, b := range s[i].bookmarks {
+			bookmarks = append(bookmarks, b)
+		}
+		s[i].mut.Unlock()
+	}
+	return bookmarks
+}
+
+// clones returns copies of all bookmarks in no particular order, each taken
+// with the lock of its shard held
+func (s *bookmarkShards) clones() []*Bookmark {
+	var bookmarks []*Bookmark
+	for i := range s {
+		s[i].mut.Lock()
+		for _, b := range s[i].bookmarks {
+			clone := *b
+			clone.Metadata = maps.Clone(b.Metadata)
+			bookmarks = append(bookmarks, &clone)
+		}
+		s[i].mut.Unlock()
+	}
+	return bookmarks
+}
+
+// each calls fn for each bookmark in no particular order with the lock of its
+// shard held, stopping at and returning the first error
+func (s *bookmarkShards) each(fn func(*Bookmark) error) error {
+	for i := range s {
+		if err := s[i].each(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reset removes all bookmarks
+func (s *bookmarkShards) reset() {
+	for i := range s {
+		s[i].mut.Lock()
+		s[i].bookmarks = nil
+		s[i].mut.Unlock()
+	}
+}
+
+func (s *bookmarkShard) each(fn func(*Bookmark) error) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	for _, b := range s.bookmarks {
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setLocked stores the bookmark of key, the caller must hold the lock
+func (s *bookmarkShard) setLocked(key TopicPartition, b *Bookmark) {
+	if s.bookmarks == nil {
+		s.bookmarks = make(map[TopicPartition]*Bookmark)
+	}
+	s.bookmarks[key] = b
+}
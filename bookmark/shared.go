@@ -0,0 +1,73 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+// sharedManagers holds the labelled managers of the process, so that all
+// components referencing a label use a single manager for its file
+var sharedManagers = struct {
+	sync.Mutex
+	m map[string]*BookmarkManager
+}{m: make(map[string]*BookmarkManager)}
+
+// withLabel marks a manager as shared under label
+func withLabel(label string) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.label = label
+	}
+}
+
+// sharedManagerFromParsed returns the manager shared under label, creating it
+// from the config when the label is referenced for the first time
+func sharedManagerFromParsed(label string, pConf *service.ParsedConfig, extraOpts []ManagerOption) (*BookmarkManager, error) {
+	filePath, err := pConf.Namespace(bfmFieldSection).FieldString(bfmFieldPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedManagers.Lock()
+	defer sharedManagers.Unlock()
+
+	if bm, exists := sharedManagers.m[label]; exists {
+		if bm.filePath != filePath {
+			return nil, fmt.Errorf("bookmark manager %s is already used with the file %s", label, bm.filePath)
+		}
+		return bm, nil
+	}
+
+	bm, err := newBookmarkFileManagerFromParsed(pConf, append(extraOpts, withLabel(label))...)
+	if err != nil {
+		return nil, err
+	}
+	sharedManagers.m[label] = bm
+	return bm, nil
+}
+
+// unregisterShared removes a shared manager once its last reference closed,
+// so that components created later start from a fresh manager
+func (bm *BookmarkManager) unregisterShared() {
+	sharedManagers.Lock()
+	defer sharedManagers.Unlock()
+
+	if sharedManagers.m[bm.label] == bm {
+		delete(sharedManagers.m, bm.label)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// SinkPosition represents the last record successfully produced to a sink,
+// the output-side counterpart of a consumer Bookmark.
+type SinkPosition struct {
+	Sink      string    `json:"sink"`
+	Sequence  int64     `json:"sequence"`
+	ID        string    `json:"id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewSinkPosition creates a new SinkPosition with validation and default values
+func NewSinkPosition(sink string, sequence int64, id string) (*SinkPosition, error) {
+	p := &SinkPosition{
+		Sink:      sink,
+		Sequence:  sequence,
+		ID:        id,
+		Timestamp: time.Now(),
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *SinkPosition) validate() error {
+	if strings.TrimSpace(p.Sink) == "" {
+		return errors.New("sink must be a non-empty string")
+	}
+	if p.Sequence < 0 {
+		return errors.New("sequence must be a non-negative integer")
+	}
+	return nil
+}
+
+// Delivered returns true if the record with the given sequence has already
+// been produced to the sink
+func (p *SinkPosition) Delivered(sequence int64) bool {
+	return sequence <= p.Sequence
+}
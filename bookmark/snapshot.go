@@ -0,0 +1,171 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotNameRegexp matches the names snapshots may have, which become part
+// of their file names
+var snapshotNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ErrSnapshotNotFound is returned when there's no snapshot of a name
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// SnapshotInfo describes a named snapshot of the bookmarks
+type SnapshotInfo struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`
+}
+
+// snapshotPrefix returns the prefix of the paths of snapshots
+func (bm *BookmarkManager) snapshotPrefix() string {
+	return bm.filePath + ".snap."
+}
+
+// snapshotPath returns the path of a named snapshot, which is stored next to
+// the bookmark file as <path>.snap.<name>
+func (bm *BookmarkManager) snapshotPath(name string) (string, error) {
+	if !snapshotNameRegexp.MatchString(name) || strings.HasSuffix(name, ".tmp") {
+		return "", fmt.Errorf("invalid snapshot name %q: names consist of letters, digits, dots, dashes and underscores", name)
+	}
+	return bm.snapshotPrefix() + name, nil
+}
+
+// Snapshot saves the full state to the bookmark file and copies it into a
+// snapshot of the given name, such as one taken before a risky replay, which
+// RestoreSnapshot returns to. Unlike backups, snapshots are never rotated
+// and must be removed with DeleteSnapshot, which is also required before
+// reusing a name.
+func (bm *BookmarkManager) Snapshot(ctx context.Context, name string) (SnapshotInfo, error) {
+	if bm.readOnly {
+		return SnapshotInfo{}, ErrReadOnly
+	}
+	path, err := bm.snapshotPath(name)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return SnapshotInfo{}, fmt.Errorf("snapshot %s already exists", name)
+	}
+	if err := bm.Compact(ctx); err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	bm.saveMutex.Lock()
+	defer bm.saveMutex.Unlock()
+
+	unlock, err := bm.lockFile(ctx, false)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(bm.filePath)
+	if err != nil {
+		return SnapshotInfo{}, fmt.Errorf("failed to read bookmark file: %w", err)
+	}
+
+	tempFile := path + ".tmp"
+	if err := bm.writeTempFile(tempFile, data); err != nil {
+		os.Remove(tempFile)
+		return SnapshotInfo{}, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return SnapshotInfo{}, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	bm.log.Infof("Took snapshot %s of %d bookmarks", name, bm.Count())
+	return SnapshotInfo{Name: name, Path: path, CreatedAt: time.Now(), Size: int64(len(data))}, nil
+}
+
+// RestoreSnapshot replaces all bookmarks and sink positions with those of a
+// named snapshot and saves them, with the same semantics as Restore
+func (bm *BookmarkManager) RestoreSnapshot(ctx context.Context, name string) error {
+	path, err := bm.snapshotPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
+	}
+	return bm.Restore(ctx, path)
+}
+
+// DeleteSnapshot removes a named snapshot
+func (bm *BookmarkManager) DeleteSnapshot(name string) error {
+	if bm.readOnly {
+		return ErrReadOnly
+	}
+	path, err := bm.snapshotPath(name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete snapshot %s: %w", name, err)
+	}
+	return nil
+}
+
+// ListSnapshots returns the named snapshots of the bookmark file, newest first
+func (bm *BookmarkManager) ListSnapshots() ([]SnapshotInfo, error) {
+	dir := filepath.Dir(bm.filePath)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	prefix := filepath.Base(bm.snapshotPrefix())
+	var snapshots []SnapshotInfo
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), prefix)
+		if entry.IsDir() || !ok || !snapshotNameRegexp.MatchString(name) || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, SnapshotInfo{
+			Name:      name,
+			Path:      filepath.Join(dir, entry.Name()),
+			CreatedAt: info.ModTime(),
+			Size:      info.Size(),
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// scheduledSnapshotPrefix is the prefix of the names of scheduled snapshots
+const scheduledSnapshotPrefix = "scheduled-"
+
+// scheduledSnapshotTimeout bounds how long a scheduled snapshot and its
+// upload may take
+const scheduledSnapshotTimeout = 5 * time.Minute
+
+// SnapshotScheduleConfig configures snapshots taken on a schedule
+type SnapshotScheduleConfig struct {
+	// Cron is a cron expression with five fields, or a descriptor such as
+	// @daily, of the times snapshots are taken at
+	Cron string
+
+	// Keep is the number of scheduled snapshots kept, the oldest are deleted
+	// beyond it. Zero keeps all.
+	Keep int
+
+	// UploadURL is the store URL snapshots are copied to, a blob object such
+	// as s3://bucket/bookmarks/{name}.json where {name} is replaced by the
+	// name of the snapshot, or a Kafka store of the form
+	// kafka://<broker>[,<broker>...]/<topic>. Empty keeps snapshots local.
+	UploadURL string
+}
+
+// WithSnapshotSchedule makes Open start taking named snapshots on a cron
+// schedule, named scheduled-<UTC time> such as scheduled-20240101T000000Z.
+// Snapshots that fail are logged and retried at the next scheduled time.
+func WithSnapshotSchedule(conf SnapshotScheduleConfig) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.snapshotSchedule = &snapshotScheduler{conf: conf, bm: bm}
+	}
+}
+
+type snapshotScheduler struct {
+	conf SnapshotScheduleConfig
+	bm   *BookmarkManager
+
+	// stop and done are guarded by the lifecycleMutex of the manager
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (s *snapshotScheduler) start() error {
+	if s.stop != nil {
+		return nil
+	}
+	schedule, err := cron.ParseStandard(s.conf.Cron)
+	if err != nil {
+		return fmt.Errorf("failed to parse snapshot schedule %q: %w", s.conf.Cron, err)
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.run(schedule, s.stop, s.done)
+	return nil
+}
+
+func (s *snapshotScheduler) close() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop, s.done = nil, nil
+}
+
+func (s *snapshotScheduler) run(schedule cron.Schedule, stop, done chan struct{}) {
+	defer close(done)
+
+	for {
+		timer := time.NewTimer(time.Until(schedule.Next(time.Now())))
+		select {
+		case <-timer.C:
+		case <-stop:
+			timer.Stop()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), scheduledSnapshotTimeout)
+		if err := s.take(ctx, time.Now()); err != nil {
+			s.bm.log.Errorf("Failed to take scheduled snapshot: %v", err)
+		}
+		cancel()
+	}
+}
+
+// take takes a snapshot, deletes the scheduled snapshots beyond the number
+// kept and uploads it when configured
+func (s *snapshotScheduler) take(ctx context.Context, now time.Time) error {
+	name := scheduledSnapshotPrefix + now.UTC().Format("20060102T150405Z")
+	info, err := s.bm.Snapshot(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := s.prune(); err != nil {
+		s.bm.log.Warnf("Failed to delete old scheduled snapshots: %v", err)
+	}
+	if s.conf.UploadURL == "" {
+		return nil
+	}
+	if err := s.upload(ctx, info); err != nil {
+		return fmt.Errorf("failed to upload snapshot %s: %w", name, err)
+	}
+	s.bm.log.Infof("Uploaded snapshot %s", name)
+	return nil
+}
+
+// prune deletes the oldest scheduled snapshots beyond the number kept
+func (s *snapshotScheduler) prune() error {
+	if s.conf.Keep <= 0 {
+		return nil
+	}
+	snapshots, err := s.bm.ListSnapshots()
+	if err != nil {
+		return err
+	}
+	kept := 0
+	var errs []error
+	for _, info := range snapshots {
+		if !strings.HasPrefix(info.Name, scheduledSnapshotPrefix) {
+			continue
+		}
+		if kept++; kept <= s.conf.Keep {
+			continue
+		}
+		if err := s.bm.DeleteSnapshot(info.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// upload copies the bookmarks of a snapshot to the upload store
+func (s *snapshotScheduler) upload(ctx context.Context, info SnapshotInfo) error {
+	data, err := os.ReadFile(info.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	bookmarkFile, err := decodeBookmarkFile(data, &RecoveryReport{})
+	if err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	opened, err := openStoreURL(ctx, strings.ReplaceAll(s.conf.UploadURL, "{name}", info.Name))
+	if err != nil {
+		return err
+	}
+	for _, b := range bookmarkFile.Bookmarks {
+		if err = opened.store.Put(ctx, b); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = opened.store.Flush(ctx)
+	}
+	if cerr := opened.close(ctx); err == nil && cerr != nil {
+		err = cerr
+	}
+	return err
+}
+
+// startSnapshotSchedule starts taking scheduled snapshots when configured.
+// The caller must hold lifecycleMutex.
+func (bm *BookmarkManager) startSnapshotSchedule() error {
+	if bm.snapshotSchedule == nil || bm.readOnly {
+		return nil
+	}
+	return bm.snapshotSchedule.start()
+}
+
+// stopSnapshotSchedule stops taking scheduled snapshots. The caller must hold
+// lifecycleMutex.
+func (bm *BookmarkManager) stopSnapshotSchedule() {
+	if bm.snapshotSchedule != nil {
+		bm.snapshotSchedule.close()
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSQLiteManager(t *testing.T, dsn string) *SQLBookmarkManager {
+	t.Helper()
+
+	m, err := NewSQLBookmarkManager(context.Background(), SQLDriverSQLite, dsn, "bookmarks")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = m.Close() })
+	return m
+}
+
+func TestSQLGroupsShareAPartition(t *testing.T) {
+	ctx := context.Background()
+	m := newTestSQLiteManager(t, "file:"+filepath.Join(t.TempDir(), "bookmarks.db"))
+
+	require.NoError(t, m.Put(ctx, &Bookmark{Group: "billing", Topic: "orders", Partition: "0", Offset: 10}))
+	require.NoError(t, m.Put(ctx, &Bookmark{Group: "audit", Topic: "orders", Partition: "0", Offset: 20}))
+	require.NoError(t, m.Put(ctx, &Bookmark{Topic: "orders", Partition: "0", Offset: 30}))
+
+	tests := []struct {
+		group  string
+		offset int64
+	}{
+		{"billing", 10},
+		{"audit", 20},
+		{"", 30},
+	}
+	for _, test := range tests {
+		b, err := m.GetInGroup(ctx, test.group, "orders", "0")
+		require.NoError(t, err)
+		assert.Equal(t, test.offset, b.Offset, "offset of group %q", test.group)
+	}
+
+	require.NoError(t, m.DeleteInGroup(ctx, "audit", "orders", "0"))
+	_, err := m.GetInGroup(ctx, "audit", "orders", "0")
+	assert.ErrorIs(t, err, ErrBookmarkNotFound)
+
+	bookmarks, err := m.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, bookmarks, 2)
+}
+
+func TestSQLLongPartitionsAndRepeatedMigrations(t *testing.T) {
+	ctx := context.Background()
+	dsn := "file:" + filepath.Join(t.TempDir(), "bookmarks.db")
+	partition := strings.Repeat("k", 1024)
+
+	m := newTestSQLiteManager(t, dsn)
+	require.NoError(t, m.Put(ctx, &Bookmark{Topic: "bucket", Partition: partition, Offset: 1}))
+	require.NoError(t, m.Close())
+
+	// Opening again finds the migrations applied
+	reopened := newTestSQLiteManager(t, dsn)
+	b, err := reopened.Get(ctx, "bucket", partition)
+	require.NoError(t, err)
+	assert.Equal(t, partition, b.Partition)
+}
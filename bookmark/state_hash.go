@@ -0,0 +1,50 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// StateHash returns a hex encoded SHA-256 hash of all bookmarks and sink
+// positions. Equal states always produce the same hash regardless of the order
+// of updates or the file formatting, which allows callers to skip redundant
+// uploads and to verify replicas are in sync.
+func (bm *BookmarkManager) StateHash() (string, error) {
+	bm.mutex.Lock()
+	defer bm.mutex.Unlock()
+
+	digest, err := stateDigest(bm.sortedBookmarks(), bm.sortedSinkPositions())
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// stateDigest returns a SHA-256 digest of sorted bookmarks and sink positions.
+// JSON marshaling orders map keys, so the digest is stable for equal states.
+func stateDigest(bookmarks []*Bookmark, sinks []*SinkPosition) ([sha256.Size]byte, error) {
+	data, err := json.Marshal(struct {
+		Bookmarks []*Bookmark     `json:"bookmarks"`
+		Sinks     []*SinkPosition `json:"sink_positions"`
+	}{bookmarks, sinks})
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("failed to marshal bookmark state: %w", err)
+	}
+	return sha256.Sum256(data), nil
+}
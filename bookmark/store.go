@@ -0,0 +1,121 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrBookmarkNotFound is returned when there's no bookmark for a topic and
+	// partition
+	ErrBookmarkNotFound = errors.New("bookmark not found")
+
+	// ErrBookmarkConflict is returned by stores shared between instances when
+	// a bookmark was changed by another instance since it was last seen, in
+	// which case the change should be retried against the current bookmark
+	ErrBookmarkConflict = errors.New("bookmark was changed concurrently")
+
+	// ErrPartitionClaimed is returned by stores shared between instances when
+	// changing a bookmark of a partition claimed or locked by another instance
+	ErrPartitionClaimed = errors.New("partition is claimed by another instance")
+)
+
+// BookmarkStore is implemented by the backends bookmarks are persisted to.
+// Components that only read and write bookmarks should depend on this
+// interface rather than on a specific backend, the file based
+// BookmarkManager is one implementation. Remote backends abandon calls once
+// their context is done.
+type BookmarkStore interface {
+	// Get returns the bookmark of a topic and partition, or an error wrapping
+	// ErrBookmarkNotFound
+	Get(ctx context.Context, topic, partition string) (*Bookmark, error)
+
+	// Put adds or replaces a bookmark
+	Put(ctx context.Context, bookmark *Bookmark) error
+
+	// Delete removes the bookmark of a topic and partition, or returns an
+	// error wrapping ErrBookmarkNotFound
+	Delete(ctx context.Context, topic, partition string) error
+
+	// List returns all bookmarks sorted by topic and partition
+	List(ctx context.Context) ([]*Bookmark, error)
+
+	// Flush persists all changes made since the last flush
+	Flush(ctx context.Context) error
+}
+
+// GroupBookmarkStore is implemented by stores that keep the bookmarks of
+// consumer groups apart, see WithGroup. Get and Delete of these stores address
+// the default group.
+type GroupBookmarkStore interface {
+	BookmarkStore
+
+	// GetInGroup returns the bookmark of a topic and partition in a group, or an
+	// error wrapping ErrBookmarkNotFound
+	GetInGroup(ctx context.Context, group, topic, partition string) (*Bookmark, error)
+
+	// DeleteInGroup removes the bookmark of a topic and partition in a group, or
+	// returns an error wrapping ErrBookmarkNotFound
+	DeleteInGroup(ctx context.Context, group, topic, partition string) error
+}
+
+var _ GroupBookmarkStore = (*BookmarkManager)(nil)
+
+// notFoundError returns an error wrapping ErrBookmarkNotFound for the
+// bookmark of a topic and partition in a group
+func notFoundError(group, topic, partition string) error {
+	if group != "" {
+		return fmt.Errorf("%w for group: %s, topic: %s, partition: %s", ErrBookmarkNotFound, group, topic, partition)
+	}
+	return fmt.Errorf("%w for topic: %s, partition: %s", ErrBookmarkNotFound, topic, partition)
+}
+
+// Get returns the bookmark of a topic and partition
+func (bm *BookmarkManager) Get(ctx context.Context, topic, partition string) (*Bookmark, error) {
+	return bm.GetBookmark(topic, partition)
+}
+
+// Put adds or replaces a bookmark
+func (bm *BookmarkManager) Put(ctx context.Context, bookmark *Bookmark) error {
+	return bm.AddBookmark(bookmark)
+}
+
+// Delete removes the bookmark of a topic and partition
+func (bm *BookmarkManager) Delete(ctx context.Context, topic, partition string) error {
+	return bm.RemoveBookmark(topic, partition)
+}
+
+// GetInGroup returns the bookmark of a topic and partition in a group
+func (bm *BookmarkManager) GetInGroup(ctx context.Context, group, topic, partition string) (*Bookmark, error) {
+	return bm.GetGroupBookmark(group, topic, partition)
+}
+
+// DeleteInGroup removes the bookmark of a topic and partition in a group
+func (bm *BookmarkManager) DeleteInGroup(ctx context.Context, group, topic, partition string) error {
+	return bm.RemoveGroupBookmark(group, topic, partition)
+}
+
+// List returns all bookmarks sorted by topic and partition
+func (bm *BookmarkManager) List(ctx context.Context) ([]*Bookmark, error) {
+	return bm.GetAllBookmarks(), nil
+}
+
+// Flush saves the bookmarks to the bookmark file
+func (bm *BookmarkManager) Flush(ctx context.Context) error {
+	return bm.SaveToFile(ctx)
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+
+	awsconfig "rpanda-connect-native-plugin-example/aws/config"
+)
+
+// remoteStore is a store the store object of the bookmark file manager
+// configures, by the section of its config fields. The key field is set when
+// the store is configured, as defaults fill in the other fields regardless.
+type remoteStore struct {
+	section string
+	key     string
+	fields  func() []*service.ConfigField
+	open    func(ctx context.Context, pConf *service.ParsedConfig) (ownedStore, error)
+}
+
+var remoteStores = []remoteStore{
+	{section: sbmFieldSection, key: sbmFieldDSN, fields: SQLBookmarkManagerConfigFields, open: func(ctx context.Context, pConf *service.ParsedConfig) (ownedStore, error) {
+		m, err := SQLBookmarkManagerFromParsed(ctx, pConf)
+		if err != nil {
+			return ownedStore{}, err
+		}
+		return ownedStore{store: m, close: func(context.Context) error { return m.Close() }}, nil
+	}},
+	{section: kbmFieldSection, key: kbmFieldSeedBrokers, fields: KafkaBookmarkManagerConfigFields, open: func(ctx context.Context, pConf *service.ParsedConfig) (ownedStore, error) {
+		m, err := KafkaBookmarkManagerFromParsed(ctx, pConf)
+		if err != nil {
+			return ownedStore{}, err
+		}
+		return ownedStore{store: m, close: m.Close}, nil
+	}},
+	{section: ebmFieldSection, key: ebmFieldEndpoints, fields: EtcdBookmarkManagerConfigFields, open: func(ctx context.Context, pConf *service.ParsedConfig) (ownedStore, error) {
+		m, err := EtcdBookmarkManagerFromParsed(ctx, pConf, nil)
+		if err != nil {
+			return ownedStore{}, err
+		}
+		return ownedStore{store: m, close: m.Close}, nil
+	}},
+	{section: cbmFieldSection, key: cbmFieldAddress, fields: ConsulBookmarkManagerConfigFields, open: func(ctx context.Context, pConf *service.ParsedConfig) (ownedStore, error) {
+		m, err := ConsulBookmarkManagerFromParsed(ctx, pConf)
+		if err != nil {
+			return ownedStore{}, err
+		}
+		return ownedStore{store: m, close: m.Close}, nil
+	}},
+	{section: dbmFieldSection, key: dbmFieldTable, fields: DynamoDBBookmarkManagerConfigFields, open: func(ctx context.Context, pConf *service.ParsedConfig) (ownedStore, error) {
+		m, err := DynamoDBBookmarkManagerFromParsed(ctx, pConf, awsconfig.GetSession)
+		if err != nil {
+			return ownedStore{}, err
+		}
+		return ownedStore{store: m, close: func(context.Context) error { return nil }}, nil
+	}},
+	{section: blbmFieldSection, key: blbmFieldURL, fields: BlobBookmarkManagerConfigFields, open: func(ctx context.Context, pConf *service.ParsedConfig) (ownedStore, error) {
+		m, err := BlobBookmarkManagerFromParsed(ctx, pConf)
+		if err != nil {
+			return ownedStore{}, err
+		}
+		return ownedStore{store: m, close: m.Close}, nil
+	}},
+}
+
+// configured returns whether the store object configures the store
+func (s remoteStore) configured(sConf *service.ParsedConfig) bool {
+	v, err := sConf.FieldAny(s.section, s.key)
+	if err != nil {
+		return false
+	}
+	switch v := v.(type) {
+	case string:
+		return v != ""
+	case []any:
+		return len(v) > 0
+	}
+	return v != nil
+}
+
+// storeConfigField returns the store object of the bookmark file manager,
+// which holds the config fields of one of the remote stores
+func storeConfigField() *service.ConfigField {
+	var fields []*service.ConfigField
+	sections := make([]string, 0, len(remoteStores))
+	for _, s := range remoteStores {
+		for _, f := range s.fields() {
+			fields = append(fields, f.Optional())
+		}
+		sections = append(sections, "`"+s.section+"`")
+	}
+	return service.NewObjectField(bfmFieldStore, fields...).
+		Description(fmt.Sprintf("A remote store configured by its fields rather than a URL, one of %s, which the bookmarks are replicated to like `mirrors` and loaded from like `fallbacks` when the bookmark file doesn't exist.", strings.Join(sections, ", "))).
+		Optional().
+		Advanced()
+}
+
+// remoteStoreFromParsed returns the option adding the remote store of the
+// store object as a mirror and a fallback, or nil when none is configured
+func remoteStoreFromParsed(bConf *service.ParsedConfig) (ManagerOption, error) {
+	if !bConf.Contains(bfmFieldStore) {
+		return nil, nil
+	}
+	sConf := bConf.Namespace(bfmFieldStore)
+
+	var found *remoteStore
+	for i, s := range remoteStores {
+		if !s.configured(sConf) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("store must configure a single store, found %s and %s", found.section, s.section)
+		}
+		found = &remoteStores[i]
+	}
+	if found == nil {
+		return nil, errors.New("store must configure one of the stores")
+	}
+
+	open := func(ctx context.Context) (ownedStore, error) {
+		return found.open(ctx, sConf)
+	}
+	return withRemoteStore(found.section, open), nil
+}
+
+// withRemoteStore adds a remote store opened by open as both a mirror and a
+// fallback
+func withRemoteStore(name string, open func(ctx context.Context) (ownedStore, error)) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.addMirror(&mirror{name: name, open: open})
+		bm.fallbacks = append(bm.fallbacks, fallbackStore{name: name, open: open})
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ownedStore is a store opened from a URL along with how to close it
+type ownedStore struct {
+	store BookmarkStore
+	close func(ctx context.Context) error
+}
+
+// openStoreURL opens the store a URL refers to, which is a blob object for
+// s3://, gs:// and azblob:// URLs, or a Kafka store for URLs of the form
+// kafka://<broker>[,<broker>...]/<topic>
+func openStoreURL(ctx context.Context, storeURL string) (ownedStore, error) {
+	if rest, ok := strings.CutPrefix(storeURL, "kafka://"); ok {
+		brokers, topic, _ := strings.Cut(rest, "/")
+		if brokers == "" || topic == "" {
+			return ownedStore{}, fmt.Errorf("kafka store URL %s requires brokers and a topic", storeURL)
+		}
+		m, err := NewKafkaBookmarkManager(ctx, KafkaBookmarkManagerConfig{
+			SeedBrokers:       strings.Split(brokers, ","),
+			Topic:             topic,
+			Partitions:        -1,
+			ReplicationFactor: -1,
+		})
+		if err != nil {
+			return ownedStore{}, err
+		}
+		return ownedStore{store: m, close: m.Close}, nil
+	}
+
+	m, err := NewBlobBookmarkManager(ctx, BlobBookmarkManagerConfig{URL: storeURL})
+	if err != nil {
+		return ownedStore{}, err
+	}
+	return ownedStore{store: m, close: m.Close}, nil
+}
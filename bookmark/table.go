@@ -0,0 +1,55 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// DumpTable writes all bookmarks to w as a table with aligned topic,
+// partition, offset and age columns, preceded by a group column when any
+// bookmark belongs to a consumer group
+func (bm *BookmarkManager) DumpTable(w io.Writer) error {
+	bookmarks := bm.GetAllBookmarks()
+	now := time.Now()
+
+	grouped := false
+	for _, b := range bookmarks {
+		grouped = grouped || b.Group != ""
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := "TOPIC\tPARTITION\tOFFSET\tAGE"
+	if grouped {
+		header = "GROUP\t" + header
+	}
+	if _, err := fmt.Fprintln(tw, header); err != nil {
+		return err
+	}
+	for _, b := range bookmarks {
+		age := now.Sub(b.Timestamp).Truncate(time.Second)
+		row := fmt.Sprintf("%s\t%s\t%s\t%s", b.Topic, b.Partition, FormatOffset(b.Offset), age)
+		if grouped {
+			row = b.Group + "\t" + row
+		}
+		if _, err := fmt.Fprintln(tw, row); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
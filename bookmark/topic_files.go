@@ -0,0 +1,197 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// topicFileSuffix is the suffix of the bookmark files of topics, which tells
+// them apart from their backups, logs and lock files
+const topicFileSuffix = ".bookmarks"
+
+// TopicFileBookmarkManager persists the bookmarks of every topic to a
+// bookmark file of its own under a directory, so that saving the offsets of
+// one topic doesn't rewrite the bookmarks of all topics and the files of
+// several topics are saved in parallel. Each file is managed by a
+// BookmarkManager created with the options given to the manager, and is
+// named after its escaped topic with the suffix ".bookmarks".
+type TopicFileBookmarkManager struct {
+	dir  string
+	opts []ManagerOption
+
+	mut      sync.RWMutex
+	managers map[string]*BookmarkManager
+}
+
+var _ GroupBookmarkStore = (*TopicFileBookmarkManager)(nil)
+
+// NewTopicFileBookmarkManager creates the directory if needed and loads the
+// bookmark files of all topics found in it
+func NewTopicFileBookmarkManager(ctx context.Context, dir string, opts ...ManagerOption) (*TopicFileBookmarkManager, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bookmark files: %w", err)
+	}
+
+	m := &TopicFileBookmarkManager{dir: dir, opts: opts, managers: make(map[string]*BookmarkManager)}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, topicFileSuffix) {
+			continue
+		}
+		topic, err := url.PathUnescape(strings.TrimSuffix(name, topicFileSuffix))
+		if err != nil || topic == "" {
+			continue
+		}
+		bm := NewBookmarkManager(filepath.Join(dir, name), opts...)
+		if err := bm.LoadFromFile(ctx); err != nil {
+			return nil, fmt.Errorf("failed to load bookmarks of topic %s: %w", topic, err)
+		}
+		m.managers[topic] = bm
+	}
+	return m, nil
+}
+
+// topicFilePath returns the path of the bookmark file of a topic
+func (m *TopicFileBookmarkManager) topicFilePath(topic string) string {
+	return filepath.Join(m.dir, url.PathEscape(topic)+topicFileSuffix)
+}
+
+// manager returns the manager of a topic, creating it when create is true,
+// or nil when the topic has none
+func (m *TopicFileBookmarkManager) manager(topic string, create bool) *BookmarkManager {
+	m.mut.RLock()
+	bm := m.managers[topic]
+	m.mut.RUnlock()
+	if bm != nil || !create {
+		return bm
+	}
+
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if bm = m.managers[topic]; bm == nil {
+		bm = NewBookmarkManager(m.topicFilePath(topic), m.opts...)
+		m.managers[topic] = bm
+	}
+	return bm
+}
+
+// Manager returns the manager of the bookmark file of a topic, for the
+// operations BookmarkStore doesn't cover, creating it when the topic has no
+// bookmarks yet
+func (m *TopicFileBookmarkManager) Manager(topic string) *BookmarkManager {
+	return m.manager(topic, true)
+}
+
+// Topics returns the topics with a bookmark file, sorted by name
+func (m *TopicFileBookmarkManager) Topics() []string {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	topics := make([]string, 0, len(m.managers))
+	for topic := range m.managers {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Get returns the bookmark of a topic and partition in the default group
+func (m *TopicFileBookmarkManager) Get(ctx context.Context, topic, partition string) (*Bookmark, error) {
+	return m.GetInGroup(ctx, "", topic, partition)
+}
+
+// GetInGroup returns the bookmark of a topic and partition in a group
+func (m *TopicFileBookmarkManager) GetInGroup(ctx context.Context, group, topic, partition string) (*Bookmark, error) {
+	bm := m.manager(topic, false)
+	if bm == nil {
+		return nil, notFoundError(group, topic, partition)
+	}
+	return bm.GetGroupBookmark(group, topic, partition)
+}
+
+// Put adds or replaces a bookmark in the file of its topic
+func (m *TopicFileBookmarkManager) Put(ctx context.Context, bookmark *Bookmark) error {
+	if bookmark == nil {
+		return errors.New("bookmark cannot be nil")
+	}
+	if bookmark.Topic == "" {
+		return errors.New("topic cannot be empty")
+	}
+	return m.manager(bookmark.Topic, true).AddBookmark(bookmark)
+}
+
+// Delete removes the bookmark of a topic and partition in the default group
+func (m *TopicFileBookmarkManager) Delete(ctx context.Context, topic, partition string) error {
+	return m.DeleteInGroup(ctx, "", topic, partition)
+}
+
+// DeleteInGroup removes the bookmark of a topic and partition in a group
+func (m *TopicFileBookmarkManager) DeleteInGroup(ctx context.Context, group, topic, partition string) error {
+	bm := m.manager(topic, false)
+	if bm == nil {
+		return notFoundError(group, topic, partition)
+	}
+	return bm.RemoveGroupBookmark(group, topic, partition)
+}
+
+// List returns the bookmarks of all topics sorted by topic and partition
+func (m *TopicFileBookmarkManager) List(ctx context.Context) ([]*Bookmark, error) {
+	var bookmarks []*Bookmark
+	for _, topic := range m.Topics() {
+		bookmarks = append(bookmarks, m.manager(topic, false).GetAllBookmarks()...)
+	}
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return lessBookmark(bookmarks[i], bookmarks[j])
+	})
+	return bookmarks, nil
+}
+
+// Flush saves the files of the topics whose bookmarks changed since they were
+// last saved, in parallel
+func (m *TopicFileBookmarkManager) Flush(ctx context.Context) error {
+	topics := m.Topics()
+	errs := make([]error, len(topics))
+
+	var wg sync.WaitGroup
+	for i, topic := range topics {
+		bm := m.manager(topic, false)
+		if !bm.IsDirty() {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := bm.SaveToFile(ctx); err != nil {
+				errs[i] = fmt.Errorf("failed to save bookmarks of topic %s: %w", topic, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
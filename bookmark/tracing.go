@@ -0,0 +1,131 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans of this package
+const tracerName = "rpanda-connect-native-plugin-example/bookmark"
+
+// WithTracerProvider traces saves and loads as spans of tp, which are children
+// of the spans in the context passed to SaveToFile, LoadFromFile and the other
+// context-aware methods. Managers created with WithResources use the tracer of
+// the pipeline by default, other managers the global tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.tracerProvider = tp
+	}
+}
+
+// newTracer returns the tracer of a manager once its options are applied
+func (bm *BookmarkManager) newTracer() trace.Tracer {
+	tp := bm.tracerProvider
+	if tp == nil && bm.res != nil {
+		tp = bm.res.OtelTracer()
+	}
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(tracerName)
+}
+
+// startSpan starts the span of a file operation
+func (bm *BookmarkManager) startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return bm.tracer.Start(ctx, "bookmark."+operation, trace.WithAttributes(
+		append(attrs, attribute.String("bookmark.file", bm.filePath))...,
+	))
+}
+
+// endSpan records the outcome of an operation and ends its span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceStore returns store with each call traced as a span of tp named
+// bookmark.<system>.<operation>, such as bookmark.sql.put, for tracing remote
+// stores like SQLBookmarkManager and KafkaBookmarkManager. A nil tp uses the
+// global tracer provider.
+func TraceStore(store BookmarkStore, tp trace.TracerProvider, system string) BookmarkStore {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &tracedStore{store: store, tracer: tp.Tracer(tracerName), system: system}
+}
+
+type tracedStore struct {
+	store  BookmarkStore
+	tracer trace.Tracer
+	system string
+}
+
+func (s *tracedStore) start(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "bookmark."+s.system+"."+operation, trace.WithAttributes(
+		append(attrs, attribute.String("bookmark.store", s.system))...,
+	))
+}
+
+func (s *tracedStore) Get(ctx context.Context, topic, partition string) (*Bookmark, error) {
+	ctx, span := s.start(ctx, "get", attribute.String("bookmark.topic", topic), attribute.String("bookmark.partition", partition))
+	b, err := s.store.Get(ctx, topic, partition)
+	endSpan(span, err)
+	return b, err
+}
+
+func (s *tracedStore) Put(ctx context.Context, bookmark *Bookmark) error {
+	var attrs []attribute.KeyValue
+	if bookmark != nil {
+		attrs = append(attrs,
+			attribute.String("bookmark.topic", bookmark.Topic),
+			attribute.String("bookmark.partition", bookmark.Partition),
+			attribute.Int64("bookmark.offset", bookmark.Offset))
+	}
+	ctx, span := s.start(ctx, "put", attrs...)
+	err := s.store.Put(ctx, bookmark)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) Delete(ctx context.Context, topic, partition string) error {
+	ctx, span := s.start(ctx, "delete", attribute.String("bookmark.topic", topic), attribute.String("bookmark.partition", partition))
+	err := s.store.Delete(ctx, topic, partition)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) List(ctx context.Context) ([]*Bookmark, error) {
+	ctx, span := s.start(ctx, "list")
+	bookmarks, err := s.store.List(ctx)
+	span.SetAttributes(attribute.Int("bookmark.count", len(bookmarks)))
+	endSpan(span, err)
+	return bookmarks, err
+}
+
+func (s *tracedStore) Flush(ctx context.Context) error {
+	ctx, span := s.start(ctx, "flush")
+	err := s.store.Flush(ctx)
+	endSpan(span, err)
+	return err
+}
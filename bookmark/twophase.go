@@ -0,0 +1,196 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrCommitNotFound is returned when there's no pending commit with an ID
+var ErrCommitNotFound = errors.New("pending commit not found")
+
+// PendingCommit is a set of offsets staged by PrepareCommit, which is kept in
+// the bookmark file until it's confirmed or rolled back. Pending commits are
+// covered by the checksum of the file. They're never appended to the
+// write-ahead log, preparing, confirming and rolling back a commit compacts
+// the log into the file instead.
+type PendingCommit struct {
+	ID         string          `json:"id"`
+	PreparedAt time.Time       `json:"prepared_at"`
+	Offsets    []PendingOffset `json:"offsets"`
+}
+
+// PendingOffset is an offset of a partition staged by a pending commit
+type PendingOffset struct {
+	Group     string `json:"group,omitempty"`
+	Topic     string `json:"topic"`
+	Partition string `json:"partition"`
+	Offset    int64  `json:"offset"`
+}
+
+// PrepareCommit stages the offsets of a transaction under id and saves them
+// in the pending section of the bookmark file, without changing the
+// bookmarks. Once the transaction was written downstream, ConfirmCommit
+// promotes the offsets to the bookmarks, otherwise Rollback discards them.
+// Pending commits survive restarts, so that after a crash the outcome of the
+// transaction can be looked up and the commit resolved, see PendingCommits.
+// Offsets that would be rejected by the regression policy are rejected here
+// already.
+func (bm *BookmarkManager) PrepareCommit(ctx context.Context, id string, offsets map[TopicPartition]int64) error {
+	if id == "" {
+		return errors.New("commit id cannot be empty")
+	}
+	commit := &PendingCommit{ID: id, PreparedAt: time.Now()}
+	for tp, offset := range offsets {
+		if err := validateOffset(offset); err != nil {
+			return fmt.Errorf("invalid offset for %s/%s: %w", tp.Topic, tp.Partition, err)
+		}
+		key := bm.generateKey(tp.Topic, tp.Partition)
+		commit.Offsets = append(commit.Offsets, PendingOffset{Group: key.Group, Topic: key.Topic, Partition: key.Partition, Offset: offset})
+	}
+	sort.Slice(commit.Offsets, func(i, j int) bool {
+		a, b := commit.Offsets[i], commit.Offsets[j]
+		return lessBookmark(&Bookmark{Group: a.Group, Topic: a.Topic, Partition: a.Partition}, &Bookmark{Group: b.Group, Topic: b.Topic, Partition: b.Partition})
+	})
+
+	bm.mutex.Lock()
+	if _, exists := bm.pending[id]; exists {
+		bm.mutex.Unlock()
+		return fmt.Errorf("commit %s is already pending", id)
+	}
+	accepted := commit.Offsets[:0]
+	for _, o := range commit.Offsets {
+		previous, _ := bm.bookmarks.get(NewGroupTopicPartition(o.Group, o.Topic, o.Partition))
+		err := bm.checkRegression(previous, o.Offset)
+		if errors.Is(err, errRegressionIgnored) {
+			continue
+		}
+		if err != nil {
+			bm.mutex.Unlock()
+			return err
+		}
+		accepted = append(accepted, o)
+	}
+	commit.Offsets = accepted
+	if bm.pending == nil {
+		bm.pending = make(map[string]*PendingCommit)
+	}
+	bm.pending[id] = commit
+	bm.mutex.Unlock()
+
+	if err := bm.Compact(ctx); err != nil {
+		bm.mutex.Lock()
+		delete(bm.pending, id)
+		bm.mutex.Unlock()
+		return fmt.Errorf("failed to save pending commit: %w", err)
+	}
+	return nil
+}
+
+// ConfirmCommit promotes the offsets staged under id to the bookmarks,
+// creating missing bookmarks, and saves them. The commit is taken out of the
+// pending commits while its offsets are promoted, so that it's promoted at
+// most once, and stays pending when promoting fails. When only the save
+// fails the bookmarks are saved with the next save.
+func (bm *BookmarkManager) ConfirmCommit(ctx context.Context, id string) error {
+	bm.mutex.Lock()
+	commit, exists := bm.pending[id]
+	if !exists {
+		bm.mutex.Unlock()
+		return fmt.Errorf("%w: %s", ErrCommitNotFound, id)
+	}
+	delete(bm.pending, id)
+
+	now := time.Now()
+	bookmarks := make([]*Bookmark, 0, len(commit.Offsets))
+	for _, o := range commit.Offsets {
+		b := &Bookmark{Group: o.Group, Topic: o.Topic, Partition: o.Partition, Metadata: make(map[string]interface{})}
+		if existing, found := bm.bookmarks.clone(NewGroupTopicPartition(o.Group, o.Topic, o.Partition)); found {
+			b = existing
+		}
+		b.Offset = o.Offset
+		b.Timestamp = now
+		bookmarks = append(bookmarks, b)
+	}
+	if err := bm.promoteLocked(bookmarks); err != nil {
+		bm.pending[id] = commit
+		bm.mutex.Unlock()
+		return fmt.Errorf("failed to promote commit %s: %w", id, err)
+	}
+	bm.mutex.Unlock()
+	bm.dispatchEvents()
+
+	if err := bm.Compact(ctx); err != nil {
+		return fmt.Errorf("failed to save confirmed commit: %w", err)
+	}
+	return nil
+}
+
+// promoteLocked validates and stores the bookmarks of a confirmed commit. The
+// caller must hold the write lock of the mutex.
+func (bm *BookmarkManager) promoteLocked(bookmarks []*Bookmark) error {
+	for _, b := range bookmarks {
+		if err := bm.validateBookmark(b); err != nil {
+			return fmt.Errorf("invalid bookmark %s/%s: %w", b.Topic, b.Partition, err)
+		}
+	}
+	return bm.addBookmarksLocked(bookmarks)
+}
+
+// Rollback discards the offsets staged under id and saves the bookmark file
+// without them
+func (bm *BookmarkManager) Rollback(ctx context.Context, id string) error {
+	bm.mutex.Lock()
+	_, exists := bm.pending[id]
+	delete(bm.pending, id)
+	bm.mutex.Unlock()
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrCommitNotFound, id)
+	}
+
+	if err := bm.Compact(ctx); err != nil {
+		return fmt.Errorf("failed to save rolled back commit: %w", err)
+	}
+	return nil
+}
+
+// PendingCommits returns the commits that were prepared but neither
+// confirmed nor rolled back, oldest first
+func (bm *BookmarkManager) PendingCommits() []*PendingCommit {
+	bm.mutex.RLock()
+	defer bm.mutex.RUnlock()
+
+	return bm.sortedPendingCommits()
+}
+
+// sortedPendingCommits returns the pending commits sorted by the time they
+// were prepared. The caller must hold the mutex.
+func (bm *BookmarkManager) sortedPendingCommits() []*PendingCommit {
+	commits := make([]*PendingCommit, 0, len(bm.pending))
+	for _, c := range bm.pending {
+		commits = append(commits, c)
+	}
+	sort.Slice(commits, func(i, j int) bool {
+		if !commits[i].PreparedAt.Equal(commits[j].PreparedAt) {
+			return commits[i].PreparedAt.Before(commits[j].PreparedAt)
+		}
+		return commits[i].ID < commits[j].ID
+	})
+	return commits
+}
@@ -0,0 +1,140 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwoPhaseCommit(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	bm := NewBookmarkManager(path)
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 5}))
+
+	offsets := map[TopicPartition]int64{
+		NewTopicPartition("orders", "0"): 10,
+		NewTopicPartition("orders", "1"): 3,
+	}
+	require.NoError(t, bm.PrepareCommit(ctx, "tx-1", offsets))
+	assert.Error(t, bm.PrepareCommit(ctx, "tx-1", offsets))
+
+	// Prepared offsets leave the bookmarks untouched and survive restarts
+	b, err := bm.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), b.Offset)
+
+	restarted := NewBookmarkManager(path)
+	require.NoError(t, restarted.LoadFromFile(ctx))
+	pending := restarted.PendingCommits()
+	require.Len(t, pending, 1)
+	assert.Equal(t, "tx-1", pending[0].ID)
+	assert.Len(t, pending[0].Offsets, 2)
+
+	require.NoError(t, restarted.ConfirmCommit(ctx, "tx-1"))
+	assert.ErrorIs(t, restarted.ConfirmCommit(ctx, "tx-1"), ErrCommitNotFound)
+	assert.Empty(t, restarted.PendingCommits())
+
+	loaded := NewBookmarkManager(path)
+	require.NoError(t, loaded.LoadFromFile(ctx))
+	assert.Empty(t, loaded.PendingCommits())
+	for tp, offset := range offsets {
+		b, err := loaded.GetBookmark(tp.Topic, tp.Partition)
+		require.NoError(t, err)
+		assert.Equal(t, offset, b.Offset)
+	}
+}
+
+func TestTwoPhaseRollback(t *testing.T) {
+	ctx := context.Background()
+	bm := NewBookmarkManager(filepath.Join(t.TempDir(), "bookmarks.json"))
+
+	require.NoError(t, bm.PrepareCommit(ctx, "tx-1", map[TopicPartition]int64{NewTopicPartition("orders", "0"): 10}))
+	require.NoError(t, bm.Rollback(ctx, "tx-1"))
+	assert.ErrorIs(t, bm.Rollback(ctx, "tx-1"), ErrCommitNotFound)
+	assert.ErrorIs(t, bm.ConfirmCommit(ctx, "tx-1"), ErrCommitNotFound)
+	assert.Equal(t, 0, bm.Count())
+}
+
+func TestTwoPhaseConfirmOnce(t *testing.T) {
+	ctx := context.Background()
+	bm := NewBookmarkManager(filepath.Join(t.TempDir(), "bookmarks.json"))
+	require.NoError(t, bm.PrepareCommit(ctx, "tx-1", map[TopicPartition]int64{NewTopicPartition("orders", "0"): 10}))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = bm.ConfirmCommit(ctx, "tx-1")
+		}(i)
+	}
+	wg.Wait()
+
+	confirmed := 0
+	for _, err := range errs {
+		if err == nil {
+			confirmed++
+			continue
+		}
+		assert.ErrorIs(t, err, ErrCommitNotFound)
+	}
+	assert.Equal(t, 1, confirmed)
+
+	b, err := bm.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), b.Version)
+}
+
+func TestTwoPhaseKeepsCommitsThatFailToPromote(t *testing.T) {
+	ctx := context.Background()
+	bm := NewBookmarkManager(filepath.Join(t.TempDir(), "bookmarks.json"), WithRegressionPolicy(RegressionReject))
+	require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: 5}))
+	require.NoError(t, bm.PrepareCommit(ctx, "tx-1", map[TopicPartition]int64{NewTopicPartition("orders", "0"): 10}))
+
+	// The bookmark moved past the staged offset in the meantime
+	require.NoError(t, bm.UpdateOffset("orders", "0", 20))
+	assert.ErrorIs(t, bm.ConfirmCommit(ctx, "tx-1"), ErrOffsetRegression)
+
+	require.Len(t, bm.PendingCommits(), 1)
+	b, err := bm.GetBookmark("orders", "0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), b.Offset)
+	require.NoError(t, bm.Rollback(ctx, "tx-1"))
+}
+
+func TestTwoPhaseChecksumCoversPendingCommits(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	bm := NewBookmarkManager(path)
+	require.NoError(t, bm.PrepareCommit(ctx, "tx-1", map[TopicPartition]int64{NewTopicPartition("orders", "0"): 10}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"offset": 10`)
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(string(data), `"offset": 10`, `"offset": 99`, 1)), 0644))
+
+	err = NewBookmarkManager(path).LoadFromFile(ctx)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+}
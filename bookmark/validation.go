@@ -0,0 +1,132 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxKafkaTopicNameLength is the longest topic name accepted by Kafka
+const maxKafkaTopicNameLength = 249
+
+// ValidateTopicName checks a topic name against the Kafka naming rules: only
+// ASCII alphanumerics, '.', '_' and '-', at most 249 characters, and neither
+// "." nor "..".
+func ValidateTopicName(topic string) error {
+	if topic == "" {
+		return errors.New("topic must be a non-empty string")
+	}
+	if len(topic) > maxKafkaTopicNameLength {
+		return fmt.Errorf("topic name is %d characters long, exceeding the maximum of %d", len(topic), maxKafkaTopicNameLength)
+	}
+	if topic == "." || topic == ".." {
+		return fmt.Errorf("topic name cannot be %q", topic)
+	}
+	for _, c := range topic {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return fmt.Errorf("topic name %q contains the illegal character %q", topic, c)
+		}
+	}
+	return nil
+}
+
+// WithTopicValidation sets whether bookmark topics are validated against the
+// Kafka topic naming rules, which is enabled by default
+func WithTopicValidation(enabled bool) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.validateTopics = enabled
+	}
+}
+
+// WithFieldLimits sets the maximum length in bytes of bookmark topics and
+// partitions. A zero value disables the respective limit.
+func WithFieldLimits(maxTopicLength, maxPartitionLength int) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.maxTopicLength = maxTopicLength
+		bm.maxPartitionLength = maxPartitionLength
+	}
+}
+
+// WithTimestampTolerance sets how far bookmark timestamps may lie in the
+// future and in the past, such timestamps usually indicate an upstream mix-up
+// of seconds and milliseconds. Bookmarks outside the tolerance are rejected
+// when reject is true and logged otherwise. A zero value disables the
+// respective check.
+func WithTimestampTolerance(maxFuture, maxPast time.Duration, reject bool) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.maxTimestampFuture = maxFuture
+		bm.maxTimestampPast = maxPast
+		bm.rejectSkewedTimestamps = reject
+	}
+}
+
+// checkTimestamp checks the bookmark timestamp against the configured
+// tolerance
+func (bm *BookmarkManager) checkTimestamp(b *Bookmark) error {
+	now := time.Now()
+
+	var err error
+	switch {
+	case bm.maxTimestampFuture > 0 && b.Timestamp.Sub(now) > bm.maxTimestampFuture:
+		err = fmt.Errorf("timestamp %s is more than %s in the future", b.Timestamp.Format(time.RFC3339), bm.maxTimestampFuture)
+	case bm.maxTimestampPast > 0 && now.Sub(b.Timestamp) > bm.maxTimestampPast:
+		err = fmt.Errorf("timestamp %s is more than %s in the past", b.Timestamp.Format(time.RFC3339), bm.maxTimestampPast)
+	default:
+		return nil
+	}
+
+	if bm.rejectSkewedTimestamps {
+		return err
+	}
+	bm.log.Warnf("Bookmark %s/%s has a suspicious %v", b.Topic, b.Partition, err)
+	return nil
+}
+
+// validateBookmark validates a bookmark along with the validation rules
+// configured for the manager, logging rejected bookmarks
+func (bm *BookmarkManager) validateBookmark(b *Bookmark) error {
+	err := bm.checkBookmark(b)
+	if err != nil {
+		bm.log.With("topic", b.Topic, "partition", b.Partition).Warnf("Rejected invalid bookmark: %v", err)
+	}
+	return err
+}
+
+func (bm *BookmarkManager) checkBookmark(b *Bookmark) error {
+	if err := b.validate(); err != nil {
+		return err
+	}
+	if bm.maxTopicLength > 0 && len(b.Topic) > bm.maxTopicLength {
+		return fmt.Errorf("topic is %d bytes long, exceeding the maximum of %d", len(b.Topic), bm.maxTopicLength)
+	}
+	if bm.maxPartitionLength > 0 && len(b.Partition) > bm.maxPartitionLength {
+		return fmt.Errorf("partition is %d bytes long, exceeding the maximum of %d", len(b.Partition), bm.maxPartitionLength)
+	}
+	if bm.validateTopics {
+		if err := ValidateTopicName(b.Topic); err != nil {
+			return err
+		}
+	}
+	if bm.metadataSchema != nil {
+		if err := bm.metadataSchema.Validate(b.Metadata); err != nil {
+			return err
+		}
+	}
+	return bm.checkTimestamp(b)
+}
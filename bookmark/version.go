@@ -0,0 +1,83 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"fmt"
+	"time"
+)
+
+// nextVersion returns the version of a bookmark replacing previous, which is
+// nil for new bookmarks
+func nextVersion(previous *Bookmark) int64 {
+	if previous == nil {
+		return 1
+	}
+	return previous.Version + 1
+}
+
+// CompareAndSetOffset updates the offset of a bookmark only when it's still at
+// the version the caller last read, and returns its new version. A version of
+// zero expects no bookmark, or one saved before bookmarks had versions, and
+// creates the bookmark when it's missing. When the bookmark is at another
+// version it was changed by another writer in the meantime, for example one
+// that took over the partition after a rebalance, and an error wrapping
+// ErrBookmarkConflict is returned rather than overwriting the newer offset.
+// Setting the current offset changes nothing and returns the current version.
+func (bm *BookmarkManager) CompareAndSetOffset(topic, partition string, expectedVersion, offset int64) (int64, error) {
+	key := bm.generateKey(topic, partition)
+	now := time.Now()
+	created := &Bookmark{
+		Group:     key.Group,
+		Topic:     key.Topic,
+		Partition: key.Partition,
+		Offset:    offset,
+		Timestamp: now,
+		Metadata:  make(map[string]interface{}),
+		Version:   nextVersion(nil),
+	}
+	if err := bm.validateBookmark(created); err != nil {
+		return 0, fmt.Errorf("invalid bookmark: %w", err)
+	}
+
+	defer bm.dispatchEvents()
+	bm.mutex.RLock()
+	defer bm.mutex.RUnlock()
+
+	shard := bm.bookmarks.shard(key)
+	shard.mut.Lock()
+	defer shard.mut.Unlock()
+
+	bookmark, exists := shard.bookmarks[key]
+	var current int64
+	if exists {
+		current = bookmark.Version
+	}
+	if current != expectedVersion {
+		return current, fmt.Errorf("%w: bookmark %s/%s is at version %d, expected version %d", ErrBookmarkConflict, topic, partition, current, expectedVersion)
+	}
+
+	if !exists {
+		ev, _ := changeEvent(nil, created)
+		bm.emit(ev)
+		shard.setLocked(key, created)
+		bm.dirty.markBookmark(key)
+		return created.Version, nil
+	}
+	if err := bm.applyOffsetLocked(key, bookmark, offset, now, offsetOpts{}); err != nil {
+		return current, err
+	}
+	return bookmark.Version, nil
+}
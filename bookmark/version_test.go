@@ -0,0 +1,72 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareAndSetOffset(t *testing.T) {
+	tests := []struct {
+		name            string
+		history         []int64
+		expectedVersion int64
+		offset          int64
+		version         int64
+		err             error
+		stored          int64
+	}{
+		{name: "creates at version zero", expectedVersion: 0, offset: 5, version: 1, stored: 5},
+		{name: "conflicts at version zero when the bookmark exists", history: []int64{5}, expectedVersion: 0, offset: 6, version: 1, err: ErrBookmarkConflict, stored: 5},
+		{name: "conflicts when the bookmark is missing", expectedVersion: 1, offset: 6, err: ErrBookmarkConflict},
+		{name: "updates at the current version", history: []int64{5}, expectedVersion: 1, offset: 6, version: 2, stored: 6},
+		{name: "conflicts at a stale version", history: []int64{5, 6}, expectedVersion: 1, offset: 7, version: 2, err: ErrBookmarkConflict, stored: 6},
+		{name: "conflicts at a future version", history: []int64{5, 6}, expectedVersion: 3, offset: 7, version: 2, err: ErrBookmarkConflict, stored: 6},
+		{name: "keeps the version of the current offset", history: []int64{5, 6}, expectedVersion: 2, offset: 6, version: 2, stored: 6},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bm := NewBookmarkManager("")
+			for i, offset := range test.history {
+				if i == 0 {
+					require.NoError(t, bm.AddBookmark(&Bookmark{Topic: "orders", Partition: "0", Offset: offset}))
+					continue
+				}
+				require.NoError(t, bm.UpdateOffset("orders", "0", offset))
+			}
+
+			version, err := bm.CompareAndSetOffset("orders", "0", test.expectedVersion, test.offset)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.version, version)
+
+			b, err := bm.GetBookmark("orders", "0")
+			if test.version == 0 {
+				assert.ErrorIs(t, err, ErrBookmarkNotFound)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.stored, b.Offset)
+			assert.Equal(t, test.version, b.Version)
+		})
+	}
+}
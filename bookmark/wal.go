@@ -0,0 +1,240 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// Operations of write-ahead log entries
+const (
+	walOpPut        = "put"
+	walOpDelete     = "delete"
+	walOpPutSink    = "put_sink"
+	walOpDeleteSink = "delete_sink"
+)
+
+// walEntry is a line of the write-ahead log, which holds the full state of a
+// changed bookmark or sink position so that replaying an entry twice is
+// harmless
+type walEntry struct {
+	Op        string        `json:"op"`
+	Bookmark  *Bookmark     `json:"bookmark,omitempty"`
+	Group     string        `json:"group,omitempty"`
+	Topic     string        `json:"topic,omitempty"`
+	Partition string        `json:"partition,omitempty"`
+	Sink      *SinkPosition `json:"sink,omitempty"`
+	SinkName  string        `json:"sink_name,omitempty"`
+}
+
+// WithWAL makes saves append the changed bookmarks to a write-ahead log
+// (<path>.wal) instead of rewriting the bookmark file, which becomes a
+// snapshot that is rewritten and the log truncated once the log holds
+// compactAfter entries. Loads replay the log on top of the snapshot.
+func WithWAL(compactAfter int) ManagerOption {
+	return func(bm *BookmarkManager) {
+		bm.walEnabled = true
+		bm.walCompactAfter = compactAfter
+	}
+}
+
+func (bm *BookmarkManager) walPath() string {
+	return bm.filePath + ".wal"
+}
+
+// Compact writes the full state to the bookmark file and truncates the
+// write-ahead log, whose size is added to the bookmark_wal_reclaimed_bytes
+// counter. Without a write-ahead log it is the same as SaveToFile.
+func (bm *BookmarkManager) Compact(ctx context.Context) error {
+	return bm.save(ctx, true)
+}
+
+// walDue returns whether the pending changes should be appended to the log
+// rather than compacted into the snapshot. The caller must hold saveMutex.
+func (bm *BookmarkManager) walDue(compact bool) bool {
+	if !bm.walEnabled || compact {
+		return false
+	}
+	return bm.walEntries+bm.dirty.len() < bm.walCompactAfter
+}
+
+// appendWAL appends the changes since the last save to the log and syncs it.
+// The caller must hold saveMutex and mutex.
+func (bm *BookmarkManager) appendWAL() error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	entries := 0
+	for _, tp := range bm.dirty.bookmarkKeys() {
+		entry := walEntry{Op: walOpDelete, Group: tp.Group, Topic: tp.Topic, Partition: tp.Partition}
+		if b, exists := bm.bookmarks.get(tp); exists {
+			entry = walEntry{Op: walOpPut, Bookmark: b}
+		}
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to marshal write-ahead log entry: %w", err)
+		}
+		entries++
+	}
+	for _, sink := range bm.dirty.sinkKeys() {
+		entry := walEntry{Op: walOpDeleteSink, SinkName: sink}
+		if p, exists := bm.sinks[sink]; exists {
+			entry = walEntry{Op: walOpPutSink, Sink: p}
+		}
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to marshal write-ahead log entry: %w", err)
+		}
+		entries++
+	}
+	if entries == 0 {
+		return nil
+	}
+
+	f, err := os.OpenFile(bm.walPath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to append to write-ahead log: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync write-ahead log: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close write-ahead log: %w", err)
+	}
+
+	bm.walEntries += entries
+	bm.dirty.reset()
+	bm.refreshOwnerLock()
+	if bm.exportCache != "" {
+		bm.exportToCache(bm.sortedBookmarks())
+	}
+	return nil
+}
+
+// truncateWAL removes the log once its entries are part of the snapshot and
+// records the size of the removed log. The caller must hold saveMutex.
+func (bm *BookmarkManager) truncateWAL() error {
+	if !bm.walEnabled {
+		return nil
+	}
+	var reclaimed int64
+	if info, err := os.Stat(bm.walPath()); err == nil {
+		reclaimed = info.Size()
+	}
+	if err := os.Remove(bm.walPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		// Replaying the stale entries over a newer snapshot would move
+		// bookmarks backwards, so the next save compacts again
+		bm.walEntries = bm.walCompactAfter
+		return fmt.Errorf("failed to truncate write-ahead log: %w", err)
+	}
+	bm.walEntries = 0
+	if bm.metrics != nil && reclaimed > 0 {
+		bm.metrics.walReclaimed.Incr(reclaimed)
+	}
+	return nil
+}
+
+// replayWAL applies the entries of the log to the loaded state. An entry that
+// can't be decoded at the end of the log is the remainder of an interrupted
+// append and is cut off, so that later appends start on a new line. It
+// returns whether a log was found. The caller must hold saveMutex and mutex.
+func (bm *BookmarkManager) replayWAL(report *RecoveryReport) (bool, error) {
+	bm.walEntries = 0
+	if !bm.walEnabled {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(bm.walPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read write-ahead log: %w", err)
+	}
+
+	valid := 0
+	for line := 1; len(data[valid:]) > 0; line++ {
+		rest := data[valid:]
+		end := bytes.IndexByte(rest, '\n')
+		if end < 0 {
+			// An append interrupted before its final newline
+			report.Skipped++
+			report.problemf("discarded the partially written entry %d of %s", line, bm.walPath())
+			if bm.readOnly {
+				break
+			}
+			if err := os.Truncate(bm.walPath(), int64(valid)); err != nil {
+				return true, fmt.Errorf("failed to truncate write-ahead log: %w", err)
+			}
+			break
+		}
+		valid += end + 1
+
+		var entry walEntry
+		if err := json.Unmarshal(rest[:end], &entry); err != nil {
+			report.Skipped++
+			report.problemf("write-ahead log entry %d is invalid: %v", line, err)
+			continue
+		}
+		if err := bm.applyWALEntry(entry); err != nil {
+			report.Skipped++
+			report.problemf("write-ahead log entry %d is invalid: %v", line, err)
+			continue
+		}
+		report.Replayed++
+		bm.walEntries++
+	}
+	return true, nil
+}
+
+func (bm *BookmarkManager) applyWALEntry(entry walEntry) error {
+	switch entry.Op {
+	case walOpPut:
+		if entry.Bookmark == nil {
+			return errors.New("missing bookmark")
+		}
+		if err := entry.Bookmark.validate(); err != nil {
+			return err
+		}
+		if entry.Bookmark.Metadata == nil {
+			entry.Bookmark.Metadata = make(map[string]interface{})
+		}
+		bm.bookmarks.set(entry.Bookmark.TopicPartition(), entry.Bookmark)
+	case walOpDelete:
+		bm.bookmarks.remove(NewGroupTopicPartition(entry.Group, entry.Topic, entry.Partition))
+	case walOpPutSink:
+		if entry.Sink == nil {
+			return errors.New("missing sink position")
+		}
+		if err := entry.Sink.validate(); err != nil {
+			return err
+		}
+		bm.sinks[entry.Sink.Sink] = entry.Sink
+	case walOpDeleteSink:
+		delete(bm.sinks, entry.SinkName)
+	default:
+		return fmt.Errorf("unknown operation: %s", entry.Op)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmark
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// WriterIdentity identifies the manager instance that wrote a bookmark file
+type WriterIdentity struct {
+	Host       string `json:"host"`
+	PID        int    `json:"pid"`
+	InstanceID string `json:"instance_id"`
+}
+
+// newWriterIdentity creates the identity of the current process, where the
+// instance id is unique for every manager
+func newWriterIdentity() WriterIdentity {
+	host, _ := os.Hostname()
+
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+
+	return WriterIdentity{
+		Host:       host,
+		PID:        os.Getpid(),
+		InstanceID: hex.EncodeToString(id),
+	}
+}
+
+// String returns a string representation of the writer identity
+func (w WriterIdentity) String() string {
+	return fmt.Sprintf("%s/%d/%s", w.Host, w.PID, w.InstanceID)
+}
+
+// GetWriterIdentity returns the identity recorded as the writer of saved
+// bookmark files
+func (bm *BookmarkManager) GetWriterIdentity() WriterIdentity {
+	return bm.writer
+}
+
+// writerPath returns the path of the file recording the writer of the
+// bookmark file
+func (bm *BookmarkManager) writerPath() string {
+	return bm.filePath + ".writer"
+}
+
+// readFileWriter reads the identity of the last writer of the bookmark file,
+// returning nil when none was recorded. The caller must hold saveMutex.
+func (bm *BookmarkManager) readFileWriter() (*WriterIdentity, error) {
+	f, err := os.Open(bm.writerPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		bm.writerInfo = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var w WriterIdentity
+	if err := json.NewDecoder(f).Decode(&w); err != nil {
+		return nil, fmt.Errorf("failed to decode writer: %w", err)
+	}
+	bm.writerInfo = info
+	return &w, nil
+}
+
+// writerChanged reports whether the writer file was replaced since it was
+// last read or written by the manager, which is the case when another process
+// saved the bookmark file, so that saves don't read it every time. The caller
+// must hold saveMutex.
+func (bm *BookmarkManager) writerChanged() bool {
+	info, err := os.Stat(bm.writerPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return bm.writerInfo != nil
+	}
+	if err != nil || bm.writerInfo == nil {
+		return true
+	}
+	return !os.SameFile(info, bm.writerInfo) || !info.ModTime().Equal(bm.writerInfo.ModTime())
+}
+
+// recordWriter records the manager as the last writer of the bookmark file
+// unless it already is. The writer is kept out of the bookmark file, so that
+// saving the same state from another process or after a restart produces a
+// byte-identical file. The caller must hold saveMutex.
+func (bm *BookmarkManager) recordWriter() error {
+	if bm.lastWriter != nil && bm.lastWriter.InstanceID == bm.writer.InstanceID {
+		return nil
+	}
+	data, err := json.Marshal(bm.writer)
+	if err != nil {
+		return err
+	}
+	tempFile := bm.writerPath() + ".tmp"
+	if err := bm.writeTempFile(tempFile, data); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to write writer file: %w", err)
+	}
+	if err := os.Rename(tempFile, bm.writerPath()); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to write writer file: %w", err)
+	}
+	bm.lastWriter = &bm.writer
+	bm.writerInfo, _ = os.Stat(bm.writerPath())
+	return nil
+}
+
+// checkConcurrentWriter warns when the bookmark file was last written by a
+// writer other than the one expected, which usually means that multiple
+// pipelines share the same bookmark file. The writer is recorded in a file of
+// its own, so that the check works for every format and compression of the
+// bookmark file. The caller must hold saveMutex.
+func (bm *BookmarkManager) checkConcurrentWriter() {
+	if !bm.writerChanged() {
+		return
+	}
+	observed, err := bm.readFileWriter()
+	if err != nil {
+		bm.log.Warnf("Failed to read writer of bookmark file %s: %v", bm.filePath, err)
+		return
+	}
+	if observed == nil || observed.InstanceID == bm.writer.InstanceID {
+		return
+	}
+	if bm.lastWriter != nil && observed.InstanceID == bm.lastWriter.InstanceID {
+		return
+	}
+	bm.log.Warnf(
+		"Bookmark file %s was unexpectedly written by %s since it was last read or written by this manager (%s). Multiple pipelines may be sharing the same bookmark file, which results in lost bookmarks!",
+		bm.filePath, observed, bm.writer,
+	)
+	bm.lastWriter = observed
+}
@@ -0,0 +1,691 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"rpanda-connect-native-plugin-example/bookmark"
+)
+
+// withStore opens the selected store for the duration of fn
+func withStore(cmd *cobra.Command, opts *storeOptions, fn func(ctx context.Context, s *store) error) error {
+	ctx := cmd.Context()
+	s, err := opts.open(ctx)
+	if err != nil {
+		return err
+	}
+	if err := fn(ctx, s); err != nil {
+		_ = s.close()
+		return err
+	}
+	return s.close()
+}
+
+func printJSON(cmd *cobra.Command, v any) error {
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func newListCommand(opts *storeOptions) *cobra.Command {
+	var topic, output string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List bookmarks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withStore(cmd, opts, func(ctx context.Context, s *store) error {
+				bookmarks, err := s.List(ctx)
+				if err != nil {
+					return err
+				}
+				if topic != "" {
+					filtered := bookmarks[:0]
+					for _, b := range bookmarks {
+						if b.Topic == topic {
+							filtered = append(filtered, b)
+						}
+					}
+					bookmarks = filtered
+				}
+
+				// An in-memory manager renders the bookmarks of any store
+				view := bookmark.NewBookmarkManager("", bookmark.WithTopicValidation(false), bookmark.WithFieldLimits(0, 0))
+				if err := view.AddBookmarks(bookmarks); err != nil {
+					return err
+				}
+				if output == "table" {
+					return view.DumpTable(cmd.OutOrStdout())
+				}
+				return view.Export(cmd.OutOrStdout(), bookmark.ExchangeFormat(output))
+			})
+		},
+	}
+	cmd.Flags().StringVar(&topic, "topic", "", "only list the bookmarks of this topic")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "the output format: table, json, csv or yaml")
+	return cmd
+}
+
+func newGetCommand(opts *storeOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <topic> <partition>",
+		Short: "Print a bookmark as JSON",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withStore(cmd, opts, func(ctx context.Context, s *store) error {
+				b, err := s.Get(ctx, args[0], args[1])
+				if err != nil {
+					return err
+				}
+				return printJSON(cmd, b)
+			})
+		},
+	}
+}
+
+func newSetCommand(opts *storeOptions) *cobra.Command {
+	var metadata string
+	cmd := &cobra.Command{
+		Use:   "set <topic> <partition> <offset>",
+		Short: "Create or replace a bookmark",
+		Long: `Creates or replaces a bookmark. The offset is the last processed offset, or
+latest, earliest or none. The metadata of an existing bookmark is kept unless
+--metadata is set.`,
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			offset, err := bookmark.ParseOffset(args[2])
+			if err != nil {
+				return err
+			}
+			var meta map[string]interface{}
+			if metadata != "" {
+				if err := json.Unmarshal([]byte(metadata), &meta); err != nil {
+					return fmt.Errorf("invalid metadata: %w", err)
+				}
+			}
+
+			return withStore(cmd, opts, func(ctx context.Context, s *store) error {
+				if meta == nil {
+					if existing, err := s.Get(ctx, args[0], args[1]); err == nil {
+						meta = existing.Metadata
+					}
+				}
+				b, err := bookmark.NewBookmarkWithTimestamp(args[0], args[1], offset, time.Now(), meta)
+				if err != nil {
+					return err
+				}
+				if err := s.Put(ctx, b); err != nil {
+					return err
+				}
+				if err := s.save(ctx); err != nil {
+					return err
+				}
+				return printJSON(cmd, b)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&metadata, "metadata", "", "the metadata of the bookmark as a JSON object")
+	return cmd
+}
+
+func newDeleteCommand(opts *storeOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <topic> <partition>",
+		Short: "Remove a bookmark",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withStore(cmd, opts, func(ctx context.Context, s *store) error {
+				if err := s.Delete(ctx, args[0], args[1]); err != nil {
+					return err
+				}
+				return s.save(ctx)
+			})
+		},
+	}
+}
+
+// parseResetTime parses an RFC 3339 time or a Unix timestamp in milliseconds
+func parseResetTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	millis, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or Unix milliseconds", s)
+	}
+	return time.UnixMilli(millis), nil
+}
+
+func newResetToTimestampCommand(opts *storeOptions) *cobra.Command {
+	var brokers []string
+	var topic string
+	var dryRun, force bool
+	cmd := &cobra.Command{
+		Use:   "reset-to-timestamp <time>",
+		Short: "Move bookmarks to the first records at or after a time",
+		Long: `Moves bookmarks so that consumption resumes at the first record produced at
+or after the given time, which is RFC 3339 or Unix milliseconds. The offsets
+are looked up in the Kafka cluster of --brokers. Partitions without records
+after the time resume at their end. Bookmarks only move backwards with --force,
+which bypasses the on_regression policy of the pipelines using them.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			at, err := parseResetTime(args[0])
+			if err != nil {
+				return err
+			}
+			if len(brokers) == 0 {
+				return errors.New("--brokers is required to look up offsets")
+			}
+			client, err := kgo.NewClient(kgo.SeedBrokers(brokers...))
+			if err != nil {
+				return fmt.Errorf("failed to create client: %w", err)
+			}
+			defer client.Close()
+
+			return withStore(cmd, opts, func(ctx context.Context, s *store) error {
+				bookmarks, err := s.List(ctx)
+				if err != nil {
+					return err
+				}
+				topics := map[string]struct{}{}
+				for _, b := range bookmarks {
+					if topic == "" || b.Topic == topic {
+						topics[b.Topic] = struct{}{}
+					}
+				}
+				if len(topics) == 0 {
+					return errors.New("no bookmarks to reset")
+				}
+				names := make([]string, 0, len(topics))
+				for t := range topics {
+					names = append(names, t)
+				}
+				sort.Strings(names)
+
+				resolved, err := bookmark.ResolveOffsetsAt(ctx, kadm.NewClient(client), at, names...)
+				if err != nil {
+					return err
+				}
+
+				out := cmd.OutOrStdout()
+				var resets []*bookmark.Bookmark
+				rewinds := 0
+				for _, b := range bookmarks {
+					if _, selected := topics[b.Topic]; !selected {
+						continue
+					}
+					offset, exists := resolved[bookmark.NewTopicPartition(b.Topic, b.Partition)]
+					if !exists {
+						fmt.Fprintf(out, "%s/%s: partition not found, skipped\n", b.Topic, b.Partition)
+						continue
+					}
+					note := ""
+					if offset < b.Offset && !b.IsSentinel() && !bookmark.IsSentinelOffset(offset) {
+						note = " (moves back)"
+						rewinds++
+					}
+					fmt.Fprintf(out, "%s/%s: %s -> %s%s\n", b.Topic, b.Partition, bookmark.FormatOffset(b.Offset), bookmark.FormatOffset(offset), note)
+					reset, err := bookmark.NewBookmarkWithTimestamp(b.Topic, b.Partition, offset, time.Now(), b.Metadata)
+					if err != nil {
+						return err
+					}
+					reset.Group = b.Group
+					resets = append(resets, reset)
+				}
+				if dryRun {
+					return nil
+				}
+				if rewinds > 0 && !force {
+					return fmt.Errorf("%d bookmarks would move backwards, pass --force to rewind them", rewinds)
+				}
+				if rewinds > 0 {
+					fmt.Fprintf(cmd.ErrOrStderr(), "forced %d bookmarks backwards\n", rewinds)
+				}
+				for _, reset := range resets {
+					if err := s.Put(ctx, reset); err != nil {
+						return err
+					}
+				}
+				return s.save(ctx)
+			})
+		},
+	}
+	cmd.Flags().StringSliceVar(&brokers, "brokers", nil, "the seed brokers of the Kafka cluster the bookmarks refer to")
+	cmd.Flags().StringVar(&topic, "topic", "", "only reset the bookmarks of this topic")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the new offsets without changing the bookmarks")
+	cmd.Flags().BoolVar(&force, "force", false, "move bookmarks backwards regardless of the regression policy")
+	return cmd
+}
+
+func newValidateCommand(opts *storeOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check a bookmark file for corruption",
+		Long: `Loads a bookmark file and prints the recovery report. Exits with an error when
+entries were skipped or repaired, or the file had to be recovered from a
+backup.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			bm, err := opts.openFile(cmd.Context())
+			if err != nil {
+				return err
+			}
+			report := bm.GetRecoveryReport()
+			out := cmd.OutOrStdout()
+			fmt.Fprintln(out, report)
+			for _, problem := range report.Problems {
+				fmt.Fprintf(out, "  %s\n", problem)
+			}
+			if report.Skipped > 0 || report.Repaired > 0 || report.RecoveredFromBackup {
+				return errors.New("bookmark file is invalid")
+			}
+			return nil
+		},
+	}
+}
+
+func newResignCommand(opts *storeOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "resign",
+		Short: "Accept changes made to a bookmark file by hand",
+		Long: `Loads a bookmark file regardless of its checksum and saves it again with a new
+checksum, so that pipelines load a file that was deliberately edited by hand
+instead of failing. Edit files with the set and delete commands where possible.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			bm, err := opts.openFile(ctx, bookmark.WithIgnoreChecksum())
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			for _, problem := range bm.GetRecoveryReport().Problems {
+				fmt.Fprintf(out, "  %s\n", problem)
+			}
+			if err := bm.Compact(ctx); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "signed %d bookmarks in %s\n", bm.Count(), opts.file)
+			return nil
+		},
+	}
+}
+
+func newConvertCommand(opts *storeOptions) *cobra.Command {
+	var output, format, compression string
+	cmd := &cobra.Command{
+		Use:   "convert",
+		Short: "Rewrite a bookmark file in another format",
+		Long: `Rewrites a bookmark file, including the entries of its write-ahead log, with
+the given format and compression. Converting in place keeps the previous file
+as a backup.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			switch bookmark.FileFormat(format) {
+			case bookmark.FileFormatJSON, bookmark.FileFormatMsgpack:
+			default:
+				return fmt.Errorf("unknown format: %s", format)
+			}
+			switch bookmark.Compression(compression) {
+			case bookmark.CompressionNone, bookmark.CompressionGzip, bookmark.CompressionZstd:
+			default:
+				return fmt.Errorf("unknown compression: %s", compression)
+			}
+
+			ctx := cmd.Context()
+			src, err := opts.openFile(ctx)
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				output = opts.file
+			}
+
+			dst := bookmark.NewBookmarkManager(output,
+				bookmark.WithWAL(0),
+				bookmark.WithFileLock(opts.lockTimeout),
+				bookmark.WithFileFormat(bookmark.FileFormat(format)),
+				bookmark.WithCompression(bookmark.Compression(compression)),
+				bookmark.WithTopicValidation(false),
+			)
+			if err := dst.AddBookmarks(src.GetAllBookmarks()); err != nil {
+				return err
+			}
+			for _, p := range src.GetAllSinkPositions() {
+				if err := dst.RecordSinkPosition(p.Sink, p.Sequence, p.ID); err != nil {
+					return err
+				}
+			}
+			if err := dst.Compact(ctx); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "wrote %d bookmarks to %s\n", dst.Count(), output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "the file to write, the bookmark file itself by default")
+	cmd.Flags().StringVar(&format, "format", string(bookmark.FileFormatJSON), "the file format: json or msgpack")
+	cmd.Flags().StringVar(&compression, "compression", string(bookmark.CompressionNone), "the compression: none, gzip or zstd")
+	return cmd
+}
+
+func newCompactCommand(opts *storeOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "compact",
+		Short: "Fold the write-ahead log into the bookmark file",
+		Long: `Writes the bookmarks, including the entries of the write-ahead log, to the
+bookmark file and truncates the log, which a running pipeline otherwise only
+does once the log is due for compaction.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+			bm, err := opts.openFile(ctx)
+			if err != nil {
+				return err
+			}
+			var reclaimed int64
+			if info, err := os.Stat(opts.file + ".wal"); err == nil {
+				reclaimed = info.Size()
+			}
+			if err := bm.Compact(ctx); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "compacted %d bookmarks into %s, reclaimed %d bytes of write-ahead log\n", bm.Count(), opts.file, reclaimed)
+			return nil
+		},
+	}
+}
+
+func newQueryCommand(opts *storeOptions) *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "query <sql>",
+		Short: "Run a read-only SQL query over the bookmarks",
+		Long: `Runs a read-only SQLite query over a snapshot of the bookmarks, with the
+tables bookmarks (grp, topic, partition, offset, timestamp, metadata) and
+sink_positions (sink, sequence, id, timestamp), such as:
+
+  bookmarkctl query "SELECT topic, count(*), max(timestamp) FROM bookmarks GROUP BY topic"`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withStore(cmd, opts, func(ctx context.Context, s *store) error {
+				bookmarks, err := s.List(ctx)
+				if err != nil {
+					return err
+				}
+
+				// An in-memory manager snapshots the bookmarks of any store
+				view := bookmark.NewBookmarkManager("", bookmark.WithTopicValidation(false), bookmark.WithFieldLimits(0, 0))
+				if err := view.AddBookmarks(bookmarks); err != nil {
+					return err
+				}
+				if s.file != nil {
+					for _, p := range s.file.GetAllSinkPositions() {
+						if err := view.RecordSinkPosition(p.Sink, p.Sequence, p.ID); err != nil {
+							return err
+						}
+					}
+				}
+				result, err := view.Query(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				if output == "json" {
+					return printJSON(cmd, result)
+				}
+				return printQueryTable(cmd, result)
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "the output format: table or json")
+	return cmd
+}
+
+// printQueryTable prints the result of a query as a table, noting when its
+// rows were truncated
+func printQueryTable(cmd *cobra.Command, result *bookmark.QueryResult) error {
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(result.Columns, "\t")))
+	for _, row := range result.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			if v == nil {
+				cells[i] = "NULL"
+				continue
+			}
+			cells[i] = fmt.Sprint(v)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if result.Truncated {
+		fmt.Fprintf(cmd.ErrOrStderr(), "the result was truncated to %d rows\n", bookmark.MaxQueryRows)
+	}
+	return nil
+}
+
+func newReconcileCommand(opts *storeOptions) *cobra.Command {
+	mirror := &storeOptions{}
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Make a mirror store match the selected store",
+		Long: `Copies the bookmarks of the selected store that are missing or differ in a
+mirror store and removes those the selected store doesn't have, which repairs
+a mirror that fell behind or diverged. The mirror is a bookmark file, a bbolt
+database or a blob object.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if mirror.file == "" && mirror.boltFile == "" && mirror.blobURL == "" {
+				return errors.New("a mirror is required: set --mirror-file, --mirror-bolt-file or --mirror-blob-url")
+			}
+			mirror.lockTimeout = opts.lockTimeout
+
+			return withStore(cmd, opts, func(ctx context.Context, s *store) error {
+				m, err := mirror.open(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to open mirror: %w", err)
+				}
+				result, err := bookmark.Reconcile(ctx, s, m)
+				if err != nil {
+					_ = m.close()
+					return err
+				}
+				if err := m.close(); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "updated %d and removed %d bookmarks\n", result.Updated, result.Removed)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&mirror.file, "mirror-file", "", "a bookmark file to reconcile")
+	cmd.Flags().StringVar(&mirror.boltFile, "mirror-bolt-file", "", "a bbolt database to reconcile")
+	cmd.Flags().StringVar(&mirror.blobURL, "mirror-blob-url", "", "the URL of a blob object to reconcile")
+	return cmd
+}
+
+func newDiffCommand(opts *storeOptions) *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "diff <other-file>",
+		Short: "Compare the bookmark file with another bookmark file",
+		Long: `Lists the bookmarks that only one of the bookmark files has, prefixed with
+- when only the bookmark file has them and + when only the other file does,
+and those whose offset, timestamp or metadata differ, prefixed with ~.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			ours, err := opts.openFile(ctx)
+			if err != nil {
+				return err
+			}
+			other := &storeOptions{file: args[0], lockTimeout: opts.lockTimeout}
+			theirs, err := other.openFile(ctx)
+			if err != nil {
+				return err
+			}
+
+			diffs := ours.Diff(theirs)
+			if output == "json" {
+				return printJSON(cmd, diffs)
+			}
+			out := cmd.OutOrStdout()
+			for _, d := range diffs {
+				name := d.Topic + "/" + d.Partition
+				if d.Group != "" {
+					name = d.Group + ":" + name
+				}
+				switch d.Kind {
+				case bookmark.DiffOnlyOurs:
+					fmt.Fprintf(out, "- %s %s\n", name, bookmark.FormatOffset(d.Ours.Offset))
+				case bookmark.DiffOnlyTheirs:
+					fmt.Fprintf(out, "+ %s %s\n", name, bookmark.FormatOffset(d.Theirs.Offset))
+				case bookmark.DiffChanged:
+					fmt.Fprintf(out, "~ %s %s -> %s\n", name, bookmark.FormatOffset(d.Ours.Offset), bookmark.FormatOffset(d.Theirs.Offset))
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "the output format: text or json")
+	return cmd
+}
+
+func newMergeCommand(opts *storeOptions) *cobra.Command {
+	var strategy string
+	cmd := &cobra.Command{
+		Use:   "merge <other-file>",
+		Short: "Merge another bookmark file into the bookmark file",
+		Long: `Adopts the bookmarks of another bookmark file, for example one of a parallel
+instance or of another environment, and saves the bookmark file. Bookmarks
+only the other file has are always adopted, and the strategy decides between
+bookmarks both files have: max-offset keeps the higher offset,
+newest-timestamp the newer bookmark and prefer-ours the bookmark of the
+bookmark file.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			ours, err := opts.openFile(ctx)
+			if err != nil {
+				return err
+			}
+			other := &storeOptions{file: args[0], lockTimeout: opts.lockTimeout}
+			theirs, err := other.openFile(ctx)
+			if err != nil {
+				return err
+			}
+
+			n, err := ours.Merge(theirs, bookmark.MergeStrategy(strategy))
+			if err != nil {
+				return err
+			}
+			if err := ours.Compact(ctx); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "merged %d bookmarks into %s\n", n, opts.file)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&strategy, "strategy", string(bookmark.MergeMaxOffset), "how to decide between bookmarks both files have: max-offset, newest-timestamp or prefer-ours")
+	return cmd
+}
+
+func newSnapshotCommand(opts *storeOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Manage named snapshots of the bookmark file",
+		Long: `Takes, lists, restores and deletes named snapshots of the bookmark file,
+which are stored next to it as <file>.snap.<name>. Take one before a risky
+replay or config change to return to it afterwards.`,
+	}
+
+	create := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Take a snapshot of the bookmarks",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bm, err := opts.openFile(cmd.Context())
+			if err != nil {
+				return err
+			}
+			info, err := bm.Snapshot(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "took snapshot %s of %d bookmarks at %s\n", info.Name, bm.Count(), info.Path)
+			return nil
+		},
+	}
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the snapshots, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			bm := bookmark.NewBookmarkManager(opts.file)
+			snapshots, err := bm.ListSnapshots()
+			if err != nil {
+				return err
+			}
+			return printJSON(cmd, snapshots)
+		},
+	}
+
+	restore := &cobra.Command{
+		Use:   "restore <name>",
+		Short: "Replace the bookmarks with those of a snapshot",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bm, err := opts.openFile(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if err := bm.RestoreSnapshot(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "restored %d bookmarks from snapshot %s\n", bm.Count(), args[0])
+			return nil
+		},
+	}
+
+	del := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a snapshot",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return bookmark.NewBookmarkManager(opts.file).DeleteSnapshot(args[0])
+		},
+	}
+
+	cmd.AddCommand(create, list, restore, del)
+	return cmd
+}
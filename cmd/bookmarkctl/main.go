@@ -0,0 +1,293 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command bookmarkctl inspects and edits bookmark files and stores without
+// hand-editing them. Changes to the store of a running pipeline are
+// overwritten by its next save, so stop the pipeline first or use its admin
+// API instead.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/spf13/cobra"
+
+	"rpanda-connect-native-plugin-example/bookmark"
+)
+
+// storeOptions select the bookmark store commands operate on
+type storeOptions struct {
+	file        string
+	topicDir    string
+	mmapFile    string
+	boltFile    string
+	lockTimeout time.Duration
+	group       string
+
+	sqlDriver string
+	sqlDSN    string
+	sqlTable  string
+
+	kafkaBrokers []string
+	kafkaTopic   string
+
+	etcdEndpoints []string
+	etcdPrefix    string
+
+	dynamoDBTable  string
+	dynamoDBRegion string
+
+	blobURL string
+
+	consulAddress string
+	consulPrefix  string
+}
+
+// store is an opened bookmark store. file is set for bookmark files, which
+// support the commands that need the file itself.
+type store struct {
+	bookmark.BookmarkStore
+	file  *bookmark.BookmarkManager
+	close func() error
+}
+
+// groupStore addresses the bookmarks of a consumer group of a store
+type groupStore struct {
+	bookmark.GroupBookmarkStore
+	group string
+}
+
+// Get returns the bookmark of a topic and partition in the group
+func (g groupStore) Get(ctx context.Context, topic, partition string) (*bookmark.Bookmark, error) {
+	return g.GetInGroup(ctx, g.group, topic, partition)
+}
+
+// Put adds or replaces a bookmark, assigning bookmarks without a group to the
+// group
+func (g groupStore) Put(ctx context.Context, b *bookmark.Bookmark) error {
+	if b.Group == "" {
+		b.Group = g.group
+	}
+	return g.GroupBookmarkStore.Put(ctx, b)
+}
+
+// Delete removes the bookmark of a topic and partition in the group
+func (g groupStore) Delete(ctx context.Context, topic, partition string) error {
+	return g.DeleteInGroup(ctx, g.group, topic, partition)
+}
+
+// List returns the bookmarks of the group
+func (g groupStore) List(ctx context.Context) ([]*bookmark.Bookmark, error) {
+	bookmarks, err := g.GroupBookmarkStore.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filtered := bookmarks[:0]
+	for _, b := range bookmarks {
+		if b.Group == g.group {
+			filtered = append(filtered, b)
+		}
+	}
+	return filtered, nil
+}
+
+// save persists the changes, compacting bookmark files so that the changes
+// don't sit in a write-ahead log
+func (s *store) save(ctx context.Context) error {
+	if s.file != nil {
+		return s.file.Compact(ctx)
+	}
+	return s.Flush(ctx)
+}
+
+// openFile loads a bookmark file, replaying its write-ahead log and holding
+// the file lock while reading and writing so that a running pipeline never
+// reads a partial write
+func (o *storeOptions) openFile(ctx context.Context, opts ...bookmark.ManagerOption) (*bookmark.BookmarkManager, error) {
+	if o.file == "" {
+		return nil, errors.New("a bookmark file is required")
+	}
+	bm := bookmark.NewBookmarkManager(o.file, append([]bookmark.ManagerOption{
+		bookmark.WithWAL(0),
+		bookmark.WithFileLock(o.lockTimeout),
+	}, opts...)...)
+	if err := bm.LoadFromFile(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", o.file, err)
+	}
+	return bm, nil
+}
+
+// open opens the selected store, addressing the bookmarks of --group when
+// it's set
+func (o *storeOptions) open(ctx context.Context) (*store, error) {
+	s, err := o.openStore(ctx)
+	if err != nil || o.group == "" {
+		return s, err
+	}
+	grouped, ok := s.BookmarkStore.(bookmark.GroupBookmarkStore)
+	if !ok {
+		_ = s.close()
+		return nil, errors.New("the selected store doesn't keep consumer groups apart, --group is not supported")
+	}
+	s.BookmarkStore = groupStore{GroupBookmarkStore: grouped, group: o.group}
+	return s, nil
+}
+
+func (o *storeOptions) openStore(ctx context.Context) (*store, error) {
+	switch {
+	case o.sqlDSN != "":
+		m, err := bookmark.NewSQLBookmarkManager(ctx, o.sqlDriver, o.sqlDSN, o.sqlTable)
+		if err != nil {
+			return nil, err
+		}
+		return &store{BookmarkStore: m, close: m.Close}, nil
+	case len(o.kafkaBrokers) > 0:
+		m, err := bookmark.NewKafkaBookmarkManager(ctx, bookmark.KafkaBookmarkManagerConfig{
+			SeedBrokers:       o.kafkaBrokers,
+			Topic:             o.kafkaTopic,
+			Partitions:        -1,
+			ReplicationFactor: -1,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &store{BookmarkStore: m, close: func() error { return m.Close(context.Background()) }}, nil
+	case len(o.etcdEndpoints) > 0:
+		m, err := bookmark.NewEtcdBookmarkManager(ctx, bookmark.EtcdBookmarkManagerConfig{
+			Endpoints: o.etcdEndpoints,
+			Prefix:    o.etcdPrefix,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &store{BookmarkStore: m, close: func() error { return m.Close(context.Background()) }}, nil
+	case o.dynamoDBTable != "":
+		awsConf, err := config.LoadDefaultConfig(ctx, config.WithRegion(o.dynamoDBRegion))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		m, err := bookmark.NewDynamoDBBookmarkManager(ctx, bookmark.DynamoDBBookmarkManagerConfig{
+			Table: o.dynamoDBTable,
+			AWS:   awsConf,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &store{BookmarkStore: m, close: func() error { return nil }}, nil
+	case o.blobURL != "":
+		m, err := bookmark.NewBlobBookmarkManager(ctx, bookmark.BlobBookmarkManagerConfig{URL: o.blobURL})
+		if err != nil {
+			return nil, err
+		}
+		return &store{BookmarkStore: m, close: func() error { return m.Close(context.Background()) }}, nil
+	case o.consulAddress != "":
+		m, err := bookmark.NewConsulBookmarkManager(ctx, bookmark.ConsulBookmarkManagerConfig{
+			Address: o.consulAddress,
+			Prefix:  o.consulPrefix,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &store{BookmarkStore: m, close: func() error { return m.Close(context.Background()) }}, nil
+	case o.topicDir != "":
+		m, err := bookmark.NewTopicFileBookmarkManager(ctx, o.topicDir,
+			bookmark.WithWAL(0),
+			bookmark.WithFileLock(o.lockTimeout),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return &store{BookmarkStore: m, close: func() error { return nil }}, nil
+	case o.mmapFile != "":
+		m, err := bookmark.NewMmapBookmarkManager(o.mmapFile)
+		if err != nil {
+			return nil, err
+		}
+		return &store{BookmarkStore: m, close: m.Close}, nil
+	case o.boltFile != "":
+		m, err := bookmark.NewBoltBookmarkManager(o.boltFile)
+		if err != nil {
+			return nil, err
+		}
+		return &store{BookmarkStore: m, close: m.Close}, nil
+	}
+
+	bm, err := o.openFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &store{BookmarkStore: bm, file: bm, close: func() error { return nil }}, nil
+}
+
+func newRootCommand() *cobra.Command {
+	opts := &storeOptions{}
+	root := &cobra.Command{
+		Use:   "bookmarkctl",
+		Short: "Inspect and edit bookmark files and stores",
+		Long: `Inspects and edits the bookmarks of a bookmark file, or of a per-topic,
+mmap, bbolt, SQL, Kafka, etcd, Consul, DynamoDB or blob bookmark store when its
+flags are set. A running pipeline overwrites changes made to its store with its
+next save, so stop it first or use its admin API.`,
+		SilenceUsage: true,
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVarP(&opts.file, "file", "f", "bookmarks.json", "the bookmark file")
+	flags.StringVar(&opts.topicDir, "topic-dir", "", "a directory with a bookmark file per topic, which selects the per-topic store")
+	flags.StringVar(&opts.mmapFile, "mmap-file", "", "a memory mapped bookmark file, which selects the mmap store")
+	flags.StringVar(&opts.boltFile, "bolt-file", "", "a bbolt database of bookmarks, which selects the bbolt store")
+	flags.StringVar(&opts.group, "group", "", "the consumer group of the bookmarks to operate on, the default group when empty")
+	flags.DurationVar(&opts.lockTimeout, "lock-timeout", 10*time.Second, "how long to wait for the file lock of the bookmark file")
+	flags.StringVar(&opts.sqlDriver, "sql-driver", bookmark.SQLDriverPostgres, "the driver of the SQL store: postgres, mysql or sqlite")
+	flags.StringVar(&opts.sqlDSN, "sql-dsn", "", "the data source name of the SQL store, which selects the SQL store")
+	flags.StringVar(&opts.sqlTable, "sql-table", "bookmarks", "the table of the SQL store")
+	flags.StringSliceVar(&opts.kafkaBrokers, "kafka-brokers", nil, "the seed brokers of the Kafka store, which select the Kafka store")
+	flags.StringVar(&opts.kafkaTopic, "kafka-topic", "", "the compacted topic of the Kafka store")
+	flags.StringSliceVar(&opts.etcdEndpoints, "etcd-endpoints", nil, "the endpoints of the etcd store, which select the etcd store")
+	flags.StringVar(&opts.etcdPrefix, "etcd-prefix", "/bookmarks/", "the key prefix of the etcd store")
+	flags.StringVar(&opts.dynamoDBTable, "dynamodb-table", "", "the table of the DynamoDB store, which selects the DynamoDB store")
+	flags.StringVar(&opts.dynamoDBRegion, "dynamodb-region", "", "the AWS region of the DynamoDB store, defaults to the region of the AWS config")
+	flags.StringVar(&opts.blobURL, "blob-url", "", "the s3://, gs:// or azblob:// URL of the object of the blob store, which selects the blob store")
+	flags.StringVar(&opts.consulAddress, "consul-address", "", "the address of the Consul agent of the Consul store, which selects the Consul store")
+	flags.StringVar(&opts.consulPrefix, "consul-prefix", "bookmarks/", "the key prefix of the Consul store")
+
+	root.AddCommand(
+		newListCommand(opts),
+		newGetCommand(opts),
+		newSetCommand(opts),
+		newDeleteCommand(opts),
+		newResetToTimestampCommand(opts),
+		newValidateCommand(opts),
+		newResignCommand(opts),
+		newConvertCommand(opts),
+		newCompactCommand(opts),
+		newQueryCommand(opts),
+		newReconcileCommand(opts),
+		newDiffCommand(opts),
+		newMergeCommand(opts),
+		newSnapshotCommand(opts),
+	)
+	return root
+}
+
+func main() {
+	if err := newRootCommand().ExecuteContext(context.Background()); err != nil {
+		os.Exit(1)
+	}
+}
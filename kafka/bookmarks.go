@@ -0,0 +1,55 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"fmt"
+	"rpanda-connect-native-plugin-example/bookmark"
+)
+
+// advanceBookmarks moves the bookmarks of several partitions forward while
+// taking the lock of the manager once per operation. Offsets at or below the
+// current bookmark are ignored, and false is returned when no bookmark
+// changed.
+func advanceBookmarks(bm *bookmark.BookmarkManager, offsets map[bookmark.TopicPartition]int64) (bool, error) {
+	keys := make([]bookmark.TopicPartition, 0, len(offsets))
+	for tp := range offsets {
+		keys = append(keys, tp)
+	}
+	found, misses := bm.GetBookmarks(keys)
+
+	updates := make(map[bookmark.TopicPartition]int64, len(found))
+	for tp, b := range found {
+		if offset := offsets[tp]; b.Offset < offset {
+			updates[tp] = offset
+		}
+	}
+	added := make([]*bookmark.Bookmark, 0, len(misses))
+	for _, tp := range misses {
+		b, err := bookmark.NewBookmark(tp.Topic, tp.Partition, offsets[tp])
+		if err != nil {
+			return false, fmt.Errorf("invalid bookmark: %w", err)
+		}
+		added = append(added, b)
+	}
+
+	if err := bm.UpdateOffsets(updates); err != nil {
+		return false, fmt.Errorf("failed to update bookmarks: %w", err)
+	}
+	if err := bm.AddBookmarks(added); err != nil {
+		return false, fmt.Errorf("failed to add bookmarks: %w", err)
+	}
+	return len(updates) > 0 || len(added) > 0, nil
+}
@@ -0,0 +1,446 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rpanda-connect-native-plugin-example/bookmark"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	bkiFieldSeedBrokers     = "seed_brokers"
+	bkiFieldTopics          = "topics"
+	bkiFieldClientID        = "client_id"
+	bkiFieldStartFromOldest = "start_from_oldest"
+	bkiFieldStartFromTime   = "start_from_timestamp"
+	bkiFieldDiscovery       = "partition_discovery_interval"
+	bkiFieldNewPartitions   = "new_partition_offset"
+)
+
+func bookmarkedKafkaInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Summary("Consumes all partitions of Kafka topics, resuming from the offsets stored in a bookmark file.").
+		Description(`
+Partitions are consumed directly without a consumer group. Each partition resumes after the offset of its bookmark, bookmarks with the sentinel offsets `+"`latest`"+` (-1) and `+"`earliest`"+` (-2) start from the newest or oldest offset, and partitions without a bookmark or with the sentinel offset `+"`none`"+` (-3) start from the oldest or newest offset depending on `+"`start_from_oldest`"+`, or from the time of `+"`start_from_timestamp`"+` when it's set. Partitions are listed when the input connects and again every `+"`partition_discovery_interval`"+`, partitions added to a topic in the meantime get a bookmark with the sentinel offset of `+"`new_partition_offset`"+` and are consumed from there.
+
+Once a batch is acknowledged the bookmark of its partition is advanced to the last offset of the batch and saved, giving at-least-once delivery. Batches of a partition acknowledged out of order only advance the bookmark once all earlier batches are acknowledged as well.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- kafka_key
+- kafka_topic
+- kafka_partition
+- kafka_offset
+- kafka_timestamp_ms
+- All record headers
+`+"```"+``).
+		Fields(
+			service.NewStringListField(bkiFieldSeedBrokers).
+				Description("A list of broker addresses to connect to. Items containing commas are split into multiple addresses.").
+				Example([]string{"localhost:9092"}),
+			service.NewStringListField(bkiFieldTopics).
+				Description("The topics to consume from."),
+			service.NewStringField(bkiFieldClientID).
+				Description("An identifier for the client connection.").
+				Default("benthos").
+				Advanced(),
+			service.NewBoolField(bkiFieldStartFromOldest).
+				Description("Whether partitions without a bookmark start from the oldest available offset, otherwise they start from the newest.").
+				Default(true),
+			service.NewStringField(bkiFieldStartFromTime).
+				Description("An RFC 3339 time that partitions without a bookmark start from instead, resuming at the first records produced at or after it. The offsets are looked up when the input connects and stored as bookmarks.").
+				Example("2024-06-01T00:00:00Z").
+				Optional(),
+			service.NewDurationField(bkiFieldDiscovery).
+				Description("How often the topics are checked for partitions added after the input connected. Zero disables discovery, so that added partitions are consumed after a restart.").
+				Default("1m").
+				Advanced(),
+			service.NewStringEnumField(bkiFieldNewPartitions, "earliest", "latest").
+				Description("Where discovered partitions start from. Records produced to a new partition before it's discovered are skipped with `latest`.").
+				Default("earliest").
+				Advanced(),
+		).
+		Fields(bookmark.BookmarkFileManagerConfigFields()...)
+}
+
+func init() {
+	service.MustRegisterBatchInput("bookmarked_kafka", bookmarkedKafkaInputSpec(),
+		func(pConf *service.ParsedConfig, res *service.Resources) (service.BatchInput, error) {
+			rdr, err := newBookmarkedKafkaInputFromParsed(pConf, res)
+			if err != nil {
+				return nil, err
+			}
+			// Kafka has no concept of propagating nacks upstream, so batches
+			// are retried until they are acknowledged
+			return service.AutoRetryNacksBatched(rdr), nil
+		})
+}
+
+//------------------------------------------------------------------------------
+
+type bookmarkedKafkaInput struct {
+	seedBrokers     []string
+	topics          []string
+	clientID        string
+	startFromOldest bool
+	startFromTime   time.Time
+	discovery       time.Duration
+	newPartitions   int64
+
+	bm  *bookmark.BookmarkManager
+	log *service.Logger
+
+	mut           sync.Mutex
+	client        *kgo.Client
+	stopDiscovery context.CancelFunc
+
+	// pending holds fetched partitions not yet read, it is only accessed by
+	// ReadBatch, which is never called concurrently
+	pending []kgo.FetchTopicPartition
+
+	checkpoints *bookmark.CheckpointCoordinator
+}
+
+func newBookmarkedKafkaInputFromParsed(pConf *service.ParsedConfig, res *service.Resources) (*bookmarkedKafkaInput, error) {
+	k := &bookmarkedKafkaInput{
+		log: res.Logger(),
+	}
+
+	var err error
+	if k.seedBrokers, err = seedBrokersFromParsed(pConf, bkiFieldSeedBrokers); err != nil {
+		return nil, err
+	}
+	if k.topics, err = pConf.FieldStringList(bkiFieldTopics); err != nil {
+		return nil, err
+	}
+	if len(k.topics) == 0 {
+		return nil, errors.New("at least one topic must be specified")
+	}
+	if k.clientID, err = pConf.FieldString(bkiFieldClientID); err != nil {
+		return nil, err
+	}
+	if k.startFromOldest, err = pConf.FieldBool(bkiFieldStartFromOldest); err != nil {
+		return nil, err
+	}
+
+	if pConf.Contains(bkiFieldStartFromTime) {
+		s, err := pConf.FieldString(bkiFieldStartFromTime)
+		if err != nil {
+			return nil, err
+		}
+		if k.startFromTime, err = time.Parse(time.RFC3339, s); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", bkiFieldStartFromTime, err)
+		}
+	}
+
+	if k.discovery, err = pConf.FieldDuration(bkiFieldDiscovery); err != nil {
+		return nil, err
+	}
+	newPartitions, err := pConf.FieldString(bkiFieldNewPartitions)
+	if err != nil {
+		return nil, err
+	}
+	if k.newPartitions, err = bookmark.ParseOffset(newPartitions); err != nil {
+		return nil, err
+	}
+
+	if k.bm, err = bookmark.BookmarkFileManagerFromParsed(pConf, bookmark.WithResources(res)); err != nil {
+		return nil, err
+	}
+	k.checkpoints = bookmark.NewCheckpointCoordinator(k.bm)
+	if err := k.bm.Open(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to open bookmarks: %w", err)
+	}
+	return k, nil
+}
+
+// seedBrokersFromParsed reads a list of broker addresses, splitting items
+// containing commas
+func seedBrokersFromParsed(pConf *service.ParsedConfig, field string) ([]string, error) {
+	list, err := pConf.FieldStringList(field)
+	if err != nil {
+		return nil, err
+	}
+
+	var brokers []string
+	for _, item := range list {
+		for _, b := range strings.Split(item, ",") {
+			if b = strings.TrimSpace(b); b != "" {
+				brokers = append(brokers, b)
+			}
+		}
+	}
+	if len(brokers) == 0 {
+		return nil, errors.New("at least one seed broker must be specified")
+	}
+	return brokers, nil
+}
+
+// startOffsets returns the offset every partition of the topics resumes from
+func (k *bookmarkedKafkaInput) startOffsets(ctx context.Context, client *kgo.Client) (map[string]map[int32]kgo.Offset, error) {
+	admin := kadm.NewClient(client)
+	if !k.startFromTime.IsZero() {
+		if _, err := k.bm.SeedFromTimestamp(ctx, admin, k.startFromTime, k.topics...); err != nil {
+			return nil, err
+		}
+	}
+
+	ends, err := admin.ListEndOffsets(ctx, k.topics...)
+	if err != nil {
+		return nil, err
+	}
+	if err := ends.Error(); err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[string]map[int32]kgo.Offset)
+	ends.Each(func(o kadm.ListedOffset) {
+		if offsets[o.Topic] == nil {
+			offsets[o.Topic] = make(map[int32]kgo.Offset)
+		}
+
+		offset := kgo.NewOffset().AtEnd()
+		if k.startFromOldest {
+			offset = kgo.NewOffset().AtStart()
+		}
+		tp := bookmark.KafkaTopicPartition(o.Topic, o.Partition)
+		if b, err := k.bm.GetBookmark(tp.Topic, tp.Partition); err == nil {
+			switch b.Offset {
+			case bookmark.OffsetLatest:
+				offset = kgo.NewOffset().AtEnd()
+			case bookmark.OffsetEarliest:
+				offset = kgo.NewOffset().AtStart()
+			case bookmark.OffsetNone:
+			default:
+				// The bookmark is the last processed offset
+				offset = kgo.NewOffset().At(b.Offset + 1)
+			}
+		}
+		offsets[o.Topic][o.Partition] = offset
+	})
+	return offsets, nil
+}
+
+func (k *bookmarkedKafkaInput) Connect(ctx context.Context) error {
+	k.mut.Lock()
+	defer k.mut.Unlock()
+
+	if k.client != nil {
+		return nil
+	}
+
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(k.seedBrokers...),
+		kgo.ClientID(k.clientID),
+	)
+	if err != nil {
+		return err
+	}
+
+	offsets, err := k.startOffsets(ctx, client)
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("failed to list partitions: %w", err)
+	}
+	for topic, partitions := range offsets {
+		for partition, offset := range partitions {
+			k.log.Debugf("Consuming %s/%d from %v", topic, partition, offset)
+		}
+	}
+	client.AddConsumePartitions(offsets)
+
+	k.client = client
+	if k.stopDiscovery != nil {
+		// Stops the discovery of a client closed by ReadBatch
+		k.stopDiscovery()
+		k.stopDiscovery = nil
+	}
+	if k.discovery > 0 {
+		known := make(map[string]map[int32]struct{}, len(offsets))
+		for topic, partitions := range offsets {
+			known[topic] = make(map[int32]struct{}, len(partitions))
+			for partition := range partitions {
+				known[topic][partition] = struct{}{}
+			}
+		}
+		discoverCtx, cancel := context.WithCancel(context.Background())
+		k.stopDiscovery = cancel
+		go k.discoverPartitions(discoverCtx, client, known)
+	}
+	return nil
+}
+
+// discoverPartitions periodically lists the partitions of the topics and
+// starts consuming the partitions missing from known, seeding their bookmarks
+// so that they're tracked from the start
+func (k *bookmarkedKafkaInput) discoverPartitions(ctx context.Context, client *kgo.Client, known map[string]map[int32]struct{}) {
+	admin := kadm.NewClient(client)
+	ticker := time.NewTicker(k.discovery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		ends, err := admin.ListEndOffsets(ctx, k.topics...)
+		if errors.Is(err, kgo.ErrClientClosed) || ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			k.log.Warnf("Failed to discover partitions: %v", err)
+			continue
+		}
+
+		start := kgo.NewOffset().AtStart()
+		if k.newPartitions == bookmark.OffsetLatest {
+			start = kgo.NewOffset().AtEnd()
+		}
+		added := make(map[string]map[int32]kgo.Offset)
+		seeds := make(map[bookmark.TopicPartition]int64)
+		ends.Each(func(o kadm.ListedOffset) {
+			if o.Err != nil {
+				return
+			}
+			if _, exists := known[o.Topic][o.Partition]; exists {
+				return
+			}
+			if known[o.Topic] == nil {
+				known[o.Topic] = make(map[int32]struct{})
+			}
+			if added[o.Topic] == nil {
+				added[o.Topic] = make(map[int32]kgo.Offset)
+			}
+			known[o.Topic][o.Partition] = struct{}{}
+			added[o.Topic][o.Partition] = start
+			seeds[bookmark.KafkaTopicPartition(o.Topic, o.Partition)] = k.newPartitions
+		})
+		if len(seeds) == 0 {
+			continue
+		}
+
+		if _, err := k.bm.SeedBookmarks(seeds); err != nil {
+			k.log.Errorf("Failed to seed bookmarks of discovered partitions: %v", err)
+		} else if err := k.bm.RequestSave(ctx); err != nil {
+			k.log.Errorf("Failed to save bookmarks of discovered partitions: %v", err)
+		}
+		for topic, partitions := range added {
+			for partition := range partitions {
+				k.log.Infof("Discovered partition %s/%d, consuming from %s", topic, partition, bookmark.FormatOffset(k.newPartitions))
+			}
+		}
+		client.AddConsumePartitions(added)
+	}
+}
+
+func (k *bookmarkedKafkaInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	k.mut.Lock()
+	client := k.client
+	k.mut.Unlock()
+
+	if client == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	for len(k.pending) == 0 {
+		fetches := client.PollFetches(ctx)
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		if fetches.IsClientClosed() {
+			k.mut.Lock()
+			if k.client == client {
+				k.client = nil
+			}
+			k.mut.Unlock()
+			return nil, nil, service.ErrNotConnected
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			k.log.Errorf("Failed to fetch %s/%d: %v", topic, partition, err)
+		})
+		fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+			if len(p.Records) > 0 {
+				k.pending = append(k.pending, p)
+			}
+		})
+	}
+
+	p := k.pending[0]
+	k.pending = k.pending[1:]
+
+	batch := make(service.MessageBatch, 0, len(p.Records))
+	for _, r := range p.Records {
+		batch = append(batch, recordToMessage(r))
+	}
+
+	tp := bookmark.KafkaTopicPartition(p.Topic, p.Partition)
+	last := p.Records[len(p.Records)-1]
+	commit := k.checkpoints.TrackState(tp, last.Offset, int64(len(p.Records)), bookmark.PartitionState{
+		LeaderEpoch:   last.LeaderEpoch,
+		HighWatermark: p.HighWatermark,
+	})
+
+	return batch, func(ctx context.Context, err error) error {
+		if err != nil {
+			return nil
+		}
+		return commit(ctx)
+	}, nil
+}
+
+func recordToMessage(r *kgo.Record) *service.Message {
+	msg := service.NewMessage(r.Value)
+	msg.MetaSetMut("kafka_key", string(r.Key))
+	msg.MetaSetMut("kafka_topic", r.Topic)
+	msg.MetaSetMut("kafka_partition", int(r.Partition))
+	msg.MetaSetMut("kafka_offset", int(r.Offset))
+	msg.MetaSetMut("kafka_timestamp_ms", r.Timestamp.UnixMilli())
+	for _, h := range r.Headers {
+		msg.MetaSetMut(h.Key, string(h.Value))
+	}
+	return msg
+}
+
+func (k *bookmarkedKafkaInput) Close(ctx context.Context) error {
+	k.mut.Lock()
+	if k.stopDiscovery != nil {
+		k.stopDiscovery()
+		k.stopDiscovery = nil
+	}
+	if k.client != nil {
+		k.client.Close()
+		k.client = nil
+	}
+	k.mut.Unlock()
+
+	return k.bm.Close(ctx)
+}
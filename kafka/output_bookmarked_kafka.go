@@ -0,0 +1,241 @@
+// Copyright 2024 Redpanda Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"rpanda-connect-native-plugin-example/bookmark"
+	"sync"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+const (
+	bkoFieldSeedBrokers = "seed_brokers"
+	bkoFieldTopic       = "topic"
+	bkoFieldKey         = "key"
+	bkoFieldClientID    = "client_id"
+	bkoFieldMaxInFlight = "max_in_flight"
+	bkoFieldBatching    = "batching"
+)
+
+func bookmarkedKafkaOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Summary("Writes messages to a Kafka topic and records the offsets of the produced records as bookmarks.").
+		Description(`
+After every batch is acknowledged by the brokers the bookmark of each partition written to is advanced to the offset of the last record produced to it, and the bookmark file is saved. This allows downstream tools to verify delivery progress per partition, for example with a `+"`bookmark_stream`"+` input.`).
+		Fields(
+			service.NewStringListField(bkoFieldSeedBrokers).
+				Description("A list of broker addresses to connect to. Items containing commas are split into multiple addresses.").
+				Example([]string{"localhost:9092"}),
+			service.NewInterpolatedStringField(bkoFieldTopic).
+				Description("The topic to write messages to."),
+			service.NewInterpolatedStringField(bkoFieldKey).
+				Description("An optional key to populate for each message.").
+				Optional(),
+			service.NewStringField(bkoFieldClientID).
+				Description("An identifier for the client connection.").
+				Default("benthos").
+				Advanced(),
+			service.NewOutputMaxInFlightField().
+				Default(10),
+			service.NewBatchPolicyField(bkoFieldBatching),
+		).
+		Fields(bookmark.BookmarkFileManagerConfigFields()...)
+}
+
+func init() {
+	service.MustRegisterBatchOutput("bookmarked_kafka_output", bookmarkedKafkaOutputSpec(),
+		func(pConf *service.ParsedConfig, res *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = pConf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = pConf.FieldBatchPolicy(bkoFieldBatching); err != nil {
+				return
+			}
+			out, err = newBookmarkedKafkaOutputFromParsed(pConf, res)
+			return
+		})
+}
+
+//------------------------------------------------------------------------------
+
+type bookmarkedKafkaOutput struct {
+	seedBrokers []string
+	topic       *service.InterpolatedString
+	key         *service.InterpolatedString
+	clientID    string
+
+	bm  *bookmark.BookmarkManager
+	log *service.Logger
+
+	mut    sync.Mutex
+	client *kgo.Client
+
+	// commitMut serializes advancing and saving the bookmarks of concurrent
+	// batches
+	commitMut sync.Mutex
+}
+
+func newBookmarkedKafkaOutputFromParsed(pConf *service.ParsedConfig, res *service.Resources) (*bookmarkedKafkaOutput, error) {
+	o := &bookmarkedKafkaOutput{log: res.Logger()}
+
+	var err error
+	if o.seedBrokers, err = seedBrokersFromParsed(pConf, bkoFieldSeedBrokers); err != nil {
+		return nil, err
+	}
+	if o.topic, err = pConf.FieldInterpolatedString(bkoFieldTopic); err != nil {
+		return nil, err
+	}
+	if pConf.Contains(bkoFieldKey) {
+		if o.key, err = pConf.FieldInterpolatedString(bkoFieldKey); err != nil {
+			return nil, err
+		}
+	}
+	if o.clientID, err = pConf.FieldString(bkoFieldClientID); err != nil {
+		return nil, err
+	}
+
+	if o.bm, err = bookmark.BookmarkFileManagerFromParsed(pConf, bookmark.WithResources(res)); err != nil {
+		return nil, err
+	}
+	if err := o.bm.Open(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to open bookmarks: %w", err)
+	}
+	return o, nil
+}
+
+func (o *bookmarkedKafkaOutput) Connect(ctx context.Context) error {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	if o.client != nil {
+		return nil
+	}
+
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(o.seedBrokers...),
+		kgo.ClientID(o.clientID),
+	)
+	if err != nil {
+		return err
+	}
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		return fmt.Errorf("failed to connect to brokers: %w", err)
+	}
+
+	o.client = client
+	return nil
+}
+
+func (o *bookmarkedKafkaOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	o.mut.Lock()
+	client := o.client
+	o.mut.Unlock()
+
+	if client == nil {
+		return service.ErrNotConnected
+	}
+
+	topicExec := batch.InterpolationExecutor(o.topic)
+	var keyExec *service.MessageBatchInterpolationExecutor
+	if o.key != nil {
+		keyExec = batch.InterpolationExecutor(o.key)
+	}
+
+	records := make([]*kgo.Record, 0, len(batch))
+	for i, msg := range batch {
+		value, err := msg.AsBytes()
+		if err != nil {
+			return err
+		}
+		topic, err := topicExec.TryString(i)
+		if err != nil {
+			return fmt.Errorf("failed to interpolate topic: %w", err)
+		}
+
+		r := &kgo.Record{Topic: topic, Value: value}
+		if keyExec != nil {
+			key, err := keyExec.TryBytes(i)
+			if err != nil {
+				return fmt.Errorf("failed to interpolate key: %w", err)
+			}
+			r.Key = key
+		}
+		records = append(records, r)
+	}
+
+	results := client.ProduceSync(ctx, records...)
+
+	var batchErr *service.BatchError
+	highest := make(map[bookmark.TopicPartition]int64)
+	for i, res := range results {
+		if res.Err != nil {
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, res.Err)
+			}
+			batchErr.Failed(i, res.Err)
+			continue
+		}
+		tp := bookmark.KafkaTopicPartition(res.Record.Topic, res.Record.Partition)
+		if offset, exists := highest[tp]; !exists || res.Record.Offset > offset {
+			highest[tp] = res.Record.Offset
+		}
+	}
+
+	if err := o.commit(ctx, highest); err != nil {
+		o.log.Errorf("Failed to record produced offsets: %v", err)
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+// commit advances the bookmarks to the highest offsets produced and saves them
+func (o *bookmarkedKafkaOutput) commit(ctx context.Context, highest map[bookmark.TopicPartition]int64) error {
+	if len(highest) == 0 {
+		return nil
+	}
+
+	o.commitMut.Lock()
+	defer o.commitMut.Unlock()
+
+	changed, err := advanceBookmarks(o.bm, highest)
+	if err != nil || !changed {
+		return err
+	}
+	if err := o.bm.RequestSave(ctx); err != nil {
+		return fmt.Errorf("failed to save bookmarks: %w", err)
+	}
+	return nil
+}
+
+func (o *bookmarkedKafkaOutput) Close(ctx context.Context) error {
+	o.mut.Lock()
+	if o.client != nil {
+		o.client.Close()
+		o.client = nil
+	}
+	o.mut.Unlock()
+
+	return o.bm.Close(ctx)
+}
@@ -29,6 +29,7 @@ import (
 	// Add your plugin packages here
 	_ "rpanda-connect-native-plugin-example/aws"
 	_ "rpanda-connect-native-plugin-example/bookmark"
+	_ "rpanda-connect-native-plugin-example/kafka"
 )
 
 func main() {